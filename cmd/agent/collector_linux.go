@@ -0,0 +1,296 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gost-panel/internal/dto"
+)
+
+// clockTicks /proc/[pid]/stat 中时间字段的单位（USER_HZ，Linux 上固定为 100）
+const clockTicks = 100
+
+// gostVersionTTL Gost 版本缓存时间
+const gostVersionTTL = time.Hour
+
+// collector Linux 主机指标采集器
+// CPU 使用率和网络速率按两次采集的差值计算
+type collector struct {
+	gostBin  string
+	diskPath string
+
+	prevCPUIdle  uint64
+	prevCPUTotal uint64
+	prevRx       uint64
+	prevTx       uint64
+	prevAt       time.Time
+
+	gostVersion   string
+	gostVersionAt time.Time
+}
+
+// newCollector 创建采集器并记录 CPU、网络计数基准
+func newCollector(gostBin, diskPath string) (*collector, error) {
+	c := &collector{gostBin: gostBin, diskPath: diskPath}
+
+	idle, total, err := readCPU()
+	if err != nil {
+		return nil, err
+	}
+	rx, tx, err := readNetDev()
+	if err != nil {
+		return nil, err
+	}
+	c.prevCPUIdle, c.prevCPUTotal = idle, total
+	c.prevRx, c.prevTx = rx, tx
+	c.prevAt = time.Now()
+	return c, nil
+}
+
+// collect 采集一次主机指标
+func (c *collector) collect() (*dto.AgentReportReq, error) {
+	report := &dto.AgentReportReq{}
+	now := time.Now()
+
+	// CPU
+	idle, total, err := readCPU()
+	if err != nil {
+		return nil, err
+	}
+	if total > c.prevCPUTotal {
+		busy := float64((total - c.prevCPUTotal) - (idle - c.prevCPUIdle))
+		report.CPUPercent = busy / float64(total-c.prevCPUTotal) * 100
+	}
+	c.prevCPUIdle, c.prevCPUTotal = idle, total
+
+	// 负载
+	if report.Load1, err = readLoad1(); err != nil {
+		return nil, err
+	}
+
+	// 内存
+	memTotal, memUsed, err := readMem()
+	if err != nil {
+		return nil, err
+	}
+	report.MemTotal, report.MemUsed = int64(memTotal), int64(memUsed)
+
+	// 磁盘
+	var fs syscall.Statfs_t
+	if err = syscall.Statfs(c.diskPath, &fs); err != nil {
+		return nil, fmt.Errorf("读取磁盘 %s 失败: %w", c.diskPath, err)
+	}
+	report.DiskTotal = int64(fs.Blocks * uint64(fs.Bsize))
+	report.DiskUsed = int64((fs.Blocks - fs.Bfree) * uint64(fs.Bsize))
+
+	// 网络
+	rx, tx, err := readNetDev()
+	if err != nil {
+		return nil, err
+	}
+	if elapsed := now.Sub(c.prevAt).Seconds(); elapsed > 0 && rx >= c.prevRx && tx >= c.prevTx {
+		report.NetRxRate = int64(float64(rx-c.prevRx) / elapsed)
+		report.NetTxRate = int64(float64(tx-c.prevTx) / elapsed)
+	}
+	c.prevRx, c.prevTx, c.prevAt = rx, tx, now
+
+	// Gost 进程
+	report.GostUptime = c.gostUptime()
+	report.GostVersion = c.version(now)
+
+	return report, nil
+}
+
+// readCPU 读取 /proc/stat 中的 CPU 空闲和总时间
+func readCPU() (idle, total uint64, err error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("无法解析 /proc/stat")
+	}
+	for i, f := range fields[1:] {
+		v, _ := strconv.ParseUint(f, 10, 64)
+		total += v
+		// idle + iowait
+		if i == 3 || i == 4 {
+			idle += v
+		}
+	}
+	return idle, total, nil
+}
+
+// readLoad1 读取 1 分钟平均负载
+func readLoad1() (float64, error) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("无法解析 /proc/loadavg")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// readMem 读取内存总量和已用量（总量 - 可用量）
+func readMem() (total, used uint64, err error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var available uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, _ := strconv.ParseUint(fields[1], 10, 64)
+		switch fields[0] {
+		case "MemTotal:":
+			total = v * 1024
+		case "MemAvailable:":
+			available = v * 1024
+		}
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("无法解析 /proc/meminfo")
+	}
+	return total, total - available, nil
+}
+
+// readNetDev 读取除回环外所有网卡的累计收发字节数
+func readNetDev() (rx, tx uint64, err error) {
+	f, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, stats, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(stats)
+		if len(fields) < 9 {
+			continue
+		}
+		r, _ := strconv.ParseUint(fields[0], 10, 64)
+		t, _ := strconv.ParseUint(fields[8], 10, 64)
+		rx += r
+		tx += t
+	}
+	return rx, tx, scanner.Err()
+}
+
+// gostUptime 查找 Gost 进程并计算运行时长（秒），未运行返回 0
+func (c *collector) gostUptime() int64 {
+	pid := c.findGostPid()
+	if pid == "" {
+		return 0
+	}
+
+	data, err := os.ReadFile(filepath.Join("/proc", pid, "stat"))
+	if err != nil {
+		return 0
+	}
+	// 进程名可能包含空格，从最后一个 ")" 之后开始解析，starttime 为第 22 个字段
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 20 {
+		return 0
+	}
+	startTicks, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	data, err = os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0
+	}
+	fields = strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	sysUptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+
+	uptime := int64(sysUptime - float64(startTicks)/clockTicks)
+	if uptime < 0 {
+		return 0
+	}
+	return uptime
+}
+
+// findGostPid 按可执行文件路径或进程名查找 Gost 进程
+func (c *collector) findGostPid() string {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return ""
+	}
+
+	// comm 最长 15 个字符
+	name := filepath.Base(c.gostBin)
+	if len(name) > 15 {
+		name = name[:15]
+	}
+
+	for _, e := range entries {
+		pid := e.Name()
+		if _, err := strconv.Atoi(pid); err != nil {
+			continue
+		}
+		if exe, err := os.Readlink(filepath.Join("/proc", pid, "exe")); err == nil && exe == c.gostBin {
+			return pid
+		}
+		if comm, err := os.ReadFile(filepath.Join("/proc", pid, "comm")); err == nil && strings.TrimSpace(string(comm)) == name {
+			return pid
+		}
+	}
+	return ""
+}
+
+// version 获取 Gost 版本（执行 gost -V，结果缓存 1 小时）
+func (c *collector) version(now time.Time) string {
+	if c.gostVersion != "" && now.Sub(c.gostVersionAt) < gostVersionTTL {
+		return c.gostVersion
+	}
+
+	out, err := exec.Command(c.gostBin, "-V").Output()
+	if err != nil {
+		return c.gostVersion
+	}
+	// 输出示例: gost v3.0.0 (go1.22.0 linux/amd64)
+	for _, f := range strings.Fields(string(out)) {
+		if strings.HasPrefix(f, "v") {
+			c.gostVersion = f
+			c.gostVersionAt = now
+			break
+		}
+	}
+	return c.gostVersion
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+
+	"gost-panel/internal/dto"
+)
+
+// collector 非 Linux 平台不支持采集
+type collector struct{}
+
+// newCollector 创建采集器
+func newCollector(gostBin, diskPath string) (*collector, error) {
+	return nil, errors.New("Agent 仅支持 Linux 系统")
+}
+
+// collect 采集主机指标
+func (c *collector) collect() (*dto.AgentReportReq, error) {
+	return nil, errors.New("Agent 仅支持 Linux 系统")
+}
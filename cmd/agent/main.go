@@ -0,0 +1,111 @@
+// Package main Gost Panel 节点 Agent
+// 与 Gost 部署在同一台主机，定时采集 CPU、内存、磁盘、网络及 Gost 进程信息并上报到面板；
+// 开启 -tunnel 时主动与面板保持反向连接，供面板无法直连的节点（NAT、防火墙后）管理 Gost
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gost-panel/internal/config"
+	"gost-panel/internal/dto"
+)
+
+func main() {
+	var (
+		panelURL     string
+		token        string
+		gostBin      string
+		diskPath     string
+		gostAPI      string
+		interval     time.Duration
+		enableTunnel bool
+	)
+	flag.StringVar(&panelURL, "panel", "", "面板地址，如 http://panel.example.com:39100")
+	flag.StringVar(&token, "token", "", "节点 Agent 令牌（在面板节点详情中获取）")
+	flag.StringVar(&gostBin, "gost", "/usr/local/bin/gost-node", "Gost 可执行文件路径")
+	flag.StringVar(&diskPath, "disk", "/", "统计磁盘使用的挂载点")
+	flag.DurationVar(&interval, "interval", 15*time.Second, "上报间隔")
+	flag.BoolVar(&enableTunnel, "tunnel", false, "与面板保持反向连接（节点为 agent 连接方式时开启）")
+	flag.StringVar(&gostAPI, "gost-api", "http://127.0.0.1:39000/api", "本机 Gost API 地址（反向连接转发目标）")
+	flag.Parse()
+
+	if panelURL == "" || token == "" {
+		log.Fatal("必须指定 -panel 和 -token")
+	}
+
+	c, err := newCollector(gostBin, diskPath)
+	if err != nil {
+		log.Fatalf("初始化采集器失败: %v", err)
+	}
+
+	reportURL := strings.TrimRight(panelURL, "/") + "/api/v1/agent/report"
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	log.Printf("Gost Panel Agent %s 已启动，上报地址 %s，间隔 %s", config.Version, reportURL, interval)
+
+	if enableTunnel {
+		go newTunnel(panelURL, token, gostAPI).run()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	for {
+		select {
+		case <-ticker.C:
+			report, err := c.collect()
+			if err != nil {
+				log.Printf("采集指标失败: %v", err)
+				continue
+			}
+			report.AgentVersion = config.Version
+			if err = send(client, reportURL, token, report); err != nil {
+				log.Printf("上报指标失败: %v", err)
+			}
+		case <-quit:
+			log.Print("Gost Panel Agent 已停止")
+			return
+		}
+	}
+}
+
+// send 上报指标到面板
+func send(client *http.Client, url, token string, report *dto.AgentReportReq) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(dto.AgentTokenHeader, token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("面板返回状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
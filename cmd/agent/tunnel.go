@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gost-panel/internal/agent"
+	"gost-panel/internal/dto"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// tunnelMinBackoff 重连最小间隔
+	tunnelMinBackoff = time.Second
+	// tunnelMaxBackoff 重连最大间隔
+	tunnelMaxBackoff = time.Minute
+)
+
+// tunnel 到面板的反向连接
+// 面板无法直连本节点时，由 Agent 主动连接面板，代为请求本机 Gost API
+type tunnel struct {
+	panelURL string
+	token    string
+	gostAPI  string
+	client   *http.Client
+}
+
+// newTunnel 创建反向连接
+func newTunnel(panelURL, token, gostAPI string) *tunnel {
+	return &tunnel{
+		panelURL: strings.TrimRight(panelURL, "/"),
+		token:    token,
+		gostAPI:  strings.TrimRight(gostAPI, "/"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// run 保持连接，断开后按指数退避重连
+func (t *tunnel) run() {
+	backoff := tunnelMinBackoff
+	for {
+		start := time.Now()
+		err := t.serve()
+		log.Printf("反向连接已断开: %v", err)
+
+		// 连接保持超过 1 分钟视为正常断开，重置退避
+		if time.Since(start) > time.Minute {
+			backoff = tunnelMinBackoff
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > tunnelMaxBackoff {
+			backoff = tunnelMaxBackoff
+		}
+	}
+}
+
+// serve 建立连接并处理面板请求直到连接断开
+func (t *tunnel) serve() error {
+	wsURL := t.panelURL + agent.ConnectPath
+	if strings.HasPrefix(wsURL, "https://") {
+		wsURL = "wss://" + strings.TrimPrefix(wsURL, "https://")
+	} else {
+		wsURL = "ws://" + strings.TrimPrefix(wsURL, "http://")
+	}
+
+	cfg, err := websocket.NewConfig(wsURL, t.panelURL)
+	if err != nil {
+		return err
+	}
+	cfg.Header.Set(dto.AgentTokenHeader, t.token)
+
+	conn, err := websocket.DialConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	log.Printf("反向连接已建立: %s", wsURL)
+
+	var writeMu sync.Mutex
+	send := func(f *agent.Frame) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return websocket.JSON.Send(conn, f)
+	}
+
+	// 心跳
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(agent.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := send(&agent.Frame{Type: agent.FramePing}); err != nil {
+					_ = conn.Close()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		var f agent.Frame
+		if err = websocket.JSON.Receive(conn, &f); err != nil {
+			return err
+		}
+		if f.Type != agent.FrameRequest {
+			continue
+		}
+
+		go func(req agent.Frame) {
+			if err := send(t.forward(&req)); err != nil {
+				log.Printf("回复面板请求失败: %v", err)
+			}
+		}(f)
+	}
+}
+
+// forward 请求本机 Gost API 并封装响应
+func (t *tunnel) forward(f *agent.Frame) *agent.Frame {
+	resp := &agent.Frame{Type: agent.FrameResponse, ID: f.ID}
+
+	req, err := http.NewRequest(f.Method, t.gostAPI+f.Path, bytes.NewReader(f.Body))
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	for k, v := range f.Header {
+		req.Header.Set(k, v)
+	}
+
+	r, err := t.client.Do(req)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	resp.Status = r.StatusCode
+	resp.Body = body
+	return resp
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"flag"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"gost-panel/internal/config"
+	"gost-panel/internal/metrics"
 	"gost-panel/internal/model"
 	"gost-panel/internal/router"
 	"gost-panel/internal/service"
@@ -22,8 +24,10 @@ import (
 func main() {
 	// 解析命令行参数
 	var configPath string
+	var recomputeTraffic bool
 	flag.StringVar(&configPath, "c", "", "配置文件路径")
 	flag.StringVar(&configPath, "config", "", "配置文件路径")
+	flag.BoolVar(&recomputeTraffic, "recompute-traffic", false, "按规则流量重新计算节点与隧道累计流量后退出（请先停止面板服务）")
 	flag.Parse()
 
 	// 加载配置
@@ -64,6 +68,16 @@ func main() {
 	}
 	logger.Info("数据库迁移完成")
 
+	// 一次性修复累计流量
+	if recomputeTraffic {
+		result, err := service.NewTrafficService(db).RecomputeTotals()
+		if err != nil {
+			logger.Fatalf("重新计算累计流量失败: %v", err)
+		}
+		logger.Infof("重新计算累计流量完成: 规则 %d 条，更新节点 %d 个、隧道 %d 个", result.Rules, result.Nodes, result.Tunnels)
+		return
+	}
+
 	// 初始化默认管理员
 	if err = initDefaultAdmin(db, cfg); err != nil {
 		logger.Fatalf("初始化管理员失败: %v", err)
@@ -82,7 +96,21 @@ func main() {
 		Secret: cfg.JWT.Secret,
 		Expire: cfg.JWT.Expire,
 	}
-	r := router.NewRouter(db, jwtCfg)
+	// 节点任务调度器（健康检测、规则同步共用）
+	scheduler := service.NewNodeScheduler(db, cfg.Scheduler)
+
+	// 观察器上报缓冲服务（路由接收上报，后台批量写入）
+	observerService := service.NewObserverService(db)
+
+	// Gost 指标抓取服务（抓取任务由调度器执行）
+	gostMetricsService := service.NewGostMetricsService(db, scheduler)
+
+	// Prometheus 指标
+	if cfg.Metrics.Enabled {
+		initMetrics(cfg.Metrics, db, observerService, gostMetricsService)
+	}
+
+	r := router.NewRouter(db, jwtCfg, scheduler, observerService)
 	r.Setup(engine)
 
 	// 启动服务器
@@ -93,18 +121,35 @@ func main() {
 		}
 	}()
 
+	// 启动节点任务调度器
+	scheduler.Start()
+
 	// 启动节点健康检测服务
-	healthService := service.NewNodeHealthService(db)
+	healthService := service.NewNodeHealthService(db, scheduler)
 	healthService.Start()
 
 	// 启动规则状态同步服务
-	syncService := service.NewRuleSyncService(db)
+	syncService := service.NewRuleSyncService(db, scheduler)
 	syncService.Start()
 
+	// 启动 Gost 指标抓取
+	gostMetricsService.Start()
+
 	// 启动自动备份服务
 	backupService := service.NewBackupService(db)
 	backupService.Start()
 
+	// 启动流量历史汇总服务
+	trafficService := service.NewTrafficService(db)
+	trafficService.Start()
+
+	// 启动定时流量报表服务
+	reportService := service.NewReportService(db)
+	reportService.Start()
+
+	// 启动观察器上报批量写入
+	observerService.Start()
+
 	// 优雅关闭
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -114,8 +159,34 @@ func main() {
 
 	// 停止相关的后台服务
 	healthService.Stop()
-	syncService.Stop()
+	scheduler.Stop()
 	backupService.Stop()
+	trafficService.Stop()
+	reportService.Stop()
+	observerService.Stop()
+}
+
+// initMetrics 注册抓取时采集的指标，配置了单独监听地址时启动指标服务
+func initMetrics(cfg config.MetricsConfig, db *gorm.DB, observerService *service.ObserverService,
+	gostMetricsService *service.GostMetricsService) {
+	metrics.Register(
+		metrics.NewResourceCollector(db),
+		metrics.NewObserverCollector(observerService.IngestStats),
+		metrics.NewGostCollector(gostMetricsService.Snapshot),
+	)
+	if cfg.Listen == "" {
+		logger.Infof("Prometheus 指标接口: %s", cfg.Path)
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, metrics.Handler(cfg.Token))
+	go func() {
+		logger.Infof("Prometheus 指标服务启动在 %s%s", cfg.Listen, cfg.Path)
+		if err := http.ListenAndServe(cfg.Listen, mux); err != nil {
+			logger.Errorf("Prometheus 指标服务启动失败: %v", err)
+		}
+	}()
 }
 
 // initDatabase 初始化数据库
@@ -146,6 +217,21 @@ func initDatabase(cfg *config.Config) (*gorm.DB, error) {
 
 // autoMigrate 自动迁移数据库表结构
 func autoMigrate(db *gorm.DB) error {
+	// 新增期望运行状态字段前的旧库需要回填
+	backfillDesired := db.Migrator().HasTable(&model.GostRule{}) &&
+		!db.Migrator().HasColumn(&model.GostRule{}, "DesiredRunning")
+	// 新增服务计数器前的旧库规则流量为服务上报的累计值，需要初始化计数器
+	seedCounters := db.Migrator().HasTable(&model.GostRule{}) &&
+		!db.Migrator().HasTable(&model.ServiceCounter{})
+	// 服务计数器按节点区分前的旧库需要删除服务名称唯一索引并回填节点
+	migrateCounterNodes := db.Migrator().HasTable(&model.ServiceCounter{}) &&
+		!db.Migrator().HasColumn(&model.ServiceCounter{}, "NodeID")
+	if migrateCounterNodes && db.Migrator().HasIndex(&model.ServiceCounter{}, "idx_service_counters_service") {
+		if err := db.Migrator().DropIndex(&model.ServiceCounter{}, "idx_service_counters_service"); err != nil {
+			return err
+		}
+	}
+
 	// 1. 执行自动迁移（添加新字段）
 	if err := db.AutoMigrate(
 		&model.User{},
@@ -154,10 +240,66 @@ func autoMigrate(db *gorm.DB) error {
 		&model.GostTunnel{},
 		&model.OperationLog{},
 		&model.SystemConfig{},
+		&model.NodeStatusHistory{},
+		&model.NodeMetric{},
+		&model.TrafficSample{},
+		&model.ServiceCounter{},
+		&model.RuleClient{},
+		&model.GostMetricSample{},
 	); err != nil {
 		return err
 	}
 
+	// 2. 回填期望运行状态：迁移前处于运行中的规则和隧道视为期望运行
+	if backfillDesired {
+		if err := db.Model(&model.GostRule{}).Where("status = ?", model.RuleStatusRunning).
+			Update("desired_running", true).Error; err != nil {
+			return err
+		}
+		if err := db.Model(&model.GostTunnel{}).Where("status = ?", model.TunnelStatusRunning).
+			Update("desired_running", true).Error; err != nil {
+			return err
+		}
+	}
+
+	// 3. 初始化服务计数器
+	if seedCounters {
+		if err := service.SeedServiceCounters(db); err != nil {
+			return err
+		}
+	}
+
+	// 3.1 为旧服务计数器回填节点
+	if migrateCounterNodes {
+		if err := service.MigrateServiceCounterNodes(db); err != nil {
+			return err
+		}
+	}
+
+	// 4. 为缺少 Agent 令牌的节点生成令牌
+	var nodes []model.GostNode
+	if err := db.Where("agent_token = '' OR agent_token IS NULL").Find(&nodes).Error; err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := db.Model(&model.GostNode{}).Where("id = ?", node.ID).
+			Update("agent_token", service.NewAgentToken()).Error; err != nil {
+			return err
+		}
+	}
+
+	// 5. 为缺少观察器上报令牌的节点生成令牌（节点观察器地址由规则同步任务自动更新）
+	nodes = nil
+	if err := db.Where("observer_token = '' OR observer_token IS NULL").Find(&nodes).Error; err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := db.Model(&model.GostNode{}).Where("id = ?", node.ID).
+			Update("observer_token", service.NewObserverToken()).Error; err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -188,6 +330,10 @@ func initSystemConfig(db *gorm.DB) error {
 			LogLevel:             "info",
 			AutoBackup:           false,
 			BackupRetentionCount: 7,
+
+			TrafficMinuteRetentionDays: 2,
+			TrafficHourRetentionDays:   30,
+			TrafficDayRetentionDays:    365,
 		}
 		if err := db.Create(sysConfig).Error; err != nil {
 			return err
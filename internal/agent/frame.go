@@ -0,0 +1,39 @@
+// Package agent 节点 Agent 反向连接
+// 面板无法直连的节点（NAT、防火墙后）由节点 Agent 主动与面板建立 WebSocket 长连接，
+// 面板发往 Gost API 的 HTTP 请求封装为帧经此连接转发，由 Agent 在本机代为请求
+package agent
+
+import "time"
+
+// ConnectPath Agent 反向连接地址（相对面板根路径）
+const ConnectPath = "/api/v1/agent/connect"
+
+const (
+	// PingInterval Agent 心跳间隔
+	PingInterval = 30 * time.Second
+	// readTimeout 超过该时间未收到任何帧视为连接断开
+	readTimeout = 3 * PingInterval
+	// writeTimeout 单帧写入超时
+	writeTimeout = 10 * time.Second
+)
+
+// FrameType 帧类型
+type FrameType string
+
+const (
+	FrameRequest  FrameType = "request"  // 面板 -> Agent：HTTP 请求
+	FrameResponse FrameType = "response" // Agent -> 面板：HTTP 响应
+	FramePing     FrameType = "ping"     // Agent -> 面板：心跳
+)
+
+// Frame 反向连接上传输的消息
+type Frame struct {
+	Type   FrameType         `json:"type"`
+	ID     uint64            `json:"id,omitempty"`     // 请求 ID，响应帧与请求帧一致
+	Method string            `json:"method,omitempty"` // 请求方法
+	Path   string            `json:"path,omitempty"`   // 请求路径（相对 Gost API 根路径，含查询参数）
+	Header map[string]string `json:"header,omitempty"` // 请求头
+	Body   []byte            `json:"body,omitempty"`   // 请求/响应体
+	Status int               `json:"status,omitempty"` // 响应状态码
+	Error  string            `json:"error,omitempty"`  // Agent 请求本机 Gost API 失败时的错误信息
+}
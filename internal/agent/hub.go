@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"gost-panel/pkg/gost"
+
+	"golang.org/x/net/websocket"
+)
+
+// ErrNotConnected 节点 Agent 未连接
+var ErrNotConnected = errors.New("节点 Agent 未连接")
+
+// Hub 管理所有节点 Agent 的反向连接，每个节点最多保留一个连接
+type Hub struct {
+	mu       sync.RWMutex
+	sessions map[uint]*Session
+}
+
+// defaultHub 全局连接管理器
+var defaultHub = NewHub()
+
+// Default 获取全局连接管理器
+func Default() *Hub {
+	return defaultHub
+}
+
+// NewHub 创建连接管理器
+func NewHub() *Hub {
+	return &Hub{sessions: make(map[uint]*Session)}
+}
+
+// Serve 登记节点连接并阻塞处理直到连接断开
+// 同一节点的旧连接（如 Agent 重连前未正常断开）会被关闭
+func (h *Hub) Serve(nodeID uint, remoteAddr string, conn *websocket.Conn) error {
+	s := newSession(nodeID, remoteAddr, conn)
+
+	h.mu.Lock()
+	old := h.sessions[nodeID]
+	h.sessions[nodeID] = s
+	h.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	err := s.serve()
+
+	h.mu.Lock()
+	if h.sessions[nodeID] == s {
+		delete(h.sessions, nodeID)
+	}
+	h.mu.Unlock()
+
+	return err
+}
+
+// Session 获取节点当前连接
+func (h *Hub) Session(nodeID uint) *Session {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.sessions[nodeID]
+}
+
+// Connected 节点 Agent 是否已连接
+func (h *Hub) Connected(nodeID uint) bool {
+	return h.Session(nodeID) != nil
+}
+
+// Disconnect 断开节点连接（节点删除、切换为直连或令牌重置时调用）
+func (h *Hub) Disconnect(nodeID uint) {
+	if s := h.Session(nodeID); s != nil {
+		s.Close()
+	}
+}
+
+// Transport 返回经节点反向连接发送请求的 http.RoundTripper
+// 每次请求时查找节点当前连接，Agent 重连后无需重新创建客户端
+func (h *Hub) Transport(nodeID uint) http.RoundTripper {
+	return &transport{hub: h, nodeID: nodeID}
+}
+
+// transport 反向连接 HTTP 传输
+type transport struct {
+	hub    *Hub
+	nodeID uint
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s := t.hub.Session(t.nodeID)
+	if s == nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		// 未连接时重试无意义，标记为永久错误
+		return nil, gost.Permanent(ErrNotConnected)
+	}
+	return s.RoundTrip(req)
+}
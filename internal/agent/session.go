@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// ErrSessionClosed 连接已断开
+var ErrSessionClosed = errors.New("节点 Agent 连接已断开")
+
+// Session 单个节点 Agent 的反向连接
+// 同一连接上的请求按 ID 多路复用，响应可乱序返回
+type Session struct {
+	nodeID     uint
+	remoteAddr string
+	conn       *websocket.Conn
+
+	writeMu sync.Mutex
+	nextID  uint64
+
+	mu      sync.Mutex
+	pending map[uint64]chan *Frame
+
+	connectedAt time.Time
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// newSession 创建连接会话
+func newSession(nodeID uint, remoteAddr string, conn *websocket.Conn) *Session {
+	return &Session{
+		nodeID:      nodeID,
+		remoteAddr:  remoteAddr,
+		conn:        conn,
+		pending:     make(map[uint64]chan *Frame),
+		connectedAt: time.Now(),
+		done:        make(chan struct{}),
+	}
+}
+
+// ConnectedAt 连接建立时间
+func (s *Session) ConnectedAt() time.Time {
+	return s.connectedAt
+}
+
+// RemoteAddr Agent 地址
+func (s *Session) RemoteAddr() string {
+	return s.remoteAddr
+}
+
+// serve 读取 Agent 发来的帧直到连接断开
+func (s *Session) serve() error {
+	defer s.Close()
+
+	for {
+		_ = s.conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		var f Frame
+		if err := websocket.JSON.Receive(s.conn, &f); err != nil {
+			return err
+		}
+
+		if f.Type != FrameResponse {
+			continue
+		}
+
+		s.mu.Lock()
+		ch, ok := s.pending[f.ID]
+		delete(s.pending, f.ID)
+		s.mu.Unlock()
+
+		if ok {
+			ch <- &f
+		}
+	}
+}
+
+// Close 关闭连接，等待中的请求立即失败
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		_ = s.conn.Close()
+	})
+}
+
+// RoundTrip 将 HTTP 请求经反向连接转发给 Agent 并等待响应
+func (s *Session) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	header := make(map[string]string, len(req.Header))
+	for k := range req.Header {
+		header[k] = req.Header.Get(k)
+	}
+
+	id := atomic.AddUint64(&s.nextID, 1)
+	ch := make(chan *Frame, 1)
+
+	s.mu.Lock()
+	s.pending[id] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	err := s.send(&Frame{
+		Type:   FrameRequest,
+		ID:     id,
+		Method: req.Method,
+		Path:   req.URL.RequestURI(),
+		Header: header,
+		Body:   body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case f := <-ch:
+		if f.Error != "" {
+			return nil, fmt.Errorf("节点 Agent 请求 Gost API 失败: %s", f.Error)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+			StatusCode:    f.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(f.Body)),
+			ContentLength: int64(len(f.Body)),
+			Request:       req,
+		}, nil
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-s.done:
+		return nil, ErrSessionClosed
+	}
+}
+
+// send 写入一帧，多个请求并发写入时串行化
+func (s *Session) send(f *Frame) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	select {
+	case <-s.done:
+		return ErrSessionClosed
+	default:
+	}
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return websocket.JSON.Send(s.conn, f)
+}
@@ -14,10 +14,15 @@ var Version = "dev"
 
 // Config 应用配置结构
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Log      LogConfig      `mapstructure:"log"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Log       LogConfig       `mapstructure:"log"`
+	Health    HealthConfig    `mapstructure:"health"`
+	Sync      SyncConfig      `mapstructure:"sync"`
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
 }
 
 // ServerConfig 服务器配置
@@ -45,6 +50,40 @@ type LogConfig struct {
 	Output string `mapstructure:"output"`
 }
 
+// HealthConfig 节点健康检测配置
+type HealthConfig struct {
+	Interval          int `mapstructure:"interval"`           // 检测间隔（秒）
+	Timeout           int `mapstructure:"timeout"`            // 单次检测超时（秒）
+	FailureThreshold  int `mapstructure:"failure_threshold"`  // 连续失败多少次判定离线
+	RecoveryThreshold int `mapstructure:"recovery_threshold"` // 连续成功多少次判定恢复在线
+}
+
+// SyncConfig 规则状态同步配置
+type SyncConfig struct {
+	Interval int `mapstructure:"interval"` // 同步间隔（秒）
+}
+
+// SchedulerConfig 后台任务调度配置
+type SchedulerConfig struct {
+	Workers    int `mapstructure:"workers"`     // 工作协程数
+	QueueSize  int `mapstructure:"queue_size"`  // 任务队列容量
+	MaxBackoff int `mapstructure:"max_backoff"` // 不可达节点的最大退避时间（秒）
+}
+
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	// SecretKey 加密存储节点凭据（如 SSH 密码、私钥）的密钥，为空时使用 jwt.secret；设置后修改将导致已保存的凭据无法解密
+	SecretKey string `mapstructure:"secret_key"`
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"` // 是否开启指标接口
+	Path    string `mapstructure:"path"`    // 指标接口路径
+	Listen  string `mapstructure:"listen"`  // 单独监听地址（如 ":9464"），为空时挂载在面板端口
+	Token   string `mapstructure:"token"`   // 访问令牌（Authorization: Bearer），为空时不校验
+}
+
 // 全局配置实例
 var cfg *Config
 
@@ -128,6 +167,46 @@ func setDefaults(cfg *Config) {
 	if cfg.Log.Output == "" {
 		cfg.Log.Output = "./logs/app.log"
 	}
+
+	// 健康检测默认配置
+	if cfg.Health.Interval <= 0 {
+		cfg.Health.Interval = 5
+	}
+	if cfg.Health.Timeout <= 0 {
+		cfg.Health.Timeout = 5
+	}
+	if cfg.Health.FailureThreshold <= 0 {
+		cfg.Health.FailureThreshold = 3
+	}
+	if cfg.Health.RecoveryThreshold <= 0 {
+		cfg.Health.RecoveryThreshold = 2
+	}
+
+	// 规则同步默认配置
+	if cfg.Sync.Interval <= 0 {
+		cfg.Sync.Interval = 5
+	}
+
+	// 调度器默认配置
+	if cfg.Scheduler.Workers <= 0 {
+		cfg.Scheduler.Workers = 16
+	}
+	if cfg.Scheduler.QueueSize <= 0 {
+		cfg.Scheduler.QueueSize = 512
+	}
+	if cfg.Scheduler.MaxBackoff <= 0 {
+		cfg.Scheduler.MaxBackoff = 60
+	}
+
+	// 指标默认配置
+	if cfg.Metrics.Path == "" {
+		cfg.Metrics.Path = "/metrics"
+	}
+
+	// 安全默认配置
+	if cfg.Security.SecretKey == "" {
+		cfg.Security.SecretKey = cfg.JWT.Secret
+	}
 }
 
 // Get 获取全局配置实例
@@ -0,0 +1,27 @@
+package dto
+
+// ==================== 节点 Agent 相关 ====================
+
+// AgentTokenHeader Agent 上报认证请求头
+const AgentTokenHeader = "X-Agent-Token"
+
+// AgentReportReq Agent 上报的主机指标
+type AgentReportReq struct {
+	CPUPercent   float64 `json:"cpu_percent"`   // CPU 使用率 (%)
+	Load1        float64 `json:"load1"`         // 1 分钟平均负载
+	MemTotal     int64   `json:"mem_total"`     // 内存总量 (bytes)
+	MemUsed      int64   `json:"mem_used"`      // 已用内存 (bytes)
+	DiskTotal    int64   `json:"disk_total"`    // 磁盘总量 (bytes)
+	DiskUsed     int64   `json:"disk_used"`     // 已用磁盘 (bytes)
+	NetRxRate    int64   `json:"net_rx_rate"`   // 网络接收速率 (bytes/s)
+	NetTxRate    int64   `json:"net_tx_rate"`   // 网络发送速率 (bytes/s)
+	GostUptime   int64   `json:"gost_uptime"`   // Gost 进程运行时长 (秒)，0 表示未运行
+	GostVersion  string  `json:"gost_version"`  // Gost 版本
+	AgentVersion string  `json:"agent_version"` // Agent 版本
+}
+
+// AgentTokenResp Agent 令牌
+type AgentTokenResp struct {
+	NodeID     uint   `json:"node_id"`
+	AgentToken string `json:"agent_token"`
+}
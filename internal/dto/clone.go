@@ -0,0 +1,37 @@
+package dto
+
+// ==================== 节点克隆相关 ====================
+
+// 克隆结果状态
+const (
+	CloneStatusCloned = "cloned" // 克隆成功
+	CloneStatusFailed = "failed" // 克隆失败
+)
+
+// NodeCloneReq 克隆节点请求
+type NodeCloneReq struct {
+	TargetNodeID   uint `json:"target_node_id" binding:"required"` // 目标节点 ID
+	IncludeTunnels bool `json:"include_tunnels"`                   // 是否同时克隆隧道（及隧道转发规则）
+	Linked         bool `json:"linked"`                            // 是否保持联动，源规则修改时同步到副本
+}
+
+// ClonePortMapping 单个规则/隧道的克隆结果及端口映射
+type ClonePortMapping struct {
+	ResourceType string `json:"resource_type"`       // 资源类型: rule, tunnel
+	SourceID     uint   `json:"source_id"`           // 源资源 ID
+	TargetID     uint   `json:"target_id,omitempty"` // 副本资源 ID
+	Name         string `json:"name"`                // 资源名称
+	SourcePort   int    `json:"source_port"`         // 源端口（规则监听端口或隧道 Relay 端口）
+	TargetPort   int    `json:"target_port"`         // 副本端口
+	Remapped     bool   `json:"remapped"`            // 端口是否因冲突被重映射
+	Status       string `json:"status"`              // 克隆状态: cloned, failed
+	Error        string `json:"error,omitempty"`     // 失败原因或启动副本时的错误
+}
+
+// NodeCloneResp 克隆节点结果
+type NodeCloneResp struct {
+	SourceNodeID uint               `json:"source_node_id"`
+	TargetNodeID uint               `json:"target_node_id"`
+	Tunnels      []ClonePortMapping `json:"tunnels"`
+	Rules        []ClonePortMapping `json:"rules"`
+}
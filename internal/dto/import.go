@@ -0,0 +1,56 @@
+package dto
+
+// ==================== 导入现有配置相关 ====================
+
+// ImportCandidate 节点上可被面板接管的对象
+type ImportCandidate struct {
+	Kind       string   `json:"kind"`                   // 对象类型: service, chain
+	Name       string   `json:"name"`                   // 对象名称
+	State      string   `json:"state,omitempty"`        // 服务运行状态
+	RuleType   string   `json:"rule_type,omitempty"`    // 导入后的规则类型: forward, tunnel
+	Protocol   string   `json:"protocol,omitempty"`     // 协议: tcp, udp
+	ListenPort int      `json:"listen_port,omitempty"`  // 监听端口
+	ListenHost string   `json:"listen_host,omitempty"`  // 监听地址，为空时监听所有地址
+	Targets    []string `json:"targets,omitempty"`      // 转发目标
+	Strategy   string   `json:"strategy,omitempty"`     // 负载均衡策略
+	Chain      string   `json:"chain,omitempty"`        // 服务引用的链名称
+	TunnelID   uint     `json:"tunnel_id,omitempty"`    // 链已被面板隧道管理时的隧道 ID
+	RelayAddr  string   `json:"relay_addr,omitempty"`   // 链指向的 Relay 地址
+	ExitNodeID uint     `json:"exit_node_id,omitempty"` // 按地址匹配到的出口节点 ID
+	Adoptable  bool     `json:"adoptable"`              // 是否可以导入
+	Reason     string   `json:"reason,omitempty"`       // 不可导入或需注意的原因
+}
+
+// ImportCandidatesResp 可导入对象列表
+type ImportCandidatesResp struct {
+	NodeID   uint              `json:"node_id"`
+	NodeName string            `json:"node_name"`
+	Services []ImportCandidate `json:"services"`
+	Chains   []ImportCandidate `json:"chains"`
+}
+
+// ImportChainItem 导入链请求项
+type ImportChainItem struct {
+	Name       string `json:"name" binding:"required"` // 链名称
+	ExitNodeID uint   `json:"exit_node_id"`            // 出口节点 ID，为空时按 Relay 地址自动匹配
+}
+
+// ImportReq 导入请求
+type ImportReq struct {
+	Services []string          `json:"services"` // 导入为规则的服务名称
+	Chains   []ImportChainItem `json:"chains"`   // 导入为隧道的链
+}
+
+// ImportResult 导入成功的对象
+type ImportResult struct {
+	Kind         string `json:"kind"`          // 对象类型: service, chain
+	Name         string `json:"name"`          // 对象名称
+	ResourceType string `json:"resource_type"` // 创建的资源类型: rule, tunnel
+	ResourceID   uint   `json:"resource_id"`   // 创建的资源 ID
+}
+
+// ImportResp 导入结果
+type ImportResp struct {
+	Adopted []ImportResult    `json:"adopted"` // 导入成功的对象
+	Failed  map[string]string `json:"failed"`  // 导入失败的对象及原因
+}
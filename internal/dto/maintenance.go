@@ -0,0 +1,33 @@
+package dto
+
+// ==================== 节点维护模式相关 ====================
+
+// 迁移结果状态
+const (
+	MigrationStatusMigrated = "migrated" // 迁移成功
+	MigrationStatusFailed   = "failed"   // 迁移失败，原对象保持不变
+)
+
+// NodeMaintenanceReq 进入维护模式请求
+type NodeMaintenanceReq struct {
+	ReplacementNodeID *uint `json:"replacement_node_id"` // 替换节点 ID，为空表示仅进入维护模式不迁移
+}
+
+// MigrationItem 单个规则/隧道的迁移结果
+type MigrationItem struct {
+	ResourceType string `json:"resource_type"`       // 资源类型: rule, tunnel
+	SourceID     uint   `json:"source_id"`           // 原资源 ID
+	TargetID     uint   `json:"target_id,omitempty"` // 新资源 ID（隧道出口替换时规则原地改绑，与原 ID 相同）
+	Name         string `json:"name"`                // 资源名称
+	Status       string `json:"status"`              // 迁移状态: migrated, failed
+	Error        string `json:"error,omitempty"`     // 失败原因
+}
+
+// NodeMaintenanceResp 维护模式操作结果
+type NodeMaintenanceResp struct {
+	NodeID            uint            `json:"node_id"`
+	Maintenance       bool            `json:"maintenance"`                   // 当前是否处于维护模式
+	ReplacementNodeID uint            `json:"replacement_node_id,omitempty"` // 替换节点 ID
+	Tunnels           []MigrationItem `json:"tunnels"`                       // 隧道迁移结果
+	Rules             []MigrationItem `json:"rules"`                         // 规则迁移结果
+}
@@ -11,6 +11,12 @@ type CreateNodeReq struct {
 	Username string `json:"username"`                                // API 认证用户名
 	Password string `json:"password"`                                // API 认证密码
 	Remark   string `json:"remark"`                                  // 备注
+
+	ConnectionMode string `json:"connection_mode" binding:"omitempty,oneof=direct agent ssh"` // 连接方式，默认 direct
+
+	NodeTLSReq
+	NodeSSHReq
+	NodeMetricsReq
 }
 
 // UpdateNodeReq 更新节点请求
@@ -21,6 +27,38 @@ type UpdateNodeReq struct {
 	Username string `json:"username"`                                // API 认证用户名
 	Password string `json:"password"`                                // API 认证密码
 	Remark   string `json:"remark"`                                  // 备注
+
+	ConnectionMode string `json:"connection_mode" binding:"omitempty,oneof=direct agent ssh"` // 连接方式，默认 direct
+
+	NodeTLSReq
+	NodeSSHReq
+	NodeMetricsReq
+}
+
+// NodeTLSReq 节点 API TLS 配置
+type NodeTLSReq struct {
+	TLSEnabled    bool   `json:"tls_enabled"`     // 使用 https 连接节点 API
+	TLSSkipVerify bool   `json:"tls_skip_verify"` // 跳过服务端证书校验
+	TLSServerName string `json:"tls_server_name"` // 证书校验使用的服务器名称
+	TLSCACert     string `json:"tls_ca_cert"`     // PEM 格式 CA 证书（证书固定）
+	TLSClientCert string `json:"tls_client_cert"` // PEM 格式客户端证书（双向认证）
+	TLSClientKey  string `json:"tls_client_key"`  // PEM 格式客户端私钥，更新时留空保持不变
+}
+
+// NodeSSHReq 节点 SSH 连接配置（连接方式为 ssh 时使用）
+type NodeSSHReq struct {
+	SSHPort       int    `json:"ssh_port" binding:"omitempty,min=1,max=65535"` // SSH 端口，默认 22
+	SSHUser       string `json:"ssh_user"`                                     // SSH 用户名
+	SSHPassword   string `json:"ssh_password"`                                 // SSH 密码，更新时留空保持不变
+	SSHPrivateKey string `json:"ssh_private_key"`                              // PEM 格式私钥，更新时留空保持不变
+	SSHPassphrase string `json:"ssh_passphrase"`                               // 私钥密码，更新时留空保持不变
+	SSHHostKey    string `json:"ssh_host_key"`                                 // 主机公钥，留空时自动获取
+}
+
+// NodeMetricsReq 节点 Gost 指标接口配置
+type NodeMetricsReq struct {
+	MetricsPort int    `json:"metrics_port" binding:"omitempty,min=1,max=65535"` // Gost 指标端口，为空时不抓取
+	MetricsPath string `json:"metrics_path"`                                     // Gost 指标路径，默认 /metrics
 }
 
 // NodeListReq 节点列表请求
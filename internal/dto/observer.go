@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 // ==================== 观察器相关 ====================
 
 // ObserverEvent GOST 观察器上报的事件
@@ -36,3 +38,18 @@ type ObserverReportReq struct {
 type ObserverReportResp struct {
 	OK bool `json:"ok"` // 是否成功
 }
+
+// ObserverIngestStats 观察器上报处理统计
+type ObserverIngestStats struct {
+	ReceivedEvents   int64     `json:"received_events"`   // 收到的事件总数
+	MergedEvents     int64     `json:"merged_events"`     // 与缓冲区中同一服务事件合并的事件数
+	DroppedEvents    int64     `json:"dropped_events"`    // 缓冲区已满被丢弃的事件数
+	RejectedRequests int64     `json:"rejected_requests"` // 因缓冲区已满被拒绝的上报请求数
+	FlushedEvents    int64     `json:"flushed_events"`    // 已写入数据库的事件数
+	Flushes          int64     `json:"flushes"`           // 刷新次数
+	FlushErrors      int64     `json:"flush_errors"`      // 刷新失败次数
+	Pending          int       `json:"pending"`           // 当前缓冲的服务事件数
+	Capacity         int       `json:"capacity"`          // 缓冲区容量
+	LastFlushAt      time.Time `json:"last_flush_at"`     // 最近一次刷新时间
+	LastFlushMillis  int64     `json:"last_flush_millis"` // 最近一次刷新耗时 (ms)
+}
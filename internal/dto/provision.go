@@ -0,0 +1,30 @@
+package dto
+
+// ==================== 节点部署相关 ====================
+
+// ProvisionNodeReq 经 SSH 部署新节点请求
+type ProvisionNodeReq struct {
+	Name           string `json:"name" binding:"required,min=1,max=100"`                      // 节点名称
+	Address        string `json:"address" binding:"required"`                                 // 服务器 IP 或域名
+	Port           int    `json:"port" binding:"omitempty,min=1,max=65535"`                   // Gost API 端口，默认 39000
+	GostVersion    string `json:"gost_version"`                                               // Gost 版本，为空使用脚本默认版本
+	ConnectionMode string `json:"connection_mode" binding:"omitempty,oneof=direct agent ssh"` // 连接方式，默认 direct
+	PanelURL       string `json:"panel_url"`                                                  // 面板地址，填写后安装 Agent（agent 连接方式必填）
+	Remark         string `json:"remark"`                                                     // 备注
+
+	NodeSSHReq
+}
+
+// NodeUpgradeReq 经 SSH 升级节点 Gost 请求
+type NodeUpgradeReq struct {
+	GostVersion string `json:"gost_version" binding:"required"` // 目标 Gost 版本
+
+	NodeSSHReq // SSH 登录信息，未填写用户名时使用节点已保存的 SSH 凭据
+}
+
+// NodeUninstallReq 经 SSH 卸载节点请求
+type NodeUninstallReq struct {
+	RemoveNode bool `json:"remove_node"` // 卸载成功后从面板删除节点
+
+	NodeSSHReq // SSH 登录信息，未填写用户名时使用节点已保存的 SSH 凭据
+}
@@ -0,0 +1,43 @@
+package dto
+
+import "time"
+
+// ==================== 配置漂移检测相关 ====================
+
+// 漂移对象类型
+const (
+	ReconcileKindService = "service" // Gost 服务
+	ReconcileKindChain   = "chain"   // Gost 链
+)
+
+// ReconcileItem 漂移对象
+type ReconcileItem struct {
+	Kind         string   `json:"kind"`                    // 对象类型: service, chain
+	Name         string   `json:"name"`                    // 对象名称
+	ResourceType string   `json:"resource_type,omitempty"` // 关联资源类型: rule, tunnel
+	ResourceID   uint     `json:"resource_id,omitempty"`   // 关联资源 ID
+	Reason       string   `json:"reason"`                  // 原因说明
+	Diffs        []string `json:"diffs,omitempty"`         // 差异字段（仅 modified）
+}
+
+// ReconcileReport 节点配置漂移报告
+type ReconcileReport struct {
+	NodeID    uint            `json:"node_id"`   // 节点 ID
+	NodeName  string          `json:"node_name"` // 节点名称
+	Missing   []ReconcileItem `json:"missing"`   // 面板期望存在但节点上缺失
+	Modified  []ReconcileItem `json:"modified"`  // 节点上存在但与面板生成的配置不一致
+	Orphans   []ReconcileItem `json:"orphans"`   // 面板命名或被记录引用，但无期望运行的记录
+	Unmanaged []ReconcileItem `json:"unmanaged"` // 非面板创建的对象（仅展示，不会被清理）
+	CheckedAt time.Time       `json:"checked_at"`
+}
+
+// ReconcileActionReq 修复/清理请求
+type ReconcileActionReq struct {
+	Names []string `json:"names"` // 仅处理指定名称的对象，为空表示处理全部
+}
+
+// ReconcileActionResp 修复/清理结果
+type ReconcileActionResp struct {
+	Succeeded []string          `json:"succeeded"` // 处理成功的对象名称
+	Failed    map[string]string `json:"failed"`    // 处理失败的对象及原因
+}
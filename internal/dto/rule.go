@@ -54,3 +54,8 @@ func (r *RuleListReq) SetDefaults() {
 		r.PageSize = 10
 	}
 }
+
+// RuleClientsReq 规则客户端排行请求
+type RuleClientsReq struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"` // 返回数量，默认 10
+}
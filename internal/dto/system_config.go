@@ -4,11 +4,13 @@ package dto
 
 // SystemConfigResp 系统配置响应
 type SystemConfigResp struct {
-	Panel  PanelConfigResp  `json:"panel"`
-	Email  EmailConfigResp  `json:"email"`
-	Config PanelSettingResp `json:"config"`
-	Log    LogConfigResp    `json:"log"`
-	Backup BackupConfigResp `json:"backup"`
+	Panel   PanelConfigResp   `json:"panel"`
+	Email   EmailConfigResp   `json:"email"`
+	Config  PanelSettingResp  `json:"config"`
+	Log     LogConfigResp     `json:"log"`
+	Backup  BackupConfigResp  `json:"backup"`
+	Traffic TrafficConfigResp `json:"traffic"`
+	Report  ReportConfigResp  `json:"report"`
 }
 
 type PublicSystemConfigResp struct {
@@ -45,13 +47,29 @@ type BackupConfigResp struct {
 	RetentionCount int  `json:"retentionCount"`
 }
 
+type TrafficConfigResp struct {
+	MinuteRetentionDays int `json:"minuteRetentionDays"`
+	HourRetentionDays   int `json:"hourRetentionDays"`
+	DayRetentionDays    int `json:"dayRetentionDays"`
+}
+
+type ReportConfigResp struct {
+	Daily      bool   `json:"daily"`
+	Weekly     bool   `json:"weekly"`
+	Monthly    bool   `json:"monthly"`
+	Recipients string `json:"recipients"`
+	Hour       int    `json:"hour"`
+}
+
 // UpdateSystemConfigReq 更新系统配置请求
 type UpdateSystemConfigReq struct {
-	Panel  PanelConfigReq  `json:"panel"`
-	Email  EmailConfigReq  `json:"email"`
-	Config PanelSettingReq `json:"config"`
-	Log    LogConfigReq    `json:"log"`
-	Backup BackupConfigReq `json:"backup"`
+	Panel   PanelConfigReq   `json:"panel"`
+	Email   EmailConfigReq   `json:"email"`
+	Config  PanelSettingReq  `json:"config"`
+	Log     LogConfigReq     `json:"log"`
+	Backup  BackupConfigReq  `json:"backup"`
+	Traffic TrafficConfigReq `json:"traffic"`
+	Report  ReportConfigReq  `json:"report"`
 }
 
 type PanelConfigReq struct {
@@ -82,3 +100,23 @@ type BackupConfigReq struct {
 	AutoBackup     bool `json:"autoBackup"`
 	RetentionCount int  `json:"retentionCount"`
 }
+
+type TrafficConfigReq struct {
+	MinuteRetentionDays int `json:"minuteRetentionDays" binding:"omitempty,min=1,max=30"`
+	HourRetentionDays   int `json:"hourRetentionDays" binding:"omitempty,min=7,max=365"`
+	DayRetentionDays    int `json:"dayRetentionDays" binding:"omitempty,min=7,max=3650"`
+}
+
+type ReportConfigReq struct {
+	Daily      bool   `json:"daily"`
+	Weekly     bool   `json:"weekly"`
+	Monthly    bool   `json:"monthly"`
+	Recipients string `json:"recipients"`                            // 收件人，逗号、分号或换行分隔
+	Hour       int    `json:"hour" binding:"omitempty,min=0,max=23"` // 发送时间（点）
+}
+
+// SendReportReq 立即发送报表请求
+type SendReportReq struct {
+	Kind    string `json:"kind" binding:"required,oneof=daily weekly monthly"` // 报表类型
+	ToEmail string `json:"toEmail"`                                            // 收件人，为空时发送给配置的报表收件人
+}
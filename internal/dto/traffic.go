@@ -0,0 +1,78 @@
+package dto
+
+import "time"
+
+// ==================== 流量历史相关 ====================
+
+// TrafficQueryReq 流量历史查询请求
+type TrafficQueryReq struct {
+	From string `form:"from"` // 开始时间（RFC3339 或 Unix 秒），默认结束时间前 24 小时
+	To   string `form:"to"`   // 结束时间（RFC3339 或 Unix 秒），默认当前时间
+	Step string `form:"step"` // 步长（如 1m、5m、1h、1d），默认按时间范围自动选择
+}
+
+// TrafficPoint 流量历史数据点（时间桶内的流量增量）
+type TrafficPoint struct {
+	Time        time.Time `json:"time"`         // 时间桶起点
+	InputBytes  int64     `json:"input_bytes"`  // 入站流量 (bytes)
+	OutputBytes int64     `json:"output_bytes"` // 出站流量 (bytes)
+	TotalBytes  int64     `json:"total_bytes"`  // 总流量 (bytes)
+	Connections int64     `json:"connections"`  // 新建连接数
+}
+
+// TrafficSeriesResp 流量历史查询响应
+type TrafficSeriesResp struct {
+	ResourceType string         `json:"resource_type"` // 对象类型 (rule, node, tunnel)
+	ResourceID   uint           `json:"resource_id"`   // 对象 ID
+	From         time.Time      `json:"from"`          // 实际查询开始时间（已按步长对齐）
+	To           time.Time      `json:"to"`            // 实际查询结束时间
+	Step         string         `json:"step"`          // 实际使用的步长
+	Source       string         `json:"source"`        // 数据来源粒度 (minute, hour, day)
+	InputBytes   int64          `json:"input_bytes"`   // 区间入站流量合计
+	OutputBytes  int64          `json:"output_bytes"`  // 区间出站流量合计
+	TotalBytes   int64          `json:"total_bytes"`   // 区间总流量合计
+	Connections  int64          `json:"connections"`   // 区间新建连接数合计
+	Points       []TrafficPoint `json:"points"`        // 数据点（空桶补零）
+}
+
+// ==================== Gost 指标相关 ====================
+
+// GostMetricQueryReq Gost 指标历史查询请求
+type GostMetricQueryReq struct {
+	Metric string `form:"metric" binding:"required"` // 指标名称，如 gost_service_handler_errors
+	TrafficQueryReq
+}
+
+// GostMetricPoint Gost 指标数据点（计数器为时间桶内增量之和，仪表盘为时间桶内最大值）
+type GostMetricPoint struct {
+	Time  time.Time `json:"time"`  // 时间桶起点
+	Value float64   `json:"value"` // 指标值
+}
+
+// GostMetricSeries 单个服务或转发链的指标序列
+type GostMetricSeries struct {
+	Object string            `json:"object"` // 服务或转发链名称
+	Total  float64           `json:"total"`  // 区间合计（仪表盘为区间最大值）
+	Points []GostMetricPoint `json:"points"` // 数据点（空桶补零）
+}
+
+// GostMetricSeriesResp Gost 指标历史查询响应
+type GostMetricSeriesResp struct {
+	NodeID uint               `json:"node_id"` // 节点 ID
+	Metric string             `json:"metric"`  // 指标名称
+	Type   string             `json:"type"`    // 指标类型 (counter, gauge)
+	From   time.Time          `json:"from"`    // 实际查询开始时间（已按步长对齐）
+	To     time.Time          `json:"to"`      // 实际查询结束时间
+	Step   string             `json:"step"`    // 实际使用的步长
+	Series []GostMetricSeries `json:"series"`  // 各服务或转发链的序列（按名称排序）
+}
+
+// GostMetricValue 最近一次抓取的 Gost 指标值（计数器为累计值）
+type GostMetricValue struct {
+	NodeID  uint
+	Node    string
+	Metric  string
+	Object  string
+	Value   float64
+	Counter bool
+}
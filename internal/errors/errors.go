@@ -41,6 +41,28 @@ var (
 	ErrNodeHasObservers = New(10005, "节点下存在流量监控，无法删除", http.StatusBadRequest)
 	// ErrNodeOffline 节点已离线
 	ErrNodeOffline = New(10006, "节点已离线", http.StatusBadRequest)
+	// ErrNodeConfigFetchFailed 获取节点配置失败
+	ErrNodeConfigFetchFailed = New(10007, "获取节点配置失败", http.StatusInternalServerError)
+	// ErrImportEmpty 未选择要导入的对象
+	ErrImportEmpty = New(10008, "请选择要导入的服务或链", http.StatusBadRequest)
+	// ErrNodeMaintenance 节点处于维护模式
+	ErrNodeMaintenance = New(10009, "节点处于维护模式，无法新建规则或隧道", http.StatusBadRequest)
+	// ErrReplacementNodeSame 替换节点与当前节点相同
+	ErrReplacementNodeSame = New(10010, "替换节点不能与当前节点相同", http.StatusBadRequest)
+	// ErrReplacementNodeUnavailable 替换节点不可用
+	ErrReplacementNodeUnavailable = New(10011, "替换节点已离线或处于维护模式", http.StatusBadRequest)
+	// ErrCloneTargetSame 克隆目标节点与源节点相同
+	ErrCloneTargetSame = New(10012, "目标节点不能与源节点相同", http.StatusBadRequest)
+	// ErrPortExhausted 没有可用端口
+	ErrPortExhausted = New(10013, "目标节点上没有可用端口", http.StatusBadRequest)
+	// ErrNodeTLSInvalid 节点 TLS 证书配置无效
+	ErrNodeTLSInvalid = New(10014, "节点 TLS 证书配置无效", http.StatusBadRequest)
+	// ErrNodeSSHAuthRequired SSH 连接缺少认证信息
+	ErrNodeSSHAuthRequired = New(10015, "SSH 连接需要填写用户名及密码或私钥", http.StatusBadRequest)
+	// ErrNodeSSHKeyInvalid SSH 私钥或主机公钥无效
+	ErrNodeSSHKeyInvalid = New(10016, "SSH 私钥或主机公钥无效", http.StatusBadRequest)
+	// ErrNodeSSHHostKeyFetchFailed 获取 SSH 主机公钥失败
+	ErrNodeSSHHostKeyFetchFailed = New(10017, "无法连接 SSH 获取主机公钥，请检查地址和端口或手动填写主机公钥", http.StatusBadRequest)
 )
 
 // ==================== 规则相关错误 (101xx) ====================
@@ -64,6 +86,8 @@ var (
 	ErrRuleTypeInvalid = New(10108, "无效的规则类型", http.StatusBadRequest)
 	// ErrTunnelChainNotFound 隧道链不存在
 	ErrTunnelChainNotFound = New(10109, "隧道未启动或链路不存在", http.StatusBadRequest)
+	// ErrRuleListenHostBound 规则绑定了节点自身的监听地址
+	ErrRuleListenHostBound = New(10110, "规则绑定了源节点的监听地址，无法复制到其他节点", http.StatusBadRequest)
 )
 
 // ==================== 隧道相关错误 (102xx) ====================
@@ -106,6 +130,41 @@ var (
 	ErrUserNotFound = New(10305, "用户不存在", http.StatusNotFound)
 )
 
+// ==================== Agent 相关错误 (105xx) ====================
+
+var (
+	// ErrAgentTokenInvalid Agent 令牌无效
+	ErrAgentTokenInvalid = New(10501, "Agent 令牌无效", http.StatusUnauthorized)
+	// ErrAgentModeDisabled 节点未启用 Agent 连接方式
+	ErrAgentModeDisabled = New(10502, "节点未启用 Agent 连接方式", http.StatusForbidden)
+)
+
+// ==================== 节点部署相关错误 (106xx) ====================
+
+var (
+	// ErrProvisionBusy 节点正在执行部署任务
+	ErrProvisionBusy = New(10601, "该节点正在执行部署任务，请稍后重试", http.StatusConflict)
+	// ErrGostVersionInvalid Gost 版本号无效
+	ErrGostVersionInvalid = New(10602, "Gost 版本号格式无效，例如: 3.2.6", http.StatusBadRequest)
+	// ErrPanelURLRequired Agent 反向连接需要面板地址
+	ErrPanelURLRequired = New(10603, "Agent 反向连接方式需要填写面板地址", http.StatusBadRequest)
+	// ErrProvisionFailed 节点脚本执行失败
+	ErrProvisionFailed = New(10604, "节点脚本执行失败", http.StatusInternalServerError)
+)
+
+// ==================== 流量统计相关错误 (107xx) ====================
+
+var (
+	// ErrTrafficRangeInvalid 查询时间范围无效
+	ErrTrafficRangeInvalid = New(10701, "查询时间范围无效，时间格式为 RFC3339 或 Unix 秒，且开始时间需早于结束时间", http.StatusBadRequest)
+	// ErrTrafficStepInvalid 查询步长无效
+	ErrTrafficStepInvalid = New(10702, "查询步长无效，需为整分钟，例如: 1m、5m、1h、1d", http.StatusBadRequest)
+	// ErrTrafficTooManyPoints 查询数据点过多
+	ErrTrafficTooManyPoints = New(10703, "查询数据点过多，请缩小时间范围或增大步长", http.StatusBadRequest)
+	// ErrGostMetricInvalid 不支持的 Gost 指标
+	ErrGostMetricInvalid = New(10704, "不支持的 Gost 指标", http.StatusBadRequest)
+)
+
 // ==================== 通用错误 (500xx) ====================
 
 var (
@@ -159,6 +218,16 @@ var (
 	ErrObserverCreateFailed = New(10414, "创建流量监控失败", http.StatusInternalServerError)
 	// ErrExtractHostFailed 提取主机IP失败
 	ErrExtractHostFailed = New(10415, "无法从API地址提取主机IP", http.StatusInternalServerError)
+	// ErrObserverTokenInvalid 观察器上报令牌无效
+	ErrObserverTokenInvalid = New(10416, "观察器上报令牌无效", http.StatusUnauthorized)
+	// ErrObserverBusy 观察器上报缓冲区已满
+	ErrObserverBusy = New(10417, "观察器上报繁忙，请稍后重试", http.StatusServiceUnavailable)
+	// ErrSMTPTLSFailed SMTP TLS 握手或证书校验失败
+	ErrSMTPTLSFailed = New(10418, "SMTP服务器TLS握手失败，请检查服务器证书", http.StatusInternalServerError)
+	// ErrReportRecipientsInvalid 报表收件人无效
+	ErrReportRecipientsInvalid = New(10419, "报表收件人邮箱格式无效", http.StatusBadRequest)
+	// ErrReportRecipientsEmpty 未配置报表收件人
+	ErrReportRecipientsEmpty = New(10420, "未配置报表收件人", http.StatusBadRequest)
 )
 
 // ==================== 隧道相关补全 (102xx) ====================
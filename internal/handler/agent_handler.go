@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/service"
+	"gost-panel/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// maxAgentReportSize Agent 单次上报主机指标的请求体上限
+const maxAgentReportSize = 64 << 10
+
+// AgentHandler 节点 Agent 控制器
+type AgentHandler struct {
+	agentService *service.AgentService
+}
+
+// NewAgentHandler 创建节点 Agent 控制器
+func NewAgentHandler(agentService *service.AgentService) *AgentHandler {
+	return &AgentHandler{agentService: agentService}
+}
+
+// Report 接收 Agent 上报的主机指标（通过 X-Agent-Token 认证）
+// 公开接口：先校验令牌再读取请求体，并限制请求体大小
+func (h *AgentHandler) Report(c *gin.Context) {
+	node, err := h.agentService.Authenticate(c.GetHeader(dto.AgentTokenHeader))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	var req dto.AgentReportReq
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAgentReportSize)
+	if err = c.ShouldBindJSON(&req); err != nil {
+		if bindErrorStatus(err) == http.StatusRequestEntityTooLarge {
+			response.Error(c, http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge, "请求体过大")
+			return
+		}
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err = h.agentService.Report(node, &req); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// Connect 建立 Agent 反向连接（WebSocket，通过 X-Agent-Token 认证）
+func (h *AgentHandler) Connect(c *gin.Context) {
+	node, err := h.agentService.Connect(c.GetHeader(dto.AgentTokenHeader))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	ip := c.ClientIP()
+	// 使用 websocket.Server 而非 websocket.Handler，跳过 Origin 校验（Agent 非浏览器客户端）
+	server := websocket.Server{
+		Handler: func(conn *websocket.Conn) {
+			h.agentService.Serve(node, ip, conn)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// GetToken 获取节点 Agent 令牌（生成安装命令）
+func (h *AgentHandler) GetToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	result, err := h.agentService.GetToken(uint(id))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ResetToken 重置节点 Agent 令牌
+func (h *AgentHandler) ResetToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	result, err := h.agentService.ResetToken(uint(id), userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
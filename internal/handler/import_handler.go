@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"strconv"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/service"
+	"gost-panel/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler 现有配置导入控制器
+type ImportHandler struct {
+	importService *service.ImportService
+}
+
+// NewImportHandler 创建现有配置导入控制器
+func NewImportHandler(importService *service.ImportService) *ImportHandler {
+	return &ImportHandler{importService: importService}
+}
+
+// ListCandidates 获取节点上可导入的服务和链
+func (h *ImportHandler) ListCandidates(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	candidates, err := h.importService.ListCandidates(c.Request.Context(), uint(id))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, candidates)
+}
+
+// Adopt 导入选中的服务和链
+func (h *ImportHandler) Adopt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	var req dto.ImportReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	result, err := h.importService.Adopt(c.Request.Context(), uint(id), &req, userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
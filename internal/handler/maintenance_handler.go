@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"strconv"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/service"
+	"gost-panel/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceHandler 节点维护模式与克隆控制器
+type MaintenanceHandler struct {
+	maintenanceService *service.MaintenanceService
+	cloneService       *service.CloneService
+}
+
+// NewMaintenanceHandler 创建节点维护模式与克隆控制器
+func NewMaintenanceHandler(maintenanceService *service.MaintenanceService, cloneService *service.CloneService) *MaintenanceHandler {
+	return &MaintenanceHandler{maintenanceService: maintenanceService, cloneService: cloneService}
+}
+
+// Enter 节点进入维护模式（可选迁移到替换节点）
+func (h *MaintenanceHandler) Enter(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	var req dto.NodeMaintenanceReq
+	if c.Request.ContentLength > 0 {
+		if err = c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	result, err := h.maintenanceService.Enter(c.Request.Context(), uint(id), &req, userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Exit 节点退出维护模式
+func (h *MaintenanceHandler) Exit(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	result, err := h.maintenanceService.Exit(c.Request.Context(), uint(id), userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Clone 将节点的规则（及可选的隧道）克隆到目标节点
+func (h *MaintenanceHandler) Clone(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	var req dto.NodeCloneReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	result, err := h.cloneService.Clone(c.Request.Context(), uint(id), &req, userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
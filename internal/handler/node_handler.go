@@ -95,7 +95,7 @@ func (h *NodeHandler) Delete(c *gin.Context) {
 	response.SuccessWithMessage(c, "删除成功", nil)
 }
 
-// GetByID 获取节点详情
+// GetByID 获取节点详情（含可用率和最近状态变更）
 func (h *NodeHandler) GetByID(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -103,13 +103,13 @@ func (h *NodeHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	node, err := h.nodeService.GetByID(uint(id))
+	detail, err := h.nodeService.GetDetail(uint(id))
 	if err != nil {
 		response.HandleError(c, err)
 		return
 	}
 
-	response.Success(c, node)
+	response.Success(c, detail)
 }
 
 // List 获取节点列表
@@ -137,7 +137,7 @@ func (h *NodeHandler) GetConfig(c *gin.Context) {
 		return
 	}
 
-	config, err := h.nodeService.GetConfig(uint(id))
+	config, err := h.nodeService.GetConfig(c.Request.Context(), uint(id))
 	if err != nil {
 		response.HandleError(c, err)
 		return
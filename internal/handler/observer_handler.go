@@ -1,13 +1,22 @@
 package handler
 
 import (
+	stderrors "errors"
+	"net/http"
+
 	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
 	"gost-panel/internal/service"
 	"gost-panel/pkg/logger"
+	"gost-panel/pkg/response"
 
 	"github.com/gin-gonic/gin"
 )
 
+// maxObserverReportSize 观察器单次上报的请求体上限
+// 每个服务和客户端各一条事件，数千条事件也远小于该上限
+const maxObserverReportSize = 4 << 20
+
 // ObserverHandler 观察器控制器
 type ObserverHandler struct {
 	observerService *service.ObserverService
@@ -19,17 +28,37 @@ func NewObserverHandler(observerService *service.ObserverService) *ObserverHandl
 }
 
 // Report 接收 GOST 观察器上报的数据
-// POST /api/v1/observer/report
+// POST /api/v1/observer/report/:token，token 为节点观察器令牌
+// 公开接口：先校验令牌再读取请求体，并限制请求体大小
 func (h *ObserverHandler) Report(c *gin.Context) {
+	node, err := h.observerService.Authenticate(c.Param("token"))
+	if err != nil {
+		if stderrors.Is(err, errors.ErrObserverTokenInvalid) {
+			logger.Warnf("拒绝观察器上报 (%s): %v", c.ClientIP(), err)
+			c.JSON(http.StatusUnauthorized, dto.ObserverReportResp{OK: false})
+			return
+		}
+		logger.Warnf("校验观察器令牌失败: %v", err)
+		c.JSON(http.StatusInternalServerError, dto.ObserverReportResp{OK: false})
+		return
+	}
+
 	var req dto.ObserverReportReq
-	if err := c.ShouldBindJSON(&req); err != nil {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxObserverReportSize)
+	if err = c.ShouldBindJSON(&req); err != nil {
 		logger.Warnf("解析观察器上报数据失败: %v", err)
-		c.JSON(400, dto.ObserverReportResp{OK: false})
+		c.JSON(bindErrorStatus(err), dto.ObserverReportResp{OK: false})
 		return
 	}
 
 	// 处理上报数据
-	if err := h.observerService.HandleReport(&req); err != nil {
+	if err = h.observerService.HandleReport(node, &req); err != nil {
+		if stderrors.Is(err, errors.ErrObserverBusy) {
+			// 统计数据为累计值，下次上报即可补上本次被丢弃的增量
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusServiceUnavailable, dto.ObserverReportResp{OK: false})
+			return
+		}
 		logger.Warnf("处理观察器上报数据失败: %v", err)
 		c.JSON(500, dto.ObserverReportResp{OK: false})
 		return
@@ -38,3 +67,18 @@ func (h *ObserverHandler) Report(c *gin.Context) {
 	// 返回成功响应（GOST 需要 ok: true 才认为上报成功）
 	c.JSON(200, dto.ObserverReportResp{OK: true})
 }
+
+// bindErrorStatus 解析请求体失败时的状态码，请求体超出上限时返回 413
+func bindErrorStatus(err error) int {
+	var maxErr *http.MaxBytesError
+	if stderrors.As(err, &maxErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// IngestStats 获取观察器上报处理统计
+// GET /api/v1/dashboard/observer
+func (h *ObserverHandler) IngestStats(c *gin.Context) {
+	response.Success(c, h.observerService.IngestStats())
+}
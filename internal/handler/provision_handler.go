@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/service"
+	"gost-panel/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProvisionHandler 节点部署控制器
+// 部署、升级、卸载接口以 Server-Sent Events 流式返回脚本输出：
+// output 事件为一行输出，done 事件表示任务成功（数据为 JSON 结果），error 事件表示任务失败（数据为错误信息）
+type ProvisionHandler struct {
+	provisionService *service.ProvisionService
+}
+
+// NewProvisionHandler 创建节点部署控制器
+func NewProvisionHandler(provisionService *service.ProvisionService) *ProvisionHandler {
+	return &ProvisionHandler{provisionService: provisionService}
+}
+
+// Provision 经 SSH 部署新节点
+func (h *ProvisionHandler) Provision(c *gin.Context) {
+	var req dto.ProvisionNodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	task, err := h.provisionService.PrepareProvision(&req, userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	streamTask(c, task)
+}
+
+// Upgrade 经 SSH 升级节点 Gost 版本
+func (h *ProvisionHandler) Upgrade(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	var req dto.NodeUpgradeReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	task, err := h.provisionService.PrepareUpgrade(uint(id), &req, userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	streamTask(c, task)
+}
+
+// Uninstall 经 SSH 卸载节点
+func (h *ProvisionHandler) Uninstall(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	var req dto.NodeUninstallReq
+	if c.Request.ContentLength > 0 {
+		if err = c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	task, err := h.provisionService.PrepareUninstall(uint(id), &req, userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	streamTask(c, task)
+}
+
+// streamTask 执行部署任务并以 Server-Sent Events 返回输出
+// 客户端断开后任务继续执行至结束，保证节点注册等收尾操作完成
+func streamTask(c *gin.Context, task *service.ProvisionTask) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	w := &sseLineWriter{c: c}
+	result, err := task.Run(w)
+	w.flushLine()
+
+	if err != nil {
+		c.SSEvent("error", err.Error())
+	} else {
+		c.SSEvent("done", result)
+	}
+	c.Writer.Flush()
+}
+
+// ansiEscape 终端颜色控制序列
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// sseLineWriter 按行将脚本输出转为 output 事件，去除终端颜色控制序列
+type sseLineWriter struct {
+	c   *gin.Context
+	buf []byte
+}
+
+// Write 实现 io.Writer
+func (w *sseLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flushLine 输出缓冲中未以换行结尾的内容
+func (w *sseLineWriter) flushLine() {
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+}
+
+// emit 发送一行输出，下载进度等以回车刷新的内容只保留最后一段
+func (w *sseLineWriter) emit(line []byte) {
+	if i := bytes.LastIndexByte(bytes.TrimRight(line, "\r"), '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	line = bytes.TrimRight(ansiEscape.ReplaceAll(line, nil), "\r")
+	w.c.SSEvent("output", string(line))
+	w.c.Writer.Flush()
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"strconv"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/service"
+	"gost-panel/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReconcileHandler 配置漂移检测控制器
+type ReconcileHandler struct {
+	reconcileService *service.ReconcileService
+}
+
+// NewReconcileHandler 创建配置漂移检测控制器
+func NewReconcileHandler(reconcileService *service.ReconcileService) *ReconcileHandler {
+	return &ReconcileHandler{reconcileService: reconcileService}
+}
+
+// Inspect 检测节点配置漂移
+func (h *ReconcileHandler) Inspect(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	report, err := h.reconcileService.Inspect(c.Request.Context(), uint(id))
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// Repair 修复节点配置漂移
+func (h *ReconcileHandler) Repair(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	var req dto.ReconcileActionReq
+	if c.Request.ContentLength > 0 {
+		if err = c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	result, err := h.reconcileService.Repair(c.Request.Context(), uint(id), &req, userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Prune 清理节点孤立配置
+func (h *ReconcileHandler) Prune(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	var req dto.ReconcileActionReq
+	if c.Request.ContentLength > 0 {
+		if err = c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	userID, _ := c.Get("userID")
+	username, _ := c.Get("username")
+
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	result, err := h.reconcileService.Prune(c.Request.Context(), uint(id), &req, userID.(uint), username.(string), ip, ua)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -64,7 +64,7 @@ func (h *RuleHandler) Update(c *gin.Context) {
 	ip := c.ClientIP()
 	ua := c.GetHeader("User-Agent")
 
-	rule, err := h.ruleService.Update(uint(id), &req, userID.(uint), username.(string), ip, ua)
+	rule, err := h.ruleService.Update(c.Request.Context(), uint(id), &req, userID.(uint), username.(string), ip, ua)
 	if err != nil {
 		response.HandleError(c, err)
 		return
@@ -87,7 +87,7 @@ func (h *RuleHandler) Delete(c *gin.Context) {
 	ip := c.ClientIP()
 	ua := c.GetHeader("User-Agent")
 
-	if err = h.ruleService.Delete(uint(id), userID.(uint), username.(string), ip, ua); err != nil {
+	if err = h.ruleService.Delete(c.Request.Context(), uint(id), userID.(uint), username.(string), ip, ua); err != nil {
 		response.HandleError(c, err)
 		return
 	}
@@ -112,6 +112,29 @@ func (h *RuleHandler) GetByID(c *gin.Context) {
 	response.Success(c, rule)
 }
 
+// TopClients 获取规则流量最多的客户端
+func (h *RuleHandler) TopClients(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的规则 ID")
+		return
+	}
+
+	var req dto.RuleClientsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	clients, err := h.ruleService.TopClients(uint(id), &req)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, clients)
+}
+
 // List 获取规则列表
 func (h *RuleHandler) List(c *gin.Context) {
 	var req dto.RuleListReq
@@ -143,7 +166,7 @@ func (h *RuleHandler) Start(c *gin.Context) {
 	ip := c.ClientIP()
 	ua := c.GetHeader("User-Agent")
 
-	if err = h.ruleService.Start(uint(id), userID.(uint), username.(string), ip, ua); err != nil {
+	if err = h.ruleService.Start(c.Request.Context(), uint(id), userID.(uint), username.(string), ip, ua); err != nil {
 		response.HandleError(c, err)
 		return
 	}
@@ -165,7 +188,7 @@ func (h *RuleHandler) Stop(c *gin.Context) {
 	ip := c.ClientIP()
 	ua := c.GetHeader("User-Agent")
 
-	if err := h.ruleService.Stop(uint(id), userID.(uint), username.(string), ip, ua); err != nil {
+	if err := h.ruleService.Stop(c.Request.Context(), uint(id), userID.(uint), username.(string), ip, ua); err != nil {
 		response.HandleError(c, err)
 		return
 	}
@@ -10,11 +10,12 @@ import (
 // StatsHandler 统计控制器
 type StatsHandler struct {
 	statsService *service.StatsService
+	scheduler    *service.NodeScheduler
 }
 
 // NewStatsHandler 创建统计控制器
-func NewStatsHandler(statsService *service.StatsService) *StatsHandler {
-	return &StatsHandler{statsService: statsService}
+func NewStatsHandler(statsService *service.StatsService, scheduler *service.NodeScheduler) *StatsHandler {
+	return &StatsHandler{statsService: statsService, scheduler: scheduler}
 }
 
 // GetDashboard 获取仪表盘数据
@@ -27,3 +28,8 @@ func (h *StatsHandler) GetDashboard(c *gin.Context) {
 
 	response.Success(c, stats)
 }
+
+// GetScheduler 获取后台任务调度器指标（队列长度、排队延迟等）
+func (h *StatsHandler) GetScheduler(c *gin.Context) {
+	response.Success(c, h.scheduler.Stats())
+}
@@ -12,13 +12,16 @@ import (
 type SystemConfigHandler struct {
 	systemConfigService *service.SystemConfigService
 	backupService       *service.BackupService
+	reportService       *service.ReportService
 }
 
 // NewSystemConfigHandler 创建系统配置控制器
-func NewSystemConfigHandler(sysService *service.SystemConfigService, backupService *service.BackupService) *SystemConfigHandler {
+func NewSystemConfigHandler(sysService *service.SystemConfigService, backupService *service.BackupService,
+	reportService *service.ReportService) *SystemConfigHandler {
 	return &SystemConfigHandler{
 		systemConfigService: sysService,
 		backupService:       backupService,
+		reportService:       reportService,
 	}
 }
 
@@ -76,6 +79,22 @@ func (h *SystemConfigHandler) TestEmail(c *gin.Context) {
 	response.SuccessWithMessage(c, "邮件发送成功", nil)
 }
 
+// SendReport 立即发送上一周期的流量报表
+func (h *SystemConfigHandler) SendReport(c *gin.Context) {
+	var req dto.SendReportReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if err := h.reportService.SendNow(&req); err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, "报表发送成功", nil)
+}
+
 // Backup 立即备份
 func (h *SystemConfigHandler) Backup(c *gin.Context) {
 	if err := h.backupService.CreateBackup(); err != nil {
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"strconv"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/service"
+	"gost-panel/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrafficHandler 流量历史控制器
+type TrafficHandler struct {
+	trafficService     *service.TrafficService
+	gostMetricsService *service.GostMetricsService
+}
+
+// NewTrafficHandler 创建流量历史控制器
+func NewTrafficHandler(trafficService *service.TrafficService, gostMetricsService *service.GostMetricsService) *TrafficHandler {
+	return &TrafficHandler{trafficService: trafficService, gostMetricsService: gostMetricsService}
+}
+
+// RuleTraffic 查询规则流量历史
+func (h *TrafficHandler) RuleTraffic(c *gin.Context) {
+	h.query(c, "无效的规则 ID", h.trafficService.QueryRule)
+}
+
+// NodeTraffic 查询节点流量历史
+func (h *TrafficHandler) NodeTraffic(c *gin.Context) {
+	h.query(c, "无效的节点 ID", h.trafficService.QueryNode)
+}
+
+// TunnelTraffic 查询隧道流量历史
+func (h *TrafficHandler) TunnelTraffic(c *gin.Context) {
+	h.query(c, "无效的隧道 ID", h.trafficService.QueryTunnel)
+}
+
+// query 解析路径 ID 和查询参数后调用对应的查询方法
+func (h *TrafficHandler) query(c *gin.Context, invalidID string,
+	fn func(uint, *dto.TrafficQueryReq) (*dto.TrafficSeriesResp, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, invalidID)
+		return
+	}
+
+	var req dto.TrafficQueryReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	result, err := fn(uint(id), &req)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// NodeGostMetrics 查询节点 Gost 指标历史
+func (h *TrafficHandler) NodeGostMetrics(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的节点 ID")
+		return
+	}
+
+	var req dto.GostMetricQueryReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.gostMetricsService.Query(uint(id), &req)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
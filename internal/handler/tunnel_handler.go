@@ -87,7 +87,7 @@ func (h *TunnelHandler) Delete(c *gin.Context) {
 	ip := c.ClientIP()
 	ua := c.GetHeader("User-Agent")
 
-	if err := h.tunnelService.Delete(uint(id), userID.(uint), username.(string), ip, ua); err != nil {
+	if err := h.tunnelService.Delete(c.Request.Context(), uint(id), userID.(uint), username.(string), ip, ua); err != nil {
 		response.HandleError(c, err)
 		return
 	}
@@ -143,7 +143,7 @@ func (h *TunnelHandler) Start(c *gin.Context) {
 	ip := c.ClientIP()
 	ua := c.GetHeader("User-Agent")
 
-	if err := h.tunnelService.Start(uint(id), userID.(uint), username.(string), ip, ua); err != nil {
+	if err := h.tunnelService.Start(c.Request.Context(), uint(id), userID.(uint), username.(string), ip, ua); err != nil {
 		response.HandleError(c, err)
 		return
 	}
@@ -165,7 +165,7 @@ func (h *TunnelHandler) Stop(c *gin.Context) {
 	ip := c.ClientIP()
 	ua := c.GetHeader("User-Agent")
 
-	if err := h.tunnelService.Stop(uint(id), userID.(uint), username.(string), ip, ua); err != nil {
+	if err := h.tunnelService.Stop(c.Request.Context(), uint(id), userID.(uint), username.(string), ip, ua); err != nil {
 		response.HandleError(c, err)
 		return
 	}
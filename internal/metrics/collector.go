@@ -0,0 +1,258 @@
+package metrics
+
+import (
+	"gost-panel/internal/dto"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+var (
+	nodeLabels   = []string{"node_id", "node"}
+	ruleLabels   = []string{"rule_id", "rule", "type", "protocol", "listen_port", "node", "tunnel"}
+	tunnelLabels = []string{"tunnel_id", "tunnel", "protocol", "entry_node", "exit_node"}
+	gostLabels   = []string{"node_id", "node", "object"}
+
+	nodeStatuses   = []model.NodeStatus{model.NodeStatusOnline, model.NodeStatusOffline, model.NodeStatusError}
+	ruleStatuses   = []model.RuleStatus{model.RuleStatusRunning, model.RuleStatusStopped, model.RuleStatusError}
+	tunnelStatuses = []model.TunnelStatus{model.TunnelStatusRunning, model.TunnelStatusStopped, model.TunnelStatusError}
+)
+
+// newDesc 创建指标描述
+func newDesc(name, help string, labels []string, extra ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help,
+		append(append([]string{}, labels...), extra...), nil)
+}
+
+// ResourceCollector 规则、隧道、节点的流量和状态指标，抓取时从数据库读取
+type ResourceCollector struct {
+	nodeRepo   *repository.NodeRepository
+	ruleRepo   *repository.RuleRepository
+	tunnelRepo *repository.TunnelRepository
+
+	nodeInput, nodeOutput, nodeConns, nodeStatus, nodeMaintenance *prometheus.Desc
+	ruleInput, ruleOutput, ruleConnsTotal, ruleErrors             *prometheus.Desc
+	ruleConns, ruleStatus, ruleDesired                            *prometheus.Desc
+	tunnelInput, tunnelOutput, tunnelStatus                       *prometheus.Desc
+	scrapeErrors                                                  *prometheus.Desc
+}
+
+// NewResourceCollector 创建规则、隧道、节点指标采集器
+func NewResourceCollector(db *gorm.DB) *ResourceCollector {
+	return &ResourceCollector{
+		nodeRepo:   repository.NewNodeRepository(db),
+		ruleRepo:   repository.NewRuleRepository(db),
+		tunnelRepo: repository.NewTunnelRepository(db),
+
+		nodeInput:       newDesc("node_input_bytes_total", "节点入站流量（以该节点为入口的规则流量之和）", nodeLabels),
+		nodeOutput:      newDesc("node_output_bytes_total", "节点出站流量", nodeLabels),
+		nodeConns:       newDesc("node_current_connections", "节点当前连接数（以该节点为入口的规则之和）", nodeLabels),
+		nodeStatus:      newDesc("node_status", "节点状态，当前状态为 1", nodeLabels, "status"),
+		nodeMaintenance: newDesc("node_maintenance", "节点是否处于维护模式", nodeLabels),
+
+		ruleInput:      newDesc("rule_input_bytes_total", "规则入站流量", ruleLabels),
+		ruleOutput:     newDesc("rule_output_bytes_total", "规则出站流量", ruleLabels),
+		ruleConnsTotal: newDesc("rule_connections_total", "规则累计连接数", ruleLabels),
+		ruleErrors:     newDesc("rule_errors_total", "规则累计错误数", ruleLabels),
+		ruleConns:      newDesc("rule_current_connections", "规则当前连接数", ruleLabels),
+		ruleStatus:     newDesc("rule_status", "规则状态，当前状态为 1", ruleLabels, "status"),
+		ruleDesired:    newDesc("rule_desired_running", "规则是否期望运行（已启动）", ruleLabels),
+
+		tunnelInput:  newDesc("tunnel_input_bytes_total", "隧道入站流量（隧道规则流量之和）", tunnelLabels),
+		tunnelOutput: newDesc("tunnel_output_bytes_total", "隧道出站流量", tunnelLabels),
+		tunnelStatus: newDesc("tunnel_status", "隧道状态，当前状态为 1", tunnelLabels, "status"),
+
+		scrapeErrors: newDesc("resource_scrape_error", "本次抓取读取数据库是否失败", nil, "resource"),
+	}
+}
+
+// Describe 实现 prometheus.Collector
+func (c *ResourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.nodeInput, c.nodeOutput, c.nodeConns, c.nodeStatus, c.nodeMaintenance,
+		c.ruleInput, c.ruleOutput, c.ruleConnsTotal, c.ruleErrors, c.ruleConns, c.ruleStatus, c.ruleDesired,
+		c.tunnelInput, c.tunnelOutput, c.tunnelStatus, c.scrapeErrors,
+	} {
+		ch <- d
+	}
+}
+
+// Collect 实现 prometheus.Collector
+func (c *ResourceCollector) Collect(ch chan<- prometheus.Metric) {
+	nodes, err := c.nodeRepo.GetAll()
+	c.scrapeError(ch, "node", err)
+	tunnels, err := c.tunnelRepo.FindAll()
+	c.scrapeError(ch, "tunnel", err)
+	rules, err := c.ruleRepo.FindAll()
+	c.scrapeError(ch, "rule", err)
+
+	nodeNames := make(map[uint]string, len(nodes))
+	for _, n := range nodes {
+		nodeNames[n.ID] = n.Name
+	}
+	tunnelByID := make(map[uint]*model.GostTunnel, len(tunnels))
+	for i := range tunnels {
+		tunnelByID[tunnels[i].ID] = &tunnels[i]
+	}
+
+	// 规则
+	nodeConns := make(map[uint]int64, len(nodes))
+	for _, r := range rules {
+		var entryNodeID uint
+		tunnelName := ""
+		if r.Type == model.RuleTypeTunnel && r.TunnelID != nil {
+			if t := tunnelByID[*r.TunnelID]; t != nil {
+				entryNodeID = t.EntryNodeID
+				tunnelName = t.Name
+			}
+		} else if r.NodeID != nil {
+			entryNodeID = *r.NodeID
+		}
+		nodeConns[entryNodeID] += r.CurrentConns
+
+		labels := []string{formatID(r.ID), r.Name, string(r.Type), string(r.Protocol),
+			formatID(uint(r.ListenPort)), nodeNames[entryNodeID], tunnelName}
+		counter(ch, c.ruleInput, r.InputBytes, labels)
+		counter(ch, c.ruleOutput, r.OutputBytes, labels)
+		counter(ch, c.ruleConnsTotal, r.TotalRequests, labels)
+		counter(ch, c.ruleErrors, r.TotalErrors, labels)
+		gauge(ch, c.ruleConns, float64(r.CurrentConns), labels)
+		gauge(ch, c.ruleDesired, boolValue(r.DesiredRunning), labels)
+		for _, st := range ruleStatuses {
+			gauge(ch, c.ruleStatus, boolValue(r.Status == st), append(labels, string(st)))
+		}
+	}
+
+	// 隧道
+	for _, t := range tunnels {
+		labels := []string{formatID(t.ID), t.Name, t.Protocol, nodeNames[t.EntryNodeID], nodeNames[t.ExitNodeID]}
+		counter(ch, c.tunnelInput, t.InputBytes, labels)
+		counter(ch, c.tunnelOutput, t.OutputBytes, labels)
+		for _, st := range tunnelStatuses {
+			gauge(ch, c.tunnelStatus, boolValue(t.Status == st), append(labels, string(st)))
+		}
+	}
+
+	// 节点
+	for _, n := range nodes {
+		labels := []string{formatID(n.ID), n.Name}
+		counter(ch, c.nodeInput, n.InputBytes, labels)
+		counter(ch, c.nodeOutput, n.OutputBytes, labels)
+		gauge(ch, c.nodeConns, float64(nodeConns[n.ID]), labels)
+		gauge(ch, c.nodeMaintenance, boolValue(n.Maintenance), labels)
+		for _, st := range nodeStatuses {
+			gauge(ch, c.nodeStatus, boolValue(n.Status == st), append(labels, string(st)))
+		}
+	}
+}
+
+// scrapeError 输出读取数据库是否失败
+func (c *ResourceCollector) scrapeError(ch chan<- prometheus.Metric, resource string, err error) {
+	gauge(ch, c.scrapeErrors, boolValue(err != nil), []string{resource})
+}
+
+// ObserverCollector 观察器上报处理指标
+type ObserverCollector struct {
+	stats func() *dto.ObserverIngestStats
+
+	received, merged, dropped, rejected, flushed, flushes, flushErrors *prometheus.Desc
+	pending, capacity, lastFlush                                       *prometheus.Desc
+}
+
+// NewObserverCollector 创建观察器上报处理指标采集器，stats 返回当前处理统计
+func NewObserverCollector(stats func() *dto.ObserverIngestStats) *ObserverCollector {
+	return &ObserverCollector{
+		stats:       stats,
+		received:    newDesc("observer_events_received_total", "收到的观察器事件数", nil),
+		merged:      newDesc("observer_events_merged_total", "与缓冲区中同一服务事件合并的事件数", nil),
+		dropped:     newDesc("observer_events_dropped_total", "缓冲区已满被丢弃的事件数", nil),
+		rejected:    newDesc("observer_requests_rejected_total", "因缓冲区已满被拒绝的上报请求数", nil),
+		flushed:     newDesc("observer_events_flushed_total", "已写入数据库的事件数", nil),
+		flushes:     newDesc("observer_flushes_total", "缓冲区刷新次数", nil),
+		flushErrors: newDesc("observer_flush_errors_total", "缓冲区刷新失败次数", nil),
+		pending:     newDesc("observer_pending_events", "当前缓冲的服务事件数", nil),
+		capacity:    newDesc("observer_buffer_capacity", "缓冲区容量", nil),
+		lastFlush:   newDesc("observer_last_flush_duration_seconds", "最近一次刷新耗时", nil),
+	}
+}
+
+// Describe 实现 prometheus.Collector
+func (c *ObserverCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.received, c.merged, c.dropped, c.rejected, c.flushed, c.flushes, c.flushErrors,
+		c.pending, c.capacity, c.lastFlush,
+	} {
+		ch <- d
+	}
+}
+
+// Collect 实现 prometheus.Collector
+func (c *ObserverCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	counter(ch, c.received, s.ReceivedEvents, nil)
+	counter(ch, c.merged, s.MergedEvents, nil)
+	counter(ch, c.dropped, s.DroppedEvents, nil)
+	counter(ch, c.rejected, s.RejectedRequests, nil)
+	counter(ch, c.flushed, s.FlushedEvents, nil)
+	counter(ch, c.flushes, s.Flushes, nil)
+	counter(ch, c.flushErrors, s.FlushErrors, nil)
+	gauge(ch, c.pending, float64(s.Pending), nil)
+	gauge(ch, c.capacity, float64(s.Capacity), nil)
+	gauge(ch, c.lastFlush, float64(s.LastFlushMillis)/1000, nil)
+}
+
+// GostCollector 转出从各节点抓取的 Gost 自身指标（最近一次抓取的值）
+// 指标名称为 gost_panel_ 加 Gost 指标名称，计数器加 _total 后缀；Gost 服务可能随时增删，不预先声明指标
+type GostCollector struct {
+	values func() []dto.GostMetricValue
+}
+
+// NewGostCollector 创建 Gost 指标转出采集器，values 返回最近一次抓取的指标值
+func NewGostCollector(values func() []dto.GostMetricValue) *GostCollector {
+	return &GostCollector{values: values}
+}
+
+// Describe 实现 prometheus.Collector（不声明指标）
+func (c *GostCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect 实现 prometheus.Collector
+func (c *GostCollector) Collect(ch chan<- prometheus.Metric) {
+	descs := make(map[string]*prometheus.Desc)
+	for _, v := range c.values() {
+		name := v.Metric
+		if v.Counter {
+			name += "_total"
+		}
+		desc, ok := descs[name]
+		if !ok {
+			desc = newDesc(name, "节点 Gost 指标 "+v.Metric, gostLabels)
+			descs[name] = desc
+		}
+		labels := []string{formatID(v.NodeID), v.Node, v.Object}
+		if v.Counter {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v.Value, labels...)
+		} else {
+			gauge(ch, desc, v.Value, labels)
+		}
+	}
+}
+
+// counter 输出计数器
+func counter(ch chan<- prometheus.Metric, desc *prometheus.Desc, v int64, labels []string) {
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), labels...)
+}
+
+// gauge 输出仪表盘
+func gauge(ch chan<- prometheus.Metric, desc *prometheus.Desc, v float64, labels []string) {
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+}
+
+// boolValue 布尔值转换为 0/1
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+// Package metrics 面板 Prometheus 指标
+// 请求、健康检测、节点任务等指标在发生时记录；规则、隧道、节点的流量和状态在抓取时从数据库读取
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 指标名称前缀
+const namespace = "gost_panel"
+
+// Registry 面板指标注册表（不使用全局默认注册表，避免引入第三方库注册的指标）
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP 请求数",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 请求处理耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	healthCheckDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "node_health_check_duration_seconds",
+		Help:      "节点健康检测（Gost API 请求）耗时",
+		Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"node_id", "node", "result"})
+
+	nodeTaskDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "node_task_duration_seconds",
+		Help:      "节点后台任务（健康检测、规则同步等）执行耗时",
+		Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"task", "node_id", "node"})
+
+	nodeTaskFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "node_task_failures_total",
+		Help:      "节点后台任务失败次数",
+	}, []string{"task", "node_id", "node"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		healthCheckDuration,
+		nodeTaskDuration,
+		nodeTaskFailures,
+	)
+}
+
+// Register 注册抓取时采集的指标
+func Register(cs ...prometheus.Collector) {
+	Registry.MustRegister(cs...)
+}
+
+// ObserveHTTPRequest 记录一次 HTTP 请求，route 为路由模板（如 /api/v1/rules/:id）
+func ObserveHTTPRequest(method, route string, status int, d time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// ObserveHealthCheck 记录一次节点健康检测
+func ObserveHealthCheck(nodeID uint, node string, d time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	healthCheckDuration.WithLabelValues(formatID(nodeID), node, result).Observe(d.Seconds())
+}
+
+// ObserveNodeTask 记录一次节点后台任务执行
+func ObserveNodeTask(task string, nodeID uint, node string, d time.Duration, err error) {
+	id := formatID(nodeID)
+	nodeTaskDuration.WithLabelValues(task, id, node).Observe(d.Seconds())
+	if err != nil {
+		nodeTaskFailures.WithLabelValues(task, id, node).Inc()
+	}
+}
+
+// Handler 指标抓取接口，token 非空时要求 Authorization: Bearer <token>
+func Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// formatID 格式化 ID 标签
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
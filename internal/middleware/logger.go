@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"strings"
 	"time"
 
 	"gost-panel/pkg/logger"
@@ -27,6 +28,10 @@ func Logger() gin.HandlerFunc {
 		clientIP := c.ClientIP()
 		method := c.Request.Method
 		path := c.Request.URL.Path
+		// 路径中的令牌（如观察器上报地址）不写入日志
+		if token := c.Param("token"); token != "" {
+			path = strings.Replace(path, token, "***", 1)
+		}
 		statusCode := c.Writer.Status()
 		bodySize := c.Writer.Size()
 
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"time"
+
+	"gost-panel/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 请求指标中间件
+// 按路由模板统计，未匹配的路由（如前端静态资源）统一记为 unmatched，避免路径作为标签导致序列无限增长
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
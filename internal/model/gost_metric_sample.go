@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// GostMetricSample Gost 自身指标的时序采样（从节点 Gost 指标接口抓取）
+// 计数器记录时间桶内的增量，仪表盘记录时间桶内最后一次抓取的值
+type GostMetricSample struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	NodeID     uint      `gorm:"not null;uniqueIndex:idx_gost_metric_samples_bucket" json:"node_id"`                                        // 节点 ID
+	Metric     string    `gorm:"size:100;not null;uniqueIndex:idx_gost_metric_samples_bucket" json:"metric"`                                // 指标名称，如 gost_service_handler_errors
+	Object     string    `gorm:"size:100;not null;uniqueIndex:idx_gost_metric_samples_bucket" json:"object"`                                // 服务或转发链名称
+	BucketTime time.Time `gorm:"not null;uniqueIndex:idx_gost_metric_samples_bucket;index:idx_gost_metric_samples_time" json:"bucket_time"` // 分钟时间桶起点 (UTC)
+	Value      float64   `gorm:"default:0" json:"value"`
+}
+
+// TableName 指定表名
+func (GostMetricSample) TableName() string {
+	return "gost_metric_samples"
+}
@@ -15,6 +15,15 @@ const (
 	NodeStatusError   NodeStatus = "error"   // 错误
 )
 
+// NodeConnectionMode 节点连接方式
+type NodeConnectionMode string
+
+const (
+	NodeConnectionDirect NodeConnectionMode = "direct" // 面板直连节点 Gost API
+	NodeConnectionAgent  NodeConnectionMode = "agent"  // 节点 Agent 主动连接面板，请求经反向连接转发
+	NodeConnectionSSH    NodeConnectionMode = "ssh"    // 面板 SSH 登录节点，经隧道访问仅监听本机的 Gost API
+)
+
 // GostNode Gost 节点模型
 type GostNode struct {
 	ID       uint       `gorm:"primaryKey" json:"id"`
@@ -25,6 +34,39 @@ type GostNode struct {
 	Password string     `gorm:"size:255" json:"password"`              // API 认证密码
 	Status   NodeStatus `gorm:"size:20;default:offline" json:"status"` // 状态
 
+	// 连接方式：NAT 或防火墙后面板无法直连的节点使用 agent 模式
+	ConnectionMode NodeConnectionMode `gorm:"size:20;default:direct" json:"connection_mode"`
+
+	// SSH 连接方式：以 Address 和 SSHPort 登录节点，经隧道访问节点本机 Port 端口的 Gost API
+	// 密码、私钥及私钥密码加密存储，不在接口中返回；主机公钥首次保存时自动获取并固定
+	SSHPort       int    `gorm:"default:22" json:"ssh_port"`
+	SSHUser       string `gorm:"size:64" json:"ssh_user"`
+	SSHPassword   string `gorm:"type:text" json:"-"`
+	SSHPrivateKey string `gorm:"type:text" json:"-"`
+	SSHPassphrase string `gorm:"type:text" json:"-"`
+	SSHHostKey    string `gorm:"type:text" json:"ssh_host_key"` // authorized_keys 格式
+
+	// API TLS：开启后使用 https 连接节点 API；配置 CA 证书时仅信任该 CA，配置客户端证书时启用双向认证
+	TLSEnabled    bool   `gorm:"default:false" json:"tls_enabled"`
+	TLSSkipVerify bool   `gorm:"default:false" json:"tls_skip_verify"` // 跳过服务端证书校验
+	TLSServerName string `gorm:"size:255" json:"tls_server_name"`      // 证书校验使用的服务器名称
+	TLSCACert     string `gorm:"type:text" json:"tls_ca_cert"`         // PEM 格式 CA 证书
+	TLSClientCert string `gorm:"type:text" json:"tls_client_cert"`     // PEM 格式客户端证书
+	TLSClientKey  string `gorm:"type:text" json:"-"`                   // PEM 格式客户端私钥（加密存储，不在接口中返回）
+
+	// Gost 自身的 Prometheus 指标接口（Gost 配置中的 metrics.addr），端口为 0 时不抓取
+	MetricsPort int    `gorm:"default:0" json:"metrics_port"`
+	MetricsPath string `gorm:"size:100" json:"metrics_path"` // 默认 /metrics
+
+	// 维护模式：不记录状态变更、不自动恢复，且拒绝新建规则和隧道
+	Maintenance bool `gorm:"default:false" json:"maintenance"`
+
+	// 节点 Agent 上报主机指标使用的认证令牌
+	AgentToken string `gorm:"size:64;index" json:"-"`
+
+	// 观察器上报令牌：写入节点观察器的上报地址，面板据此识别上报节点，不在接口中返回
+	ObserverToken string `gorm:"size:64;index" json:"-"`
+
 	// 流量统计
 	TotalBytes  int64 `gorm:"default:0" json:"total_bytes"`
 	InputBytes  int64 `gorm:"default:0" json:"input_bytes"`
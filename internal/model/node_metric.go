@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// NodeMetric 节点主机指标采样（由节点 Agent 上报）
+type NodeMetric struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	NodeID       uint      `gorm:"index:idx_node_metrics_node_time;not null" json:"node_id"` // 节点 ID
+	CPUPercent   float64   `json:"cpu_percent"`                                              // CPU 使用率 (%)
+	Load1        float64   `json:"load1"`                                                    // 1 分钟平均负载
+	MemTotal     int64     `json:"mem_total"`                                                // 内存总量 (bytes)
+	MemUsed      int64     `json:"mem_used"`                                                 // 已用内存 (bytes)
+	DiskTotal    int64     `json:"disk_total"`                                               // 磁盘总量 (bytes)
+	DiskUsed     int64     `json:"disk_used"`                                                // 已用磁盘 (bytes)
+	NetRxRate    int64     `json:"net_rx_rate"`                                              // 网络接收速率 (bytes/s)
+	NetTxRate    int64     `json:"net_tx_rate"`                                              // 网络发送速率 (bytes/s)
+	GostUptime   int64     `json:"gost_uptime"`                                              // Gost 进程运行时长 (秒)，0 表示未运行
+	GostVersion  string    `gorm:"size:50" json:"gost_version"`                              // Gost 版本
+	AgentVersion string    `gorm:"size:50" json:"agent_version"`                             // Agent 版本
+	CreatedAt    time.Time `gorm:"index:idx_node_metrics_node_time" json:"created_at"`
+}
+
+// TableName 指定表名
+func (NodeMetric) TableName() string {
+	return "node_metrics"
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// NodeStatusHistory 节点状态变更记录
+type NodeStatusHistory struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	NodeID      uint       `gorm:"index;not null" json:"node_id"`    // 节点 ID
+	FromStatus  NodeStatus `gorm:"size:20" json:"from_status"`       // 变更前状态
+	ToStatus    NodeStatus `gorm:"size:20" json:"to_status"`         // 变更后状态
+	Reason      string     `gorm:"type:text" json:"reason"`          // 变更原因（如最后一次检测错误）
+	Maintenance bool       `gorm:"default:false" json:"maintenance"` // 变更发生在维护期间（计划内停机）
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (NodeStatusHistory) TableName() string {
+	return "node_status_histories"
+}
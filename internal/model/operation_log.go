@@ -36,6 +36,14 @@ const (
 	ActionDelete         = "delete"          // 删除
 	ActionStart          = "start"           // 启动
 	ActionStop           = "stop"            // 停止
+	ActionRepair         = "repair"          // 修复配置漂移
+	ActionPrune          = "prune"           // 清理孤立配置
+	ActionImport         = "import"          // 导入现有配置
+	ActionMaintenance    = "maintenance"     // 进入/退出维护模式
+	ActionMigrate        = "migrate"         // 迁移规则/隧道
+	ActionClone          = "clone"           // 克隆节点规则/隧道
+	ActionResetToken     = "reset_token"     // 重置 Agent 令牌
+	ActionProvision      = "provision"       // 经 SSH 部署/升级/卸载节点
 )
 
 // 资源类型常量
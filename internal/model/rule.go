@@ -43,13 +43,21 @@ type GostRule struct {
 	TunnelID   *uint        `gorm:"index" json:"tunnel_id"`                       // 隧道 ID（隧道转发时使用）
 	Protocol   RuleProtocol `gorm:"size:10;not null;default:tcp" json:"protocol"` // 协议
 	ListenPort int          `gorm:"not null" json:"listen_port"`                  // 监听端口
+	ListenHost string       `gorm:"size:100" json:"listen_host"`                  // 监听地址（导入时保留原绑定地址，为空时监听所有地址）
 
 	Targets   []string   `gorm:"type:json;serializer:json" json:"targets"` // 多目标列表 (host:port)
 	Strategy  string     `gorm:"size:20;default:round" json:"strategy"`    // 负载均衡策略 (round, random, fifo)
 	EnableTLS bool       `gorm:"default:false" json:"enable_tls"`          // 是否启用 TLS
-	Status    RuleStatus `gorm:"size:20;default:stopped" json:"status"`    // 状态
+	Status    RuleStatus `gorm:"size:20;default:stopped" json:"status"`    // 状态（节点实际运行状态）
+	StatusMsg string     `gorm:"type:text" json:"status_msg"`              // 错误状态的原因（如 Gost 服务报错信息）
 	ServiceID string     `gorm:"size:100" json:"service_id"`               // Gost 服务 ID
 
+	// 期望运行状态（用户启动后为 true，停止后为 false），节点恢复在线时据此自动重建
+	DesiredRunning bool `gorm:"default:false" json:"desired_running"`
+
+	// 联动镜像：指向源规则 ID，源规则修改时同步到此规则（用于热备入口节点）
+	MirrorOfID *uint `gorm:"index" json:"mirror_of_id"`
+
 	// 流量监控配置
 	ObserverID string `gorm:"size:100" json:"observer_id"` // 观察器 ID
 
@@ -58,6 +66,8 @@ type GostRule struct {
 	OutputBytes   int64 `gorm:"default:0" json:"output_bytes"`   // 出站总流量 (bytes)
 	TotalBytes    int64 `gorm:"default:0" json:"total_bytes"`    // 总流量 (Input + Output)
 	TotalRequests int64 `gorm:"default:0" json:"total_requests"` // 总请求数
+	TotalErrors   int64 `gorm:"default:0" json:"total_errors"`   // 总错误数
+	CurrentConns  int64 `gorm:"default:0" json:"current_conns"`  // 当前连接数（服务停止后清零）
 
 	Remark    string         `gorm:"type:text" json:"remark"` // 备注
 	CreatedAt time.Time      `json:"created_at"`
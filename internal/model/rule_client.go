@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// RuleClient 规则按客户端聚合的流量统计
+// 由服务处理器级别的观察器上报，用于查看正在使用规则的来源及排查滥用
+type RuleClient struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	RuleID       uint      `gorm:"not null;uniqueIndex:idx_rule_clients_client" json:"rule_id"`         // 规则 ID
+	Client       string    `gorm:"size:100;not null;uniqueIndex:idx_rule_clients_client" json:"client"` // 客户端标识（Gost 上报的来源）
+	InputBytes   int64     `gorm:"default:0" json:"input_bytes"`                                        // 入站流量 (bytes)
+	OutputBytes  int64     `gorm:"default:0" json:"output_bytes"`                                       // 出站流量 (bytes)
+	TotalBytes   int64     `gorm:"default:0;index" json:"total_bytes"`                                  // 总流量
+	TotalConns   int64     `gorm:"default:0" json:"total_conns"`                                        // 总连接数
+	TotalErrors  int64     `gorm:"default:0" json:"total_errors"`                                       // 总错误数
+	CurrentConns int64     `gorm:"default:0" json:"current_conns"`                                      // 当前连接数
+	LastSeenAt   time.Time `gorm:"index" json:"last_seen_at"`                                           // 最近一次上报时间
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RuleClient) TableName() string {
+	return "rule_clients"
+}
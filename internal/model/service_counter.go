@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// ServiceCounter Gost 服务最近一次上报的累计计数器
+// 观察器上报的是服务启动以来的累计值，与上次值相减得到增量；计数器变小说明服务已重启
+// 处理器级别的客户端统计以 "{服务名称}/{客户端}" 为键记录
+// 接管的服务保留原名称（如 service-0），不同节点可能同名，计数器按节点区分
+type ServiceCounter struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	NodeID      uint      `gorm:"not null;default:0;uniqueIndex:idx_service_counter_node_service" json:"node_id"` // 上报节点 ID
+	Service     string    `gorm:"size:100;not null;uniqueIndex:idx_service_counter_node_service" json:"service"`  // Gost 服务名称
+	InputBytes  int64     `gorm:"default:0" json:"input_bytes"`                                                   // 累计入站流量 (bytes)
+	OutputBytes int64     `gorm:"default:0" json:"output_bytes"`                                                  // 累计出站流量 (bytes)
+	TotalConns  int64     `gorm:"default:0" json:"total_conns"`                                                   // 累计连接数
+	TotalErrs   int64     `gorm:"default:0" json:"total_errs"`                                                    // 累计错误数
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ServiceCounter) TableName() string {
+	return "service_counters"
+}
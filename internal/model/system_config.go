@@ -31,6 +31,22 @@ type SystemConfig struct {
 	AutoBackup           bool `gorm:"default:false" json:"auto_backup"`
 	BackupRetentionCount int  `gorm:"default:7" json:"backup_retention_count"`
 
+	// 流量历史保留天数（分钟、小时、天粒度）
+	TrafficMinuteRetentionDays int `gorm:"default:2" json:"traffic_minute_retention_days"`
+	TrafficHourRetentionDays   int `gorm:"default:30" json:"traffic_hour_retention_days"`
+	TrafficDayRetentionDays    int `gorm:"default:365" json:"traffic_day_retention_days"`
+
+	// 定时流量报表（按本地时区的自然日、周、月统计上一周期）
+	ReportDaily      bool   `gorm:"default:false" json:"report_daily"`
+	ReportWeekly     bool   `gorm:"default:false" json:"report_weekly"`
+	ReportMonthly    bool   `gorm:"default:false" json:"report_monthly"`
+	ReportRecipients string `gorm:"size:1000" json:"report_recipients"` // 收件人，逗号、分号或换行分隔
+	ReportHour       int    `gorm:"default:8" json:"report_hour"`       // 周期结束后当天几点发送 (0-23)
+	// 最近一次已发送报表的统计周期起点，避免重启后重复发送
+	ReportDailySentAt   time.Time `json:"-"`
+	ReportWeeklySentAt  time.Time `json:"-"`
+	ReportMonthlySentAt time.Time `json:"-"`
+
 	// 面板配置
 	SiteTitle string `gorm:"size:100;default:Gost Panel" json:"site_title"`
 	LogoURL   string `gorm:"size:255" json:"logo_url"`
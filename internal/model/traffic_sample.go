@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+)
+
+// TrafficResourceType 流量统计对象类型
+type TrafficResourceType string
+
+const (
+	TrafficResourceRule   TrafficResourceType = "rule"   // 规则
+	TrafficResourceNode   TrafficResourceType = "node"   // 节点
+	TrafficResourceTunnel TrafficResourceType = "tunnel" // 隧道
+)
+
+// TrafficPeriod 流量采样粒度
+type TrafficPeriod string
+
+const (
+	TrafficPeriodMinute TrafficPeriod = "minute" // 分钟（观察器上报累加）
+	TrafficPeriodHour   TrafficPeriod = "hour"   // 小时（由分钟采样汇总）
+	TrafficPeriodDay    TrafficPeriod = "day"    // 天（由小时采样汇总，按本地时区零点对齐）
+)
+
+// TrafficSample 流量时序采样，记录对象在一个时间桶内的流量增量
+type TrafficSample struct {
+	ID           uint                `gorm:"primaryKey" json:"id"`
+	ResourceType TrafficResourceType `gorm:"size:20;not null;uniqueIndex:idx_traffic_samples_bucket" json:"resource_type"` // 对象类型
+	ResourceID   uint                `gorm:"not null;uniqueIndex:idx_traffic_samples_bucket" json:"resource_id"`           // 对象 ID
+	Period       TrafficPeriod       `gorm:"size:10;not null;uniqueIndex:idx_traffic_samples_bucket;index:idx_traffic_samples_period_time" json:"period"`
+	BucketTime   time.Time           `gorm:"not null;uniqueIndex:idx_traffic_samples_bucket;index:idx_traffic_samples_period_time" json:"bucket_time"` // 时间桶起点 (UTC)
+	InputBytes   int64               `gorm:"default:0" json:"input_bytes"`                                                                             // 入站流量增量 (bytes)
+	OutputBytes  int64               `gorm:"default:0" json:"output_bytes"`                                                                            // 出站流量增量 (bytes)
+	Connections  int64               `gorm:"default:0" json:"connections"`                                                                             // 新建连接数增量
+}
+
+// TableName 指定表名
+func (TrafficSample) TableName() string {
+	return "traffic_samples"
+}
@@ -26,10 +26,18 @@ type GostTunnel struct {
 	RelayPort   int          `gorm:"default:8443" json:"relay_port"`      // 出口节点 Relay 服务端口
 	Status      TunnelStatus `gorm:"size:20;default:stopped" json:"status"`
 
+	// 期望运行状态（用户启动后为 true，停止后为 false），节点恢复在线时据此自动重建
+	DesiredRunning bool `gorm:"default:false" json:"desired_running"`
+
 	// Gost 服务相关 ID（启动时创建）
 	ServiceID string `gorm:"size:100" json:"service_id"` // 出口节点 Relay 服务 ID
 	ChainID   string `gorm:"size:100" json:"chain_id"`   // 入口节点 Chain ID
 
+	// 流量统计（隧道规则流量之和，由观察器更新）
+	InputBytes  int64 `gorm:"default:0" json:"input_bytes"`  // 入站总流量 (bytes)
+	OutputBytes int64 `gorm:"default:0" json:"output_bytes"` // 出站总流量 (bytes)
+	TotalBytes  int64 `gorm:"default:0" json:"total_bytes"`  // 总流量 (Input + Output)
+
 	Remark    string         `gorm:"type:text" json:"remark"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"time"
+
+	"gost-panel/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gostMetricBucketColumns Gost 指标采样唯一键
+var gostMetricBucketColumns = []clause.Column{
+	{Name: "node_id"}, {Name: "metric"}, {Name: "object"}, {Name: "bucket_time"},
+}
+
+// GostMetricRepository Gost 指标采样仓库
+type GostMetricRepository struct {
+	*BaseRepository
+}
+
+// NewGostMetricRepository 创建 Gost 指标采样仓库
+func NewGostMetricRepository(db *gorm.DB) *GostMetricRepository {
+	return &GostMetricRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Accumulate 累加计数器增量：时间桶已存在时在原值上累加
+func (r *GostMetricRepository) Accumulate(samples []model.GostMetricSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   gostMetricBucketColumns,
+		DoUpdates: clause.Assignments(map[string]interface{}{"value": gorm.Expr("value + excluded.value")}),
+	}).Create(&samples).Error
+}
+
+// Save 写入仪表盘值：时间桶已存在时覆盖原值
+func (r *GostMetricRepository) Save(samples []model.GostMetricSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   gostMetricBucketColumns,
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).Create(&samples).Error
+}
+
+// FindByMetric 查询节点指标在 [start, end) 内的采样（按时间正序）
+func (r *GostMetricRepository) FindByMetric(nodeID uint, metric string, start, end time.Time) ([]model.GostMetricSample, error) {
+	var samples []model.GostMetricSample
+	err := r.DB.Where("node_id = ? AND metric = ? AND bucket_time >= ? AND bucket_time < ?",
+		nodeID, metric, start.UTC(), end.UTC()).
+		Order("bucket_time ASC").Find(&samples).Error
+	return samples, err
+}
+
+// DeleteBefore 删除指定时间之前的采样
+func (r *GostMetricRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.DB.Where("bucket_time < ?", before.UTC()).Delete(&model.GostMetricSample{})
+	return result.RowsAffected, result.Error
+}
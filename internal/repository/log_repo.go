@@ -63,3 +63,14 @@ func (r *OperationLogRepository) FindByUserID(userID uint, opt *QueryOption) ([]
 
 	return r.List(opt)
 }
+
+// ListRecent 查询最近的操作日志，排除指定的操作类型
+func (r *OperationLogRepository) ListRecent(limit int, excludeActions ...string) ([]model.OperationLog, error) {
+	var logs []model.OperationLog
+	db := r.DB.Model(&model.OperationLog{})
+	if len(excludeActions) > 0 {
+		db = db.Where("action NOT IN ?", excludeActions)
+	}
+	err := db.Order("created_at DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
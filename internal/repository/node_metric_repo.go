@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"time"
+
+	"gost-panel/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// NodeMetricRepository 节点主机指标仓库
+type NodeMetricRepository struct {
+	*BaseRepository
+}
+
+// NewNodeMetricRepository 创建节点主机指标仓库
+func NewNodeMetricRepository(db *gorm.DB) *NodeMetricRepository {
+	return &NodeMetricRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Create 创建指标采样
+func (r *NodeMetricRepository) Create(metric *model.NodeMetric) error {
+	return r.DB.Create(metric).Error
+}
+
+// FindLatest 查询节点最新的指标采样，不存在时返回 nil
+func (r *NodeMetricRepository) FindLatest(nodeID uint) (*model.NodeMetric, error) {
+	var metrics []model.NodeMetric
+	err := r.DB.Where("node_id = ?", nodeID).Order("created_at DESC").Limit(1).Find(&metrics).Error
+	if err != nil || len(metrics) == 0 {
+		return nil, err
+	}
+	return &metrics[0], nil
+}
+
+// FindSince 查询指定时间之后的指标采样（按时间正序）
+func (r *NodeMetricRepository) FindSince(nodeID uint, since time.Time) ([]model.NodeMetric, error) {
+	var metrics []model.NodeMetric
+	err := r.DB.Where("node_id = ? AND created_at >= ?", nodeID, since).
+		Order("created_at ASC").Find(&metrics).Error
+	return metrics, err
+}
+
+// DeleteBefore 删除指定时间之前的指标采样
+func (r *NodeMetricRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.DB.Where("created_at < ?", before).Delete(&model.NodeMetric{})
+	return result.RowsAffected, result.Error
+}
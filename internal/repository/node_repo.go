@@ -113,6 +113,36 @@ func (r *NodeRepository) UpdateStatus(id uint, status model.NodeStatus) error {
 	return r.UpdateField(&model.GostNode{}, id, "status", status)
 }
 
+// FindByAgentToken 根据 Agent 令牌查询节点
+func (r *NodeRepository) FindByAgentToken(token string) (*model.GostNode, error) {
+	var node model.GostNode
+	err := r.DB.Where("agent_token = ?", token).First(&node).Error
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// FindByObserverToken 根据观察器上报令牌查询节点
+func (r *NodeRepository) FindByObserverToken(token string) (*model.GostNode, error) {
+	var node model.GostNode
+	err := r.DB.Where("observer_token = ?", token).First(&node).Error
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// UpdateAgentToken 更新节点 Agent 令牌
+func (r *NodeRepository) UpdateAgentToken(id uint, token string) error {
+	return r.DB.Model(&model.GostNode{}).Where("id = ?", id).Update("agent_token", token).Error
+}
+
+// UpdateMaintenance 更新节点维护模式
+func (r *NodeRepository) UpdateMaintenance(id uint, maintenance bool) error {
+	return r.DB.Model(&model.GostNode{}).Where("id = ?", id).Update("maintenance", maintenance).Error
+}
+
 // UpdateLastCheck 更新最后检查时间
 func (r *NodeRepository) UpdateLastCheck(id uint) error {
 	return r.UpdateField(&model.GostNode{}, id, "last_check_at", time.Now())
@@ -125,6 +155,20 @@ func (r *NodeRepository) GetAllOnline() ([]model.GostNode, error) {
 	return nodes, err
 }
 
+// GetAll 获取所有节点
+func (r *NodeRepository) GetAll() ([]model.GostNode, error) {
+	var nodes []model.GostNode
+	err := r.DB.Find(&nodes).Error
+	return nodes, err
+}
+
+// FindAllWithDeleted 查询全部节点（包含已删除节点，用于流量报表）
+func (r *NodeRepository) FindAllWithDeleted() ([]model.GostNode, error) {
+	var nodes []model.GostNode
+	err := r.DB.Unscoped().Find(&nodes).Error
+	return nodes, err
+}
+
 // CountByStatus 按状态统计数量
 func (r *NodeRepository) CountByStatus(status model.NodeStatus) (int64, error) {
 	var count int64
@@ -148,3 +192,12 @@ func (r *NodeRepository) UpdateStats(id uint, inputBytes, outputBytes int64) err
 			"total_bytes":  gorm.Expr("total_bytes + ?", inputBytes+outputBytes),
 		}).Error
 }
+
+// SetStats 设置节点累计流量
+func (r *NodeRepository) SetStats(id uint, inputBytes, outputBytes int64) error {
+	return r.UpdateFields(&model.GostNode{}, id, map[string]interface{}{
+		"input_bytes":  inputBytes,
+		"output_bytes": outputBytes,
+		"total_bytes":  inputBytes + outputBytes,
+	})
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"time"
+
+	"gost-panel/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// NodeStatusHistoryRepository 节点状态变更记录仓库
+type NodeStatusHistoryRepository struct {
+	*BaseRepository
+}
+
+// NewNodeStatusHistoryRepository 创建节点状态变更记录仓库
+func NewNodeStatusHistoryRepository(db *gorm.DB) *NodeStatusHistoryRepository {
+	return &NodeStatusHistoryRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Create 创建状态变更记录
+func (r *NodeStatusHistoryRepository) Create(history *model.NodeStatusHistory) error {
+	return r.DB.Create(history).Error
+}
+
+// FindLastBefore 查询指定时间之前最后一条状态变更记录，不存在时返回 nil
+func (r *NodeStatusHistoryRepository) FindLastBefore(nodeID uint, before time.Time) (*model.NodeStatusHistory, error) {
+	var histories []model.NodeStatusHistory
+	err := r.DB.Where("node_id = ? AND created_at < ?", nodeID, before).
+		Order("created_at DESC").Limit(1).Find(&histories).Error
+	if err != nil || len(histories) == 0 {
+		return nil, err
+	}
+	return &histories[0], nil
+}
+
+// FindSince 查询指定时间之后的状态变更记录（按时间正序）
+func (r *NodeStatusHistoryRepository) FindSince(nodeID uint, since time.Time) ([]model.NodeStatusHistory, error) {
+	var histories []model.NodeStatusHistory
+	err := r.DB.Where("node_id = ? AND created_at >= ?", nodeID, since).
+		Order("created_at ASC").Find(&histories).Error
+	return histories, err
+}
+
+// ListRecent 查询最近的状态变更记录（按时间倒序）
+func (r *NodeStatusHistoryRepository) ListRecent(nodeID uint, limit int) ([]model.NodeStatusHistory, error) {
+	var histories []model.NodeStatusHistory
+	err := r.DB.Where("node_id = ?", nodeID).
+		Order("created_at DESC").Limit(limit).Find(&histories).Error
+	return histories, err
+}
+
+// FindBetween 查询全部节点在 [start, end) 内的状态变更记录（按时间正序）
+func (r *NodeStatusHistoryRepository) FindBetween(start, end time.Time, limit int) ([]model.NodeStatusHistory, error) {
+	var histories []model.NodeStatusHistory
+	err := r.DB.Where("created_at >= ? AND created_at < ?", start, end).
+		Order("created_at ASC").Limit(limit).Find(&histories).Error
+	return histories, err
+}
+
+// DeleteBefore 删除指定时间之前的状态变更记录
+func (r *NodeStatusHistoryRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.DB.Where("created_at < ?", before).Delete(&model.NodeStatusHistory{})
+	return result.RowsAffected, result.Error
+}
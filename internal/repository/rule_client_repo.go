@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"gost-panel/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RuleClientRepository 规则客户端统计仓库
+type RuleClientRepository struct {
+	*BaseRepository
+}
+
+// NewRuleClientRepository 创建规则客户端统计仓库
+func NewRuleClientRepository(db *gorm.DB) *RuleClientRepository {
+	return &RuleClientRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Accumulate 累加客户端流量增量，并覆盖当前连接数和最近上报时间
+func (r *RuleClientRepository) Accumulate(client *model.RuleClient) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "rule_id"}, {Name: "client"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"input_bytes":   gorm.Expr("input_bytes + excluded.input_bytes"),
+			"output_bytes":  gorm.Expr("output_bytes + excluded.output_bytes"),
+			"total_bytes":   gorm.Expr("total_bytes + excluded.total_bytes"),
+			"total_conns":   gorm.Expr("total_conns + excluded.total_conns"),
+			"total_errors":  gorm.Expr("total_errors + excluded.total_errors"),
+			"current_conns": gorm.Expr("excluded.current_conns"),
+			"last_seen_at":  gorm.Expr("excluded.last_seen_at"),
+		}),
+	}).Create(client).Error
+}
+
+// FindTop 查询规则流量最多的客户端
+func (r *RuleClientRepository) FindTop(ruleID uint, limit int) ([]model.RuleClient, error) {
+	var clients []model.RuleClient
+	err := r.DB.Where("rule_id = ?", ruleID).
+		Order("total_bytes DESC").Order("id ASC").
+		Limit(limit).Find(&clients).Error
+	return clients, err
+}
+
+// FindTopActive 查询指定时间之后活跃过的客户端中累计流量最多的若干个（不区分规则）
+func (r *RuleClientRepository) FindTopActive(since time.Time, limit int) ([]model.RuleClient, error) {
+	var clients []model.RuleClient
+	err := r.DB.Where("last_seen_at >= ?", since).
+		Order("total_bytes DESC").Order("id ASC").
+		Limit(limit).Find(&clients).Error
+	return clients, err
+}
+
+// ResetCurrentConns 清零规则所有客户端的当前连接数（服务停止或重建时）
+func (r *RuleClientRepository) ResetCurrentConns(ruleID uint) error {
+	return r.DB.Model(&model.RuleClient{}).
+		Where("rule_id = ? AND current_conns <> 0", ruleID).
+		Update("current_conns", 0).Error
+}
+
+// DeleteByRuleID 删除规则的全部客户端统计
+func (r *RuleClientRepository) DeleteByRuleID(ruleID uint) error {
+	return r.DB.Where("rule_id = ?", ruleID).Delete(&model.RuleClient{}).Error
+}
+
+// DeleteInactiveBefore 删除指定时间之前就不再活跃的客户端统计
+func (r *RuleClientRepository) DeleteInactiveBefore(before time.Time) (int64, error) {
+	result := r.DB.Where("last_seen_at < ?", before).Delete(&model.RuleClient{})
+	return result.RowsAffected, result.Error
+}
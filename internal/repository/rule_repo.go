@@ -76,6 +76,13 @@ func (r *RuleRepository) List(opt *QueryOption) ([]model.GostRule, int64, error)
 	return rules, total, nil
 }
 
+// FindAll 查询全部规则
+func (r *RuleRepository) FindAll() ([]model.GostRule, error) {
+	var rules []model.GostRule
+	err := r.DB.Find(&rules).Error
+	return rules, err
+}
+
 // FindByNodeID 根据节点 ID 查询规则
 func (r *RuleRepository) FindByNodeID(nodeID uint) ([]model.GostRule, error) {
 	var rules []model.GostRule
@@ -83,6 +90,34 @@ func (r *RuleRepository) FindByNodeID(nodeID uint) ([]model.GostRule, error) {
 	return rules, err
 }
 
+// FindByEntryNodeID 查询以指定节点为入口的所有规则
+// 包含该节点上的端口转发规则，以及入口为该节点的隧道上的隧道转发规则
+func (r *RuleRepository) FindByEntryNodeID(nodeID uint) ([]model.GostRule, error) {
+	var rules []model.GostRule
+	tunnelIDs := r.DB.Model(&model.GostTunnel{}).Select("id").Where("entry_node_id = ?", nodeID)
+	err := r.DB.Preload("Tunnel").
+		Where("(type = ? AND node_id = ?) OR (type = ? AND tunnel_id IN (?))",
+			model.RuleTypeForward, nodeID, model.RuleTypeTunnel, tunnelIDs).
+		Find(&rules).Error
+	return rules, err
+}
+
+// FindByServiceID 根据服务名称查询以指定节点为入口的规则
+// 不同节点上的服务可能同名（如导入的规则），需按入口节点区分
+func (r *RuleRepository) FindByServiceID(nodeID uint, serviceID string) (*model.GostRule, error) {
+	var rule model.GostRule
+	tunnelIDs := r.DB.Model(&model.GostTunnel{}).Select("id").Where("entry_node_id = ?", nodeID)
+	err := r.DB.Preload("Node").Preload("Tunnel").
+		Where("service_id = ?", serviceID).
+		Where("(type = ? AND node_id = ?) OR (type = ? AND tunnel_id IN (?))",
+			model.RuleTypeForward, nodeID, model.RuleTypeTunnel, tunnelIDs).
+		First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
 // FindByTunnelID 根据隧道 ID 查询规则
 func (r *RuleRepository) FindByTunnelID(tunnelID uint) ([]model.GostRule, error) {
 	var rules []model.GostRule
@@ -90,6 +125,29 @@ func (r *RuleRepository) FindByTunnelID(tunnelID uint) ([]model.GostRule, error)
 	return rules, err
 }
 
+// FindByTunnelIDs 根据隧道 ID 列表查询规则
+func (r *RuleRepository) FindByTunnelIDs(tunnelIDs []uint) ([]model.GostRule, error) {
+	var rules []model.GostRule
+	if len(tunnelIDs) == 0 {
+		return rules, nil
+	}
+	err := r.DB.Where("tunnel_id IN ?", tunnelIDs).Find(&rules).Error
+	return rules, err
+}
+
+// FindMirrors 查询联动镜像到指定源规则的规则
+func (r *RuleRepository) FindMirrors(sourceID uint) ([]model.GostRule, error) {
+	var rules []model.GostRule
+	err := r.DB.Where("mirror_of_id = ?", sourceID).Find(&rules).Error
+	return rules, err
+}
+
+// ClearMirrorOf 解除指定源规则的所有联动镜像
+func (r *RuleRepository) ClearMirrorOf(sourceID uint) error {
+	return r.DB.Model(&model.GostRule{}).Where("mirror_of_id = ?", sourceID).
+		Update("mirror_of_id", nil).Error
+}
+
 // ExistsByPort 检查端口是否已被使用
 func (r *RuleRepository) ExistsByPort(nodeID uint, port int, excludeID ...uint) (bool, error) {
 	var count int64
@@ -105,9 +163,26 @@ func (r *RuleRepository) ExistsByPort(nodeID uint, port int, excludeID ...uint)
 	return count > 0, nil
 }
 
-// UpdateStatus 更新规则状态
+// UpdateStatus 更新规则状态（同时清除状态原因）
 func (r *RuleRepository) UpdateStatus(id uint, status model.RuleStatus) error {
-	return r.UpdateField(&model.GostRule{}, id, "status", status)
+	return r.UpdateStatusMsg(id, status, "")
+}
+
+// UpdateStatusMsg 更新规则状态及状态原因（非运行状态时清零当前连接数）
+func (r *RuleRepository) UpdateStatusMsg(id uint, status model.RuleStatus, msg string) error {
+	fields := map[string]interface{}{
+		"status":     status,
+		"status_msg": msg,
+	}
+	if status != model.RuleStatusRunning {
+		fields["current_conns"] = 0
+	}
+	return r.UpdateFields(&model.GostRule{}, id, fields)
+}
+
+// UpdateDesiredRunning 更新期望运行状态
+func (r *RuleRepository) UpdateDesiredRunning(id uint, desired bool) error {
+	return r.UpdateField(&model.GostRule{}, id, "desired_running", desired)
 }
 
 // UpdateServiceID 更新服务 ID
@@ -123,6 +198,11 @@ func (r *RuleRepository) UpdateServiceInfo(id uint, serviceID, chainID string) e
 	})
 }
 
+// UpdateTunnelID 更新规则使用的隧道
+func (r *RuleRepository) UpdateTunnelID(id uint, tunnelID uint) error {
+	return r.DB.Model(&model.GostRule{}).Where("id = ?", id).Update("tunnel_id", tunnelID).Error
+}
+
 // UpdateObserverID 更新观察器 ID
 func (r *RuleRepository) UpdateObserverID(id uint, observerID string) error {
 	return r.UpdateField(&model.GostRule{}, id, "observer_id", observerID)
@@ -160,17 +240,31 @@ func (r *RuleRepository) CountByType(ruleType model.RuleType) (int64, error) {
 func (r *RuleRepository) StopByNodeID(nodeID uint) error {
 	return r.DB.Model(&model.GostRule{}).
 		Where("node_id = ? AND status = ?", nodeID, model.RuleStatusRunning).
-		Update("status", model.RuleStatusStopped).Error
+		Updates(map[string]interface{}{"status": model.RuleStatusStopped, "current_conns": 0}).Error
 }
 
-// UpdateStats 更新流量统计
-func (r *RuleRepository) UpdateStats(id uint, inputBytes, outputBytes, totalRequests int64) error {
-	return r.UpdateFields(&model.GostRule{}, id, map[string]interface{}{
-		"input_bytes":    inputBytes,
-		"output_bytes":   outputBytes,
-		"total_bytes":    inputBytes + outputBytes,
-		"total_requests": totalRequests,
-	})
+// AddStats 累加规则流量
+func (r *RuleRepository) AddStats(id uint, inputBytes, outputBytes, totalRequests, totalErrors int64) error {
+	return r.DB.Model(&model.GostRule{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"input_bytes":    gorm.Expr("input_bytes + ?", inputBytes),
+			"output_bytes":   gorm.Expr("output_bytes + ?", outputBytes),
+			"total_bytes":    gorm.Expr("total_bytes + ?", inputBytes+outputBytes),
+			"total_requests": gorm.Expr("total_requests + ?", totalRequests),
+			"total_errors":   gorm.Expr("total_errors + ?", totalErrors),
+		}).Error
+}
+
+// UpdateCurrentConns 更新规则当前连接数
+func (r *RuleRepository) UpdateCurrentConns(id uint, conns int64) error {
+	return r.DB.Model(&model.GostRule{}).Where("id = ?", id).Update("current_conns", conns).Error
+}
+
+// FindAllWithDeleted 查询全部规则（包含已删除规则，用于重新计算累计流量）
+func (r *RuleRepository) FindAllWithDeleted() ([]model.GostRule, error) {
+	var rules []model.GostRule
+	err := r.DB.Unscoped().Find(&rules).Error
+	return rules, err
 }
 
 // StopByTunnelIDs 停止指定隧道列表关联的所有规则
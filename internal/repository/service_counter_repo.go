@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"gost-panel/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ServiceCounterRepository 服务累计计数器仓库
+type ServiceCounterRepository struct {
+	*BaseRepository
+}
+
+// NewServiceCounterRepository 创建服务累计计数器仓库
+func NewServiceCounterRepository(db *gorm.DB) *ServiceCounterRepository {
+	return &ServiceCounterRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Find 查询节点上服务最近一次上报的计数器，不存在时返回 nil
+func (r *ServiceCounterRepository) Find(nodeID uint, service string) (*model.ServiceCounter, error) {
+	var counters []model.ServiceCounter
+	err := r.DB.Where("node_id = ? AND service = ?", nodeID, service).Limit(1).Find(&counters).Error
+	if err != nil || len(counters) == 0 {
+		return nil, err
+	}
+	return &counters[0], nil
+}
+
+// Save 保存服务计数器（按节点和服务名称覆盖）
+func (r *ServiceCounterRepository) Save(counter *model.ServiceCounter) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "node_id"}, {Name: "service"}},
+		DoUpdates: clause.AssignmentColumns([]string{"input_bytes", "output_bytes", "total_conns", "total_errs", "updated_at"}),
+	}).Create(counter).Error
+}
+
+// Delete 删除节点上的服务计数器及其客户端计数器（服务重建后计数从零开始）
+// 客户端计数器按 "{服务名称}/" 前缀的字节范围匹配，服务名称中的 % 和 _ 不会被当作通配符
+func (r *ServiceCounterRepository) Delete(nodeID uint, service string) error {
+	return r.DB.Where("node_id = ? AND (service = ? OR (service >= ? AND service < ?))",
+		nodeID, service, service+"/", service+"0").
+		Delete(&model.ServiceCounter{}).Error
+}
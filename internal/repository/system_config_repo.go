@@ -47,3 +47,8 @@ func (r *SystemConfigRepository) Update(config *model.SystemConfig) error {
 	config.ID = 1
 	return r.db.Save(config).Error
 }
+
+// UpdateColumn 更新单个配置字段（后台任务使用，避免覆盖同时保存的其他配置）
+func (r *SystemConfigRepository) UpdateColumn(column string, value any) error {
+	return r.db.Model(&model.SystemConfig{ID: 1}).UpdateColumn(column, value).Error
+}
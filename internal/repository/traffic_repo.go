@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"time"
+
+	"gost-panel/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// trafficBucketColumns 流量采样唯一键
+var trafficBucketColumns = []clause.Column{
+	{Name: "resource_type"}, {Name: "resource_id"}, {Name: "period"}, {Name: "bucket_time"},
+}
+
+// TrafficRepository 流量时序采样仓库
+type TrafficRepository struct {
+	*BaseRepository
+}
+
+// NewTrafficRepository 创建流量时序采样仓库
+func NewTrafficRepository(db *gorm.DB) *TrafficRepository {
+	return &TrafficRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Accumulate 累加采样：时间桶已存在时在原值上累加增量
+func (r *TrafficRepository) Accumulate(samples []model.TrafficSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns: trafficBucketColumns,
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"input_bytes":  gorm.Expr("input_bytes + excluded.input_bytes"),
+			"output_bytes": gorm.Expr("output_bytes + excluded.output_bytes"),
+			"connections":  gorm.Expr("connections + excluded.connections"),
+		}),
+	}).Create(&samples).Error
+}
+
+// Replace 替换指定粒度在 [start, end) 内的全部采样（用于幂等汇总，清除不再存在的时间桶）
+func (r *TrafficRepository) Replace(period model.TrafficPeriod, start, end time.Time, samples []model.TrafficSample) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("period = ? AND bucket_time >= ? AND bucket_time < ?", period, start.UTC(), end.UTC()).
+			Delete(&model.TrafficSample{}).Error; err != nil {
+			return err
+		}
+		if len(samples) == 0 {
+			return nil
+		}
+		return tx.Create(&samples).Error
+	})
+}
+
+// FindByPeriod 查询指定粒度在 [start, end) 内的全部采样
+func (r *TrafficRepository) FindByPeriod(period model.TrafficPeriod, start, end time.Time) ([]model.TrafficSample, error) {
+	var samples []model.TrafficSample
+	err := r.DB.Where("period = ? AND bucket_time >= ? AND bucket_time < ?", period, start.UTC(), end.UTC()).
+		Find(&samples).Error
+	return samples, err
+}
+
+// FindByResource 查询对象指定粒度在 [start, end) 内的采样（按时间正序）
+func (r *TrafficRepository) FindByResource(resourceType model.TrafficResourceType, resourceID uint, period model.TrafficPeriod, start, end time.Time) ([]model.TrafficSample, error) {
+	var samples []model.TrafficSample
+	err := r.DB.Where("resource_type = ? AND resource_id = ? AND period = ? AND bucket_time >= ? AND bucket_time < ?",
+		resourceType, resourceID, period, start.UTC(), end.UTC()).
+		Order("bucket_time ASC").Find(&samples).Error
+	return samples, err
+}
+
+// DeleteBefore 删除指定粒度在指定时间之前的采样
+func (r *TrafficRepository) DeleteBefore(period model.TrafficPeriod, before time.Time) (int64, error) {
+	result := r.DB.Where("period = ? AND bucket_time < ?", period, before.UTC()).Delete(&model.TrafficSample{})
+	return result.RowsAffected, result.Error
+}
+
+// TrafficTotals 流量合计
+type TrafficTotals struct {
+	InputBytes  int64
+	OutputBytes int64
+	Connections int64
+}
+
+// SumByType 统计指定类型全部对象指定粒度在 [start, end) 内的流量合计
+func (r *TrafficRepository) SumByType(resourceType model.TrafficResourceType, period model.TrafficPeriod, start, end time.Time) (*TrafficTotals, error) {
+	var totals TrafficTotals
+	err := r.DB.Model(&model.TrafficSample{}).
+		Select("COALESCE(SUM(input_bytes), 0) AS input_bytes, COALESCE(SUM(output_bytes), 0) AS output_bytes, COALESCE(SUM(connections), 0) AS connections").
+		Where("resource_type = ? AND period = ? AND bucket_time >= ? AND bucket_time < ?", resourceType, period, start.UTC(), end.UTC()).
+		Scan(&totals).Error
+	return &totals, err
+}
+
+// SumByResource 按对象统计指定类型指定粒度在 [start, end) 内的流量合计
+func (r *TrafficRepository) SumByResource(resourceType model.TrafficResourceType, period model.TrafficPeriod, start, end time.Time) (map[uint]*TrafficTotals, error) {
+	var rows []struct {
+		ResourceID uint
+		TrafficTotals
+	}
+	err := r.DB.Model(&model.TrafficSample{}).
+		Select("resource_id, SUM(input_bytes) AS input_bytes, SUM(output_bytes) AS output_bytes, SUM(connections) AS connections").
+		Where("resource_type = ? AND period = ? AND bucket_time >= ? AND bucket_time < ?", resourceType, period, start.UTC(), end.UTC()).
+		Group("resource_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[uint]*TrafficTotals, len(rows))
+	for i := range rows {
+		totals[rows[i].ResourceID] = &rows[i].TrafficTotals
+	}
+	return totals, nil
+}
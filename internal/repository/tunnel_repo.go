@@ -81,6 +81,11 @@ func (r *TunnelRepository) UpdateStatus(id uint, status model.TunnelStatus) erro
 	return r.UpdateField(&model.GostTunnel{}, id, "status", status)
 }
 
+// UpdateDesiredRunning 更新期望运行状态
+func (r *TunnelRepository) UpdateDesiredRunning(id uint, desired bool) error {
+	return r.UpdateField(&model.GostTunnel{}, id, "desired_running", desired)
+}
+
 // CountAll 统计总数
 func (r *TunnelRepository) CountAll() (int64, error) {
 	var count int64
@@ -88,6 +93,13 @@ func (r *TunnelRepository) CountAll() (int64, error) {
 	return count, err
 }
 
+// FindAll 查询全部隧道
+func (r *TunnelRepository) FindAll() ([]model.GostTunnel, error) {
+	var tunnels []model.GostTunnel
+	err := r.DB.Find(&tunnels).Error
+	return tunnels, err
+}
+
 // FindByNodeID 查找节点相关的隧道
 func (r *TunnelRepository) FindByNodeID(nodeID uint) ([]model.GostTunnel, error) {
 	var tunnels []model.GostTunnel
@@ -95,6 +107,15 @@ func (r *TunnelRepository) FindByNodeID(nodeID uint) ([]model.GostTunnel, error)
 	return tunnels, err
 }
 
+// ExistsByChainID 检查入口节点上是否已有隧道使用该 Chain
+func (r *TunnelRepository) ExistsByChainID(entryNodeID uint, chainID string) (bool, error) {
+	var count int64
+	err := r.DB.Model(&model.GostTunnel{}).
+		Where("entry_node_id = ? AND chain_id = ?", entryNodeID, chainID).
+		Count(&count).Error
+	return count > 0, err
+}
+
 // StopByNodeID 停止与该节点相关的所有隧道
 func (r *TunnelRepository) StopByNodeID(nodeID uint) error {
 	return r.DB.Model(&model.GostTunnel{}).
@@ -129,3 +150,29 @@ func (r *TunnelRepository) UpdateServiceInfo(id uint, serviceID, chainID string)
 			"chain_id":   chainID,
 		}).Error
 }
+
+// AddStats 累加隧道流量
+func (r *TunnelRepository) AddStats(id uint, inputBytes, outputBytes int64) error {
+	return r.DB.Model(&model.GostTunnel{}).Where("id = ?", id).
+		Updates(map[string]any{
+			"input_bytes":  gorm.Expr("input_bytes + ?", inputBytes),
+			"output_bytes": gorm.Expr("output_bytes + ?", outputBytes),
+			"total_bytes":  gorm.Expr("total_bytes + ?", inputBytes+outputBytes),
+		}).Error
+}
+
+// SetStats 设置隧道累计流量
+func (r *TunnelRepository) SetStats(id uint, inputBytes, outputBytes int64) error {
+	return r.UpdateFields(&model.GostTunnel{}, id, map[string]any{
+		"input_bytes":  inputBytes,
+		"output_bytes": outputBytes,
+		"total_bytes":  inputBytes + outputBytes,
+	})
+}
+
+// FindAllWithDeleted 查询全部隧道（包含已删除隧道，用于重新计算累计流量）
+func (r *TunnelRepository) FindAllWithDeleted() ([]model.GostTunnel, error) {
+	var tunnels []model.GostTunnel
+	err := r.DB.Unscoped().Find(&tunnels).Error
+	return tunnels, err
+}
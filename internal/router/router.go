@@ -3,6 +3,7 @@ package router
 import (
 	"gost-panel/internal/config"
 	"gost-panel/internal/handler"
+	"gost-panel/internal/metrics"
 	"gost-panel/internal/middleware"
 	"gost-panel/internal/repository"
 	"gost-panel/internal/service"
@@ -14,15 +15,19 @@ import (
 
 // Router 路由配置
 type Router struct {
-	db     *gorm.DB
-	jwtCfg *jwt.Config
+	db        *gorm.DB
+	jwtCfg    *jwt.Config
+	scheduler *service.NodeScheduler
+	observer  *service.ObserverService
 }
 
 // NewRouter 创建路由实例
-func NewRouter(db *gorm.DB, jwtCfg *jwt.Config) *Router {
+func NewRouter(db *gorm.DB, jwtCfg *jwt.Config, scheduler *service.NodeScheduler, observer *service.ObserverService) *Router {
 	return &Router{
-		db:     db,
-		jwtCfg: jwtCfg,
+		db:        db,
+		jwtCfg:    jwtCfg,
+		scheduler: scheduler,
+		observer:  observer,
 	}
 }
 
@@ -33,6 +38,7 @@ func (r *Router) Setup(engine *gin.Engine) {
 
 	// 全局中间件
 	engine.Use(middleware.CORS())
+	engine.Use(middleware.Metrics())
 	engine.Use(middleware.Logger())
 	engine.Use(middleware.Recovery())
 	engine.Use(middleware.ErrorHandler())
@@ -42,6 +48,11 @@ func (r *Router) Setup(engine *gin.Engine) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Prometheus 指标（未配置单独监听地址时挂载在面板端口）
+	if metricsCfg := config.Get().Metrics; metricsCfg.Enabled && metricsCfg.Listen == "" {
+		engine.GET(metricsCfg.Path, gin.WrapH(metrics.Handler(metricsCfg.Token)))
+	}
+
 	// API v1 路由组
 	apiV1 := engine.Group("/api/v1")
 
@@ -57,28 +68,47 @@ func (r *Router) Setup(engine *gin.Engine) {
 	tunnelService := service.NewTunnelService(r.db)
 	statsService := service.NewStatsService(r.db)
 	logService := service.NewLogService(r.db)
-	observerService := service.NewObserverService(r.db)
+	reconcileService := service.NewReconcileService(r.db)
+	importService := service.NewImportService(r.db)
+	maintenanceService := service.NewMaintenanceService(r.db)
+	cloneService := service.NewCloneService(r.db)
+	agentService := service.NewAgentService(r.db)
+	provisionService := service.NewProvisionService(r.db)
+	trafficService := service.NewTrafficService(r.db)
+	gostMetricsService := service.NewGostMetricsService(r.db, r.scheduler)
 
 	// 初始化系统配置
 	systemConfigRepo := repository.NewSystemConfigRepository(r.db)
 	systemConfigService := service.NewSystemConfigService(systemConfigRepo)
 	backupService := service.NewBackupService(r.db)
+	reportService := service.NewReportService(r.db)
 
 	// 初始化控制器
 	authHandler := handler.NewAuthHandler(authService)
 	nodeHandler := handler.NewNodeHandler(nodeService)
 	ruleHandler := handler.NewRuleHandler(ruleService)
 	tunnelHandler := handler.NewTunnelHandler(tunnelService)
-	statsHandler := handler.NewStatsHandler(statsService)
+	statsHandler := handler.NewStatsHandler(statsService, r.scheduler)
 	logHandler := handler.NewLogHandler(logService)
-	observerHandler := handler.NewObserverHandler(observerService)
-	systemConfigHandler := handler.NewSystemConfigHandler(systemConfigService, backupService)
+	observerHandler := handler.NewObserverHandler(r.observer)
+	reconcileHandler := handler.NewReconcileHandler(reconcileService)
+	importHandler := handler.NewImportHandler(importService)
+	maintenanceHandler := handler.NewMaintenanceHandler(maintenanceService, cloneService)
+	agentHandler := handler.NewAgentHandler(agentService)
+	provisionHandler := handler.NewProvisionHandler(provisionService)
+	trafficHandler := handler.NewTrafficHandler(trafficService, gostMetricsService)
+	systemConfigHandler := handler.NewSystemConfigHandler(systemConfigService, backupService, reportService)
 
 	// 公开路由（无需认证）
 	{
 		apiV1.POST("/auth/login", authHandler.Login)
-		// 流量上报接口
+		// 流量上报接口（节点观察器令牌认证，未携带令牌的旧地址一律拒绝）
+		apiV1.POST("/observer/report/:token", observerHandler.Report)
 		apiV1.POST("/observer/report", observerHandler.Report)
+		// 节点 Agent 主机指标上报接口（Agent 令牌认证）
+		apiV1.POST("/agent/report", agentHandler.Report)
+		// 节点 Agent 反向连接（WebSocket，agent 连接方式节点使用）
+		apiV1.GET("/agent/connect", agentHandler.Connect)
 		// 公开系统配置
 		apiV1.GET("/system/public-config", systemConfigHandler.GetPublicConfig)
 	}
@@ -94,6 +124,8 @@ func (r *Router) Setup(engine *gin.Engine) {
 
 		// 仪表盘统计
 		authRoutes.GET("/dashboard/stats", statsHandler.GetDashboard)
+		authRoutes.GET("/dashboard/scheduler", statsHandler.GetScheduler)
+		authRoutes.GET("/dashboard/observer", observerHandler.IngestStats)
 
 		// 节点管理
 		authRoutes.GET("/nodes", nodeHandler.List)
@@ -102,6 +134,21 @@ func (r *Router) Setup(engine *gin.Engine) {
 		authRoutes.PUT("/nodes/:id", nodeHandler.Update)
 		authRoutes.DELETE("/nodes/:id", nodeHandler.Delete)
 		authRoutes.GET("/nodes/:id/config", nodeHandler.GetConfig)
+		authRoutes.GET("/nodes/:id/reconcile", reconcileHandler.Inspect)
+		authRoutes.POST("/nodes/:id/reconcile/repair", reconcileHandler.Repair)
+		authRoutes.POST("/nodes/:id/reconcile/prune", reconcileHandler.Prune)
+		authRoutes.GET("/nodes/:id/import", importHandler.ListCandidates)
+		authRoutes.POST("/nodes/:id/import", importHandler.Adopt)
+		authRoutes.POST("/nodes/:id/maintenance", maintenanceHandler.Enter)
+		authRoutes.DELETE("/nodes/:id/maintenance", maintenanceHandler.Exit)
+		authRoutes.POST("/nodes/:id/clone", maintenanceHandler.Clone)
+		authRoutes.GET("/nodes/:id/install-command", agentHandler.GetToken)
+		authRoutes.POST("/nodes/:id/agent-token", agentHandler.ResetToken)
+		authRoutes.POST("/nodes/provision", provisionHandler.Provision)
+		authRoutes.POST("/nodes/:id/upgrade", provisionHandler.Upgrade)
+		authRoutes.POST("/nodes/:id/uninstall", provisionHandler.Uninstall)
+		authRoutes.GET("/nodes/:id/traffic", trafficHandler.NodeTraffic)
+		authRoutes.GET("/nodes/:id/gost-metrics", trafficHandler.NodeGostMetrics)
 
 		// 规则管理
 		authRoutes.GET("/rules", ruleHandler.List)
@@ -111,6 +158,8 @@ func (r *Router) Setup(engine *gin.Engine) {
 		authRoutes.DELETE("/rules/:id", ruleHandler.Delete)
 		authRoutes.POST("/rules/:id/start", ruleHandler.Start)
 		authRoutes.POST("/rules/:id/stop", ruleHandler.Stop)
+		authRoutes.GET("/rules/:id/traffic", trafficHandler.RuleTraffic)
+		authRoutes.GET("/rules/:id/clients", ruleHandler.TopClients)
 
 		// 隧道管理
 		authRoutes.GET("/tunnels", tunnelHandler.List)
@@ -120,6 +169,7 @@ func (r *Router) Setup(engine *gin.Engine) {
 		authRoutes.DELETE("/tunnels/:id", tunnelHandler.Delete)
 		authRoutes.POST("/tunnels/:id/start", tunnelHandler.Start)
 		authRoutes.POST("/tunnels/:id/stop", tunnelHandler.Stop)
+		authRoutes.GET("/tunnels/:id/traffic", trafficHandler.TunnelTraffic)
 
 		// 操作日志
 		authRoutes.GET("/logs", logHandler.List)
@@ -128,6 +178,7 @@ func (r *Router) Setup(engine *gin.Engine) {
 		authRoutes.GET("/system/config", systemConfigHandler.GetConfig)
 		authRoutes.PUT("/system/config", systemConfigHandler.UpdateConfig)
 		authRoutes.POST("/system/email/test", systemConfigHandler.TestEmail)
+		authRoutes.POST("/system/report/send", systemConfigHandler.SendReport)
 		authRoutes.POST("/system/backup", systemConfigHandler.Backup)
 	}
 
@@ -0,0 +1,160 @@
+package service
+
+import (
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"gost-panel/internal/agent"
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/internal/utils"
+	"gost-panel/pkg/logger"
+
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	// agentTokenBytes Agent 令牌随机字节数
+	agentTokenBytes = 24
+	// nodeMetricRetention 节点主机指标保留时长
+	nodeMetricRetention = 7 * 24 * time.Hour
+	// hostMetricWindow 节点详情中返回的主机指标时间范围
+	hostMetricWindow = time.Hour
+)
+
+// AgentService 节点 Agent 服务
+// 接收节点 Agent 上报的主机指标（CPU、内存、磁盘、网络、Gost 进程信息），
+// 并承载 agent 连接方式节点的反向连接
+type AgentService struct {
+	nodeRepo   *repository.NodeRepository
+	metricRepo *repository.NodeMetricRepository
+	logService *LogService
+}
+
+// NewAgentService 创建节点 Agent 服务
+func NewAgentService(db *gorm.DB) *AgentService {
+	return &AgentService{
+		nodeRepo:   repository.NewNodeRepository(db),
+		metricRepo: repository.NewNodeMetricRepository(db),
+		logService: NewLogService(db),
+	}
+}
+
+// NewAgentToken 生成节点 Agent 令牌
+func NewAgentToken() string {
+	return utils.RandomToken(agentTokenBytes)
+}
+
+// Authenticate 根据令牌查找所属节点
+func (s *AgentService) Authenticate(token string) (*model.GostNode, error) {
+	if token == "" {
+		return nil, errors.ErrAgentTokenInvalid
+	}
+
+	node, err := s.nodeRepo.FindByAgentToken(token)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrAgentTokenInvalid
+		}
+		return nil, err
+	}
+	return node, nil
+}
+
+// Report 保存 Agent 上报的主机指标，节点由 Authenticate 根据令牌确定
+func (s *AgentService) Report(node *model.GostNode, req *dto.AgentReportReq) error {
+	metric := &model.NodeMetric{
+		NodeID:       node.ID,
+		CPUPercent:   req.CPUPercent,
+		Load1:        req.Load1,
+		MemTotal:     req.MemTotal,
+		MemUsed:      req.MemUsed,
+		DiskTotal:    req.DiskTotal,
+		DiskUsed:     req.DiskUsed,
+		NetRxRate:    req.NetRxRate,
+		NetTxRate:    req.NetTxRate,
+		GostUptime:   req.GostUptime,
+		GostVersion:  req.GostVersion,
+		AgentVersion: req.AgentVersion,
+	}
+	if err := s.metricRepo.Create(metric); err != nil {
+		return err
+	}
+
+	logger.Debugf("[Agent] 节点 %s 上报指标: CPU %.1f%%, 内存 %d/%d", node.Name, req.CPUPercent, req.MemUsed, req.MemTotal)
+	return nil
+}
+
+// Connect 校验反向连接请求，仅 agent 连接方式的节点允许建立
+func (s *AgentService) Connect(token string) (*model.GostNode, error) {
+	node, err := s.Authenticate(token)
+	if err != nil {
+		return nil, err
+	}
+	if node.ConnectionMode != model.NodeConnectionAgent {
+		return nil, errors.ErrAgentModeDisabled
+	}
+	return node, nil
+}
+
+// Serve 处理已建立的反向连接，阻塞直到连接断开
+func (s *AgentService) Serve(node *model.GostNode, remoteAddr string, conn *websocket.Conn) {
+	logger.Infof("[Agent] 节点 %s 反向连接已建立 (%s)", node.Name, remoteAddr)
+	err := agent.Default().Serve(node.ID, remoteAddr, conn)
+	logger.Infof("[Agent] 节点 %s 反向连接已断开: %v", node.Name, err)
+}
+
+// GetToken 获取节点 Agent 令牌，用于生成安装命令（节点列表和详情不返回令牌）
+// 升级前创建的节点没有令牌时补发
+func (s *AgentService) GetToken(nodeID uint) (*dto.AgentTokenResp, error) {
+	node, err := s.nodeRepo.FindByID(nodeID)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrNodeNotFound
+		}
+		return nil, err
+	}
+
+	token := node.AgentToken
+	if token == "" {
+		token = NewAgentToken()
+		if err = s.nodeRepo.UpdateAgentToken(nodeID, token); err != nil {
+			return nil, err
+		}
+	}
+
+	return &dto.AgentTokenResp{NodeID: nodeID, AgentToken: token}, nil
+}
+
+// ResetToken 重新生成节点 Agent 令牌，旧令牌立即失效，已建立的反向连接同时断开
+func (s *AgentService) ResetToken(nodeID uint, userID uint, username string, ip, userAgent string) (*dto.AgentTokenResp, error) {
+	node, err := s.nodeRepo.FindByID(nodeID)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrNodeNotFound
+		}
+		return nil, err
+	}
+
+	token := NewAgentToken()
+	if err = s.nodeRepo.UpdateAgentToken(nodeID, token); err != nil {
+		return nil, err
+	}
+	agent.Default().Disconnect(nodeID)
+
+	s.logService.Record(
+		userID,
+		username,
+		model.ActionResetToken,
+		model.ResourceTypeNode,
+		nodeID,
+		fmt.Sprintf("重置节点 Agent 令牌: %s", node.Name),
+		ip,
+		userAgent)
+
+	return &dto.AgentTokenResp{NodeID: nodeID, AgentToken: token}, nil
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"sort"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// CloneService 节点克隆服务
+// 将源节点的端口转发规则（可选包括隧道及隧道转发规则）复制到目标节点，端口冲突时自动顺延；
+// 开启联动后副本规则记录源规则 ID，源规则修改时由 RuleService 同步到副本
+type CloneService struct {
+	nodeRepo      *repository.NodeRepository
+	ruleRepo      *repository.RuleRepository
+	tunnelRepo    *repository.TunnelRepository
+	ruleService   *RuleService
+	tunnelService *TunnelService
+	logService    *LogService
+}
+
+// cloneContext 一次克隆的上下文
+type cloneContext struct {
+	ctx       context.Context
+	source    *model.GostNode
+	target    *model.GostNode
+	linked    bool
+	ports     *portAllocator
+	userID    uint
+	username  string
+	ip        string
+	userAgent string
+}
+
+// NewCloneService 创建节点克隆服务
+func NewCloneService(db *gorm.DB) *CloneService {
+	return &CloneService{
+		nodeRepo:      repository.NewNodeRepository(db),
+		ruleRepo:      repository.NewRuleRepository(db),
+		tunnelRepo:    repository.NewTunnelRepository(db),
+		ruleService:   NewRuleService(db),
+		tunnelService: NewTunnelService(db),
+		logService:    NewLogService(db),
+	}
+}
+
+// Clone 将节点上的规则（及可选的隧道）克隆到目标节点
+// 先克隆隧道，再克隆端口转发规则，最后克隆以源节点为入口的隧道上的隧道转发规则
+func (s *CloneService) Clone(ctx context.Context, nodeID uint, req *dto.NodeCloneReq, userID uint, username string, ip, userAgent string) (*dto.NodeCloneResp, error) {
+	if req.TargetNodeID == nodeID {
+		return nil, errors.ErrCloneTargetSame
+	}
+
+	source, err := s.findNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.findNode(req.TargetNodeID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Maintenance {
+		return nil, errors.ErrNodeMaintenance
+	}
+
+	c := &cloneContext{
+		ctx:       ctx,
+		source:    source,
+		target:    target,
+		linked:    req.Linked,
+		ports:     newPortAllocator(ctx, s.nodeRepo, s.ruleRepo, s.tunnelRepo),
+		userID:    userID,
+		username:  username,
+		ip:        ip,
+		userAgent: userAgent,
+	}
+
+	resp := &dto.NodeCloneResp{
+		SourceNodeID: source.ID,
+		TargetNodeID: target.ID,
+		Tunnels:      []dto.ClonePortMapping{},
+		Rules:        []dto.ClonePortMapping{},
+	}
+
+	// 1. 隧道：源节点为入口的隧道副本用于承载隧道转发规则副本
+	tunnelCopies := make(map[uint]uint)
+	if req.IncludeTunnels {
+		tunnels, err := s.tunnelRepo.FindByNodeID(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tunnels {
+			mapping := s.cloneTunnel(c, t)
+			if mapping.Status == dto.CloneStatusCloned && t.EntryNodeID == nodeID {
+				tunnelCopies[t.ID] = mapping.TargetID
+			}
+			resp.Tunnels = append(resp.Tunnels, mapping)
+		}
+	}
+
+	// 2. 端口转发规则
+	rules, err := s.ruleRepo.FindByNodeID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if r.Type != model.RuleTypeForward {
+			continue
+		}
+		resp.Rules = append(resp.Rules, s.cloneRule(c, r, nil))
+	}
+
+	// 3. 隧道转发规则
+	tunnelIDs := make([]uint, 0, len(tunnelCopies))
+	for id := range tunnelCopies {
+		tunnelIDs = append(tunnelIDs, id)
+	}
+	sort.Slice(tunnelIDs, func(i, j int) bool { return tunnelIDs[i] < tunnelIDs[j] })
+	tunnelRules, err := s.ruleRepo.FindByTunnelIDs(tunnelIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range tunnelRules {
+		copyID := tunnelCopies[*r.TunnelID]
+		resp.Rules = append(resp.Rules, s.cloneRule(c, r, &copyID))
+	}
+
+	s.logService.Record(
+		userID,
+		username,
+		model.ActionClone,
+		model.ResourceTypeNode,
+		nodeID,
+		fmt.Sprintf("克隆节点 %s -> %s: 隧道 %d 条，规则 %d 条，联动: %v",
+			source.Name, target.Name, len(resp.Tunnels), len(resp.Rules), req.Linked),
+		ip,
+		userAgent)
+	logger.Infof("克隆节点 %s -> %s 完成: 隧道 %d 条，规则 %d 条", source.Name, target.Name, len(resp.Tunnels), len(resp.Rules))
+
+	return resp, nil
+}
+
+// findNode 查询节点
+func (s *CloneService) findNode(nodeID uint) (*model.GostNode, error) {
+	node, err := s.nodeRepo.FindByID(nodeID)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrNodeNotFound
+		}
+		return nil, err
+	}
+	return node, nil
+}
+
+// cloneTunnel 克隆隧道，源节点替换为目标节点
+// 源隧道期望运行时启动副本，启动失败保留副本记录并返回错误信息
+func (s *CloneService) cloneTunnel(c *cloneContext, t model.GostTunnel) dto.ClonePortMapping {
+	mapping := dto.ClonePortMapping{
+		ResourceType: model.ResourceTypeTunnel,
+		SourceID:     t.ID,
+		Name:         t.Name,
+		SourcePort:   t.RelayPort,
+	}
+
+	tunnel := &model.GostTunnel{
+		Name:        t.Name,
+		EntryNodeID: t.EntryNodeID,
+		ExitNodeID:  t.ExitNodeID,
+		Protocol:    t.Protocol,
+		Remark:      t.Remark,
+		Status:      model.TunnelStatusStopped,
+	}
+	if t.EntryNodeID == c.source.ID {
+		tunnel.EntryNodeID = c.target.ID
+	} else {
+		tunnel.ExitNodeID = c.target.ID
+	}
+	if tunnel.EntryNodeID == tunnel.ExitNodeID {
+		return failClone(mapping, errors.ErrTunnelNodeSame)
+	}
+
+	relayPort, err := c.ports.allocate(tunnel.ExitNodeID, t.RelayPort)
+	if err != nil {
+		return failClone(mapping, err)
+	}
+	tunnel.RelayPort = relayPort
+
+	if err = s.tunnelRepo.Create(tunnel); err != nil {
+		c.ports.release(tunnel.ExitNodeID, relayPort)
+		return failClone(mapping, err)
+	}
+
+	mapping.TargetID = tunnel.ID
+	mapping.TargetPort = relayPort
+	mapping.Remapped = relayPort != t.RelayPort
+	mapping.Status = dto.CloneStatusCloned
+
+	if t.DesiredRunning {
+		if err = s.tunnelService.Start(c.ctx, tunnel.ID, c.userID, c.username, c.ip, c.userAgent); err != nil {
+			mapping.Error = fmt.Sprintf("副本已创建，启动失败: %v", err)
+		}
+	}
+	return mapping
+}
+
+// cloneRule 克隆规则，监听端口冲突时顺延
+// tunnelID 不为空时为隧道转发规则，改用隧道副本
+func (s *CloneService) cloneRule(c *cloneContext, r model.GostRule, tunnelID *uint) dto.ClonePortMapping {
+	mapping := dto.ClonePortMapping{
+		ResourceType: model.ResourceTypeRule,
+		SourceID:     r.ID,
+		Name:         r.Name,
+		SourcePort:   r.ListenPort,
+	}
+
+	listenHost, err := portableListenHost(r.ListenHost)
+	if err != nil {
+		return failClone(mapping, err)
+	}
+	port, err := c.ports.allocate(c.target.ID, r.ListenPort)
+	if err != nil {
+		return failClone(mapping, err)
+	}
+
+	rule := &model.GostRule{
+		Name:       r.Name,
+		Type:       r.Type,
+		Protocol:   r.Protocol,
+		ListenPort: port,
+		ListenHost: listenHost,
+		Targets:    r.Targets,
+		Strategy:   r.Strategy,
+		EnableTLS:  r.EnableTLS,
+		Remark:     r.Remark,
+		Status:     model.RuleStatusStopped,
+	}
+	if tunnelID != nil {
+		rule.TunnelID = tunnelID
+	} else {
+		rule.NodeID = &c.target.ID
+	}
+	if c.linked {
+		sourceID := r.ID
+		rule.MirrorOfID = &sourceID
+	}
+
+	if err = s.ruleRepo.Create(rule); err != nil {
+		c.ports.release(c.target.ID, port)
+		return failClone(mapping, err)
+	}
+
+	mapping.TargetID = rule.ID
+	mapping.TargetPort = port
+	mapping.Remapped = port != r.ListenPort
+	mapping.Status = dto.CloneStatusCloned
+
+	if r.DesiredRunning {
+		if err = s.ruleService.Start(c.ctx, rule.ID, c.userID, c.username, c.ip, c.userAgent); err != nil {
+			mapping.Error = fmt.Sprintf("副本已创建，启动失败: %v", err)
+		}
+	}
+	return mapping
+}
+
+// portableListenHost 规则复制到其他节点时使用的监听地址
+// 回环地址在任何节点上含义相同，可以沿用；绑定源节点自身地址的规则无法复制，避免在目标节点上扩大监听范围
+func portableListenHost(host string) (string, error) {
+	if host == "" || host == "localhost" {
+		return host, nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return host, nil
+	}
+	return "", errors.ErrRuleListenHostBound
+}
+
+// failClone 标记克隆失败
+func failClone(mapping dto.ClonePortMapping, err error) dto.ClonePortMapping {
+	mapping.Status = dto.CloneStatusFailed
+	mapping.Error = err.Error()
+	return mapping
+}
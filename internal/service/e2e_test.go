@@ -0,0 +1,1965 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	stderrors "errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gost-panel/internal/config"
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/metrics"
+	"gost-panel/internal/model"
+	"gost-panel/internal/utils"
+	"gost-panel/pkg/gost"
+	"gost-panel/pkg/gost/gosttest"
+	"gost-panel/pkg/logger"
+
+	"github.com/glebarez/sqlite"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// 端到端测试：服务层连接 gosttest 模拟的 Gost 节点及内存 SQLite 数据库，
+// 覆盖节点、隧道、规则从创建、启停到故障恢复的完整流程
+
+func TestMain(m *testing.M) {
+	if _, err := config.Load(""); err != nil {
+		panic(err)
+	}
+	if err := logger.Init(&logger.Config{Level: "error", Format: "console"}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// testEnv 测试环境
+type testEnv struct {
+	t   *testing.T
+	ctx context.Context
+	db  *gorm.DB
+
+	nodes    *NodeService
+	rules    *RuleService
+	tunnels  *TunnelService
+	health   *NodeHealthService
+	sync     *RuleSyncService
+	observer *ObserverService
+	traffic  *TrafficService
+}
+
+// newTestEnv 创建测试环境：内存数据库、已配置面板地址的系统配置
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接独立，限制为单连接
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if err = db.AutoMigrate(
+		&model.User{},
+		&model.GostNode{},
+		&model.GostRule{},
+		&model.GostTunnel{},
+		&model.OperationLog{},
+		&model.SystemConfig{},
+		&model.NodeStatusHistory{},
+		&model.NodeMetric{},
+		&model.TrafficSample{},
+		&model.ServiceCounter{},
+		&model.RuleClient{},
+		&model.GostMetricSample{},
+	); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+	if err = db.Create(&model.SystemConfig{ID: 1, PanelURL: "http://panel.test"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler := NewNodeScheduler(db, config.Get().Scheduler)
+	t.Cleanup(scheduler.Stop)
+
+	env := &testEnv{
+		t:        t,
+		ctx:      context.Background(),
+		db:       db,
+		nodes:    NewNodeService(db),
+		rules:    NewRuleService(db),
+		tunnels:  NewTunnelService(db),
+		health:   NewNodeHealthService(db, scheduler),
+		sync:     NewRuleSyncService(db, scheduler),
+		observer: NewObserverService(db),
+		traffic:  NewTrafficService(db),
+	}
+	env.health.cfg.FailureThreshold = 2
+	env.health.cfg.RecoveryThreshold = 1
+	return env
+}
+
+// newNode 创建连接模拟服务的节点
+func (e *testEnv) newNode(name string) (*model.GostNode, *gosttest.Server) {
+	e.t.Helper()
+
+	srv := gosttest.NewServer()
+	srv.SetAuth("admin", "secret")
+	e.t.Cleanup(srv.Close)
+
+	node, err := e.nodes.Create(&dto.CreateNodeReq{
+		Name:     name,
+		Address:  srv.Host(),
+		Port:     srv.Port(),
+		Username: "admin",
+		Password: "secret",
+	}, 1, "admin", "", "")
+	if err != nil {
+		e.t.Fatalf("创建节点失败: %v", err)
+	}
+	e.t.Cleanup(func() { _ = e.nodes.Delete(node.ID, 1, "admin", "", "") })
+	return node, srv
+}
+
+// onlineNode 创建节点并经健康检测置为在线
+func (e *testEnv) onlineNode(name string) (*model.GostNode, *gosttest.Server) {
+	e.t.Helper()
+	node, srv := e.newNode(name)
+	e.check(node.ID)
+	if got := e.node(node.ID).Status; got != model.NodeStatusOnline {
+		e.t.Fatalf("节点 %s 状态 = %s, 期望 online", name, got)
+	}
+	return node, srv
+}
+
+// check 执行一次节点健康检测
+func (e *testEnv) check(nodeID uint) {
+	e.t.Helper()
+	_ = e.health.checkNode(e.ctx, *e.node(nodeID))
+}
+
+// syncNode 执行一次节点规则状态同步
+func (e *testEnv) syncNode(nodeID uint) error {
+	e.t.Helper()
+	return e.sync.syncNodeRules(e.ctx, *e.node(nodeID))
+}
+
+// node 查询节点
+func (e *testEnv) node(id uint) *model.GostNode {
+	e.t.Helper()
+	var node model.GostNode
+	if err := e.db.First(&node, id).Error; err != nil {
+		e.t.Fatalf("查询节点 %d 失败: %v", id, err)
+	}
+	return &node
+}
+
+// rule 查询规则
+func (e *testEnv) rule(id uint) *model.GostRule {
+	e.t.Helper()
+	var rule model.GostRule
+	if err := e.db.First(&rule, id).Error; err != nil {
+		e.t.Fatalf("查询规则 %d 失败: %v", id, err)
+	}
+	return &rule
+}
+
+// tunnel 查询隧道
+func (e *testEnv) tunnel(id uint) *model.GostTunnel {
+	e.t.Helper()
+	var tunnel model.GostTunnel
+	if err := e.db.First(&tunnel, id).Error; err != nil {
+		e.t.Fatalf("查询隧道 %d 失败: %v", id, err)
+	}
+	return &tunnel
+}
+
+// forwardRule 创建端口转发规则
+func (e *testEnv) forwardRule(nodeID uint, port int) *model.GostRule {
+	e.t.Helper()
+	rule, err := e.rules.Create(&dto.CreateRuleReq{
+		NodeID:     &nodeID,
+		Name:       fmt.Sprintf("forward-%d", port),
+		Type:       string(model.RuleTypeForward),
+		Protocol:   "tcp",
+		ListenPort: port,
+		Targets:    []string{"10.0.0.1:80"},
+	}, 1, "admin", "", "")
+	if err != nil {
+		e.t.Fatalf("创建规则失败: %v", err)
+	}
+	return rule
+}
+
+// newTunnel 创建隧道
+func (e *testEnv) newTunnel(entryID, exitID uint) *model.GostTunnel {
+	e.t.Helper()
+	tunnel, err := e.tunnels.Create(&dto.CreateTunnelReq{
+		Name:        "tunnel",
+		EntryNodeID: entryID,
+		ExitNodeID:  exitID,
+		Protocol:    "tcp",
+		RelayPort:   20000,
+	}, 1, "admin", "", "")
+	if err != nil {
+		e.t.Fatalf("创建隧道失败: %v", err)
+	}
+	return tunnel
+}
+
+// adoptForward 在节点上写入面板之外创建的端口转发服务并导入为规则
+func (e *testEnv) adoptForward(nodeID uint, srv *gosttest.Server, name string, port int) *model.GostRule {
+	e.t.Helper()
+	return e.adoptForwardAddr(nodeID, srv, name, fmt.Sprintf(":%d", port))
+}
+
+// adoptForwardAddr 在节点上手动创建监听指定地址的转发服务并导入为规则
+func (e *testEnv) adoptForwardAddr(nodeID uint, srv *gosttest.Server, name, addr string) *model.GostRule {
+	e.t.Helper()
+	srv.Put("services", &gost.ServiceConfig{
+		Name:      name,
+		Addr:      addr,
+		Handler:   &gost.HandlerConfig{Type: "tcp"},
+		Listener:  &gost.ListenerConfig{Type: "tcp"},
+		Forwarder: &gost.ForwarderConfig{Nodes: []*gost.ForwarderNode{{Name: "target-0", Addr: "10.0.0.1:80"}}},
+	})
+	resp, err := NewImportService(e.db).Adopt(e.ctx, nodeID, &dto.ImportReq{Services: []string{name}}, 1, "admin", "", "")
+	if err != nil || len(resp.Adopted) != 1 {
+		e.t.Fatalf("导入服务 %s 失败: %v %+v", name, err, resp)
+	}
+	return e.rule(resp.Adopted[0].ResourceID)
+}
+
+// report 模拟节点观察器上报服务累计统计
+func (e *testEnv) report(nodeID uint, service string, in, out, conns int64) {
+	e.t.Helper()
+	e.ingest(nodeID, dto.ObserverEvent{
+		Kind:    "service",
+		Service: service,
+		Type:    "stats",
+		Stats:   &dto.ObserverStats{InputBytes: in, OutputBytes: out, TotalConns: conns},
+	})
+}
+
+// ingest 模拟节点观察器上报事件，并立即写入缓冲区中的事件
+func (e *testEnv) ingest(nodeID uint, events ...dto.ObserverEvent) {
+	e.t.Helper()
+	if err := e.observer.HandleReport(e.node(nodeID), &dto.ObserverReportReq{Events: events}); err != nil {
+		e.t.Fatal(err)
+	}
+	if err := e.observer.Flush(); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+func TestNodeLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.newNode("node-1")
+
+	if node.Status != model.NodeStatusOffline {
+		t.Fatalf("新建节点状态 = %s, 期望 offline", node.Status)
+	}
+
+	// 健康检测通过后上线
+	env.check(node.ID)
+	if got := env.node(node.ID).Status; got != model.NodeStatusOnline {
+		t.Fatalf("检测通过后节点状态 = %s, 期望 online", got)
+	}
+
+	rule := env.forwardRule(node.ID, 10001)
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+
+	// 连续失败达到阈值后离线，规则随之停止
+	srv.Inject(gosttest.Fault{Status: http.StatusInternalServerError})
+	env.check(node.ID)
+	if got := env.node(node.ID).Status; got != model.NodeStatusOnline {
+		t.Fatalf("未达到失败阈值时节点状态 = %s, 期望 online", got)
+	}
+	env.check(node.ID)
+	if got := env.node(node.ID).Status; got != model.NodeStatusOffline {
+		t.Fatalf("达到失败阈值后节点状态 = %s, 期望 offline", got)
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusStopped {
+		t.Fatalf("节点离线后规则状态 = %s, 期望 stopped", got)
+	}
+
+	// 节点重启丢失配置，恢复后重建期望运行的规则
+	srv.ClearFaults()
+	srv.Remove("services", "rule-"+fmt.Sprint(rule.ID))
+	env.check(node.ID)
+	if got := env.node(node.ID).Status; got != model.NodeStatusOnline {
+		t.Fatalf("恢复后节点状态 = %s, 期望 online", got)
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusRunning {
+		t.Fatalf("恢复后规则状态 = %s, 期望 running", got)
+	}
+	if !srv.Has("services", fmt.Sprintf("rule-%d", rule.ID)) {
+		t.Fatal("节点恢复后规则服务未重建")
+	}
+}
+
+func TestMaintenanceExitRecovers(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+	maintenance := NewMaintenanceService(env.db)
+	if _, err := maintenance.Enter(env.ctx, node.ID, &dto.NodeMaintenanceReq{}, 1, "admin", "", ""); err != nil {
+		t.Fatalf("进入维护模式失败: %v", err)
+	}
+
+	// 计划内重启：离线时规则停止，恢复在线时维护中不自动重建
+	srv.Inject(gosttest.Fault{Status: http.StatusInternalServerError})
+	env.check(node.ID)
+	env.check(node.ID)
+	srv.ClearFaults()
+	srv.Remove("services", fmt.Sprintf("rule-%d", rule.ID))
+	env.check(node.ID)
+	if got := env.node(node.ID).Status; got != model.NodeStatusOnline {
+		t.Fatalf("重启后节点状态 = %s, 期望 online", got)
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusStopped {
+		t.Fatalf("维护中恢复在线后规则状态 = %s, 期望 stopped", got)
+	}
+
+	// 维护期间的状态变更带维护标记记录，前后衔接
+	var histories []model.NodeStatusHistory
+	env.db.Where("node_id = ? AND maintenance = ?", node.ID, true).Order("id").Find(&histories)
+	if len(histories) != 2 || !histories[0].Maintenance || !histories[1].Maintenance ||
+		histories[0].ToStatus != model.NodeStatusOffline || histories[1].FromStatus != model.NodeStatusOffline {
+		t.Fatalf("维护期间状态变更记录 = %+v", histories)
+	}
+
+	// 退出维护时节点在线，立即重建期望运行的规则
+	if _, err := maintenance.Exit(env.ctx, node.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("退出维护模式失败: %v", err)
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusRunning {
+		t.Fatalf("退出维护后规则状态 = %s, 期望 running", got)
+	}
+	if !srv.Has("services", fmt.Sprintf("rule-%d", rule.ID)) {
+		t.Fatal("退出维护后规则服务未重建")
+	}
+}
+
+// tunnelRule 创建并启动隧道转发规则
+func (e *testEnv) tunnelRule(tunnelID uint, port int) *model.GostRule {
+	e.t.Helper()
+	rule, err := e.rules.Create(&dto.CreateRuleReq{
+		TunnelID:   &tunnelID,
+		Name:       fmt.Sprintf("via-tunnel-%d", port),
+		Type:       string(model.RuleTypeTunnel),
+		Protocol:   "tcp",
+		ListenPort: port,
+		Targets:    []string{"10.0.0.1:80"},
+	}, 1, "admin", "", "")
+	if err != nil {
+		e.t.Fatalf("创建隧道规则失败: %v", err)
+	}
+	if err = e.rules.Start(e.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		e.t.Fatalf("启动隧道规则失败: %v", err)
+	}
+	return rule
+}
+
+func TestMaintenanceReplacesTunnelEntry(t *testing.T) {
+	env := newTestEnv(t)
+	entry, entrySrv := env.onlineNode("entry")
+	exit, _ := env.onlineNode("exit")
+	replacement, replacementSrv := env.onlineNode("replacement")
+	tunnel := env.newTunnel(entry.ID, exit.ID)
+	if err := env.tunnels.Start(env.ctx, tunnel.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动隧道失败: %v", err)
+	}
+	movable := env.tunnelRule(tunnel.ID, 10001)
+	blocked := env.tunnelRule(tunnel.ID, 10002)
+
+	// 替换节点上 10002 端口已被面板外的服务占用
+	replacementSrv.Put("services", &gost.ServiceConfig{Name: "manual", Addr: ":10002", Handler: &gost.HandlerConfig{Type: "tcp"}})
+
+	replacementID := replacement.ID
+	resp, err := NewMaintenanceService(env.db).Enter(env.ctx, entry.ID, &dto.NodeMaintenanceReq{ReplacementNodeID: &replacementID}, 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("进入维护模式失败: %v", err)
+	}
+	if len(resp.Tunnels) != 1 || resp.Tunnels[0].Status != dto.MigrationStatusMigrated || resp.Tunnels[0].Error == "" {
+		t.Fatalf("隧道迁移结果 = %+v, 期望新隧道已创建且提示原隧道保留", resp.Tunnels)
+	}
+	newTunnel := env.tunnel(resp.Tunnels[0].TargetID)
+	if newTunnel.EntryNodeID != replacement.ID || newTunnel.ExitNodeID != exit.ID || newTunnel.Status != model.TunnelStatusRunning {
+		t.Fatalf("新隧道 = entry %d exit %d status %s", newTunnel.EntryNodeID, newTunnel.ExitNodeID, newTunnel.Status)
+	}
+
+	results := map[uint]dto.MigrationItem{}
+	for _, item := range resp.Rules {
+		results[item.SourceID] = item
+	}
+
+	// 可迁移的规则在替换节点上以新隧道重建，原规则停止
+	moved := results[movable.ID]
+	if moved.Status != dto.MigrationStatusMigrated {
+		t.Fatalf("规则迁移结果 = %+v", moved)
+	}
+	var svc gost.ServiceConfig
+	if !replacementSrv.Get("services", fmt.Sprintf("rule-%d", moved.TargetID), &svc) ||
+		svc.Handler == nil || svc.Handler.Chain != tunnelChainName(newTunnel) {
+		t.Fatalf("替换节点上的规则服务 = %+v, 期望使用新隧道 Chain", svc.Handler)
+	}
+	if got := env.rule(movable.ID).Status; got != model.RuleStatusStopped {
+		t.Fatalf("迁移后原规则状态 = %s, 期望 stopped", got)
+	}
+
+	// 端口冲突的规则迁移失败，原规则和原隧道保持运行
+	if item := results[blocked.ID]; item.Status != dto.MigrationStatusFailed {
+		t.Fatalf("端口冲突规则迁移结果 = %+v, 期望失败", item)
+	}
+	if got := env.rule(blocked.ID).Status; got != model.RuleStatusRunning {
+		t.Fatalf("迁移失败后原规则状态 = %s, 期望 running", got)
+	}
+	if !entrySrv.Has("services", fmt.Sprintf("rule-%d", blocked.ID)) {
+		t.Fatal("迁移失败后原规则服务被删除")
+	}
+	if got := env.tunnel(tunnel.ID).Status; got != model.TunnelStatusRunning {
+		t.Fatalf("部分规则迁移失败后原隧道状态 = %s, 期望 running", got)
+	}
+	if !entrySrv.Has("chains", tunnelChainName(tunnel)) {
+		t.Fatal("部分规则迁移失败后原隧道 Chain 被删除")
+	}
+}
+
+func TestMaintenanceReplacesTunnelExit(t *testing.T) {
+	env := newTestEnv(t)
+	entry, entrySrv := env.onlineNode("entry")
+	exit, exitSrv := env.onlineNode("exit")
+	replacement, replacementSrv := env.onlineNode("replacement")
+	tunnel := env.newTunnel(entry.ID, exit.ID)
+	if err := env.tunnels.Start(env.ctx, tunnel.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动隧道失败: %v", err)
+	}
+	rule := env.tunnelRule(tunnel.ID, 10001)
+	maintenance := NewMaintenanceService(env.db)
+	replacementID := replacement.ID
+
+	// 替换节点创建 Relay 服务失败：新隧道回滚，规则仍使用原隧道运行
+	replacementSrv.Inject(gosttest.Fault{Method: http.MethodPost, Path: "/config/services", Status: http.StatusInternalServerError})
+	resp, err := maintenance.Enter(env.ctx, exit.ID, &dto.NodeMaintenanceReq{ReplacementNodeID: &replacementID}, 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("进入维护模式失败: %v", err)
+	}
+	if len(resp.Tunnels) != 1 || resp.Tunnels[0].Status != dto.MigrationStatusFailed || len(resp.Rules) != 0 {
+		t.Fatalf("迁移结果 = tunnels %+v rules %+v, 期望隧道迁移失败", resp.Tunnels, resp.Rules)
+	}
+	var count int64
+	env.db.Model(&model.GostTunnel{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("迁移失败后隧道数 = %d, 期望新隧道已回滚", count)
+	}
+	if got := env.rule(rule.ID); got.Status != model.RuleStatusRunning || got.TunnelID == nil || *got.TunnelID != tunnel.ID {
+		t.Fatalf("迁移失败后规则 = status %s tunnel %v, 期望仍使用原隧道运行", got.Status, got.TunnelID)
+	}
+	if !exitSrv.Has("services", tunnelRelayServiceName(tunnel)) {
+		t.Fatal("迁移失败后原隧道 Relay 服务被删除")
+	}
+
+	// 故障消除后重试：规则原地改绑到新隧道，原隧道停止
+	replacementSrv.ClearFaults()
+	if resp, err = maintenance.Enter(env.ctx, exit.ID, &dto.NodeMaintenanceReq{ReplacementNodeID: &replacementID}, 1, "admin", "", ""); err != nil {
+		t.Fatalf("进入维护模式失败: %v", err)
+	}
+	if len(resp.Tunnels) != 1 || resp.Tunnels[0].Status != dto.MigrationStatusMigrated || resp.Tunnels[0].Error != "" {
+		t.Fatalf("隧道迁移结果 = %+v", resp.Tunnels)
+	}
+	if len(resp.Rules) != 1 || resp.Rules[0].Status != dto.MigrationStatusMigrated || resp.Rules[0].TargetID != rule.ID {
+		t.Fatalf("规则迁移结果 = %+v, 期望原地改绑", resp.Rules)
+	}
+	newTunnel := env.tunnel(resp.Tunnels[0].TargetID)
+	if newTunnel.EntryNodeID != entry.ID || newTunnel.ExitNodeID != replacement.ID {
+		t.Fatalf("新隧道 = entry %d exit %d", newTunnel.EntryNodeID, newTunnel.ExitNodeID)
+	}
+	if !replacementSrv.Has("services", tunnelRelayServiceName(newTunnel)) {
+		t.Fatal("替换节点上未创建 Relay 服务")
+	}
+	if got := env.rule(rule.ID); got.Status != model.RuleStatusRunning || got.TunnelID == nil || *got.TunnelID != newTunnel.ID {
+		t.Fatalf("改绑后规则 = status %s tunnel %v", got.Status, got.TunnelID)
+	}
+	var svc gost.ServiceConfig
+	if !entrySrv.Get("services", fmt.Sprintf("rule-%d", rule.ID), &svc) || svc.Handler == nil || svc.Handler.Chain != tunnelChainName(newTunnel) {
+		t.Fatalf("改绑后规则服务 = %+v, 期望使用新隧道 Chain", svc.Handler)
+	}
+	if got := env.tunnel(tunnel.ID).Status; got != model.TunnelStatusStopped {
+		t.Fatalf("迁移后原隧道状态 = %s, 期望 stopped", got)
+	}
+	if exitSrv.Has("services", tunnelRelayServiceName(tunnel)) {
+		t.Fatal("迁移后原隧道 Relay 服务仍存在")
+	}
+}
+
+func TestNodeUptimeExcludesMaintenance(t *testing.T) {
+	now := time.Now()
+	node := &model.GostNode{Status: model.NodeStatusOnline, CreatedAt: now.Add(-48 * time.Hour)}
+	at := func(h int) time.Time { return now.Add(time.Duration(h-24) * time.Hour) }
+	histories := []model.NodeStatusHistory{
+		// 维护期间离线 4 小时，不计入统计窗口
+		{FromStatus: model.NodeStatusOnline, ToStatus: model.NodeStatusOffline, Maintenance: true, CreatedAt: at(4)},
+		{FromStatus: model.NodeStatusOffline, ToStatus: model.NodeStatusOnline, Maintenance: true, CreatedAt: at(8)},
+		// 非维护期间离线 2 小时
+		{FromStatus: model.NodeStatusOnline, ToStatus: model.NodeStatusOffline, CreatedAt: at(12)},
+		{FromStatus: model.NodeStatusOffline, ToStatus: model.NodeStatusOnline, CreatedAt: at(14)},
+	}
+	if got := nodeUptime(node, nil, histories, now, 24*time.Hour); got != 90 {
+		t.Fatalf("可用率 = %v, 期望 90 (18h/20h)", got)
+	}
+}
+
+func TestRecoverAdoptedRule(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	rule := env.adoptForward(node.ID, srv, "web-forward", 18080)
+
+	srv.Inject(gosttest.Fault{Status: http.StatusInternalServerError})
+	env.check(node.ID)
+	env.check(node.ID)
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusStopped {
+		t.Fatalf("节点离线后规则状态 = %s, 期望 stopped", got)
+	}
+
+	// 节点重启丢失配置，恢复后以原有名称重建导入的规则
+	srv.ClearFaults()
+	srv.Remove("services", "web-forward")
+	env.check(node.ID)
+	if got := env.rule(rule.ID); got.Status != model.RuleStatusRunning || got.ServiceID != "web-forward" {
+		t.Fatalf("恢复后规则 = %s (%s), 期望 web-forward 运行中", got.ServiceID, got.Status)
+	}
+	if !srv.Has("services", "web-forward") || srv.Has("services", fmt.Sprintf("rule-%d", rule.ID)) {
+		t.Fatalf("恢复后节点服务 = %v, 期望只有 web-forward", srv.Names("services"))
+	}
+}
+
+func TestNodeUpdateInvalidatesClient(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+
+	// 节点 API 密码变更后，使用旧凭据的缓存客户端必须失效
+	srv.SetAuth("admin", "changed")
+	if _, err := env.nodes.Update(node.ID, &dto.UpdateNodeReq{
+		Name:     node.Name,
+		Address:  node.Address,
+		Port:     node.Port,
+		Username: "admin",
+		Password: "changed",
+	}, 1, "admin", "", ""); err != nil {
+		t.Fatalf("更新节点失败: %v", err)
+	}
+	if _, err := env.nodes.GetConfig(env.ctx, node.ID); err != nil {
+		t.Fatalf("更新凭据后获取节点配置失败: %v", err)
+	}
+}
+
+// clientCertPEM 生成 PEM 格式的自签名客户端证书和私钥
+func clientCertPEM(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gost-panel"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestNodeTLSClientKeySecret(t *testing.T) {
+	env := newTestEnv(t)
+	cert, key := clientCertPEM(t)
+	tlsReq := dto.NodeTLSReq{TLSEnabled: true, TLSClientCert: cert, TLSClientKey: key}
+
+	node, err := env.nodes.Create(&dto.CreateNodeReq{
+		Name: "mtls", Address: "127.0.0.1", Port: 18080, NodeTLSReq: tlsReq,
+	}, 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("创建节点失败: %v", err)
+	}
+	stored := env.node(node.ID)
+	if !strings.HasPrefix(stored.TLSClientKey, "enc:") {
+		t.Fatal("客户端私钥未加密存储")
+	}
+	if data, _ := json.Marshal(stored); strings.Contains(string(data), "PRIVATE KEY") || strings.Contains(string(data), "tls_client_key") {
+		t.Fatal("接口返回了客户端私钥")
+	}
+	if got := utils.GetNodeTLSConfig(stored); got.ClientKey != strings.TrimSpace(key) {
+		t.Fatal("客户端私钥解密结果不一致")
+	}
+
+	// 更新时私钥留空保持不变，移除客户端证书时一并清空
+	tlsReq.TLSClientKey = ""
+	update := &dto.UpdateNodeReq{Name: "mtls", Address: "127.0.0.1", Port: 18080, NodeTLSReq: tlsReq}
+	if _, err = env.nodes.Update(node.ID, update, 1, "admin", "", ""); err != nil {
+		t.Fatalf("更新节点失败: %v", err)
+	}
+	if got := env.node(node.ID).TLSClientKey; got != stored.TLSClientKey {
+		t.Fatal("私钥留空更新后私钥被修改")
+	}
+	update.TLSClientCert = ""
+	if _, err = env.nodes.Update(node.ID, update, 1, "admin", "", ""); err != nil {
+		t.Fatalf("更新节点失败: %v", err)
+	}
+	if got := env.node(node.ID).TLSClientKey; got != "" {
+		t.Fatal("移除客户端证书后私钥未清空")
+	}
+}
+
+func TestForwardRuleLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+	serviceName := fmt.Sprintf("rule-%d", rule.ID)
+
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+	var svc gost.ServiceConfig
+	if !srv.Get("services", serviceName, &svc) {
+		t.Fatal("规则服务未创建")
+	}
+	if svc.Addr != ":10001" || svc.Observer == "" {
+		t.Fatalf("规则服务配置错误: addr=%s observer=%s", svc.Addr, svc.Observer)
+	}
+	var observer gost.ObserverConfig
+	if !srv.Get("observers", svc.Observer, &observer) {
+		t.Fatal("观察器未创建")
+	}
+	if want := "http://panel.test/api/v1/observer/report/" + env.node(node.ID).ObserverToken; observer.Plugin.Addr != want {
+		t.Fatalf("观察器上报地址 = %s, 期望 %s", observer.Plugin.Addr, want)
+	}
+	if got := env.rule(rule.ID); got.Status != model.RuleStatusRunning || !got.DesiredRunning {
+		t.Fatalf("启动后规则状态 = %s (desired=%v), 期望 running", got.Status, got.DesiredRunning)
+	}
+	if srv.Saves() == 0 {
+		t.Fatal("启动规则后未保存节点配置")
+	}
+
+	if err := env.rules.Stop(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("停止规则失败: %v", err)
+	}
+	if srv.Has("services", serviceName) {
+		t.Fatal("停止规则后服务仍存在")
+	}
+	if got := env.rule(rule.ID); got.Status != model.RuleStatusStopped || got.DesiredRunning {
+		t.Fatalf("停止后规则状态 = %s (desired=%v), 期望 stopped", got.Status, got.DesiredRunning)
+	}
+
+	if err := env.rules.Delete(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("删除规则失败: %v", err)
+	}
+	if err := env.db.First(&model.GostRule{}, rule.ID).Error; !stderrors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("删除后规则仍存在: %v", err)
+	}
+}
+
+func TestAdoptedRuleLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	rule := env.adoptForward(node.ID, srv, "web-forward", 18080)
+	if rule.ServiceID != "web-forward" || rule.Status != model.RuleStatusRunning {
+		t.Fatalf("导入后规则 = %s (%s), 期望沿用服务名称 web-forward 并运行中", rule.ServiceID, rule.Status)
+	}
+
+	// 沿用原有名称的服务同样按规则统计流量
+	env.report(node.ID, "web-forward", 100, 200, 1)
+	if got := env.rule(rule.ID); got.InputBytes != 100 || got.OutputBytes != 200 {
+		t.Fatalf("导入规则流量 = %d/%d, 期望 100/200", got.InputBytes, got.OutputBytes)
+	}
+
+	// 停止后再启动，使用原有名称重建服务，不另建 rule-{id}
+	if err := env.rules.Stop(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("停止规则失败: %v", err)
+	}
+	if srv.Has("services", "web-forward") {
+		t.Fatal("停止规则后服务仍存在")
+	}
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+	if !srv.Has("services", "web-forward") || srv.Has("services", fmt.Sprintf("rule-%d", rule.ID)) {
+		t.Fatalf("重新启动后节点服务 = %v, 期望只有 web-forward", srv.Names("services"))
+	}
+	if got := env.rule(rule.ID); got.ServiceID != "web-forward" || got.Status != model.RuleStatusRunning {
+		t.Fatalf("重新启动后规则 = %s (%s), 期望 web-forward 运行中", got.ServiceID, got.Status)
+	}
+}
+
+func TestAdoptedRuleKeepsListenHost(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	target, _ := env.onlineNode("node-2")
+
+	local := env.adoptForwardAddr(node.ID, srv, "admin-ui", "127.0.0.1:8080")
+	public := env.adoptForwardAddr(node.ID, srv, "public-web", "203.0.113.10:8081")
+	wildcard := env.adoptForwardAddr(node.ID, srv, "any-web", "0.0.0.0:8082")
+	if local.ListenHost != "127.0.0.1" || public.ListenHost != "203.0.113.10" || wildcard.ListenHost != "" {
+		t.Fatalf("导入的监听地址 = %q, %q, %q", local.ListenHost, public.ListenHost, wildcard.ListenHost)
+	}
+
+	// 停止后重建的服务仍只监听原地址
+	for _, r := range []*model.GostRule{local, public} {
+		if err := env.rules.Stop(env.ctx, r.ID, 1, "admin", "", ""); err != nil {
+			t.Fatalf("停止规则失败: %v", err)
+		}
+		if err := env.rules.Start(env.ctx, r.ID, 1, "admin", "", ""); err != nil {
+			t.Fatalf("启动规则失败: %v", err)
+		}
+	}
+	var svc gost.ServiceConfig
+	if !srv.Get("services", "admin-ui", &svc) || svc.Addr != "127.0.0.1:8080" {
+		t.Fatalf("重建后服务监听地址 = %q, 期望 127.0.0.1:8080", svc.Addr)
+	}
+	if !srv.Get("services", "public-web", &svc) || svc.Addr != "203.0.113.10:8081" {
+		t.Fatalf("重建后服务监听地址 = %q, 期望 203.0.113.10:8081", svc.Addr)
+	}
+
+	// 回环地址可以复制到其他节点，绑定源节点公网地址的规则不能复制
+	resp, err := NewCloneService(env.db).Clone(env.ctx, node.ID, &dto.NodeCloneReq{TargetNodeID: target.ID}, 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("克隆节点失败: %v", err)
+	}
+	for _, m := range resp.Rules {
+		switch m.SourceID {
+		case local.ID, wildcard.ID:
+			if m.Status != dto.CloneStatusCloned {
+				t.Fatalf("规则 %s 克隆结果 = %+v", m.Name, m)
+			}
+			if m.SourceID == local.ID && env.rule(m.TargetID).ListenHost != "127.0.0.1" {
+				t.Fatalf("回环地址未沿用: %q", env.rule(m.TargetID).ListenHost)
+			}
+		case public.ID:
+			if m.Status != dto.CloneStatusFailed {
+				t.Fatalf("绑定公网地址的规则克隆结果 = %+v, 期望失败", m)
+			}
+		}
+	}
+}
+
+func TestReconcileWildcardListenAddr(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	env.adoptForwardAddr(node.ID, srv, "https", "0.0.0.0:443")
+	env.adoptForwardAddr(node.ID, srv, "https-v6", "[::]:8443")
+	env.adoptForwardAddr(node.ID, srv, "admin-ui", "127.0.0.1:8080")
+
+	// 通配地址与面板生成的 ":端口" 等价，不视为漂移
+	report, err := NewReconcileService(env.db).Inspect(env.ctx, node.ID)
+	if err != nil {
+		t.Fatalf("检查配置漂移失败: %v", err)
+	}
+	if len(report.Modified) != 0 || len(report.Missing) != 0 {
+		t.Fatalf("导入通配地址服务后漂移 = modified %+v, missing %+v", report.Modified, report.Missing)
+	}
+
+	// 规则保存了监听主机时比较主机
+	var svc gost.ServiceConfig
+	srv.Get("services", "admin-ui", &svc)
+	svc.Addr = "0.0.0.0:8080"
+	srv.Put("services", &svc)
+	report, _ = NewReconcileService(env.db).Inspect(env.ctx, node.ID)
+	if len(report.Modified) != 1 || report.Modified[0].Name != "admin-ui" {
+		t.Fatalf("监听主机被修改后漂移 = %+v, 期望 admin-ui", report.Modified)
+	}
+
+	// 修复导入的服务时沿用原服务名称和绑定地址
+	resp, err := NewReconcileService(env.db).Repair(env.ctx, node.ID, &dto.ReconcileActionReq{Names: []string{"admin-ui"}}, 1, "admin", "", "")
+	if err != nil || len(resp.Succeeded) != 1 {
+		t.Fatalf("修复漂移失败: %v %+v", err, resp)
+	}
+	if !srv.Get("services", "admin-ui", &svc) || svc.Addr != "127.0.0.1:8080" {
+		t.Fatalf("修复后服务监听地址 = %s, 期望 127.0.0.1:8080", svc.Addr)
+	}
+	report, _ = NewReconcileService(env.db).Inspect(env.ctx, node.ID)
+	if len(report.Modified) != 0 || len(report.Missing) != 0 {
+		t.Fatalf("修复后漂移 = modified %+v, missing %+v", report.Modified, report.Missing)
+	}
+}
+
+func TestCloneRemapsPorts(t *testing.T) {
+	env := newTestEnv(t)
+	source, _ := env.onlineNode("node-1")
+	target, targetSrv := env.onlineNode("node-2")
+	env.db.Model(&model.GostNode{}).Where("id = ?", target.ID).Update("metrics_port", 10003)
+
+	// 目标节点上面板外创建的服务、已有规则、API 端口和指标端口都不能复用
+	targetSrv.Put("services", &gost.ServiceConfig{Name: "manual", Addr: ":10001", Handler: &gost.HandlerConfig{Type: "tcp"}})
+	env.forwardRule(target.ID, 10002)
+	conflicts := map[uint]int{}
+	for _, port := range []int{10001, 10002, target.Port, 10003} {
+		conflicts[env.forwardRule(source.ID, port).ID] = port
+	}
+	free := env.forwardRule(source.ID, 10100)
+	if err := env.rules.Start(env.ctx, free.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+
+	resp, err := NewCloneService(env.db).Clone(env.ctx, source.ID, &dto.NodeCloneReq{TargetNodeID: target.ID, Linked: true}, 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("克隆节点失败: %v", err)
+	}
+	if len(resp.Rules) != 5 {
+		t.Fatalf("克隆结果 = %+v, 期望 5 条规则", resp.Rules)
+	}
+	assigned := map[int]bool{}
+	var mirrorID uint
+	for _, m := range resp.Rules {
+		if m.Status != dto.CloneStatusCloned {
+			t.Fatalf("规则 %s 克隆失败: %s", m.Name, m.Error)
+		}
+		if port, ok := conflicts[m.SourceID]; ok && (!m.Remapped || m.TargetPort == port) {
+			t.Fatalf("冲突端口 %d 未重映射: %+v", port, m)
+		}
+		if m.SourceID == free.ID {
+			if m.Remapped || m.TargetPort != 10100 {
+				t.Fatalf("空闲端口被重映射: %+v", m)
+			}
+			mirrorID = m.TargetID
+		}
+		if assigned[m.TargetPort] {
+			t.Fatalf("端口 %d 被重复分配", m.TargetPort)
+		}
+		assigned[m.TargetPort] = true
+	}
+
+	// 源规则启动过，副本同样运行；联动副本随源规则更新
+	if got := env.rule(mirrorID); got.Status != model.RuleStatusRunning || got.MirrorOfID == nil || *got.MirrorOfID != free.ID {
+		t.Fatalf("副本规则 = status %s, mirror_of %v", got.Status, got.MirrorOfID)
+	}
+	if err := env.rules.Stop(env.ctx, free.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("停止规则失败: %v", err)
+	}
+	if _, err := env.rules.Update(env.ctx, free.ID, &dto.UpdateRuleReq{Name: "web", Protocol: "tcp", ListenPort: 10100,
+		Targets: []string{"10.0.0.2:80"}}, 1, "admin", "", ""); err != nil {
+		t.Fatalf("更新源规则失败: %v", err)
+	}
+	var svc gost.ServiceConfig
+	targetSrv.Get("services", fmt.Sprintf("rule-%d", mirrorID), &svc)
+	if targets, _ := forwarderSummary(svc.Forwarder); len(targets) != 1 || targets[0] != "10.0.0.2:80" {
+		t.Fatalf("联动副本服务目标 = %v, 期望 [10.0.0.2:80]", targets)
+	}
+}
+
+func TestRuleStartFailure(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+
+	srv.Inject(gosttest.Fault{Method: http.MethodPost, Path: "/config/services", Status: http.StatusInternalServerError})
+	err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", "")
+	if !stderrors.Is(err, errors.ErrRuleStartFailed) {
+		t.Fatalf("节点创建服务失败时应返回启动失败，实际: %v", err)
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusError {
+		t.Fatalf("启动失败后规则状态 = %s, 期望 error", got)
+	}
+}
+
+func TestSyncRuleStatus(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+	serviceName := fmt.Sprintf("rule-%d", rule.ID)
+
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+
+	// 服务运行失败
+	srv.SetServiceState(serviceName, "failed")
+	if err := env.syncNode(node.ID); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusError {
+		t.Fatalf("服务失败后规则状态 = %s, 期望 error", got)
+	}
+
+	// 服务恢复
+	srv.SetServiceState(serviceName, "running")
+	if err := env.syncNode(node.ID); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusRunning {
+		t.Fatalf("服务恢复后规则状态 = %s, 期望 running", got)
+	}
+
+	// 服务在面板之外被删除，规则期望运行，同步时重新创建
+	srv.Remove("services", serviceName)
+	if err := env.syncNode(node.ID); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if !srv.Has("services", serviceName) {
+		t.Fatal("服务删除后同步未重新创建")
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusRunning {
+		t.Fatalf("服务重建后规则状态 = %s, 期望 running", got)
+	}
+
+	// 规则已停止时服务被删除，同步为停止且不重建
+	if err := env.db.Model(&model.GostRule{}).Where("id = ?", rule.ID).Update("desired_running", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	srv.Remove("services", serviceName)
+	if err := env.syncNode(node.ID); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if srv.Has("services", serviceName) {
+		t.Fatal("未期望运行的规则不应重建服务")
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusStopped {
+		t.Fatalf("服务删除后规则状态 = %s, 期望 stopped", got)
+	}
+
+	// 节点 API 不可用时返回错误，由调度器退避
+	srv.Inject(gosttest.Fault{Status: http.StatusInternalServerError})
+	if err := env.syncNode(node.ID); err == nil {
+		t.Fatal("节点 API 不可用时同步应返回错误")
+	}
+}
+
+func TestSyncRetriesFailedStart(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+	serviceName := fmt.Sprintf("rule-%d", rule.ID)
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+
+	// 节点离线期间重启丢失服务，恢复在线时创建服务失败
+	srv.Inject(gosttest.Fault{Status: http.StatusInternalServerError})
+	env.check(node.ID)
+	env.check(node.ID)
+	srv.Remove("services", serviceName)
+	srv.ClearFaults()
+	srv.Inject(gosttest.Fault{Method: http.MethodPost, Path: "/config/services", Status: http.StatusInternalServerError})
+	env.check(node.ID)
+	if got := env.node(node.ID).Status; got != model.NodeStatusOnline {
+		t.Fatalf("节点状态 = %s, 期望 online", got)
+	}
+	if srv.Has("services", serviceName) {
+		t.Fatal("注入故障后服务不应创建")
+	}
+
+	// 同步重试仍失败，进入退避
+	if err := env.syncNode(node.ID); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	key := fmt.Sprintf("rule-%d", rule.ID)
+	if b := env.sync.retries[key]; b == nil || b.failures != 1 {
+		t.Fatalf("重试失败后退避状态 = %+v, 期望失败 1 次", b)
+	}
+
+	// 退避期间不重试
+	srv.ClearFaults()
+	if err := env.syncNode(node.ID); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if srv.Has("services", serviceName) {
+		t.Fatal("退避期间不应重试启动")
+	}
+
+	// 退避结束后重试成功
+	env.sync.retries[key].until = time.Time{}
+	if err := env.syncNode(node.ID); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if !srv.Has("services", serviceName) {
+		t.Fatal("退避结束后未重试启动")
+	}
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusRunning {
+		t.Fatalf("重试成功后规则状态 = %s, 期望 running", got)
+	}
+	if _, ok := env.sync.retries[key]; ok {
+		t.Fatal("重试成功后应清除退避状态")
+	}
+}
+
+func TestTunnelLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	entry, entrySrv := env.onlineNode("entry")
+	exit, exitSrv := env.onlineNode("exit")
+	tunnel := env.newTunnel(entry.ID, exit.ID)
+
+	relayName := fmt.Sprintf("relay-tunnel-%d", tunnel.ID)
+	chainName := fmt.Sprintf("tunnel-%d-chain", tunnel.ID)
+
+	if err := env.tunnels.Start(env.ctx, tunnel.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动隧道失败: %v", err)
+	}
+	if !exitSrv.Has("services", relayName) {
+		t.Fatal("出口节点 Relay 服务未创建")
+	}
+	var chain gost.ChainConfig
+	if !entrySrv.Get("chains", chainName, &chain) {
+		t.Fatal("入口节点 Chain 未创建")
+	}
+	if want := fmt.Sprintf("%s:%d", exit.Address, 20000); chain.Hops[0].Nodes[0].Addr != want {
+		t.Fatalf("Chain 连接地址 = %s, 期望 %s", chain.Hops[0].Nodes[0].Addr, want)
+	}
+	if got := env.tunnel(tunnel.ID).Status; got != model.TunnelStatusRunning {
+		t.Fatalf("启动后隧道状态 = %s, 期望 running", got)
+	}
+
+	// 隧道规则在入口节点创建使用 Chain 的转发服务
+	tunnelID := tunnel.ID
+	rule, err := env.rules.Create(&dto.CreateRuleReq{
+		TunnelID:   &tunnelID,
+		Name:       "via-tunnel",
+		Type:       string(model.RuleTypeTunnel),
+		Protocol:   "tcp",
+		ListenPort: 10002,
+		Targets:    []string{"10.0.0.1:80"},
+	}, 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("创建隧道规则失败: %v", err)
+	}
+	if err = env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动隧道规则失败: %v", err)
+	}
+	var svc gost.ServiceConfig
+	if !entrySrv.Get("services", fmt.Sprintf("rule-%d", rule.ID), &svc) {
+		t.Fatal("隧道规则服务未创建")
+	}
+	if svc.Handler == nil || svc.Handler.Chain != chainName {
+		t.Fatalf("隧道规则服务未使用隧道 Chain: %+v", svc.Handler)
+	}
+
+	// 入口节点 Chain 丢失后，隧道期望运行，同步时重新创建
+	entrySrv.Remove("chains", chainName)
+	if err = env.syncNode(entry.ID); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	if !entrySrv.Has("chains", chainName) {
+		t.Fatal("Chain 丢失后同步未重新创建")
+	}
+	if got := env.tunnel(tunnel.ID).Status; got != model.TunnelStatusRunning {
+		t.Fatalf("Chain 重建后隧道状态 = %s, 期望 running", got)
+	}
+
+	// 停止后两端对象均删除
+	if err = env.tunnels.Stop(env.ctx, tunnel.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("停止隧道失败: %v", err)
+	}
+	if exitSrv.Has("services", relayName) || entrySrv.Has("chains", chainName) {
+		t.Fatal("停止隧道后节点对象仍存在")
+	}
+	if got := env.tunnel(tunnel.ID); got.Status != model.TunnelStatusStopped || got.DesiredRunning {
+		t.Fatalf("停止后隧道状态 = %s (desired=%v), 期望 stopped", got.Status, got.DesiredRunning)
+	}
+}
+
+func TestTunnelStartRollback(t *testing.T) {
+	env := newTestEnv(t)
+	entry, entrySrv := env.onlineNode("entry")
+	exit, exitSrv := env.onlineNode("exit")
+	tunnel := env.newTunnel(entry.ID, exit.ID)
+
+	// 入口节点创建 Chain 失败，回滚出口节点的 Relay 服务
+	entrySrv.Inject(gosttest.Fault{Method: http.MethodPost, Path: "/config/chains", Status: http.StatusInternalServerError})
+	err := env.tunnels.Start(env.ctx, tunnel.ID, 1, "admin", "", "")
+	if !stderrors.Is(err, errors.ErrTunnelChainCreateFailed) {
+		t.Fatalf("应返回创建 Chain 失败，实际: %v", err)
+	}
+	if names := exitSrv.Names("services"); len(names) != 0 {
+		t.Fatalf("回滚后出口节点仍有服务: %v", names)
+	}
+	if got := env.tunnel(tunnel.ID).Status; got != model.TunnelStatusError {
+		t.Fatalf("启动失败后隧道状态 = %s, 期望 error", got)
+	}
+
+	// 出口节点创建 Relay 失败，不在入口节点创建 Chain
+	entrySrv.ClearFaults()
+	exitSrv.Inject(gosttest.Fault{Method: http.MethodPost, Path: "/config/services", Status: http.StatusInternalServerError})
+	err = env.tunnels.Start(env.ctx, tunnel.ID, 1, "admin", "", "")
+	if !stderrors.Is(err, errors.ErrTunnelRelayCreateFailed) {
+		t.Fatalf("应返回创建 Relay 失败，实际: %v", err)
+	}
+	if entrySrv.Requests(http.MethodPost, "/config/chains") != 1 {
+		t.Fatal("Relay 创建失败后不应再创建 Chain")
+	}
+}
+
+func TestTrafficHistory(t *testing.T) {
+	env := newTestEnv(t)
+	node, _ := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+
+	// 观察器上报累计值，第三次上报前计数器重置（节点重启）
+	service := fmt.Sprintf("rule-%d", rule.ID)
+	env.report(node.ID, service, 100, 1000, 1)
+	env.report(node.ID, service, 300, 3000, 3)
+	env.report(node.ID, service, 50, 500, 1)
+
+	if err := env.traffic.Rollup(time.Now()); err != nil {
+		t.Fatalf("汇总流量失败: %v", err)
+	}
+
+	// 分钟、小时、天粒度合计均为各次增量之和
+	for _, step := range []string{"1m", "1h", "1d"} {
+		series, err := env.traffic.QueryRule(rule.ID, &dto.TrafficQueryReq{Step: step})
+		if err != nil {
+			t.Fatalf("查询 %s 步长流量失败: %v", step, err)
+		}
+		if series.InputBytes != 350 || series.OutputBytes != 3500 || series.Connections != 4 {
+			t.Fatalf("%s 步长流量合计 = %d/%d/%d, 期望 350/3500/4",
+				step, series.InputBytes, series.OutputBytes, series.Connections)
+		}
+		var total int64
+		for _, p := range series.Points {
+			total += p.TotalBytes
+		}
+		if total != 3850 {
+			t.Fatalf("%s 步长数据点总流量 = %d, 期望 3850", step, total)
+		}
+	}
+
+	// 节点流量历史同步记录
+	series, err := env.traffic.QueryNode(node.ID, &dto.TrafficQueryReq{})
+	if err != nil {
+		t.Fatalf("查询节点流量失败: %v", err)
+	}
+	if series.TotalBytes != 3850 || series.Step != "5m" {
+		t.Fatalf("节点流量 = %d (step=%s), 期望 3850 (step=5m)", series.TotalBytes, series.Step)
+	}
+
+	// 汇总可重复执行
+	if err = env.traffic.Rollup(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if series, _ = env.traffic.QueryRule(rule.ID, &dto.TrafficQueryReq{Step: "1h"}); series.InputBytes != 350 {
+		t.Fatalf("重复汇总后入站流量 = %d, 期望 350", series.InputBytes)
+	}
+
+	if _, err = env.traffic.QueryRule(rule.ID, &dto.TrafficQueryReq{Step: "30s"}); !stderrors.Is(err, errors.ErrTrafficStepInvalid) {
+		t.Fatalf("非整分钟步长应返回步长无效，实际: %v", err)
+	}
+	if _, err = env.traffic.QueryRule(rule.ID, &dto.TrafficQueryReq{From: "1700000000", To: "1600000000"}); !stderrors.Is(err, errors.ErrTrafficRangeInvalid) {
+		t.Fatalf("开始时间晚于结束时间应返回范围无效，实际: %v", err)
+	}
+	if _, err = env.traffic.QueryRule(rule.ID, &dto.TrafficQueryReq{From: "1600000000", Step: "1m"}); !stderrors.Is(err, errors.ErrTrafficTooManyPoints) {
+		t.Fatalf("数据点过多应返回错误，实际: %v", err)
+	}
+}
+
+func TestObserverDeltaAccounting(t *testing.T) {
+	env := newTestEnv(t)
+	entry, _ := env.onlineNode("entry")
+	exit, _ := env.onlineNode("exit")
+	tunnel := env.newTunnel(entry.ID, exit.ID)
+	forward := env.forwardRule(entry.ID, 10001)
+
+	tunnelID := tunnel.ID
+	viaTunnel, err := env.rules.Create(&dto.CreateRuleReq{
+		TunnelID:   &tunnelID,
+		Name:       "via-tunnel",
+		Type:       string(model.RuleTypeTunnel),
+		Protocol:   "tcp",
+		ListenPort: 10002,
+		Targets:    []string{"10.0.0.1:80"},
+	}, 1, "admin", "", "")
+	if err != nil {
+		t.Fatalf("创建隧道规则失败: %v", err)
+	}
+	forwardService := fmt.Sprintf("rule-%d", forward.ID)
+	tunnelService := fmt.Sprintf("rule-%d", viaTunnel.ID)
+
+	// 累计值未变化的重复上报不增加流量
+	env.report(entry.ID, forwardService, 100, 1000, 1)
+	env.report(entry.ID, forwardService, 100, 1000, 1)
+	env.report(entry.ID, tunnelService, 10, 20, 1)
+	if got := env.node(entry.ID); got.InputBytes != 110 || got.OutputBytes != 1020 {
+		t.Fatalf("节点流量 = %d/%d, 期望 110/1020", got.InputBytes, got.OutputBytes)
+	}
+	if got := env.tunnel(tunnel.ID); got.InputBytes != 10 || got.TotalBytes != 30 {
+		t.Fatalf("隧道流量 = %d/%d, 期望 10/30", got.InputBytes, got.TotalBytes)
+	}
+
+	// Gost 重启计数器归零，本次累计值计为增量
+	env.report(entry.ID, forwardService, 5, 5, 1)
+	if got := env.rule(forward.ID); got.InputBytes != 105 || got.OutputBytes != 1005 || got.TotalRequests != 2 {
+		t.Fatalf("计数器重置后规则流量 = %d/%d/%d, 期望 105/1005/2", got.InputBytes, got.OutputBytes, got.TotalRequests)
+	}
+
+	// 面板重建服务后计数从零开始，即使新累计值大于旧值也全部计入
+	if err = env.rules.Start(env.ctx, forward.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+	env.report(entry.ID, forwardService, 200, 2000, 2)
+	if got := env.rule(forward.ID); got.InputBytes != 305 {
+		t.Fatalf("服务重建后规则入站流量 = %d, 期望 305", got.InputBytes)
+	}
+	if got := env.node(entry.ID); got.InputBytes != 315 || got.OutputBytes != 3025 {
+		t.Fatalf("节点流量 = %d/%d, 期望 315/3025", got.InputBytes, got.OutputBytes)
+	}
+
+	// 重新计算修复虚高的节点流量
+	if err = env.db.Model(&model.GostNode{}).Where("id = ?", entry.ID).
+		Update("input_bytes", 999999).Error; err != nil {
+		t.Fatal(err)
+	}
+	result, err := env.traffic.RecomputeTotals()
+	if err != nil {
+		t.Fatalf("重新计算累计流量失败: %v", err)
+	}
+	if result.Nodes != 2 || result.Tunnels != 1 {
+		t.Fatalf("重新计算结果 = %+v, 期望更新 2 个节点、1 个隧道", result)
+	}
+	if got := env.node(entry.ID); got.InputBytes != 315 || got.TotalBytes != 3340 {
+		t.Fatalf("重新计算后节点流量 = %d/%d, 期望 315/3340", got.InputBytes, got.TotalBytes)
+	}
+	if got := env.node(exit.ID); got.TotalBytes != 0 {
+		t.Fatalf("出口节点流量 = %d, 期望 0", got.TotalBytes)
+	}
+}
+
+func TestObserverCountersSurviveRecovery(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+	service := fmt.Sprintf("rule-%d", rule.ID)
+	env.report(node.ID, service, 100, 1000, 1)
+
+	// 网络中断导致节点离线，Gost 服务仍在运行并保留累计值
+	srv.Inject(gosttest.Fault{Status: http.StatusInternalServerError})
+	env.check(node.ID)
+	env.check(node.ID)
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusStopped {
+		t.Fatalf("节点离线后规则状态 = %s, 期望 stopped", got)
+	}
+	srv.ClearFaults()
+	env.check(node.ID)
+	if got := env.rule(rule.ID).Status; got != model.RuleStatusRunning {
+		t.Fatalf("恢复后规则状态 = %s, 期望 running", got)
+	}
+
+	// 恢复后的上报只计入与恢复前累计值的差值
+	env.report(node.ID, service, 150, 1500, 2)
+	if got := env.rule(rule.ID); got.InputBytes != 150 || got.OutputBytes != 1500 || got.TotalRequests != 2 {
+		t.Fatalf("恢复后规则流量 = %d/%d/%d, 期望 150/1500/2", got.InputBytes, got.OutputBytes, got.TotalRequests)
+	}
+	if got := env.node(node.ID); got.InputBytes != 150 || got.OutputBytes != 1500 {
+		t.Fatalf("恢复后节点流量 = %d/%d, 期望 150/1500", got.InputBytes, got.OutputBytes)
+	}
+}
+
+func TestObserverCountersPerNode(t *testing.T) {
+	env := newTestEnv(t)
+	node1, srv1 := env.onlineNode("node-1")
+	node2, srv2 := env.onlineNode("node-2")
+
+	// 两个节点各自接管同名的默认服务，计数器互不覆盖
+	rule1 := env.adoptForward(node1.ID, srv1, "service-0", 18080)
+	rule2 := env.adoptForward(node2.ID, srv2, "service-0", 18080)
+	env.report(node1.ID, "service-0", 100, 1000, 1)
+	env.report(node2.ID, "service-0", 50, 500, 1)
+	env.report(node1.ID, "service-0", 120, 1200, 2)
+	env.report(node2.ID, "service-0", 60, 600, 2)
+	if got := env.rule(rule1.ID); got.InputBytes != 120 || got.OutputBytes != 1200 {
+		t.Fatalf("节点 1 规则流量 = %d/%d, 期望 120/1200", got.InputBytes, got.OutputBytes)
+	}
+	if got := env.rule(rule2.ID); got.InputBytes != 60 || got.OutputBytes != 600 {
+		t.Fatalf("节点 2 规则流量 = %d/%d, 期望 60/600", got.InputBytes, got.OutputBytes)
+	}
+
+	// 删除规则只清除本节点、本服务的计数器，名称中的 _ 不作为通配符
+	other := env.adoptForward(node1.ID, srv1, "webx1", 18081)
+	client := func(bytes int64) dto.ObserverEvent {
+		return dto.ObserverEvent{Kind: "handler", Service: "webx1", Type: "stats", Client: "10.0.0.1",
+			Stats: &dto.ObserverStats{InputBytes: bytes, OutputBytes: bytes, TotalConns: 1}}
+	}
+	env.ingest(node1.ID, client(100))
+	web := env.adoptForward(node1.ID, srv1, "web_1", 18082)
+	if err := env.rules.Delete(env.ctx, web.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("删除规则失败: %v", err)
+	}
+	if err := env.rules.Delete(env.ctx, rule2.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("删除规则失败: %v", err)
+	}
+	env.ingest(node1.ID, client(150))
+	env.report(node1.ID, "service-0", 130, 1300, 3)
+	clients, _ := env.rules.TopClients(other.ID, &dto.RuleClientsReq{})
+	if len(clients) != 1 || clients[0].InputBytes != 150 {
+		t.Fatalf("删除其他规则后客户端统计 = %+v, 期望入站 150", clients)
+	}
+	if got := env.rule(rule1.ID).InputBytes; got != 130 {
+		t.Fatalf("删除节点 2 规则后节点 1 规则入站 = %d, 期望 130", got)
+	}
+}
+
+func TestObserverReportAuth(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	other, _ := env.onlineNode("node-2")
+	rule := env.forwardRule(node.ID, 10001)
+	service := fmt.Sprintf("rule-%d", rule.ID)
+
+	// 缺少或错误的令牌直接拒绝
+	for _, token := range []string{"", "invalid"} {
+		if _, err := env.observer.Authenticate(token); !stderrors.Is(err, errors.ErrObserverTokenInvalid) {
+			t.Fatalf("令牌 %q 应被拒绝，实际: %v", token, err)
+		}
+	}
+
+	// 其他节点的令牌不能上报该节点上的服务
+	env.report(other.ID, service, 100, 100, 1)
+	if got := env.rule(rule.ID).TotalBytes; got != 0 {
+		t.Fatalf("其他节点上报后规则流量 = %d, 期望 0", got)
+	}
+	if got := env.node(other.ID).TotalBytes; got != 0 {
+		t.Fatalf("其他节点上报后该节点流量 = %d, 期望 0", got)
+	}
+
+	env.report(node.ID, service, 100, 100, 1)
+	if got := env.rule(rule.ID).TotalBytes; got != 200 {
+		t.Fatalf("规则流量 = %d, 期望 200", got)
+	}
+
+	// 升级前创建的观察器不带令牌，同步任务自动更新上报地址
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+	srv.Put("observers", &gost.ObserverConfig{
+		Name:   globalObserverName,
+		Plugin: &gost.PluginConfig{Type: "http", Addr: "http://panel.test/api/v1/observer/report"},
+	})
+	if err := env.syncNode(node.ID); err != nil {
+		t.Fatalf("同步失败: %v", err)
+	}
+	var observer gost.ObserverConfig
+	srv.Get("observers", globalObserverName, &observer)
+	if want := "http://panel.test/api/v1/observer/report/" + env.node(node.ID).ObserverToken; observer.Plugin.Addr != want {
+		t.Fatalf("同步后观察器上报地址 = %s, 期望 %s", observer.Plugin.Addr, want)
+	}
+}
+
+func TestObserverStatusEvents(t *testing.T) {
+	env := newTestEnv(t)
+	node, _ := env.onlineNode("node-1")
+	other, _ := env.onlineNode("node-2")
+	rule := env.forwardRule(node.ID, 10001)
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+	service := fmt.Sprintf("rule-%d", rule.ID)
+
+	status := func(nodeID uint, state, msg string) {
+		t.Helper()
+		env.ingest(nodeID, dto.ObserverEvent{
+			Kind:    "service",
+			Service: service,
+			Type:    "status",
+			Status:  &dto.ObserverStatus{State: state, Msg: msg},
+		})
+	}
+
+	const bindErr = "listen tcp :10001: bind: address already in use"
+
+	// 其他节点上报的状态被忽略
+	status(other.ID, "failed", bindErr)
+	if got := env.rule(rule.ID); got.Status != model.RuleStatusRunning || got.StatusMsg != "" {
+		t.Fatalf("其他节点上报后规则状态 = %s (%q), 期望 running", got.Status, got.StatusMsg)
+	}
+
+	// 服务失败时立即标记为错误并保留 Gost 的错误信息
+	status(node.ID, "failed", bindErr)
+	if got := env.rule(rule.ID); got.Status != model.RuleStatusError || got.StatusMsg != bindErr {
+		t.Fatalf("失败上报后规则状态 = %s (%q), 期望 error (%q)", got.Status, got.StatusMsg, bindErr)
+	}
+
+	// 服务恢复后清除错误信息
+	status(node.ID, "running", "service running")
+	if got := env.rule(rule.ID); got.Status != model.RuleStatusRunning || got.StatusMsg != "" {
+		t.Fatalf("恢复上报后规则状态 = %s (%q), 期望 running", got.Status, got.StatusMsg)
+	}
+}
+
+func TestObserverClientStats(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+	service := fmt.Sprintf("rule-%d", rule.ID)
+
+	// 服务和处理器均开启观察器
+	var svc gost.ServiceConfig
+	srv.Get("services", service, &svc)
+	if svc.Handler == nil || svc.Handler.Observer != globalObserverName {
+		t.Fatalf("处理器观察器未配置: %+v", svc.Handler)
+	}
+
+	send := func(events ...dto.ObserverEvent) {
+		t.Helper()
+		env.ingest(node.ID, events...)
+	}
+	serviceStats := func(current, errs int64) dto.ObserverEvent {
+		return dto.ObserverEvent{Kind: "service", Service: service, Type: "stats",
+			Stats: &dto.ObserverStats{InputBytes: 1000, OutputBytes: 1000, TotalConns: 3, CurrentConns: current, TotalErrs: errs}}
+	}
+	clientStats := func(client string, bytes, current int64) dto.ObserverEvent {
+		return dto.ObserverEvent{Kind: "handler", Service: service, Type: "stats", Client: client,
+			Stats: &dto.ObserverStats{InputBytes: bytes, OutputBytes: bytes, TotalConns: 1, CurrentConns: current}}
+	}
+
+	send(serviceStats(2, 1), clientStats("10.0.0.1", 800, 1), clientStats("10.0.0.2", 200, 1))
+
+	// 当前连接数和错误数记录在规则上，处理器事件不重复计入规则流量
+	got := env.rule(rule.ID)
+	if got.CurrentConns != 2 || got.TotalErrors != 1 || got.TotalBytes != 2000 {
+		t.Fatalf("规则统计 = conns %d, errs %d, bytes %d, 期望 2, 1, 2000", got.CurrentConns, got.TotalErrors, got.TotalBytes)
+	}
+
+	// 连接断开时累计值不变，当前连接数仍需更新；错误数按增量累加
+	send(serviceStats(0, 3), clientStats("10.0.0.1", 900, 0))
+	got = env.rule(rule.ID)
+	if got.CurrentConns != 0 || got.TotalErrors != 3 {
+		t.Fatalf("规则统计 = conns %d, errs %d, 期望 0, 3", got.CurrentConns, got.TotalErrors)
+	}
+
+	clients, err := env.rules.TopClients(rule.ID, &dto.RuleClientsReq{})
+	if err != nil {
+		t.Fatalf("查询客户端排行失败: %v", err)
+	}
+	if len(clients) != 2 || clients[0].Client != "10.0.0.1" || clients[1].Client != "10.0.0.2" {
+		t.Fatalf("客户端排行 = %+v", clients)
+	}
+	if clients[0].TotalBytes != 1800 || clients[0].CurrentConns != 0 || clients[1].CurrentConns != 1 {
+		t.Fatalf("客户端统计 = %+v", clients)
+	}
+
+	// 停止规则后当前连接数清零
+	send(serviceStats(1, 3))
+	if err := env.rules.Stop(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("停止规则失败: %v", err)
+	}
+	if got := env.rule(rule.ID).CurrentConns; got != 0 {
+		t.Fatalf("停止后规则当前连接数 = %d, 期望 0", got)
+	}
+	clients, _ = env.rules.TopClients(rule.ID, &dto.RuleClientsReq{Limit: 1})
+	if len(clients) != 1 || clients[0].CurrentConns != 0 {
+		t.Fatalf("停止后客户端排行 = %+v", clients)
+	}
+}
+
+func TestObserverIngestBuffer(t *testing.T) {
+	env := newTestEnv(t)
+	node, _ := env.onlineNode("node-1")
+	rule1 := env.forwardRule(node.ID, 10001)
+	rule2 := env.forwardRule(node.ID, 10002)
+	reporter := env.node(node.ID)
+
+	stats := func(rule *model.GostRule, bytes int64) dto.ObserverEvent {
+		return dto.ObserverEvent{Kind: "service", Service: fmt.Sprintf("rule-%d", rule.ID), Type: "stats",
+			Stats: &dto.ObserverStats{InputBytes: bytes, OutputBytes: bytes, TotalConns: 1}}
+	}
+	report := func(events ...dto.ObserverEvent) error {
+		return env.observer.HandleReport(reporter, &dto.ObserverReportReq{Events: events})
+	}
+
+	// 上报只进入缓冲区，刷新后才写入数据库
+	for _, bytes := range []int64{100, 300, 500} {
+		if err := report(stats(rule1, bytes)); err != nil {
+			t.Fatalf("上报失败: %v", err)
+		}
+	}
+	if got := env.rule(rule1.ID).TotalBytes; got != 0 {
+		t.Fatalf("刷新前规则流量 = %d, 期望 0", got)
+	}
+	if err := env.observer.Flush(); err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+	if got := env.rule(rule1.ID).TotalBytes; got != 1000 {
+		t.Fatalf("合并后规则流量 = %d, 期望 1000", got)
+	}
+
+	// 两次刷新之间服务重启，重启前的增量不丢失
+	if err := report(stats(rule1, 800), stats(rule1, 200)); err != nil {
+		t.Fatalf("上报失败: %v", err)
+	}
+	if err := env.observer.Flush(); err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+	if got := env.rule(rule1.ID).TotalBytes; got != 1600+400 {
+		t.Fatalf("重启后规则流量 = %d, 期望 2000", got)
+	}
+
+	// 缓冲区已满时拒绝新服务的事件，已缓冲的服务仍可合并
+	env.observer.ingest.capacity = 1
+	if err := report(stats(rule1, 300)); err != nil {
+		t.Fatalf("上报失败: %v", err)
+	}
+	if err := report(stats(rule2, 100)); !stderrors.Is(err, errors.ErrObserverBusy) {
+		t.Fatalf("缓冲区已满时上报结果 = %v, 期望 ErrObserverBusy", err)
+	}
+	if err := report(stats(rule1, 400)); err != nil {
+		t.Fatalf("合并上报失败: %v", err)
+	}
+	if err := env.observer.Flush(); err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+	if got := env.rule(rule1.ID).TotalBytes; got != 2000+400 {
+		t.Fatalf("规则流量 = %d, 期望 2400", got)
+	}
+	if got := env.rule(rule2.ID).TotalBytes; got != 0 {
+		t.Fatalf("被拒绝的规则流量 = %d, 期望 0", got)
+	}
+
+	m := env.observer.IngestStats()
+	if m.ReceivedEvents != 8 || m.MergedEvents != 4 || m.DroppedEvents != 1 || m.RejectedRequests != 1 ||
+		m.FlushedEvents != 4 || m.Flushes != 3 || m.Pending != 0 {
+		t.Fatalf("上报处理统计 = %+v", m)
+	}
+}
+
+func TestMetricsExport(t *testing.T) {
+	env := newTestEnv(t)
+	node, _ := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+	if err := env.rules.Start(env.ctx, rule.ID, 1, "admin", "", ""); err != nil {
+		t.Fatalf("启动规则失败: %v", err)
+	}
+	env.ingest(node.ID, dto.ObserverEvent{Kind: "service", Service: fmt.Sprintf("rule-%d", rule.ID), Type: "stats",
+		Stats: &dto.ObserverStats{InputBytes: 300, OutputBytes: 700, TotalConns: 4, CurrentConns: 2, TotalErrs: 1}})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics.NewResourceCollector(env.db), metrics.NewObserverCollector(env.observer.IngestStats))
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("采集指标失败: %v", err)
+	}
+
+	// value 按名称和标签查找指标值
+	value := func(name string, labels map[string]string) float64 {
+		t.Helper()
+		for _, f := range families {
+			if f.GetName() != name {
+				continue
+			}
+		metric:
+			for _, m := range f.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if want, ok := labels[l.GetName()]; ok && want != l.GetValue() {
+						continue metric
+					}
+				}
+				if m.GetCounter() != nil {
+					return m.GetCounter().GetValue()
+				}
+				return m.GetGauge().GetValue()
+			}
+		}
+		t.Fatalf("未找到指标 %s %v", name, labels)
+		return 0
+	}
+
+	ruleLabels := map[string]string{"rule": rule.Name, "node": node.Name, "type": "forward"}
+	checks := []struct {
+		name   string
+		labels map[string]string
+		want   float64
+	}{
+		{"gost_panel_rule_input_bytes_total", ruleLabels, 300},
+		{"gost_panel_rule_output_bytes_total", ruleLabels, 700},
+		{"gost_panel_rule_errors_total", ruleLabels, 1},
+		{"gost_panel_rule_current_connections", ruleLabels, 2},
+		{"gost_panel_rule_status", map[string]string{"rule": rule.Name, "status": "running"}, 1},
+		{"gost_panel_rule_status", map[string]string{"rule": rule.Name, "status": "error"}, 0},
+		{"gost_panel_node_input_bytes_total", map[string]string{"node": node.Name}, 300},
+		{"gost_panel_node_current_connections", map[string]string{"node": node.Name}, 2},
+		{"gost_panel_node_status", map[string]string{"node": node.Name, "status": "online"}, 1},
+		{"gost_panel_observer_events_flushed_total", nil, 1},
+	}
+	for _, c := range checks {
+		if got := value(c.name, c.labels); got != c.want {
+			t.Errorf("%s%v = %v, 期望 %v", c.name, c.labels, got, c.want)
+		}
+	}
+
+	// 设置令牌后需要携带 Bearer 令牌访问
+	h := metrics.Handler("secret")
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "secret": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("令牌 %q 访问指标接口状态码 = %d, 期望 %d", token, rec.Code, want)
+		}
+	}
+}
+
+// TestGostMetricsScrape 抓取节点 Gost 指标：第一次抓取为基准，之后按差值累加，计数器变小视为重启
+func TestGostMetricsScrape(t *testing.T) {
+	env := newTestEnv(t)
+	node, srv := env.onlineNode("node-1")
+	if _, err := env.nodes.Update(node.ID, &dto.UpdateNodeReq{
+		Name: node.Name, Address: node.Address, Port: node.Port, Username: "admin", Password: "secret",
+		NodeMetricsReq: dto.NodeMetricsReq{MetricsPort: srv.Port(), MetricsPath: "metrics"},
+	}, 1, "admin", "", ""); err != nil {
+		t.Fatalf("更新节点失败: %v", err)
+	}
+	node = env.node(node.ID)
+	if node.MetricsPath != "/metrics" {
+		t.Fatalf("指标路径 = %q, 期望 /metrics", node.MetricsPath)
+	}
+
+	gostMetrics := NewGostMetricsService(env.db, nil)
+	scrape := func(at time.Time, text string) {
+		t.Helper()
+		srv.SetMetrics(text)
+		if err := gostMetrics.Scrape(env.ctx, node, at); err != nil {
+			t.Fatalf("抓取 Gost 指标失败: %v", err)
+		}
+	}
+
+	base := time.Now().Truncate(time.Minute).Add(-10 * time.Minute)
+	scrape(base, `# TYPE gost_service_handler_errors counter
+gost_service_handler_errors{host="h",service="svc-a"} 5
+gost_service_handler_errors{host="h",service="svc-b"} 7
+# TYPE gost_service_requests_in_flight gauge
+gost_service_requests_in_flight{host="h",service="svc-a"} 3
+# TYPE gost_chain_errors counter
+gost_chain_errors{chain="chain-1",host="h",node="n1"} 1
+gost_chain_errors{chain="chain-1",host="h",node="n2"} 1
+`)
+	// svc-b 重启后计数从 2 开始，chain-1 两个节点的错误合并计算
+	scrape(base.Add(time.Minute), `# TYPE gost_service_handler_errors counter
+gost_service_handler_errors{host="h",service="svc-a"} 9
+gost_service_handler_errors{host="h",service="svc-b"} 2
+# TYPE gost_service_requests_in_flight gauge
+gost_service_requests_in_flight{host="h",service="svc-a"} 1
+# TYPE gost_chain_errors counter
+gost_chain_errors{chain="chain-1",host="h",node="n1"} 2
+gost_chain_errors{chain="chain-1",host="h",node="n2"} 4
+`)
+
+	query := func(metric string) map[string]float64 {
+		t.Helper()
+		resp, err := gostMetrics.Query(node.ID, &dto.GostMetricQueryReq{
+			Metric:          metric,
+			TrafficQueryReq: dto.TrafficQueryReq{From: base.Format(time.RFC3339), Step: "1h"},
+		})
+		if err != nil {
+			t.Fatalf("查询 %s 失败: %v", metric, err)
+		}
+		totals := make(map[string]float64)
+		for _, s := range resp.Series {
+			totals[s.Object] = s.Total
+		}
+		return totals
+	}
+
+	if got := query("gost_service_handler_errors_total"); got["svc-a"] != 4 || got["svc-b"] != 2 || len(got) != 2 {
+		t.Errorf("服务错误数 = %v, 期望 svc-a=4 svc-b=2", got)
+	}
+	if got := query("gost_chain_errors"); got["chain-1"] != 4 {
+		t.Errorf("转发链错误数 = %v, 期望 chain-1=4", got)
+	}
+	if got := query("gost_service_requests_in_flight"); got["svc-a"] != 3 {
+		t.Errorf("并发请求数区间最大值 = %v, 期望 svc-a=3", got)
+	}
+	if _, err := gostMetrics.Query(node.ID, &dto.GostMetricQueryReq{Metric: "gost_unknown"}); err != errors.ErrGostMetricInvalid {
+		t.Errorf("查询未知指标错误 = %v, 期望 ErrGostMetricInvalid", err)
+	}
+
+	// 面板指标接口转出最近一次抓取的累计值
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics.NewGostCollector(gostMetrics.Snapshot))
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("采集指标失败: %v", err)
+	}
+	found := false
+	for _, f := range families {
+		if f.GetName() != "gost_panel_gost_chain_errors_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			found = m.GetCounter().GetValue() == 6
+		}
+	}
+	if !found {
+		t.Error("未转出 gost_panel_gost_chain_errors_total = 6")
+	}
+}
+
+// TestDashboardStats 仪表盘流量合计、排行、趋势和异常列表
+func TestDashboardStats(t *testing.T) {
+	env := newTestEnv(t)
+	node, _ := env.onlineNode("node-1")
+	busy := env.forwardRule(node.ID, 10001)
+	idle := env.forwardRule(node.ID, 10002)
+	env.report(node.ID, fmt.Sprintf("rule-%d", busy.ID), 100, 900, 2)
+	env.report(node.ID, fmt.Sprintf("rule-%d", idle.ID), 10, 10, 1)
+	if err := env.traffic.Rollup(time.Now()); err != nil {
+		t.Fatalf("汇总流量失败: %v", err)
+	}
+
+	ruleRepo := env.rules.ruleRepo
+	if err := ruleRepo.UpdateStatusMsg(idle.ID, model.RuleStatusError, "listen tcp :10002: bind: address already in use"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ruleRepo.UpdateCurrentConns(idle.ID, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := NewStatsService(env.db).GetDashboardStats()
+	if err != nil {
+		t.Fatalf("获取仪表盘统计失败: %v", err)
+	}
+
+	for name, total := range map[string]TrafficTotal{"today": stats.Traffic.Today, "week": stats.Traffic.Week, "month": stats.Traffic.Month} {
+		if total.TotalBytes != 1020 || total.Connections != 3 {
+			t.Errorf("%s 流量 = %d/%d, 期望 1020/3", name, total.TotalBytes, total.Connections)
+		}
+	}
+	if last := stats.Trend[len(stats.Trend)-1]; len(stats.Trend) != 24 || last.TotalBytes != 1020 {
+		t.Errorf("趋势 = %d 点, 最后一小时 %d, 期望 24 点 1020", len(stats.Trend), last.TotalBytes)
+	}
+
+	if top := stats.TopRules.ByTraffic; len(top) != 2 || top[0].ID != busy.ID || top[0].TotalBytes != 1000 || top[0].Spark[23] != 1000 {
+		t.Errorf("规则流量排行 = %+v, 期望 %s 第一", top, busy.Name)
+	}
+	if top := stats.TopRules.ByConnections; len(top) != 2 || top[0].ID != idle.ID || top[0].CurrentConns != 3 {
+		t.Errorf("规则连接数排行 = %+v, 期望 %s 第一", top, idle.Name)
+	}
+	if top := stats.TopNodes.ByTraffic; len(top) != 1 || top[0].TotalBytes != 1020 {
+		t.Errorf("节点流量排行 = %+v, 期望 1020", top)
+	}
+
+	if stats.Rules.Error != 1 || len(stats.Problems) != 1 || stats.Problems[0].ID != idle.ID ||
+		!strings.Contains(stats.Problems[0].Reason, "address already in use") {
+		t.Errorf("异常 = %d %+v, 期望规则 %s 及其错误信息", stats.Rules.Error, stats.Problems, idle.Name)
+	}
+	if len(stats.RecentLogs) == 0 {
+		t.Error("最近操作为空")
+	}
+}
+
+func TestTrafficReport(t *testing.T) {
+	env := newTestEnv(t)
+	node, _ := env.onlineNode("node-1")
+	busy := env.forwardRule(node.ID, 10001)
+	removed := env.forwardRule(node.ID, 10002)
+	if err := env.db.Delete(&model.GostRule{}, removed.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 2026-10-14 为周三，日报统计 10-13，周报统计 10-05 ~ 10-11，月报统计 9 月
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.Local)
+	start, end := reportPeriod(ReportKindDaily, now)
+	if want := time.Date(2026, 10, 13, 0, 0, 0, 0, time.Local); !start.Equal(want) || !end.Equal(want.AddDate(0, 0, 1)) {
+		t.Fatalf("日报周期 = %s ~ %s", start, end)
+	}
+	if s, e := reportPeriod(ReportKindWeekly, now); !s.Equal(time.Date(2026, 10, 5, 0, 0, 0, 0, time.Local)) || !e.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("周报周期 = %s ~ %s", s, e)
+	}
+	if s, e := reportPeriod(ReportKindMonthly, now); !s.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local)) || !e.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("月报周期 = %s ~ %s", s, e)
+	}
+
+	samples := []model.TrafficSample{
+		{ResourceType: model.TrafficResourceRule, ResourceID: busy.ID, BucketTime: start, InputBytes: 2048, OutputBytes: 1024, Connections: 5},
+		{ResourceType: model.TrafficResourceRule, ResourceID: removed.ID, BucketTime: start, InputBytes: 512, OutputBytes: 512, Connections: 1},
+		{ResourceType: model.TrafficResourceNode, ResourceID: node.ID, BucketTime: start, InputBytes: 2560, OutputBytes: 1536, Connections: 6},
+		{ResourceType: model.TrafficResourceRule, ResourceID: busy.ID, BucketTime: start.AddDate(0, 0, -1), InputBytes: 1024, OutputBytes: 1024, Connections: 2},
+	}
+	for i := range samples {
+		samples[i].Period = model.TrafficPeriodDay
+	}
+	if err := env.db.Create(&samples).Error; err != nil {
+		t.Fatal(err)
+	}
+	history := &model.NodeStatusHistory{NodeID: node.ID, FromStatus: model.NodeStatusOnline, ToStatus: model.NodeStatusOffline,
+		Reason: "连续 3 次检查失败", CreatedAt: start.Add(10 * time.Hour)}
+	if err := env.db.Create(history).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	reports := NewReportService(env.db)
+	data, err := reports.build(ReportKindDaily, start, end, now)
+	if err != nil {
+		t.Fatalf("生成报表失败: %v", err)
+	}
+	if data.Total.TotalBytes != 4096 || data.Total.Connections != 6 || data.Change != "增长 100.0%" {
+		t.Errorf("合计 = %+v, 变化 %s, 期望 4096/6 增长 100.0%%", data.Total, data.Change)
+	}
+	if len(data.TopRules) != 2 || data.TopRules[0].Name != busy.Name || data.TopRules[0].Share != 75 || !data.TopRules[1].Deleted {
+		t.Errorf("规则排行 = %+v", data.TopRules)
+	}
+	if len(data.Nodes) != 1 || data.Nodes[0].TotalBytes != 4096 || len(data.StatusChanges) != 1 {
+		t.Errorf("节点 = %+v, 状态变更 = %+v", data.Nodes, data.StatusChanges)
+	}
+
+	data.SiteTitle = "Gost Panel"
+	html, err := renderReport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"流量日报", "2026-10-13", node.Name, busy.Name, removed.Name + " (已删除)", "3.00 KB", "连续 3 次检查失败", "配额状态"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("报表缺少 %q", want)
+		}
+	}
+
+	cfg := &model.SystemConfig{ReportDaily: true, ReportWeekly: true, ReportHour: 8}
+	if !reportDue(cfg, ReportKindDaily, now) || !reportDue(cfg, ReportKindWeekly, now) || reportDue(cfg, ReportKindMonthly, now) {
+		t.Error("已到发送时间的日报、周报应发送，未启用的月报不应发送")
+	}
+	cfg.ReportDailySentAt = start
+	if reportDue(cfg, ReportKindDaily, now) {
+		t.Error("已发送的日报不应重复发送")
+	}
+	cfg.ReportDailySentAt, cfg.ReportHour = time.Time{}, 10
+	if reportDue(cfg, ReportKindDaily, now) || !reportDue(cfg, ReportKindWeekly, now) {
+		t.Error("未到发送时间的日报不应发送，周报应在周一发送时间后补发")
+	}
+
+	if to, err := parseRecipients("ops@example.com, Admin <admin@example.com>\nnoc@example.com;"); err != nil || len(to) != 3 || to[1] != "admin@example.com" {
+		t.Errorf("解析收件人 = %v, %v", to, err)
+	}
+	if _, err := parseRecipients("ops@example.com, not-an-email"); !stderrors.Is(err, errors.ErrReportRecipientsInvalid) {
+		t.Errorf("无效收件人错误 = %v, 期望 ErrReportRecipientsInvalid", err)
+	}
+	if err := reports.SendNow(&dto.SendReportReq{Kind: "daily"}); !stderrors.Is(err, errors.ErrReportRecipientsEmpty) {
+		t.Errorf("未配置收件人发送错误 = %v, 期望 ErrReportRecipientsEmpty", err)
+	}
+}
+
+func TestAgentTokenHidden(t *testing.T) {
+	env := newTestEnv(t)
+	agents := NewAgentService(env.db)
+	node, _ := env.newNode("node-1")
+
+	// 节点列表和详情不返回令牌
+	data, err := json.Marshal(env.node(node.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "agent_token") {
+		t.Fatalf("节点 JSON 不应包含 Agent 令牌: %s", data)
+	}
+
+	// 安装命令接口返回令牌
+	resp, err := agents.GetToken(node.ID)
+	if err != nil {
+		t.Fatalf("获取令牌失败: %v", err)
+	}
+	if resp.AgentToken == "" || resp.AgentToken != env.node(node.ID).AgentToken {
+		t.Fatalf("令牌 = %q, 期望节点令牌", resp.AgentToken)
+	}
+
+	// 没有令牌的节点补发令牌，补发后可用于认证
+	if err = env.db.Model(&model.GostNode{}).Where("id = ?", node.ID).Update("agent_token", "").Error; err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = agents.GetToken(node.ID); err != nil || resp.AgentToken == "" {
+		t.Fatalf("补发令牌失败: %v", err)
+	}
+	if got, err := agents.Authenticate(resp.AgentToken); err != nil || got.ID != node.ID {
+		t.Fatalf("补发的令牌认证失败: %v", err)
+	}
+}
+
+func TestTrafficRollupHalfHourZone(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("IST", 5*3600+30*60)
+	t.Cleanup(func() { time.Local = local })
+
+	env := newTestEnv(t)
+	node, _ := env.onlineNode("node-1")
+	rule := env.forwardRule(node.ID, 10001)
+
+	// 本地 23:40 与次日 00:10 的流量分属两天（UTC 同一小时内）
+	lateNight := time.Date(2026, 10, 13, 23, 40, 0, 0, time.Local)
+	afterMidnight := time.Date(2026, 10, 14, 0, 10, 0, 0, time.Local)
+	if err := env.traffic.Record(rule, node.ID, TrafficDelta{InputBytes: 100}, lateNight); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.traffic.Record(rule, node.ID, TrafficDelta{InputBytes: 10}, afterMidnight); err != nil {
+		t.Fatal(err)
+	}
+
+	// 升级前按 UTC 整点写入的小时桶（本地 23:30）由汇总替换
+	legacy := model.TrafficSample{
+		ResourceType: model.TrafficResourceRule,
+		ResourceID:   rule.ID,
+		Period:       model.TrafficPeriodHour,
+		BucketTime:   lateNight.UTC().Truncate(time.Hour),
+		InputBytes:   110,
+	}
+	if err := env.db.Create(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 10, 14, 1, 0, 0, 0, time.Local)
+	if err := env.traffic.rollup(now, hourBucket(now).Add(-3*time.Hour), dayBucket(now).AddDate(0, 0, -1)); err != nil {
+		t.Fatalf("汇总流量失败: %v", err)
+	}
+
+	hours, err := env.traffic.repo.FindByResource(model.TrafficResourceRule, rule.ID, model.TrafficPeriodHour, lateNight.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hours) != 2 || !hours[0].BucketTime.Equal(time.Date(2026, 10, 13, 23, 0, 0, 0, time.Local)) ||
+		hours[0].InputBytes != 100 || hours[1].InputBytes != 10 {
+		t.Fatalf("小时采样 = %+v, 期望本地 23:00 100 字节、00:00 10 字节", hours)
+	}
+
+	days, err := env.traffic.repo.FindByResource(model.TrafficResourceRule, rule.ID, model.TrafficPeriodDay, dayBucket(lateNight), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 2 || days[0].InputBytes != 100 || days[1].InputBytes != 10 {
+		t.Fatalf("天采样 = %+v, 期望前一天 100 字节、当天 10 字节", days)
+	}
+}
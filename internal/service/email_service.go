@@ -1,93 +1,108 @@
 package service
 
 import (
-	"crypto/tls"
-	"fmt"
+	stderrors "errors"
+	"net/mail"
+	"strings"
+
 	"gost-panel/internal/dto"
 	"gost-panel/internal/errors"
-	"net/smtp"
+	"gost-panel/internal/model"
+	"gost-panel/pkg/logger"
+	"gost-panel/pkg/mailer"
 )
 
-// SendTestEmail 发送测试邮件
-func (s *SystemConfigService) SendTestEmail(req *dto.EmailConfigReq) error {
-	// 验证必要参数
-	if req.Host == "" || req.Port == 0 || req.FromEmail == "" {
-		return errors.ErrSMTPConfigIncomplete
+// newMailer 根据 SMTP 配置创建发件器，发件人显示为站点标题
+func newMailer(host string, port int, username, password, from, siteTitle string) (*mailer.Mailer, error) {
+	if host == "" || port == 0 || from == "" {
+		return nil, errors.ErrSMTPConfigIncomplete
 	}
-
-	auth := smtp.PlainAuth("", req.Username, req.Password, req.Host)
-
-	toEmail := req.FromEmail
-	if req.ToEmail != "" {
-		toEmail = req.ToEmail
+	if siteTitle != "" && !strings.Contains(from, "<") {
+		from = (&mail.Address{Name: siteTitle, Address: from}).String()
 	}
+	m, err := mailer.New(mailer.Config{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+	})
+	if err != nil {
+		return nil, smtpError(err)
+	}
+	return m, nil
+}
 
-	to := []string{toEmail}
-
-	msg := []byte(fmt.Sprintf("To: %s\r\n"+
-		"Subject: Gost Panel 测试邮件\r\n"+
-		"\r\n"+
-		"这是一封来自 Gost Panel 的测试邮件。\r\n"+
-		"如果您收到这封邮件，说明您的 SMTP 配置正确。\r\n", toEmail))
-
-	addr := fmt.Sprintf("%s:%d", req.Host, req.Port)
-
-	// 如果端口是 465，通常使用 SMTPS (隐式 TLS)
-	// 如果端口是 587，通常使用 STARTTLS
-
-	if req.Port == 465 {
-		// SMTPS 需自定义 dialer
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: true, // 允许自签名证书，生产环境建议关闭
-			ServerName:         req.Host,
-		}
-
-		conn, err := tls.Dial("tcp", addr, tlsConfig)
-		if err != nil {
-			return errors.ErrSMTPConnectFailed
-		}
-
-		client, err := smtp.NewClient(conn, req.Host)
-		if err != nil {
-			return errors.ErrSMTPClientFailed
-		}
-		defer func(client *smtp.Client) {
-			_ = client.Close()
-		}(client)
-
-		if req.Username != "" && req.Password != "" {
-			if err = client.Auth(auth); err != nil {
-				return errors.ErrSMTPAuthFailed
-			}
-		}
-
-		if err = client.Mail(req.FromEmail); err != nil {
-			return errors.ErrSMTPSenderFailed
-		}
+// newSystemMailer 使用系统配置中的 SMTP 配置创建发件器
+func newSystemMailer(cfg *model.SystemConfig) (*mailer.Mailer, error) {
+	return newMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom, cfg.SiteTitle)
+}
 
-		if err = client.Rcpt(to[0]); err != nil {
-			return errors.ErrSMTPRecipientFailed
-		}
+// smtpError 将发送错误转换为业务错误，原始错误写入日志
+func smtpError(err error) error {
+	var mailErr *mailer.Error
+	if !stderrors.As(err, &mailErr) {
+		logger.Warnf("发送邮件失败: %v", err)
+		return errors.ErrSMTPConnectFailed
+	}
+	logger.Warnf("发送邮件失败: %v", mailErr)
+	switch mailErr.Stage {
+	case mailer.StageConfig:
+		return errors.ErrSMTPConfigIncomplete
+	case mailer.StageTLS:
+		return errors.ErrSMTPTLSFailed
+	case mailer.StageAuth:
+		return errors.ErrSMTPAuthFailed
+	case mailer.StageSender:
+		return errors.ErrSMTPSenderFailed
+	case mailer.StageRecipient:
+		return errors.ErrSMTPRecipientFailed
+	case mailer.StageData:
+		return errors.ErrSMTPWriteFailed
+	default:
+		return errors.ErrSMTPConnectFailed
+	}
+}
 
-		w, err := client.Data()
-		if err != nil {
-			return errors.ErrSMTPDataFailed
+// parseRecipients 解析逗号、分号或换行分隔的收件人列表
+func parseRecipients(s string) ([]string, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r' || r == '，' || r == '；'
+	})
+	var recipients []string
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
 		}
-
-		_, err = w.Write(msg)
+		addr, err := mail.ParseAddress(f)
 		if err != nil {
-			return errors.ErrSMTPWriteFailed
-		}
-
-		err = w.Close()
-		if err != nil {
-			return errors.ErrSMTPCloseFailed
+			return nil, errors.ErrReportRecipientsInvalid
 		}
+		recipients = append(recipients, addr.Address)
+	}
+	return recipients, nil
+}
 
-		return client.Quit()
+// SendTestEmail 发送测试邮件
+func (s *SystemConfigService) SendTestEmail(req *dto.EmailConfigReq) error {
+	m, err := newMailer(req.Host, req.Port, req.Username, req.Password, req.FromEmail, "")
+	if err != nil {
+		return err
+	}
 
+	toEmail := req.FromEmail
+	if req.ToEmail != "" {
+		toEmail = req.ToEmail
 	}
 
-	// 标准 SMTP / STARTTLS
-	return smtp.SendMail(addr, auth, req.FromEmail, to, msg)
+	err = m.Send(&mailer.Message{
+		To:      []string{toEmail},
+		Subject: "Gost Panel 测试邮件",
+		Text:    "这是一封来自 Gost Panel 的测试邮件。\r\n如果您收到这封邮件，说明您的 SMTP 配置正确。\r\n",
+	})
+	if err != nil {
+		return smtpError(err)
+	}
+	return nil
 }
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/internal/utils"
+	"gost-panel/pkg/logger"
+
+	prommodel "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"gorm.io/gorm"
+)
+
+const (
+	// gostMetricsInterval Gost 指标抓取间隔
+	gostMetricsInterval = time.Minute
+	// gostMetricsMaxBody 指标响应最大读取长度
+	gostMetricsMaxBody = 16 << 20
+)
+
+// gostMetricDef 抓取并保存的 Gost 指标
+type gostMetricDef struct {
+	name    string // Gost 指标名称（不含 _total 后缀）
+	label   string // 区分对象的标签
+	counter bool   // 是否为计数器
+}
+
+// gostMetricDefs 保存的关键指标，其余指标忽略
+var gostMetricDefs = []gostMetricDef{
+	{name: "gost_service_requests", label: "service", counter: true},
+	{name: "gost_service_transfer_input_bytes", label: "service", counter: true},
+	{name: "gost_service_transfer_output_bytes", label: "service", counter: true},
+	{name: "gost_service_handler_errors", label: "service", counter: true},
+	{name: "gost_service_requests_in_flight", label: "service"},
+	{name: "gost_chain_errors", label: "chain", counter: true},
+}
+
+// findGostMetricDef 按名称查找关键指标
+func findGostMetricDef(name string) (gostMetricDef, bool) {
+	for _, def := range gostMetricDefs {
+		if def.name == name {
+			return def, true
+		}
+	}
+	return gostMetricDef{}, false
+}
+
+// gostSeriesKey 指标 + 对象 唯一标识
+type gostSeriesKey struct {
+	metric string
+	object string
+}
+
+// gostScrape 节点最近一次抓取的结果
+type gostScrape struct {
+	node   string
+	values map[gostSeriesKey]float64 // 计数器为累计值
+}
+
+// GostMetricsService Gost 指标抓取服务
+// 定时抓取节点 Gost 自身的 Prometheus 指标接口，计数器按两次抓取的差值累加到分钟时间桶，
+// 覆盖未经观察器上报的服务（如在面板外创建的服务）
+type GostMetricsService struct {
+	repo      *repository.GostMetricRepository
+	nodeRepo  *repository.NodeRepository
+	scheduler *NodeScheduler
+
+	mu     sync.Mutex
+	latest map[uint]*gostScrape // 节点 ID -> 最近一次抓取结果
+}
+
+// NewGostMetricsService 创建 Gost 指标抓取服务
+func NewGostMetricsService(db *gorm.DB, scheduler *NodeScheduler) *GostMetricsService {
+	return &GostMetricsService{
+		repo:      repository.NewGostMetricRepository(db),
+		nodeRepo:  repository.NewNodeRepository(db),
+		scheduler: scheduler,
+		latest:    make(map[uint]*gostScrape),
+	}
+}
+
+// Start 注册 Gost 指标抓取任务，随调度器一起停止
+func (s *GostMetricsService) Start() {
+	s.scheduler.Register(&NodeTask{
+		Name:     "gost-metrics",
+		Interval: gostMetricsInterval,
+		Run:      s.scrapeNode,
+	})
+	logger.Infof("Gost 指标抓取服务已启动 (间隔 %s)", gostMetricsInterval)
+}
+
+// scrapeNode 抓取单个节点的 Gost 指标
+// 未配置指标端口、离线或 Agent 连接方式的节点跳过
+func (s *GostMetricsService) scrapeNode(ctx context.Context, node model.GostNode) error {
+	if node.MetricsPort == 0 || node.ConnectionMode == model.NodeConnectionAgent {
+		s.forget(node.ID)
+		return nil
+	}
+	if node.Status == model.NodeStatusOffline {
+		return nil
+	}
+	return s.Scrape(ctx, &node, time.Now())
+}
+
+// Scrape 抓取节点 Gost 指标并保存关键指标
+// 节点的第一次抓取只作为基准，不写入采样
+func (s *GostMetricsService) Scrape(ctx context.Context, node *model.GostNode, at time.Time) error {
+	values, err := s.fetch(ctx, node)
+	if err != nil {
+		return fmt.Errorf("抓取节点 %s Gost 指标失败: %w", node.Name, err)
+	}
+
+	s.mu.Lock()
+	prev := s.latest[node.ID]
+	s.latest[node.ID] = &gostScrape{node: node.Name, values: values}
+	s.mu.Unlock()
+
+	bucket := at.UTC().Truncate(time.Minute)
+	var counters, gauges []model.GostMetricSample
+	for key, v := range values {
+		def, _ := findGostMetricDef(key.metric)
+		sample := model.GostMetricSample{
+			NodeID:     node.ID,
+			Metric:     key.metric,
+			Object:     key.object,
+			BucketTime: bucket,
+			Value:      v,
+		}
+		if !def.counter {
+			gauges = append(gauges, sample)
+			continue
+		}
+		if prev == nil {
+			continue
+		}
+		// 计数器变小说明 Gost 重启或服务重建，此时累计值即为增量；基准中没有的对象为新建服务
+		if last := prev.values[key]; v >= last {
+			sample.Value = v - last
+		}
+		if sample.Value > 0 {
+			counters = append(counters, sample)
+		}
+	}
+
+	if err = s.repo.Accumulate(counters); err != nil {
+		return fmt.Errorf("保存节点 %s Gost 指标失败: %w", node.Name, err)
+	}
+	if err = s.repo.Save(gauges); err != nil {
+		return fmt.Errorf("保存节点 %s Gost 指标失败: %w", node.Name, err)
+	}
+	return nil
+}
+
+// fetch 请求节点 Gost 指标接口并解析关键指标，同一对象多个序列（如不同 host）求和
+func (s *GostMetricsService) fetch(ctx context.Context, node *model.GostNode) (map[gostSeriesKey]float64, error) {
+	client, url, err := utils.GetGostMetricsClient(node)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("指标接口返回 %s", resp.Status)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(io.LimitReader(resp.Body, gostMetricsMaxBody))
+	if err != nil {
+		return nil, fmt.Errorf("解析指标失败: %w", err)
+	}
+
+	values := make(map[gostSeriesKey]float64)
+	for _, def := range gostMetricDefs {
+		family := families[def.name]
+		if family == nil {
+			family = families[def.name+"_total"]
+		}
+		if family == nil {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			object := metricLabel(m, def.label)
+			if object == "" {
+				continue
+			}
+			values[gostSeriesKey{def.name, object}] += metricValue(m)
+		}
+	}
+	return values, nil
+}
+
+// metricLabel 获取指标的标签值
+func metricLabel(m *prommodel.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+// metricValue 获取计数器、仪表盘或无类型指标的值
+func metricValue(m *prommodel.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	default:
+		return m.GetUntyped().GetValue()
+	}
+}
+
+// forget 移除节点的抓取基准（节点关闭指标抓取时调用）
+func (s *GostMetricsService) forget(nodeID uint) {
+	s.mu.Lock()
+	delete(s.latest, nodeID)
+	s.mu.Unlock()
+}
+
+// Snapshot 获取各节点最近一次抓取的关键指标值（供面板 Prometheus 指标接口转出）
+func (s *GostMetricsService) Snapshot() []dto.GostMetricValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []dto.GostMetricValue
+	for nodeID, scrape := range s.latest {
+		for key, v := range scrape.values {
+			def, _ := findGostMetricDef(key.metric)
+			result = append(result, dto.GostMetricValue{
+				NodeID:  nodeID,
+				Node:    scrape.node,
+				Metric:  key.metric,
+				Object:  key.object,
+				Value:   v,
+				Counter: def.counter,
+			})
+		}
+	}
+	return result
+}
+
+// Query 查询节点 Gost 指标历史，按对象分别返回序列
+func (s *GostMetricsService) Query(nodeID uint, req *dto.GostMetricQueryReq) (*dto.GostMetricSeriesResp, error) {
+	if _, err := s.nodeRepo.FindByID(nodeID); err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrNodeNotFound
+		}
+		return nil, err
+	}
+	return s.query(nodeID, req, time.Now())
+}
+
+// query 按步长聚合节点指标采样：计数器求和，仪表盘取最大值，空时间桶补零
+func (s *GostMetricsService) query(nodeID uint, req *dto.GostMetricQueryReq, now time.Time) (*dto.GostMetricSeriesResp, error) {
+	def, ok := findGostMetricDef(strings.TrimSuffix(req.Metric, "_total"))
+	if !ok {
+		return nil, errors.ErrGostMetricInvalid
+	}
+	from, to, err := parseTrafficRange(&req.TrafficQueryReq, now)
+	if err != nil {
+		return nil, err
+	}
+	step, err := parseTrafficStep(req.Step, to.Sub(from))
+	if err != nil {
+		return nil, err
+	}
+
+	start := alignTrafficBucket(from, step)
+	var bounds []time.Time
+	for t := start; t.Before(to); t = nextTrafficBucket(t, step) {
+		if len(bounds) >= trafficMaxPoints {
+			return nil, errors.ErrTrafficTooManyPoints
+		}
+		bounds = append(bounds, t)
+	}
+
+	samples, err := s.repo.FindByMetric(nodeID, def.name, start, to)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.GostMetricSeriesResp{
+		NodeID: nodeID,
+		Metric: def.name,
+		Type:   "gauge",
+		From:   start,
+		To:     to,
+		Step:   formatTrafficStep(step),
+		Series: []dto.GostMetricSeries{},
+	}
+	if def.counter {
+		resp.Type = "counter"
+	}
+
+	// 采样按时间正序，逐个归入所在对象的时间桶
+	series := make(map[string]*dto.GostMetricSeries)
+	for _, sample := range samples {
+		ser, ok := series[sample.Object]
+		if !ok {
+			ser = &dto.GostMetricSeries{Object: sample.Object, Points: make([]dto.GostMetricPoint, len(bounds))}
+			for i, t := range bounds {
+				ser.Points[i].Time = t
+			}
+			series[sample.Object] = ser
+		}
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i].After(sample.BucketTime) }) - 1
+		if i < 0 {
+			continue
+		}
+		p := &ser.Points[i]
+		if def.counter {
+			p.Value += sample.Value
+			ser.Total += sample.Value
+		} else {
+			p.Value = max(p.Value, sample.Value)
+			ser.Total = max(ser.Total, sample.Value)
+		}
+	}
+
+	for _, ser := range series {
+		resp.Series = append(resp.Series, *ser)
+	}
+	sort.Slice(resp.Series, func(i, j int) bool { return resp.Series[i].Object < resp.Series[j].Object })
+	return resp, nil
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/internal/utils"
+	"gost-panel/pkg/gost"
+	"gost-panel/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// ImportService 现有配置导入服务
+// 将节点上手动创建的转发服务和链接管为面板的规则和隧道，沿用原有名称，不重建服务
+type ImportService struct {
+	nodeRepo         *repository.NodeRepository
+	ruleRepo         *repository.RuleRepository
+	tunnelRepo       *repository.TunnelRepository
+	logService       *LogService
+	reconcileService *ReconcileService
+}
+
+// NewImportService 创建现有配置导入服务
+func NewImportService(db *gorm.DB) *ImportService {
+	return &ImportService{
+		nodeRepo:         repository.NewNodeRepository(db),
+		ruleRepo:         repository.NewRuleRepository(db),
+		tunnelRepo:       repository.NewTunnelRepository(db),
+		logService:       NewLogService(db),
+		reconcileService: NewReconcileService(db),
+	}
+}
+
+// ListCandidates 列出节点上未被面板管理的转发服务和链
+func (s *ImportService) ListCandidates(ctx context.Context, nodeID uint) (*dto.ImportCandidatesResp, error) {
+	node, err := s.reconcileService.findOnlineNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.listCandidates(ctx, node)
+}
+
+// Adopt 导入选中的服务和链
+// 先导入链（生成隧道），再导入服务（生成规则），以便隧道转发服务关联到刚导入的隧道
+func (s *ImportService) Adopt(ctx context.Context, nodeID uint, req *dto.ImportReq, userID uint, username string, ip, userAgent string) (*dto.ImportResp, error) {
+	if len(req.Services) == 0 && len(req.Chains) == 0 {
+		return nil, errors.ErrImportEmpty
+	}
+
+	node, err := s.reconcileService.findOnlineNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if node.Maintenance {
+		return nil, errors.ErrNodeMaintenance
+	}
+
+	candidates, err := s.listCandidates(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ImportResp{
+		Adopted: []dto.ImportResult{},
+		Failed:  make(map[string]string),
+	}
+
+	// 1. 链 -> 隧道
+	chains := make(map[string]dto.ImportCandidate, len(candidates.Chains))
+	for _, c := range candidates.Chains {
+		chains[c.Name] = c
+	}
+	adoptedChains := make(map[string]uint)
+	for _, item := range req.Chains {
+		c, ok := chains[item.Name]
+		if !ok {
+			resp.Failed[item.Name] = "链不存在或已被面板管理"
+			continue
+		}
+		tunnel, err := s.adoptChain(ctx, node, c, item.ExitNodeID)
+		if err != nil {
+			resp.Failed[item.Name] = err.Error()
+			continue
+		}
+		adoptedChains[c.Name] = tunnel.ID
+		resp.Adopted = append(resp.Adopted, dto.ImportResult{
+			Kind:         dto.ReconcileKindChain,
+			Name:         c.Name,
+			ResourceType: model.ResourceTypeTunnel,
+			ResourceID:   tunnel.ID,
+		})
+
+		s.logService.Record(
+			userID,
+			username,
+			model.ActionImport,
+			model.ResourceTypeTunnel,
+			tunnel.ID,
+			fmt.Sprintf("导入隧道: %s (节点 %s 上的链 %s)", tunnel.Name, node.Name, c.Name),
+			ip,
+			userAgent)
+	}
+
+	// 2. 服务 -> 规则
+	services := make(map[string]dto.ImportCandidate, len(candidates.Services))
+	for _, c := range candidates.Services {
+		services[c.Name] = c
+	}
+	for _, name := range req.Services {
+		c, ok := services[name]
+		if !ok {
+			resp.Failed[name] = "服务不存在或已被面板管理"
+			continue
+		}
+		if c.Chain != "" && c.TunnelID == 0 {
+			c.TunnelID = adoptedChains[c.Chain]
+		}
+		rule, err := s.adoptService(node, c)
+		if err != nil {
+			resp.Failed[name] = err.Error()
+			continue
+		}
+		resp.Adopted = append(resp.Adopted, dto.ImportResult{
+			Kind:         dto.ReconcileKindService,
+			Name:         c.Name,
+			ResourceType: model.ResourceTypeRule,
+			ResourceID:   rule.ID,
+		})
+
+		s.logService.Record(
+			userID,
+			username,
+			model.ActionImport,
+			model.ResourceTypeRule,
+			rule.ID,
+			fmt.Sprintf("导入规则: %s (节点 %s 上的服务 %s)", rule.Name, node.Name, c.Name),
+			ip,
+			userAgent)
+	}
+
+	logger.Infof("节点 %s 导入完成: 成功 %d, 失败 %d", node.Name, len(resp.Adopted), len(resp.Failed))
+	return resp, nil
+}
+
+// listCandidates 对比节点配置与数据库记录，生成可导入对象列表
+func (s *ImportService) listCandidates(ctx context.Context, node *model.GostNode) (*dto.ImportCandidatesResp, error) {
+	desired, err := s.reconcileService.buildDesiredState(node)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := utils.GetGostClient(node).GetConfig(ctx)
+	if err != nil {
+		logger.Warnf("获取节点 %s 配置失败: %v", node.Name, err)
+		return nil, errors.ErrNodeConfigFetchFailed
+	}
+
+	nodes, _, err := s.nodeRepo.List(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ImportCandidatesResp{
+		NodeID:   node.ID,
+		NodeName: node.Name,
+		Services: []dto.ImportCandidate{},
+		Chains:   []dto.ImportCandidate{},
+	}
+
+	unmanagedChains := make(map[string]bool)
+	for i := range cfg.Chains {
+		chain := &cfg.Chains[i]
+		if isManagedObject(dto.ReconcileKindChain, chain.Name, desired) {
+			continue
+		}
+		unmanagedChains[chain.Name] = true
+		resp.Chains = append(resp.Chains, chainCandidate(chain, nodes, node.ID))
+	}
+
+	for i := range cfg.Services {
+		svc := &cfg.Services[i]
+		if isManagedObject(dto.ReconcileKindService, svc.Name, desired) {
+			continue
+		}
+
+		c := serviceCandidate(svc)
+		if c.Adoptable && c.Chain != "" {
+			if o, ok := desired.ownedChains[c.Chain]; ok {
+				c.TunnelID = o.resourceID
+			} else if unmanagedChains[c.Chain] {
+				c.Reason = fmt.Sprintf("需同时导入链 %s", c.Chain)
+			} else {
+				c.Adoptable = false
+				c.Reason = fmt.Sprintf("引用的链 %s 不存在", c.Chain)
+			}
+		}
+		resp.Services = append(resp.Services, c)
+	}
+
+	return resp, nil
+}
+
+// adoptChain 将链导入为隧道
+func (s *ImportService) adoptChain(ctx context.Context, node *model.GostNode, c dto.ImportCandidate, exitNodeID uint) (*model.GostTunnel, error) {
+	if !c.Adoptable {
+		return nil, fmt.Errorf("不可导入: %s", c.Reason)
+	}
+
+	exists, err := s.tunnelRepo.ExistsByChainID(node.ID, c.Name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("链已被其他隧道使用")
+	}
+
+	if exitNodeID == 0 {
+		exitNodeID = c.ExitNodeID
+	}
+	if exitNodeID == 0 {
+		return nil, fmt.Errorf("未匹配到出口节点，请手动指定")
+	}
+	if exitNodeID == node.ID {
+		return nil, errors.ErrTunnelNodeSame
+	}
+	exitNode, err := s.nodeRepo.FindByID(exitNodeID)
+	if err != nil {
+		return nil, errors.ErrExitNodeNotFound
+	}
+	if exitNode.Status == model.NodeStatusOffline {
+		return nil, errors.ErrExitNodeOffline
+	}
+
+	// 在出口节点上查找对应端口的 Relay 服务
+	_, portStr, _ := net.SplitHostPort(c.RelayAddr)
+	relayPort, _ := strconv.Atoi(portStr)
+	exitCfg, err := utils.GetGostClient(exitNode).GetConfig(ctx)
+	if err != nil {
+		return nil, errors.ErrNodeConfigFetchFailed
+	}
+	relayName := ""
+	for _, svc := range exitCfg.Services {
+		if svc.Handler != nil && svc.Handler.Type == "relay" && listenPort(svc.Addr) == relayPort {
+			relayName = svc.Name
+			break
+		}
+	}
+	if relayName == "" {
+		return nil, fmt.Errorf("出口节点 %s 上未找到端口 %d 的 Relay 服务", exitNode.Name, relayPort)
+	}
+
+	tunnel := &model.GostTunnel{
+		Name:           c.Name,
+		EntryNodeID:    node.ID,
+		ExitNodeID:     exitNode.ID,
+		Protocol:       c.Protocol,
+		RelayPort:      relayPort,
+		Status:         model.TunnelStatusRunning,
+		DesiredRunning: true,
+		ServiceID:      relayName,
+		ChainID:        c.Name,
+		Remark:         "导入自节点现有配置",
+	}
+	if err = s.tunnelRepo.Create(tunnel); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("导入隧道成功: %s (Relay: %s@%s -> Chain: %s@%s)", tunnel.Name, relayName, exitNode.Name, c.Name, node.Name)
+	return tunnel, nil
+}
+
+// adoptService 将转发服务导入为规则
+func (s *ImportService) adoptService(node *model.GostNode, c dto.ImportCandidate) (*model.GostRule, error) {
+	if !c.Adoptable {
+		return nil, fmt.Errorf("不可导入: %s", c.Reason)
+	}
+
+	exists, err := s.ruleRepo.ExistsByPort(node.ID, c.ListenPort)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.ErrRulePortExists
+	}
+
+	rule := &model.GostRule{
+		Name:       c.Name,
+		Type:       model.RuleTypeForward,
+		Protocol:   model.RuleProtocol(c.Protocol),
+		ListenPort: c.ListenPort,
+		ListenHost: c.ListenHost,
+		Targets:    c.Targets,
+		Strategy:   c.Strategy,
+		Status:     utils.GostStateToRuleStatus(c.State),
+		ServiceID:  c.Name,
+		// 导入的服务在节点上已存在，视为期望运行
+		DesiredRunning: true,
+		Remark:         "导入自节点现有配置",
+	}
+	if c.Chain != "" {
+		if c.TunnelID == 0 {
+			return nil, fmt.Errorf("引用的链 %s 未被面板管理", c.Chain)
+		}
+		rule.Type = model.RuleTypeTunnel
+		rule.TunnelID = &c.TunnelID
+	} else {
+		rule.NodeID = &node.ID
+	}
+
+	if err = s.ruleRepo.Create(rule); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("导入规则成功: %s (:%d)", rule.Name, rule.ListenPort)
+	return rule, nil
+}
+
+// isManagedObject 判断对象是否已由面板管理（被记录引用、符合面板命名规则或为节点 API TLS 入口服务）
+func isManagedObject(kind, name string, desired *desiredState) bool {
+	if kind == dto.ReconcileKindService && name == nodeAPITLSServiceName {
+		return true
+	}
+	if _, ok := desired.owns(kind, name); ok {
+		return true
+	}
+	_, _, ok := parsePanelObjectName(kind, name)
+	return ok
+}
+
+// serviceCandidate 解析服务配置，判断能否导入为转发规则
+func serviceCandidate(svc *gost.ServiceConfig) dto.ImportCandidate {
+	// 保留服务绑定的地址，重建服务时不扩大监听范围
+	host, port := splitListenAddr(svc.Addr)
+	c := dto.ImportCandidate{
+		Kind:       dto.ReconcileKindService,
+		Name:       svc.Name,
+		ListenPort: port,
+		ListenHost: host,
+	}
+	if svc.Status != nil {
+		c.State = svc.Status.State
+	}
+
+	if svc.Handler == nil || (svc.Handler.Type != "tcp" && svc.Handler.Type != "udp") {
+		c.Reason = "仅支持导入 TCP/UDP 端口转发服务"
+		if svc.Handler != nil && svc.Handler.Type == "relay" {
+			c.Reason = "隧道出口 Relay 服务，请在入口节点导入对应的链"
+		}
+		return c
+	}
+	if svc.Listener != nil && svc.Listener.Type != "" && svc.Listener.Type != svc.Handler.Type {
+		c.Reason = fmt.Sprintf("监听器类型 %s 与处理器类型 %s 不一致", svc.Listener.Type, svc.Handler.Type)
+		return c
+	}
+	if c.ListenPort == 0 {
+		c.Reason = fmt.Sprintf("无法解析监听地址 %s", svc.Addr)
+		return c
+	}
+
+	targets, strategy := forwarderSummary(svc.Forwarder)
+	if len(targets) == 0 {
+		c.Reason = "服务未配置转发目标"
+		return c
+	}
+
+	c.Protocol = svc.Handler.Type
+	c.Targets = targets
+	c.Strategy = strategy
+	c.Chain = svc.Handler.Chain
+	c.RuleType = string(model.RuleTypeForward)
+	if c.Chain != "" {
+		c.RuleType = string(model.RuleTypeTunnel)
+	}
+	c.Adoptable = true
+	return c
+}
+
+// chainCandidate 解析链配置，判断能否导入为隧道
+// 仅支持单跳单节点、使用 relay 连接器的链，并按 Relay 地址匹配出口节点
+func chainCandidate(chain *gost.ChainConfig, nodes []model.GostNode, entryNodeID uint) dto.ImportCandidate {
+	c := dto.ImportCandidate{
+		Kind: dto.ReconcileKindChain,
+		Name: chain.Name,
+	}
+
+	if len(chain.Hops) != 1 || len(chain.Hops[0].Nodes) != 1 {
+		c.Reason = "仅支持导入单跳单节点的链"
+		return c
+	}
+	n := chain.Hops[0].Nodes[0]
+	if n.Connector == nil || n.Connector.Type != "relay" {
+		c.Reason = "仅支持导入 relay 连接器的链"
+		return c
+	}
+	c.Protocol = "tcp"
+	if n.Dialer != nil && n.Dialer.Type != "" {
+		c.Protocol = n.Dialer.Type
+	}
+	if c.Protocol != "tcp" && c.Protocol != "udp" {
+		c.Reason = fmt.Sprintf("不支持的拨号器类型 %s", c.Protocol)
+		return c
+	}
+
+	host, _, err := net.SplitHostPort(n.Addr)
+	if err != nil || listenPort(n.Addr) == 0 {
+		c.Reason = fmt.Sprintf("无法解析 Relay 地址 %s", n.Addr)
+		return c
+	}
+	c.RelayAddr = n.Addr
+
+	for _, node := range nodes {
+		if node.ID != entryNodeID && node.Address == host {
+			c.ExitNodeID = node.ID
+			break
+		}
+	}
+	if c.ExitNodeID == 0 {
+		c.Reason = "未按地址匹配到出口节点，导入时需手动指定"
+	}
+
+	c.Adoptable = true
+	return c
+}
+
+// listenPort 从监听地址中解析端口，失败返回 0
+func listenPort(addr string) int {
+	_, port := splitListenAddr(addr)
+	return port
+}
+
+// splitListenAddr 从监听地址中解析主机和端口，失败时端口为 0
+// 0.0.0.0、:: 等通配地址与空主机相同，均返回空主机
+func splitListenAddr(addr string) (string, int) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = ""
+	}
+	return host, port
+}
@@ -10,6 +10,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// systemUsername 后台任务记录操作日志时使用的用户名
+const systemUsername = "system"
+
 // LogService 日志服务
 // 负责操作日志的查询
 type LogService struct {
@@ -0,0 +1,537 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/internal/utils"
+	"gost-panel/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// MaintenanceService 节点维护模式服务
+// 进入维护模式后节点的状态变更记录为计划内停机、离线后恢复时不自动重建，并拒绝新建规则和隧道；
+// 退出维护模式时节点在线则立即重建期望运行的隧道和规则；
+// 可选将节点上的规则和隧道迁移到替换节点：先在替换节点重建并确认运行，再停止原规则/隧道
+type MaintenanceService struct {
+	nodeRepo      *repository.NodeRepository
+	ruleRepo      *repository.RuleRepository
+	tunnelRepo    *repository.TunnelRepository
+	historyRepo   *repository.NodeStatusHistoryRepository
+	ruleService   *RuleService
+	tunnelService *TunnelService
+	recovery      *nodeRecovery
+	logService    *LogService
+}
+
+// migration 一次迁移的上下文
+type migration struct {
+	ctx       context.Context
+	source    *model.GostNode
+	target    *model.GostNode
+	ports     *portAllocator
+	userID    uint
+	username  string
+	ip        string
+	userAgent string
+}
+
+// NewMaintenanceService 创建节点维护模式服务
+func NewMaintenanceService(db *gorm.DB) *MaintenanceService {
+	return &MaintenanceService{
+		nodeRepo:      repository.NewNodeRepository(db),
+		ruleRepo:      repository.NewRuleRepository(db),
+		tunnelRepo:    repository.NewTunnelRepository(db),
+		historyRepo:   repository.NewNodeStatusHistoryRepository(db),
+		ruleService:   NewRuleService(db),
+		tunnelService: NewTunnelService(db),
+		recovery:      newNodeRecovery(db),
+		logService:    NewLogService(db),
+	}
+}
+
+// Enter 节点进入维护模式，指定替换节点时迁移节点上的隧道和端口转发规则
+func (s *MaintenanceService) Enter(ctx context.Context, nodeID uint, req *dto.NodeMaintenanceReq, userID uint, username string, ip, userAgent string) (*dto.NodeMaintenanceResp, error) {
+	node, err := s.findNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *model.GostNode
+	if req.ReplacementNodeID != nil && *req.ReplacementNodeID != 0 {
+		if *req.ReplacementNodeID == nodeID {
+			return nil, errors.ErrReplacementNodeSame
+		}
+		if target, err = s.findNode(*req.ReplacementNodeID); err != nil {
+			return nil, err
+		}
+		if target.Maintenance || target.Status != model.NodeStatusOnline {
+			return nil, errors.ErrReplacementNodeUnavailable
+		}
+	}
+
+	if err = s.nodeRepo.UpdateMaintenance(nodeID, true); err != nil {
+		return nil, err
+	}
+	node.Maintenance = true
+	s.markMaintenance(node, "进入维护模式")
+
+	s.logService.Record(
+		userID,
+		username,
+		model.ActionMaintenance,
+		model.ResourceTypeNode,
+		nodeID,
+		fmt.Sprintf("节点进入维护模式: %s", node.Name),
+		ip,
+		userAgent)
+	logger.Infof("节点 %s 进入维护模式", node.Name)
+
+	resp := &dto.NodeMaintenanceResp{
+		NodeID:      nodeID,
+		Maintenance: true,
+		Tunnels:     []dto.MigrationItem{},
+		Rules:       []dto.MigrationItem{},
+	}
+	if target == nil {
+		return resp, nil
+	}
+	resp.ReplacementNodeID = target.ID
+
+	m := &migration{
+		ctx:       ctx,
+		source:    node,
+		target:    target,
+		ports:     newPortAllocator(ctx, s.nodeRepo, s.ruleRepo, s.tunnelRepo),
+		userID:    userID,
+		username:  username,
+		ip:        ip,
+		userAgent: userAgent,
+	}
+
+	// 1. 隧道（含使用该隧道的规则）
+	tunnels, err := s.tunnelRepo.FindByNodeID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tunnels {
+		item, ruleItems := s.migrateTunnel(m, t)
+		resp.Tunnels = append(resp.Tunnels, item)
+		resp.Rules = append(resp.Rules, ruleItems...)
+	}
+
+	// 2. 端口转发规则
+	rules, err := s.ruleRepo.FindByNodeID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if r.Type != model.RuleTypeForward {
+			continue
+		}
+		resp.Rules = append(resp.Rules, s.recreateRule(m, r, nil))
+	}
+
+	migrated, failed := countMigration(resp.Tunnels)
+	ruleMigrated, ruleFailed := countMigration(resp.Rules)
+	s.logService.Record(
+		userID,
+		username,
+		model.ActionMigrate,
+		model.ResourceTypeNode,
+		nodeID,
+		fmt.Sprintf("迁移节点 %s -> %s: 隧道成功 %d 失败 %d，规则成功 %d 失败 %d",
+			node.Name, target.Name, migrated, failed, ruleMigrated, ruleFailed),
+		ip,
+		userAgent)
+	logger.Infof("节点 %s 迁移到 %s 完成: 隧道成功 %d 失败 %d，规则成功 %d 失败 %d",
+		node.Name, target.Name, migrated, failed, ruleMigrated, ruleFailed)
+
+	return resp, nil
+}
+
+// Exit 节点退出维护模式
+// 维护期间节点离线又恢复时不会自动重建，退出时节点在线则重建期望运行的隧道和规则；
+// 节点仍离线时由健康检测在恢复在线后重建
+func (s *MaintenanceService) Exit(ctx context.Context, nodeID uint, userID uint, username string, ip, userAgent string) (*dto.NodeMaintenanceResp, error) {
+	node, err := s.findNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.nodeRepo.UpdateMaintenance(nodeID, false); err != nil {
+		return nil, err
+	}
+	node.Maintenance = false
+	s.markMaintenance(node, "退出维护模式")
+
+	s.logService.Record(
+		userID,
+		username,
+		model.ActionMaintenance,
+		model.ResourceTypeNode,
+		nodeID,
+		fmt.Sprintf("节点退出维护模式: %s", node.Name),
+		ip,
+		userAgent)
+	logger.Infof("节点 %s 退出维护模式", node.Name)
+
+	if node.Status == model.NodeStatusOnline {
+		s.recovery.recoverNode(ctx, *node)
+	}
+
+	return &dto.NodeMaintenanceResp{
+		NodeID:      nodeID,
+		Maintenance: false,
+		Tunnels:     []dto.MigrationItem{},
+		Rules:       []dto.MigrationItem{},
+	}, nil
+}
+
+// markMaintenance 节点离线时记录进入/退出维护的标记，作为计划内停机时段的起止
+// 节点在线时无需标记：在线时长不受维护影响，维护期间的离线由健康检测记录
+func (s *MaintenanceService) markMaintenance(node *model.GostNode, reason string) {
+	if node.Status == model.NodeStatusOnline {
+		return
+	}
+	if err := s.historyRepo.Create(&model.NodeStatusHistory{
+		NodeID:      node.ID,
+		FromStatus:  node.Status,
+		ToStatus:    node.Status,
+		Reason:      reason,
+		Maintenance: node.Maintenance,
+	}); err != nil {
+		logger.Errorf("记录节点 %s 维护状态失败: %v", node.Name, err)
+	}
+}
+
+// findNode 查询节点
+func (s *MaintenanceService) findNode(nodeID uint) (*model.GostNode, error) {
+	node, err := s.nodeRepo.FindByID(nodeID)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrNodeNotFound
+		}
+		return nil, err
+	}
+	return node, nil
+}
+
+// migrateTunnel 将隧道迁移到替换节点
+// 替换入口时在新入口上重建使用该隧道的规则；替换出口时规则监听端口不变，将规则原地改绑到新隧道
+func (s *MaintenanceService) migrateTunnel(m *migration, t model.GostTunnel) (dto.MigrationItem, []dto.MigrationItem) {
+	item := dto.MigrationItem{
+		ResourceType: model.ResourceTypeTunnel,
+		SourceID:     t.ID,
+		Name:         t.Name,
+	}
+
+	replaceEntry := t.EntryNodeID == m.source.ID
+	tunnel := &model.GostTunnel{
+		Name:        t.Name,
+		EntryNodeID: t.EntryNodeID,
+		ExitNodeID:  t.ExitNodeID,
+		Protocol:    t.Protocol,
+		RelayPort:   t.RelayPort,
+		Remark:      t.Remark,
+		Status:      model.TunnelStatusStopped,
+	}
+	if replaceEntry {
+		tunnel.EntryNodeID = m.target.ID
+	} else {
+		tunnel.ExitNodeID = m.target.ID
+	}
+
+	if tunnel.EntryNodeID == tunnel.ExitNodeID {
+		return failMigration(item, errors.ErrTunnelNodeSame), nil
+	}
+
+	// 迁移期间原隧道仍在运行，出口节点相同时 Relay 端口需要顺延
+	relayPort, err := m.ports.allocate(tunnel.ExitNodeID, tunnel.RelayPort)
+	if err != nil {
+		return failMigration(item, err), nil
+	}
+	tunnel.RelayPort = relayPort
+
+	if err = s.tunnelRepo.Create(tunnel); err != nil {
+		m.ports.release(tunnel.ExitNodeID, relayPort)
+		return failMigration(item, err), nil
+	}
+
+	active := t.DesiredRunning || t.Status == model.TunnelStatusRunning
+	if active {
+		err = s.tunnelService.Start(m.ctx, tunnel.ID, m.userID, m.username, m.ip, m.userAgent)
+		if err == nil {
+			err = s.verifyTunnel(m.ctx, tunnel.ID)
+		}
+		if err != nil {
+			_ = s.tunnelService.Stop(m.ctx, tunnel.ID, m.userID, m.username, m.ip, m.userAgent)
+			_ = s.tunnelRepo.Delete(tunnel.ID)
+			m.ports.release(tunnel.ExitNodeID, relayPort)
+			return failMigration(item, err), nil
+		}
+	}
+
+	item.TargetID = tunnel.ID
+	item.Status = dto.MigrationStatusMigrated
+
+	// 迁移使用该隧道的规则
+	rules, err := s.ruleRepo.FindByTunnelID(t.ID)
+	if err != nil {
+		item.Error = fmt.Sprintf("查询隧道规则失败，原隧道保持运行: %v", err)
+		return item, nil
+	}
+
+	var ruleItems []dto.MigrationItem
+	allMigrated := true
+	for _, r := range rules {
+		var ri dto.MigrationItem
+		if replaceEntry {
+			ri = s.recreateRule(m, r, &tunnel.ID)
+		} else {
+			ri = s.rebindRule(m, r, tunnel.ID)
+		}
+		if ri.Status != dto.MigrationStatusMigrated {
+			allMigrated = false
+		}
+		ruleItems = append(ruleItems, ri)
+	}
+
+	// 所有规则迁移成功后才停止原隧道，避免仍在使用的规则中断
+	if !allMigrated {
+		item.Error = "部分规则迁移失败，原隧道保持运行"
+		return item, ruleItems
+	}
+	if active {
+		if err = s.tunnelService.Stop(m.ctx, t.ID, m.userID, m.username, m.ip, m.userAgent); err != nil {
+			item.Error = fmt.Sprintf("停止原隧道失败: %v", err)
+		}
+	}
+
+	return item, ruleItems
+}
+
+// recreateRule 在替换节点上重建规则，确认运行后停止原规则
+// tunnelID 不为空时为隧道转发规则，改用新隧道
+func (s *MaintenanceService) recreateRule(m *migration, r model.GostRule, tunnelID *uint) dto.MigrationItem {
+	item := dto.MigrationItem{
+		ResourceType: model.ResourceTypeRule,
+		SourceID:     r.ID,
+		Name:         r.Name,
+	}
+
+	listenHost, err := portableListenHost(r.ListenHost)
+	if err != nil {
+		return failMigration(item, err)
+	}
+	if err = m.ports.reserve(m.target.ID, r.ListenPort); err != nil {
+		return failMigration(item, err)
+	}
+
+	rule := &model.GostRule{
+		Name:       r.Name,
+		Type:       r.Type,
+		Protocol:   r.Protocol,
+		ListenPort: r.ListenPort,
+		ListenHost: listenHost,
+		Targets:    r.Targets,
+		Strategy:   r.Strategy,
+		EnableTLS:  r.EnableTLS,
+		Remark:     r.Remark,
+		Status:     model.RuleStatusStopped,
+	}
+	if tunnelID != nil {
+		rule.TunnelID = tunnelID
+	} else {
+		rule.NodeID = &m.target.ID
+	}
+
+	if err = s.ruleRepo.Create(rule); err != nil {
+		m.ports.release(m.target.ID, rule.ListenPort)
+		return failMigration(item, err)
+	}
+
+	if r.DesiredRunning || r.Status == model.RuleStatusRunning {
+		err = s.ruleService.Start(m.ctx, rule.ID, m.userID, m.username, m.ip, m.userAgent)
+		if err == nil {
+			err = s.verifyRule(m.ctx, rule.ID)
+		}
+		if err != nil {
+			_ = s.ruleService.Stop(m.ctx, rule.ID, m.userID, m.username, m.ip, m.userAgent)
+			_ = s.ruleRepo.Delete(rule.ID)
+			m.ports.release(m.target.ID, rule.ListenPort)
+			return failMigration(item, err)
+		}
+
+		item.TargetID = rule.ID
+		item.Status = dto.MigrationStatusMigrated
+		if err = s.ruleService.Stop(m.ctx, r.ID, m.userID, m.username, m.ip, m.userAgent); err != nil {
+			item.Error = fmt.Sprintf("停止原规则失败: %v", err)
+		}
+		return item
+	}
+
+	item.TargetID = rule.ID
+	item.Status = dto.MigrationStatusMigrated
+	return item
+}
+
+// rebindRule 将隧道转发规则改绑到新隧道（入口节点不变，无法并行运行两份规则）
+// 运行中的规则会先停止再以新隧道启动，失败时改回原隧道并尝试恢复运行
+func (s *MaintenanceService) rebindRule(m *migration, r model.GostRule, tunnelID uint) dto.MigrationItem {
+	item := dto.MigrationItem{
+		ResourceType: model.ResourceTypeRule,
+		SourceID:     r.ID,
+		Name:         r.Name,
+	}
+
+	active := r.DesiredRunning || r.Status == model.RuleStatusRunning
+	if active {
+		if err := s.ruleService.Stop(m.ctx, r.ID, m.userID, m.username, m.ip, m.userAgent); err != nil {
+			return failMigration(item, err)
+		}
+	}
+
+	if err := s.ruleRepo.UpdateTunnelID(r.ID, tunnelID); err != nil {
+		s.restoreRule(m, r, active)
+		return failMigration(item, err)
+	}
+
+	if active {
+		err := s.ruleService.Start(m.ctx, r.ID, m.userID, m.username, m.ip, m.userAgent)
+		if err == nil {
+			err = s.verifyRule(m.ctx, r.ID)
+		}
+		if err != nil {
+			_ = s.ruleService.Stop(m.ctx, r.ID, m.userID, m.username, m.ip, m.userAgent)
+			if r.TunnelID != nil {
+				_ = s.ruleRepo.UpdateTunnelID(r.ID, *r.TunnelID)
+			}
+			s.restoreRule(m, r, active)
+			return failMigration(item, err)
+		}
+	}
+
+	item.TargetID = r.ID
+	item.Status = dto.MigrationStatusMigrated
+	return item
+}
+
+// restoreRule 改绑失败后尝试恢复原规则运行
+func (s *MaintenanceService) restoreRule(m *migration, r model.GostRule, active bool) {
+	if !active {
+		return
+	}
+	if err := s.ruleService.Start(m.ctx, r.ID, m.userID, m.username, m.ip, m.userAgent); err != nil {
+		logger.Warnf("[Maintenance] 恢复规则 %s 失败: %v", r.Name, err)
+	}
+}
+
+// verifyRule 确认规则在节点上处于运行状态
+func (s *MaintenanceService) verifyRule(ctx context.Context, ruleID uint) error {
+	rule, err := s.ruleRepo.FindByID(ruleID)
+	if err != nil {
+		return err
+	}
+	if rule.Status != model.RuleStatusRunning {
+		return errors.ErrRuleStartFailed
+	}
+
+	node, err := s.nodeRepo.FindByID(s.ruleService.getEntryNodeID(rule))
+	if err != nil {
+		return errors.ErrNodeNotFound
+	}
+	gostCfg, err := utils.GetGostClient(node).GetConfig(ctx)
+	if err != nil {
+		return errors.ErrNodeConfigFetchFailed
+	}
+
+	name := ruleServiceName(rule)
+	for _, svc := range gostCfg.Services {
+		if svc.Name != name {
+			continue
+		}
+		state := "stopped"
+		if svc.Status != nil {
+			state = svc.Status.State
+		}
+		if utils.GostStateToRuleStatus(state) != model.RuleStatusRunning {
+			return fmt.Errorf("服务 %s 未运行 (状态: %s)", name, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("节点 %s 上未找到服务 %s", node.Name, name)
+}
+
+// verifyTunnel 确认隧道出口 Relay 服务运行且入口 Chain 存在
+func (s *MaintenanceService) verifyTunnel(ctx context.Context, tunnelID uint) error {
+	tunnel, err := s.tunnelRepo.FindByID(tunnelID)
+	if err != nil {
+		return err
+	}
+	if tunnel.Status != model.TunnelStatusRunning {
+		return errors.ErrTunnelNotRunning
+	}
+
+	exitNode, err := s.nodeRepo.FindByID(tunnel.ExitNodeID)
+	if err != nil {
+		return errors.ErrExitNodeNotFound
+	}
+	exitCfg, err := utils.GetGostClient(exitNode).GetConfig(ctx)
+	if err != nil {
+		return errors.ErrNodeConfigFetchFailed
+	}
+	relayName := tunnelRelayServiceName(tunnel)
+	relayRunning := false
+	for _, svc := range exitCfg.Services {
+		if svc.Name == relayName && svc.Status != nil &&
+			utils.GostStateToTunnelStatus(svc.Status.State) == model.TunnelStatusRunning {
+			relayRunning = true
+			break
+		}
+	}
+	if !relayRunning {
+		return fmt.Errorf("出口节点 %s 上的 Relay 服务 %s 未运行", exitNode.Name, relayName)
+	}
+
+	entryNode, err := s.nodeRepo.FindByID(tunnel.EntryNodeID)
+	if err != nil {
+		return errors.ErrEntryNodeNotFound
+	}
+	entryCfg, err := utils.GetGostClient(entryNode).GetConfig(ctx)
+	if err != nil {
+		return errors.ErrNodeConfigFetchFailed
+	}
+	chainName := tunnelChainName(tunnel)
+	for _, chain := range entryCfg.Chains {
+		if chain.Name == chainName {
+			return nil
+		}
+	}
+	return fmt.Errorf("入口节点 %s 上未找到 Chain %s", entryNode.Name, chainName)
+}
+
+// failMigration 标记迁移失败
+func failMigration(item dto.MigrationItem, err error) dto.MigrationItem {
+	item.Status = dto.MigrationStatusFailed
+	item.Error = err.Error()
+	return item
+}
+
+// countMigration 统计迁移成功和失败数量
+func countMigration(items []dto.MigrationItem) (migrated, failed int) {
+	for _, item := range items {
+		if item.Status == dto.MigrationStatusMigrated {
+			migrated++
+		} else {
+			failed++
+		}
+	}
+	return migrated, failed
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// nodeRecovery 节点恢复可用后重建期望运行的隧道和规则
+// 由健康检测（节点恢复在线）和维护模式（退出维护）共用
+type nodeRecovery struct {
+	ruleRepo      *repository.RuleRepository
+	tunnelRepo    *repository.TunnelRepository
+	ruleService   *RuleService
+	tunnelService *TunnelService
+}
+
+// newNodeRecovery 创建节点恢复
+func newNodeRecovery(db *gorm.DB) *nodeRecovery {
+	return &nodeRecovery{
+		ruleRepo:      repository.NewRuleRepository(db),
+		tunnelRepo:    repository.NewTunnelRepository(db),
+		ruleService:   NewRuleService(db),
+		tunnelService: NewTunnelService(db),
+	}
+}
+
+// recoverNode 节点恢复在线后，按依赖顺序重建期望运行的隧道和规则
+// 先重建与该节点相关的隧道，再重建以该节点为入口的规则及依赖已恢复隧道的规则
+func (s *nodeRecovery) recoverNode(ctx context.Context, node model.GostNode) {
+	var recoveredTunnels []uint
+
+	// 1. 隧道（该节点为入口或出口）
+	tunnels, err := s.tunnelRepo.FindByNodeID(node.ID)
+	if err != nil {
+		logger.Errorf("[Recover] 获取节点 %s 隧道失败: %v", node.Name, err)
+		return
+	}
+	for _, t := range tunnels {
+		if !t.DesiredRunning || t.Status == model.TunnelStatusRunning {
+			continue
+		}
+		if err = s.tunnelService.Start(ctx, t.ID, 0, systemUsername, "", ""); err != nil {
+			logger.Warnf("[Recover] 节点 %s 恢复隧道 %s 失败: %v", node.Name, t.Name, err)
+			continue
+		}
+		recoveredTunnels = append(recoveredTunnels, t.ID)
+	}
+
+	// 2. 规则（该节点为入口，以及使用已恢复隧道的规则）
+	rules, err := s.ruleRepo.FindByEntryNodeID(node.ID)
+	if err != nil {
+		logger.Errorf("[Recover] 获取节点 %s 规则失败: %v", node.Name, err)
+		return
+	}
+	tunnelRules, err := s.ruleRepo.FindByTunnelIDs(recoveredTunnels)
+	if err != nil {
+		logger.Errorf("[Recover] 获取隧道规则失败: %v", err)
+	}
+	rules = append(rules, tunnelRules...)
+
+	recovered := make(map[uint]bool)
+	for _, r := range rules {
+		if recovered[r.ID] || !r.DesiredRunning || r.Status == model.RuleStatusRunning {
+			continue
+		}
+		recovered[r.ID] = true
+		if err = s.ruleService.Start(ctx, r.ID, 0, systemUsername, "", ""); err != nil {
+			logger.Warnf("[Recover] 节点 %s 恢复规则 %s 失败: %v", node.Name, r.Name, err)
+		}
+	}
+
+	if len(recoveredTunnels) > 0 || len(recovered) > 0 {
+		logger.Infof("[Recover] 节点 %s 恢复在线，已重建隧道 %d 条，规则 %d 条", node.Name, len(recoveredTunnels), len(recovered))
+	}
+}
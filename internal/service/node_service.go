@@ -2,13 +2,19 @@
 package service
 
 import (
+	"context"
 	stderrors "errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
+	"gost-panel/internal/agent"
 	"gost-panel/internal/dto"
 	"gost-panel/internal/errors"
 	"gost-panel/internal/model"
 	"gost-panel/internal/repository"
+	"gost-panel/internal/sshtunnel"
 	"gost-panel/internal/utils"
 	"gost-panel/pkg/gost"
 	"gost-panel/pkg/logger"
@@ -19,21 +25,63 @@ import (
 // NodeService 节点服务
 // 负责节点的 CRUD 操作和业务逻辑处理
 type NodeService struct {
-	nodeRepo   *repository.NodeRepository
-	logService *LogService
+	nodeRepo    *repository.NodeRepository
+	historyRepo *repository.NodeStatusHistoryRepository
+	metricRepo  *repository.NodeMetricRepository
+	logService  *LogService
 }
 
+// NodeDetail 节点详情（含健康统计和主机指标）
+type NodeDetail struct {
+	*model.GostNode
+	Health NodeHealthStats `json:"health"`
+	Host   NodeHostStats   `json:"host"`
+	Agent  NodeAgentStatus `json:"agent"`
+}
+
+// NodeAgentStatus 节点 Agent 反向连接状态（仅 agent 连接方式）
+type NodeAgentStatus struct {
+	Connected   bool       `json:"connected"`
+	RemoteAddr  string     `json:"remote_addr,omitempty"`
+	ConnectedAt *time.Time `json:"connected_at,omitempty"`
+}
+
+// NodeHostStats 节点主机指标（由节点 Agent 上报）
+type NodeHostStats struct {
+	Latest  *model.NodeMetric  `json:"latest"`  // 最新采样，未安装 Agent 时为空
+	Samples []model.NodeMetric `json:"samples"` // 最近一小时的采样
+}
+
+// NodeHealthStats 节点健康统计
+type NodeHealthStats struct {
+	Uptime24h         float64                   `json:"uptime_24h"`         // 近 24 小时可用率 (%)
+	Uptime7d          float64                   `json:"uptime_7d"`          // 近 7 天可用率 (%)
+	Uptime30d         float64                   `json:"uptime_30d"`         // 近 30 天可用率 (%)
+	Flaps24h          int                       `json:"flaps_24h"`          // 近 24 小时状态变更次数
+	RecentTransitions []model.NodeStatusHistory `json:"recent_transitions"` // 最近的状态变更记录
+}
+
+// recentTransitionLimit 节点详情中返回的最近状态变更记录数
+const recentTransitionLimit = 20
+
 // NewNodeService 创建节点服务
 func NewNodeService(db *gorm.DB) *NodeService {
 	return &NodeService{
-		nodeRepo:   repository.NewNodeRepository(db),
-		logService: NewLogService(db),
+		nodeRepo:    repository.NewNodeRepository(db),
+		historyRepo: repository.NewNodeStatusHistoryRepository(db),
+		metricRepo:  repository.NewNodeMetricRepository(db),
+		logService:  NewLogService(db),
 	}
 }
 
 // Create 创建节点
 // 创建前会检查节点名称是否已存在
 func (s *NodeService) Create(req *dto.CreateNodeReq, userID uint, username string, ip, userAgent string) (*model.GostNode, error) {
+	return s.create(req, NewAgentToken(), userID, username, ip, userAgent)
+}
+
+// create 使用指定 Agent 令牌创建节点（经 SSH 部署时令牌需先写入节点 Agent 配置）
+func (s *NodeService) create(req *dto.CreateNodeReq, agentToken string, userID uint, username string, ip, userAgent string) (*model.GostNode, error) {
 	// 检查名称是否存在
 	exists, err := s.nodeRepo.ExistsByName(req.Name)
 	if err != nil {
@@ -52,6 +100,20 @@ func (s *NodeService) Create(req *dto.CreateNodeReq, userID uint, username strin
 		Password: req.Password,
 		Remark:   req.Remark,
 		Status:   model.NodeStatusOffline,
+
+		ConnectionMode: nodeConnectionMode(req.ConnectionMode),
+		AgentToken:     agentToken,
+		ObserverToken:  NewObserverToken(),
+	}
+	if err = applyNodeTLS(node, &req.NodeTLSReq); err != nil {
+		return nil, err
+	}
+	applyNodeMetrics(node, &req.NodeMetricsReq)
+	if err = validateNodeTLS(node); err != nil {
+		return nil, err
+	}
+	if err = applyNodeSSH(node, &req.NodeSSHReq, false); err != nil {
+		return nil, err
 	}
 
 	if err = s.nodeRepo.Create(node); err != nil {
@@ -95,6 +157,7 @@ func (s *NodeService) Update(id uint, req *dto.UpdateNodeReq, userID uint, usern
 	}
 
 	// 更新节点
+	addrChanged := node.Address != req.Address || (req.SSHPort != 0 && node.SSHPort != req.SSHPort)
 	node.Name = req.Name
 	node.Address = req.Address
 	node.Port = req.Port
@@ -102,9 +165,29 @@ func (s *NodeService) Update(id uint, req *dto.UpdateNodeReq, userID uint, usern
 	node.Password = req.Password
 	node.Remark = req.Remark
 
+	oldMode := node.ConnectionMode
+	node.ConnectionMode = nodeConnectionMode(req.ConnectionMode)
+	if err = applyNodeTLS(node, &req.NodeTLSReq); err != nil {
+		return nil, err
+	}
+	applyNodeMetrics(node, &req.NodeMetricsReq)
+	if err = validateNodeTLS(node); err != nil {
+		return nil, err
+	}
+	if err = applyNodeSSH(node, &req.NodeSSHReq, addrChanged); err != nil {
+		return nil, err
+	}
+
 	if err = s.nodeRepo.Update(node); err != nil {
 		return nil, err
 	}
+	sshtunnel.Default().Close(node.ID)
+	utils.InvalidateGostClient(node.ID)
+
+	// 切换为直连后断开遗留的反向连接
+	if oldMode == model.NodeConnectionAgent && node.ConnectionMode != model.NodeConnectionAgent {
+		agent.Default().Disconnect(node.ID)
+	}
 
 	// 记录操作日志
 	s.logService.Record(
@@ -146,6 +229,9 @@ func (s *NodeService) Delete(id uint, userID uint, username string, ip, userAgen
 	if err = s.nodeRepo.Delete(id); err != nil {
 		return err
 	}
+	agent.Default().Disconnect(id)
+	sshtunnel.Default().Close(id)
+	utils.InvalidateGostClient(id)
 
 	// 记录操作日志
 	s.logService.Record(
@@ -174,6 +260,255 @@ func (s *NodeService) GetByID(id uint) (*model.GostNode, error) {
 	return node, nil
 }
 
+// GetDetail 获取节点详情及健康统计
+func (s *NodeService) GetDetail(id uint) (*NodeDetail, error) {
+	node, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	since := now.Add(-statusHistoryRetention)
+
+	// 窗口起点之前的最后一次变更决定起始状态
+	before, err := s.historyRepo.FindLastBefore(id, since)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := s.historyRepo.FindSince(id, since)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := s.historyRepo.ListRecent(id, recentTransitionLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := NodeHealthStats{
+		Uptime24h:         nodeUptime(node, before, histories, now, 24*time.Hour),
+		Uptime7d:          nodeUptime(node, before, histories, now, 7*24*time.Hour),
+		Uptime30d:         nodeUptime(node, before, histories, now, 30*24*time.Hour),
+		RecentTransitions: recent,
+	}
+	for _, h := range histories {
+		// 维护期间的变更及进入/退出维护的标记不算抖动
+		if h.CreatedAt.After(now.Add(-24*time.Hour)) && !h.Maintenance && h.FromStatus != h.ToStatus {
+			stats.Flaps24h++
+		}
+	}
+
+	latest, err := s.metricRepo.FindLatest(id)
+	if err != nil {
+		return nil, err
+	}
+	samples, err := s.metricRepo.FindSince(id, now.Add(-hostMetricWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &NodeDetail{
+		GostNode: node,
+		Health:   stats,
+		Host:     NodeHostStats{Latest: latest, Samples: samples},
+	}
+	if session := agent.Default().Session(id); session != nil {
+		connectedAt := session.ConnectedAt()
+		detail.Agent = NodeAgentStatus{
+			Connected:   true,
+			RemoteAddr:  session.RemoteAddr(),
+			ConnectedAt: &connectedAt,
+		}
+	}
+	return detail, nil
+}
+
+// applyNodeTLS 将请求中的 TLS 配置写入节点，客户端私钥加密存储
+// 更新时私钥留空保持不变；未配置客户端证书时清空私钥
+func applyNodeTLS(node *model.GostNode, req *dto.NodeTLSReq) error {
+	node.TLSEnabled = req.TLSEnabled
+	node.TLSSkipVerify = req.TLSSkipVerify
+	node.TLSServerName = strings.TrimSpace(req.TLSServerName)
+	node.TLSCACert = strings.TrimSpace(req.TLSCACert)
+	node.TLSClientCert = strings.TrimSpace(req.TLSClientCert)
+
+	if node.TLSClientCert == "" {
+		node.TLSClientKey = ""
+		return nil
+	}
+	if key := strings.TrimSpace(req.TLSClientKey); key != "" {
+		encrypted, err := utils.EncryptSecret(key)
+		if err != nil {
+			return err
+		}
+		node.TLSClientKey = encrypted
+	}
+	return nil
+}
+
+// applyNodeMetrics 将请求中的 Gost 指标接口配置写入节点
+func applyNodeMetrics(node *model.GostNode, req *dto.NodeMetricsReq) {
+	node.MetricsPort = req.MetricsPort
+	node.MetricsPath = ""
+	if req.MetricsPort == 0 {
+		return
+	}
+	node.MetricsPath = strings.TrimSpace(req.MetricsPath)
+	if node.MetricsPath == "" {
+		node.MetricsPath = "/metrics"
+	} else if !strings.HasPrefix(node.MetricsPath, "/") {
+		node.MetricsPath = "/" + node.MetricsPath
+	}
+}
+
+// validateNodeTLS 校验节点 TLS 证书能否解析
+func validateNodeTLS(node *model.GostNode) error {
+	tlsCfg := utils.GetNodeTLSConfig(node)
+	if tlsCfg == nil {
+		return nil
+	}
+	if _, err := tlsCfg.Build(); err != nil {
+		logger.Warnf("节点 %s TLS 配置无效: %v", node.Name, err)
+		return errors.ErrNodeTLSInvalid
+	}
+	return nil
+}
+
+// applyNodeSSH 将请求中的 SSH 配置写入节点，凭据加密存储
+// 更新时凭据留空保持不变；未填写主机公钥或节点地址变更时连接节点重新获取；非 SSH 连接方式清空凭据
+func applyNodeSSH(node *model.GostNode, req *dto.NodeSSHReq, addrChanged bool) error {
+	if node.ConnectionMode != model.NodeConnectionSSH {
+		node.SSHUser = ""
+		node.SSHPassword = ""
+		node.SSHPrivateKey = ""
+		node.SSHPassphrase = ""
+		node.SSHHostKey = ""
+		return nil
+	}
+
+	node.SSHPort = req.SSHPort
+	if node.SSHPort == 0 {
+		node.SSHPort = 22
+	}
+	node.SSHUser = strings.TrimSpace(req.SSHUser)
+
+	secrets := []struct {
+		value  string
+		target *string
+	}{
+		{req.SSHPassword, &node.SSHPassword},
+		{strings.TrimSpace(req.SSHPrivateKey), &node.SSHPrivateKey},
+		{req.SSHPassphrase, &node.SSHPassphrase},
+	}
+	for _, secret := range secrets {
+		if secret.value == "" {
+			continue
+		}
+		encrypted, err := utils.EncryptSecret(secret.value)
+		if err != nil {
+			return err
+		}
+		*secret.target = encrypted
+	}
+
+	if node.SSHUser == "" || (node.SSHPassword == "" && node.SSHPrivateKey == "") {
+		return errors.ErrNodeSSHAuthRequired
+	}
+
+	// 校验私钥及私钥密码
+	sshCfg, err := utils.GetNodeSSHConfig(node)
+	if err != nil {
+		logger.Warnf("节点 %s SSH 凭据解密失败: %v", node.Name, err)
+		return errors.ErrNodeSSHKeyInvalid
+	}
+	if sshCfg.PrivateKey != "" {
+		if _, err = sshtunnel.AuthMethods(sshCfg); err != nil {
+			logger.Warnf("节点 %s SSH 私钥无效: %v", node.Name, err)
+			return errors.ErrNodeSSHKeyInvalid
+		}
+	}
+
+	hostKey := strings.TrimSpace(req.SSHHostKey)
+	if hostKey == "" || (addrChanged && hostKey == node.SSHHostKey) {
+		hostKey, err = sshtunnel.FetchHostKey(sshCfg.Addr, sshCfg.Timeout)
+		if err != nil {
+			logger.Warnf("节点 %s 获取 SSH 主机公钥失败: %v", node.Name, err)
+			return errors.ErrNodeSSHHostKeyFetchFailed
+		}
+		logger.Infof("节点 %s 记录 SSH 主机公钥: %s", node.Name, hostKey)
+	} else if err = sshtunnel.ValidateHostKey(hostKey); err != nil {
+		return errors.ErrNodeSSHKeyInvalid
+	}
+	node.SSHHostKey = hostKey
+
+	return nil
+}
+
+// nodeConnectionMode 解析连接方式，未指定时为直连
+func nodeConnectionMode(mode string) model.NodeConnectionMode {
+	switch model.NodeConnectionMode(mode) {
+	case model.NodeConnectionAgent, model.NodeConnectionSSH:
+		return model.NodeConnectionMode(mode)
+	}
+	return model.NodeConnectionDirect
+}
+
+// nodeUptime 根据状态变更记录计算节点在窗口内的在线时长占比 (%)
+// histories 需按时间正序；窗口起点早于节点创建时间时从创建时间开始计算；
+// 维护期间的离线时长属于计划内停机，不计入统计窗口
+func nodeUptime(node *model.GostNode, before *model.NodeStatusHistory, histories []model.NodeStatusHistory, now time.Time, window time.Duration) float64 {
+	start := now.Add(-window)
+	if node.CreatedAt.After(start) {
+		start = node.CreatedAt
+	}
+	total := now.Sub(start)
+	if total <= 0 {
+		if node.Status == model.NodeStatusOnline {
+			return 100
+		}
+		return 0
+	}
+
+	var status model.NodeStatus
+	planned := false // 当前时段由维护期间的变更开始
+	if before != nil {
+		status, planned = before.ToStatus, before.Maintenance
+	}
+
+	var online, excluded time.Duration
+	cursor := start
+	account := func(until time.Time) {
+		if status == model.NodeStatusOnline {
+			online += until.Sub(cursor)
+		} else if planned {
+			excluded += until.Sub(cursor)
+		}
+		cursor = until
+	}
+	for _, h := range histories {
+		if !h.CreatedAt.After(start) {
+			status, planned = h.ToStatus, h.Maintenance
+			continue
+		}
+		// 窗口起点前无记录时，以窗口内首次变更的原状态作为起始状态
+		if status == "" {
+			status = h.FromStatus
+		}
+		account(h.CreatedAt)
+		status, planned = h.ToStatus, h.Maintenance
+	}
+
+	if status == "" {
+		status = node.Status
+	}
+	account(now)
+
+	total -= excluded
+	if total <= 0 {
+		return 100
+	}
+	return math.Round(float64(online)/float64(total)*10000) / 100
+}
+
 // List 获取节点列表
 func (s *NodeService) List(req *dto.NodeListReq) ([]model.GostNode, int64, error) {
 	// 设置默认值
@@ -203,7 +538,7 @@ func (s *NodeService) List(req *dto.NodeListReq) ([]model.GostNode, int64, error
 	return s.nodeRepo.List(opt)
 }
 
-// CreateGostClient 创建节点的 Gost 客户端
+// CreateGostClient 获取节点的 Gost 客户端（按节点缓存复用）
 func (s *NodeService) CreateGostClient(id uint) (*gost.Client, error) {
 	node, err := s.nodeRepo.FindByID(id)
 	if err != nil {
@@ -214,7 +549,7 @@ func (s *NodeService) CreateGostClient(id uint) (*gost.Client, error) {
 }
 
 // GetConfig 获取节点配置
-func (s *NodeService) GetConfig(id uint) (*gost.GostConfig, error) {
+func (s *NodeService) GetConfig(ctx context.Context, id uint) (*gost.GostConfig, error) {
 	node, err := s.nodeRepo.FindByID(id)
 	if err != nil {
 		return nil, err
@@ -222,7 +557,7 @@ func (s *NodeService) GetConfig(id uint) (*gost.GostConfig, error) {
 
 	client := utils.GetGostClient(node)
 
-	config, err := client.GetConfig()
+	config, err := client.GetConfig(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("获取节点配置失败: %v", err)
 	}
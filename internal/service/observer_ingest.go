@@ -0,0 +1,268 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// observerFlushInterval 缓冲事件定时写入数据库的间隔
+	observerFlushInterval = 2 * time.Second
+	// observerBufferCapacity 缓冲区最多容纳的服务事件数（按节点、服务、客户端、事件类型合并后计数）
+	observerBufferCapacity = 20000
+)
+
+// observerEventKey 缓冲区合并键，同一键的事件只保留最新值
+type observerEventKey struct {
+	nodeID  uint
+	kind    string
+	service string
+	client  string
+	typ     string
+}
+
+// pendingEvent 缓冲中的事件
+// 统计数据为服务启动以来的累计值，同一服务多次上报只需保留最新一次；
+// 计数器变小说明期间服务重启，此时保留重启前的最后一次，避免丢失重启前的增量
+type pendingEvent struct {
+	node   *model.GostNode
+	events []dto.ObserverEvent
+}
+
+// merge 合并同一服务的新事件
+func (p *pendingEvent) merge(event dto.ObserverEvent) {
+	last := &p.events[len(p.events)-1]
+	if event.Stats != nil && last.Stats != nil && statsDecreased(last.Stats, event.Stats) {
+		p.events = append(p.events, event)
+		return
+	}
+	*last = event
+}
+
+// statsDecreased 任一累计计数器是否变小
+func statsDecreased(last, stats *dto.ObserverStats) bool {
+	return stats.InputBytes < last.InputBytes || stats.OutputBytes < last.OutputBytes ||
+		stats.TotalConns < last.TotalConns || stats.TotalErrs < last.TotalErrs
+}
+
+// observerIngest 观察器上报缓冲区
+// 上报请求只将事件按服务合并到内存，由后台定时在一个事务中批量写入，避免每个事件都同步读写数据库
+type observerIngest struct {
+	mu       sync.Mutex
+	pending  map[observerEventKey]*pendingEvent
+	capacity int // 缓冲区容量，缓冲事件数达到一半时提前刷新
+
+	flushMu     sync.Mutex // 保证同一时间只有一次刷新
+	flushSignal chan struct{}
+	stopChan    chan struct{}
+	doneChan    chan struct{}
+
+	received        atomic.Int64
+	merged          atomic.Int64
+	dropped         atomic.Int64
+	rejected        atomic.Int64
+	flushed         atomic.Int64
+	flushes         atomic.Int64
+	flushErrors     atomic.Int64
+	lastFlushAt     atomic.Int64 // Unix 毫秒
+	lastFlushMillis atomic.Int64
+}
+
+// newObserverIngest 创建观察器上报缓冲区
+func newObserverIngest() *observerIngest {
+	return &observerIngest{
+		pending:     make(map[observerEventKey]*pendingEvent),
+		capacity:    observerBufferCapacity,
+		flushSignal: make(chan struct{}, 1),
+	}
+}
+
+// Start 启动后台刷新
+func (s *ObserverService) Start() {
+	s.ingest.stopChan = make(chan struct{})
+	s.ingest.doneChan = make(chan struct{})
+	go s.flushLoop()
+	logger.Info("观察器上报缓冲服务已启动")
+}
+
+// Stop 停止后台刷新，并写入剩余的缓冲事件
+func (s *ObserverService) Stop() {
+	if s.ingest.stopChan == nil {
+		return
+	}
+	close(s.ingest.stopChan)
+	<-s.ingest.doneChan
+	logger.Info("观察器上报缓冲服务已停止")
+}
+
+// flushLoop 定时或缓冲区达到阈值时刷新
+func (s *ObserverService) flushLoop() {
+	defer close(s.ingest.doneChan)
+
+	ticker := time.NewTicker(observerFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.ingest.flushSignal:
+		case <-s.ingest.stopChan:
+			if err := s.Flush(); err != nil {
+				logger.Errorf("停止前写入观察器事件失败: %v", err)
+			}
+			return
+		}
+		if err := s.Flush(); err != nil {
+			logger.Errorf("写入观察器事件失败: %v", err)
+		}
+	}
+}
+
+// enqueue 将事件合并到缓冲区，缓冲区已满时丢弃新服务的事件并返回 ErrObserverBusy
+func (s *ObserverService) enqueue(node *model.GostNode, events []dto.ObserverEvent) error {
+	in := s.ingest
+	in.received.Add(int64(len(events)))
+
+	in.mu.Lock()
+	var dropped int64
+	for _, event := range events {
+		if !acceptEvent(&event) {
+			continue
+		}
+		key := observerEventKey{node.ID, event.Kind, event.Service, event.Client, event.Type}
+		if p, ok := in.pending[key]; ok {
+			p.merge(event)
+			in.merged.Add(1)
+			continue
+		}
+		if len(in.pending) >= in.capacity {
+			dropped++
+			continue
+		}
+		in.pending[key] = &pendingEvent{node: node, events: []dto.ObserverEvent{event}}
+	}
+	size := len(in.pending)
+	in.mu.Unlock()
+
+	if size >= in.capacity/2 {
+		select {
+		case in.flushSignal <- struct{}{}:
+		default:
+		}
+	}
+
+	if dropped > 0 {
+		in.dropped.Add(dropped)
+		in.rejected.Add(1)
+		return errors.ErrObserverBusy
+	}
+	return nil
+}
+
+// acceptEvent 只缓冲服务级别的统计、状态事件和处理器级别的客户端统计事件
+func acceptEvent(event *dto.ObserverEvent) bool {
+	switch {
+	case event.Service == "":
+		return false
+	case event.Type == "stats":
+		return event.Stats != nil
+	case event.Type == "status":
+		return event.Status != nil && event.Kind != "handler"
+	default:
+		return false
+	}
+}
+
+// Flush 将缓冲事件在一个事务中写入数据库
+// 单个事件处理失败只记录日志；事务提交失败时未被新事件覆盖的事件放回缓冲区，下次重试
+func (s *ObserverService) Flush() error {
+	in := s.ingest
+	in.flushMu.Lock()
+	defer in.flushMu.Unlock()
+
+	in.mu.Lock()
+	batch := in.pending
+	in.pending = make(map[observerEventKey]*pendingEvent, len(batch))
+	in.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	var count int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txs := newObserverService(tx)
+		for _, p := range batch {
+			for i := range p.events {
+				if err := txs.processEvent(p.node, &p.events[i]); err != nil {
+					logger.Warnf("处理节点 %s 观察器事件失败: %v", p.node.Name, err)
+				}
+				count++
+			}
+		}
+		return nil
+	})
+
+	elapsed := time.Since(start)
+	in.flushes.Add(1)
+	in.lastFlushAt.Store(start.UnixMilli())
+	in.lastFlushMillis.Store(elapsed.Milliseconds())
+	if err != nil {
+		in.flushErrors.Add(1)
+		s.requeue(batch)
+		return err
+	}
+	in.flushed.Add(count)
+	logger.Debugf("写入观察器事件 %d 条，耗时 %s", count, elapsed)
+	return nil
+}
+
+// requeue 将写入失败的事件放回缓冲区（已有更新事件的服务不再放回）
+func (s *ObserverService) requeue(batch map[observerEventKey]*pendingEvent) {
+	in := s.ingest
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for key, p := range batch {
+		if _, ok := in.pending[key]; ok {
+			continue
+		}
+		if len(in.pending) >= in.capacity {
+			in.dropped.Add(int64(len(p.events)))
+			continue
+		}
+		in.pending[key] = p
+	}
+}
+
+// IngestStats 获取观察器上报处理统计
+func (s *ObserverService) IngestStats() *dto.ObserverIngestStats {
+	in := s.ingest
+	in.mu.Lock()
+	pending := len(in.pending)
+	in.mu.Unlock()
+
+	stats := &dto.ObserverIngestStats{
+		ReceivedEvents:   in.received.Load(),
+		MergedEvents:     in.merged.Load(),
+		DroppedEvents:    in.dropped.Load(),
+		RejectedRequests: in.rejected.Load(),
+		FlushedEvents:    in.flushed.Load(),
+		Flushes:          in.flushes.Load(),
+		FlushErrors:      in.flushErrors.Load(),
+		Pending:          pending,
+		Capacity:         in.capacity,
+		LastFlushMillis:  in.lastFlushMillis.Load(),
+	}
+	if ms := in.lastFlushAt.Load(); ms > 0 {
+		stats.LastFlushAt = time.UnixMilli(ms)
+	}
+	return stats
+}
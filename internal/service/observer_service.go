@@ -1,105 +1,344 @@
 package service
 
 import (
+	"context"
+	stderrors "errors"
+	"fmt"
 	"gost-panel/internal/dto"
 	"gost-panel/internal/errors"
 	"gost-panel/internal/model"
 	"gost-panel/internal/repository"
+	"gost-panel/internal/utils"
 	"gost-panel/pkg/gost"
 	"gost-panel/pkg/logger"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// observerTokenBytes 观察器上报令牌随机字节数
+const observerTokenBytes = 24
+
+// NewObserverToken 生成节点观察器上报令牌
+func NewObserverToken() string {
+	return utils.RandomToken(observerTokenBytes)
+}
+
 // ObserverService 观察器服务
 type ObserverService struct {
-	ruleRepo *repository.RuleRepository
-	nodeRepo *repository.NodeRepository
+	db          *gorm.DB
+	ingest      *observerIngest
+	ruleRepo    *repository.RuleRepository
+	nodeRepo    *repository.NodeRepository
+	tunnelRepo  *repository.TunnelRepository
+	counterRepo *repository.ServiceCounterRepository
+	clientRepo  *repository.RuleClientRepository
+	traffic     *TrafficService
 }
 
 // NewObserverService 创建观察器服务
 func NewObserverService(db *gorm.DB) *ObserverService {
+	s := newObserverService(db)
+	s.ingest = newObserverIngest()
+	return s
+}
+
+// newObserverService 创建不带上报缓冲区的观察器服务，刷新缓冲区时以事务创建
+func newObserverService(db *gorm.DB) *ObserverService {
 	return &ObserverService{
-		ruleRepo: repository.NewRuleRepository(db),
-		nodeRepo: repository.NewNodeRepository(db),
+		db:          db,
+		ruleRepo:    repository.NewRuleRepository(db),
+		nodeRepo:    repository.NewNodeRepository(db),
+		tunnelRepo:  repository.NewTunnelRepository(db),
+		counterRepo: repository.NewServiceCounterRepository(db),
+		clientRepo:  repository.NewRuleClientRepository(db),
+		traffic:     NewTrafficService(db),
 	}
 }
 
 // HandleReport 处理观察器上报的数据
-func (s *ObserverService) HandleReport(req *dto.ObserverReportReq) error {
-	for _, event := range req.Events {
-		if err := s.processEvent(&event); err != nil {
-			logger.Warnf("处理观察器事件失败: %v", err)
+// node 为 Authenticate 根据上报地址中的令牌确定的节点，仅接受属于该节点的服务的事件
+// 事件合并到缓冲区后立即返回，由后台批量写入数据库
+func (s *ObserverService) HandleReport(node *model.GostNode, req *dto.ObserverReportReq) error {
+	return s.enqueue(node, req.Events)
+}
+
+// Authenticate 根据观察器上报令牌查找所属节点
+// 接口在解析上报数据前先校验令牌，未认证的请求不读取请求体
+func (s *ObserverService) Authenticate(token string) (*model.GostNode, error) {
+	if token == "" {
+		return nil, errors.ErrObserverTokenInvalid
+	}
+
+	node, err := s.nodeRepo.FindByObserverToken(token)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrObserverTokenInvalid
 		}
+		return nil, err
 	}
-	return nil
+	return node, nil
 }
 
 // processEvent 处理单个事件
-func (s *ObserverService) processEvent(event *dto.ObserverEvent) error {
-	// 只处理统计类型的事件
-	if event.Type != "stats" || event.Stats == nil {
+func (s *ObserverService) processEvent(node *model.GostNode, event *dto.ObserverEvent) error {
+	if !acceptEvent(event) {
 		return nil
 	}
 
-	serviceName := event.Service
-	if serviceName == "" {
-		return nil
+	rule, err := s.findRule(node, event.Service)
+	if err != nil || rule == nil {
+		return err
+	}
+
+	switch {
+	case event.Kind == "handler":
+		// 处理器级别事件按客户端统计，流量已包含在服务级别统计中
+		if event.Client == "" {
+			return nil
+		}
+		return s.updateClientStats(node, rule, event.Service, event.Client, event.Stats)
+	case event.Type == "status":
+		return s.updateRuleStatus(rule, event.Status)
+	default:
+		return s.updateRuleStats(node, rule, event.Service, event.Stats)
+	}
+}
+
+// findRule 根据服务名称查找规则，并校验服务属于上报节点
+// 优先按上报节点上记录的服务名称匹配（包含导入时沿用原有名称的规则），
+// 其次解析 rule-{id}，forward-{id} 和 tunnel-{id} 保持向后兼容；非规则服务或规则已删除时返回 nil
+func (s *ObserverService) findRule(node *model.GostNode, serviceName string) (*model.GostRule, error) {
+	rule, err := s.ruleRepo.FindByServiceID(node.ID, serviceName)
+	if err == nil {
+		return rule, nil
+	}
+	if !stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var id uint
+	for _, prefix := range []string{"rule-", "forward-", "tunnel-"} {
+		ok, err := parseServiceID(serviceName, prefix, &id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+	}
+	if id == 0 {
+		return nil, nil
 	}
 
-	// 解析服务名称，格式: rule-{id} 或 forward-{id} 或 tunnel-{id}
-	if strings.HasPrefix(serviceName, "rule-") {
-		return s.updateRuleStats(serviceName, event.Stats, "rule-")
-	} else if strings.HasPrefix(serviceName, "forward-") {
-		// 保持向后兼容
-		return s.updateRuleStats(serviceName, event.Stats, "forward-")
-	} else if strings.HasPrefix(serviceName, "tunnel-") {
-		// 保持向后兼容
-		return s.updateRuleStats(serviceName, event.Stats, "tunnel-")
+	rule, err = s.ruleRepo.FindByID(id)
+	if err != nil {
+		// 如果找不到规则，可能已被删除，忽略错误
+		return nil, nil
+	}
+
+	// 规则服务只运行在入口节点上，拒绝其他节点代为上报
+	if ruleEntryNodeID(rule) != node.ID {
+		return nil, fmt.Errorf("服务 %s 不属于节点 %s，忽略上报", serviceName, node.Name)
+	}
+	return rule, nil
+}
+
+// ruleEntryNodeID 规则服务所在的入口节点 ID
+func ruleEntryNodeID(rule *model.GostRule) uint {
+	if rule.Type == model.RuleTypeTunnel && rule.Tunnel != nil {
+		return rule.Tunnel.EntryNodeID
+	}
+	if rule.NodeID != nil {
+		return *rule.NodeID
+	}
+	return 0
+}
+
+// updateRuleStatus 根据服务状态事件更新规则状态
+// 服务失败时记录 Gost 上报的错误信息（如 "address already in use"），无需等待定时同步
+func (s *ObserverService) updateRuleStatus(rule *model.GostRule, status *dto.ObserverStatus) error {
+	newStatus := utils.GostStateToRuleStatus(status.State)
+	msg := ""
+	if newStatus == model.RuleStatusError {
+		msg = status.Msg
+	}
+	if rule.Status == newStatus && rule.StatusMsg == msg {
+		return nil
 	}
 
+	if err := s.ruleRepo.UpdateStatusMsg(rule.ID, newStatus, msg); err != nil {
+		return err
+	}
+	logger.Infof("规则 %d (%s) 状态变更: %s -> %s (Gost State: %s, Msg: %s)",
+		rule.ID, rule.Name, rule.Status, newStatus, status.State, status.Msg)
 	return nil
 }
 
 // updateRuleStats 更新规则统计
-func (s *ObserverService) updateRuleStats(serviceName string, stats *dto.ObserverStats, prefix string) error {
-	// 解析 ID
-	var id uint
-	if _, err := parseServiceID(serviceName, prefix, &id); err != nil {
+func (s *ObserverService) updateRuleStats(node *model.GostNode, rule *model.GostRule, serviceName string, stats *dto.ObserverStats) error {
+	nodeID := node.ID
+	id := rule.ID
+
+	// 1. 更新当前连接数（连接断开时累计值不变，需单独更新）
+	if stats.CurrentConns != rule.CurrentConns {
+		if err := s.ruleRepo.UpdateCurrentConns(id, stats.CurrentConns); err != nil {
+			return err
+		}
+	}
+
+	// 2. 与该服务上次上报的累计值比较得到增量，并记住本次累计值
+	delta, err := s.counterDelta(nodeID, serviceName, stats)
+	if err != nil || delta.IsZero() {
+		return err
+	}
+
+	// 3. 累加规则流量，隧道规则同时累加隧道流量
+	if err = s.ruleRepo.AddStats(id, delta.InputBytes, delta.OutputBytes, delta.Connections, delta.Errors); err != nil {
 		return err
 	}
+	if rule.Type == model.RuleTypeTunnel && rule.Tunnel != nil {
+		if err = s.tunnelRepo.AddStats(rule.Tunnel.ID, delta.InputBytes, delta.OutputBytes); err != nil {
+			logger.Warnf("更新隧道流量失败: %v", err)
+		}
+	}
+
+	// 4. 累加节点流量
+	if err = s.nodeRepo.UpdateStats(nodeID, delta.InputBytes, delta.OutputBytes); err != nil {
+		logger.Warnf("更新节点流量失败: %v", err)
+	}
 
-	// 更新规则统计数据
-	if err := s.ruleRepo.UpdateStats(id, stats.InputBytes, stats.OutputBytes, stats.TotalConns); err != nil {
+	// 5. 记录流量历史
+	if err = s.traffic.Record(rule, nodeID, delta, time.Now()); err != nil {
+		logger.Warnf("记录流量历史失败: %v", err)
+	}
+
+	logger.Debugf("更新规则统计: %s, In: +%d, Out: +%d, Conns: +%d, Errs: +%d",
+		serviceName, delta.InputBytes, delta.OutputBytes, delta.Connections, delta.Errors)
+	return nil
+}
+
+// updateClientStats 更新规则的客户端统计
+func (s *ObserverService) updateClientStats(node *model.GostNode, rule *model.GostRule, serviceName, client string, stats *dto.ObserverStats) error {
+	delta, err := s.counterDelta(node.ID, serviceName+"/"+client, stats)
+	if err != nil {
 		return err
 	}
 
-	// 同步更新节点统计
-	// 1. 查询规则获取关联节点
-	rule, err := s.ruleRepo.FindByID(id)
+	// 无新增流量时仍需刷新当前连接数
+	return s.clientRepo.Accumulate(&model.RuleClient{
+		RuleID:       rule.ID,
+		Client:       client,
+		InputBytes:   delta.InputBytes,
+		OutputBytes:  delta.OutputBytes,
+		TotalBytes:   delta.InputBytes + delta.OutputBytes,
+		TotalConns:   delta.Connections,
+		TotalErrors:  delta.Errors,
+		CurrentConns: stats.CurrentConns,
+		LastSeenAt:   time.Now(),
+	})
+}
+
+// counterDelta 与计数器上次记录的累计值比较得到增量，并记住本次累计值
+func (s *ObserverService) counterDelta(nodeID uint, key string, stats *dto.ObserverStats) (TrafficDelta, error) {
+	last, err := s.counterRepo.Find(nodeID, key)
 	if err != nil {
-		// 如果找不到规则，可能已被删除，忽略错误
-		return nil
+		return TrafficDelta{}, err
 	}
+	delta, reset := statsDelta(last, stats)
+	if reset {
+		logger.Infof("服务 %s 计数器已重置（Gost 服务重启），本次累计值计为增量", key)
+	}
+	if err = s.counterRepo.Save(&model.ServiceCounter{
+		NodeID:      nodeID,
+		Service:     key,
+		InputBytes:  stats.InputBytes,
+		OutputBytes: stats.OutputBytes,
+		TotalConns:  stats.TotalConns,
+		TotalErrs:   stats.TotalErrs,
+	}); err != nil {
+		return TrafficDelta{}, err
+	}
+	return delta, nil
+}
 
-	// 2. 确定节点 ID
-	var nodeID uint
-	if rule.Type == model.RuleTypeTunnel && rule.Tunnel != nil {
-		nodeID = rule.Tunnel.EntryNodeID
-	} else if rule.NodeID != nil {
-		nodeID = *rule.NodeID
+// statsDelta 计算本次上报相对上次累计值的增量
+// 任一计数器变小说明 Gost 服务已重启、计数从零开始，此时本次累计值即为增量
+func statsDelta(last *model.ServiceCounter, stats *dto.ObserverStats) (TrafficDelta, bool) {
+	current := TrafficDelta{
+		InputBytes:  stats.InputBytes,
+		OutputBytes: stats.OutputBytes,
+		Connections: stats.TotalConns,
+		Errors:      stats.TotalErrs,
 	}
+	if last == nil {
+		return current, false
+	}
+	if stats.InputBytes < last.InputBytes || stats.OutputBytes < last.OutputBytes ||
+		stats.TotalConns < last.TotalConns || stats.TotalErrs < last.TotalErrs {
+		return current, true
+	}
+	return TrafficDelta{
+		InputBytes:  stats.InputBytes - last.InputBytes,
+		OutputBytes: stats.OutputBytes - last.OutputBytes,
+		Connections: stats.TotalConns - last.TotalConns,
+		Errors:      stats.TotalErrs - last.TotalErrs,
+	}, false
+}
 
-	// 3. 更新节点流量
-	if nodeID > 0 {
-		if err := s.nodeRepo.UpdateStats(nodeID, stats.InputBytes, stats.OutputBytes); err != nil {
-			logger.Warnf("更新节点流量失败: %v", err)
+// SeedServiceCounters 以规则现有的流量统计初始化服务计数器
+// 旧版本规则流量直接保存服务上报的累计值，升级后首次上报据此计算增量，避免重复计入
+func SeedServiceCounters(db *gorm.DB) error {
+	var rules []model.GostRule
+	if err := db.Preload("Tunnel").Where("input_bytes > 0 OR output_bytes > 0 OR total_requests > 0").Find(&rules).Error; err != nil {
+		return err
+	}
+
+	counterRepo := repository.NewServiceCounterRepository(db)
+	for _, rule := range rules {
+		if err := counterRepo.Save(&model.ServiceCounter{
+			NodeID:      ruleEntryNodeID(&rule),
+			Service:     ruleServiceName(&rule),
+			InputBytes:  rule.InputBytes,
+			OutputBytes: rule.OutputBytes,
+			TotalConns:  rule.TotalRequests,
+		}); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// MigrateServiceCounterNodes 为按节点区分前的服务计数器回填节点
+// 计数器按规则服务所在的入口节点归属，找不到对应规则的计数器已无用，直接删除
+func MigrateServiceCounterNodes(db *gorm.DB) error {
+	var rules []model.GostRule
+	if err := db.Preload("Tunnel").Find(&rules).Error; err != nil {
+		return err
+	}
+	nodeIDs := make(map[string]uint, len(rules))
+	for i := range rules {
+		nodeIDs[ruleServiceName(&rules[i])] = ruleEntryNodeID(&rules[i])
+	}
 
-	logger.Debugf("更新规则统计: %s%d, In: %d, Out: %d, Req: %d",
-		prefix, id, stats.InputBytes, stats.OutputBytes, stats.TotalConns)
+	var counters []model.ServiceCounter
+	if err := db.Where("node_id = 0").Find(&counters).Error; err != nil {
+		return err
+	}
+	for _, counter := range counters {
+		service, _, _ := strings.Cut(counter.Service, "/")
+		nodeID, ok := nodeIDs[service]
+		var err error
+		if ok && nodeID != 0 {
+			err = db.Model(&model.ServiceCounter{}).Where("id = ?", counter.ID).Update("node_id", nodeID).Error
+		} else {
+			err = db.Delete(&model.ServiceCounter{}, counter.ID).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -132,31 +371,47 @@ func parseUint(s string, result *uint) (bool, error) {
 	return true, nil
 }
 
-// EnsureGlobalObserver 确保全局流量监控观察器存在
+// globalObserverName 全局观察器名称，确保每个节点只有一个观察器
+const globalObserverName = "observer-global"
+
+// EnsureGlobalObserver 确保节点的全局流量监控观察器存在且上报地址携带该节点的令牌
 // 返回 observerName (如果成功) 或 error
-func EnsureGlobalObserver(client *gost.Client, sysRepo *repository.SystemConfigRepository) (string, error) {
+func EnsureGlobalObserver(ctx context.Context, client *gost.Client, node *model.GostNode, sysRepo *repository.SystemConfigRepository) (string, error) {
+	observer, err := buildGlobalObserver(node, sysRepo)
+	if err != nil {
+		return "", err
+	}
+
+	// 先更新以覆盖旧的上报地址，不存在时再创建
+	err = client.UpdateObserver(ctx, observer)
+	if gost.IsNotFound(err) {
+		err = client.CreateObserver(ctx, observer)
+	}
+	if err != nil {
+		logger.Warnf("创建/更新观察器失败: %v", err)
+		return "", errors.ErrObserverCreateFailed
+	}
+
+	logger.Infof("确保观察器存在: %s (节点: %s)", observer.Name, node.Name)
+	return observer.Name, nil
+}
+
+// buildGlobalObserver 生成节点的全局观察器配置
+// 上报地址以路径携带节点观察器令牌: {面板地址}/api/v1/observer/report/{token}
+func buildGlobalObserver(node *model.GostNode, sysRepo *repository.SystemConfigRepository) (*gost.ObserverConfig, error) {
 	// 获取系统配置中的面板地址
 	sysConfig, err := sysRepo.Get()
 	if err != nil || sysConfig.PanelURL == "" {
-		return "", errors.ErrPanelURLNotFound
+		return nil, errors.ErrPanelURLNotFound
 	}
 
 	// 使用固定名称，确保每个节点只有一个观察器
-	observerName := "observer-global"
-	observer := &gost.ObserverConfig{
-		Name: observerName,
+	return &gost.ObserverConfig{
+		Name: globalObserverName,
 		Plugin: &gost.PluginConfig{
 			Type:    "http",
-			Addr:    sysConfig.PanelURL + "/api/v1/observer/report",
+			Addr:    strings.TrimRight(sysConfig.PanelURL, "/") + "/api/v1/observer/report/" + node.ObserverToken,
 			Timeout: "10s",
 		},
-	}
-
-	if err = client.CreateObserver(observer); err != nil {
-		logger.Warnf("创建/更新观察器失败: %v", err)
-		return "", errors.ErrObserverCreateFailed
-	}
-
-	logger.Infof("确保观察器存在: %s (URL: %s)", observerName, sysConfig.PanelURL)
-	return observerName, nil
+	}, nil
 }
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"time"
 
+	"gost-panel/internal/config"
+	"gost-panel/internal/metrics"
 	"gost-panel/internal/model"
 	"gost-panel/internal/repository"
 	"gost-panel/internal/utils"
@@ -12,43 +16,70 @@ import (
 	"gorm.io/gorm"
 )
 
+// statusHistoryRetention 节点状态变更记录保留时长（覆盖最长的可用率统计窗口）
+const statusHistoryRetention = 30 * 24 * time.Hour
+
 // NodeHealthService 节点健康检测服务
-// 使用 Gost API 进行健康检查
+// 使用 Gost API 进行健康检查，连续失败/成功达到阈值后才变更节点状态，避免抖动
 type NodeHealthService struct {
-	nodeRepo   *repository.NodeRepository
-	ruleRepo   *repository.RuleRepository
-	tunnelRepo *repository.TunnelRepository
-	ticker     *time.Ticker
-	stopChan   chan struct{}
-	wg         sync.WaitGroup
+	nodeRepo    *repository.NodeRepository
+	ruleRepo    *repository.RuleRepository
+	tunnelRepo  *repository.TunnelRepository
+	historyRepo *repository.NodeStatusHistoryRepository
+	metricRepo  *repository.NodeMetricRepository
+	recovery    *nodeRecovery
+	scheduler   *NodeScheduler
+	cfg         config.HealthConfig
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+
+	mu      sync.Mutex
+	streaks map[uint]*healthStreak // 节点 ID -> 连续检测结果
+}
+
+// healthStreak 节点连续检测结果计数
+type healthStreak struct {
+	failures  int // 连续失败次数
+	successes int // 连续成功次数
 }
 
 // NewNodeHealthService 创建节点健康检测服务
-func NewNodeHealthService(db *gorm.DB) *NodeHealthService {
+func NewNodeHealthService(db *gorm.DB, scheduler *NodeScheduler) *NodeHealthService {
 	return &NodeHealthService{
-		nodeRepo:   repository.NewNodeRepository(db),
-		ruleRepo:   repository.NewRuleRepository(db),
-		tunnelRepo: repository.NewTunnelRepository(db),
-		stopChan:   make(chan struct{}),
+		nodeRepo:    repository.NewNodeRepository(db),
+		ruleRepo:    repository.NewRuleRepository(db),
+		tunnelRepo:  repository.NewTunnelRepository(db),
+		historyRepo: repository.NewNodeStatusHistoryRepository(db),
+		metricRepo:  repository.NewNodeMetricRepository(db),
+		recovery:    newNodeRecovery(db),
+		scheduler:   scheduler,
+		cfg:         config.Get().Health,
+		stopChan:    make(chan struct{}),
+		streaks:     make(map[uint]*healthStreak),
 	}
 }
 
-// Start 启动定时健康检测（每 5 秒）
+// Start 注册健康检测任务（间隔由 health.interval 配置），并启动过期数据清理
 func (s *NodeHealthService) Start() {
-	s.ticker = time.NewTicker(5 * time.Second)
-	s.wg.Add(1)
+	s.scheduler.Register(&NodeTask{
+		Name:     "health",
+		Interval: time.Duration(s.cfg.Interval) * time.Second,
+		Run:      s.checkNode,
+	})
+	logger.Infof("节点健康检测服务已启动 (间隔 %ds, 失败阈值 %d, 恢复阈值 %d)",
+		s.cfg.Interval, s.cfg.FailureThreshold, s.cfg.RecoveryThreshold)
 
+	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		logger.Info("节点健康检测服务已启动")
-
-		// 立即执行一次
-		s.checkAll()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
 
+		s.cleanup()
 		for {
 			select {
-			case <-s.ticker.C:
-				s.checkAll()
+			case <-ticker.C:
+				s.cleanup()
 			case <-s.stopChan:
 				logger.Info("节点健康检测服务已停止")
 				return
@@ -58,79 +89,153 @@ func (s *NodeHealthService) Start() {
 }
 
 // Stop 停止健康检测
+// 健康检测任务随调度器一起停止
 func (s *NodeHealthService) Stop() {
-	if s.ticker != nil {
-		s.ticker.Stop()
-	}
 	close(s.stopChan)
 	s.wg.Wait()
 }
 
-// checkAll 检测所有资源
-func (s *NodeHealthService) checkAll() {
-	s.checkNodes()
-}
+// checkNode 检测单个节点并处理状态变更
+// 节点判定为离线且本次检测失败时返回错误，由调度器对该节点退避
+func (s *NodeHealthService) checkNode(ctx context.Context, n model.GostNode) error {
+	probeErr := s.checkNodeHealth(ctx, n)
+	status := s.evaluate(n, probeErr)
 
-// checkNodes 检测所有节点
-// 使用 Gost API 的 /config 接口进行健康检查
-func (s *NodeHealthService) checkNodes() {
-	nodes, _, err := s.nodeRepo.List(nil)
-	if err != nil {
-		logger.Errorf("获取节点列表失败: %v", err)
-		return
+	// 状态变更处理
+	if status != n.Status {
+		if err := s.nodeRepo.UpdateStatus(n.ID, status); err != nil {
+			logger.Errorf("更新节点 %s 状态失败: %v", n.Name, err)
+		}
+		// 维护中的节点状态变化属于预期，记录为维护期间的变更，不计入可用率
+		if n.Maintenance {
+			logger.Debugf("节点 %s (维护中) 状态变更: %s -> %s", n.Name, n.Status, status)
+		} else {
+			logger.Infof("节点 %s 状态变更: %s -> %s", n.Name, n.Status, status)
+		}
+		s.recordTransition(n, status, probeErr)
 	}
 
-	for _, node := range nodes {
-		go func(n model.GostNode) {
-			status := s.checkNodeHealth(n)
+	_ = s.nodeRepo.UpdateLastCheck(n.ID)
 
-			// 状态变更处理
-			if status != n.Status {
-				logger.Infof("节点 %s 状态变更: %s -> %s", n.Name, n.Status, status)
-				if err = s.nodeRepo.UpdateStatus(n.ID, status); err != nil {
-					logger.Errorf("更新节点 %s 状态失败: %v", n.Name, err)
-				}
-			}
+	if status == model.NodeStatusOnline {
+		logger.Debugf("节点 %s 在线", n.Name)
+		// 节点从离线恢复，重建期望运行的隧道和规则（维护中的节点不自动恢复）
+		if n.Status != model.NodeStatusOnline && !n.Maintenance {
+			s.recovery.recoverNode(ctx, n)
+		}
+		return nil
+	}
 
-			if status == model.NodeStatusOnline {
-				logger.Debugf("节点 %s 在线", n.Name)
-			} else {
-				// 停止其关联的所有规则和隧道
-				_ = s.ruleRepo.StopByNodeID(n.ID)
-
-				// 查找并停止受影响的隧道关联的规则
-				if tunnels, err := s.tunnelRepo.FindByNodeID(n.ID); err == nil && len(tunnels) > 0 {
-					var tunnelIDs []uint
-					for _, t := range tunnels {
-						tunnelIDs = append(tunnelIDs, t.ID)
-					}
-					_ = s.ruleRepo.StopByTunnelIDs(tunnelIDs)
-				}
-
-				_ = s.tunnelRepo.StopByNodeID(n.ID)
-				logger.Debugf("节点 %s 离线, status=%s, old=%s", n.Name, status, n.Status)
-			}
+	// 停止其关联的所有规则和隧道
+	_ = s.ruleRepo.StopByNodeID(n.ID)
 
-			_ = s.nodeRepo.UpdateLastCheck(n.ID)
-		}(node)
+	// 查找并停止受影响的隧道关联的规则
+	if tunnels, err := s.tunnelRepo.FindByNodeID(n.ID); err == nil && len(tunnels) > 0 {
+		var tunnelIDs []uint
+		for _, t := range tunnels {
+			tunnelIDs = append(tunnelIDs, t.ID)
+		}
+		_ = s.ruleRepo.StopByTunnelIDs(tunnelIDs)
 	}
+
+	_ = s.tunnelRepo.StopByNodeID(n.ID)
+	logger.Debugf("节点 %s 离线, status=%s, old=%s", n.Name, status, n.Status)
+
+	// 检测成功但尚未达到恢复阈值时不退避，保证恢复判定及时
+	return probeErr
 }
 
 // checkNodeHealth 检查单个节点的健康状态
-// 通过调用 Gost API 的 /config 接口来判断节点是否可用
-func (s *NodeHealthService) checkNodeHealth(node model.GostNode) model.NodeStatus {
+// 通过调用 Gost API 的 /config 接口来判断节点是否可用，返回 nil 表示检测通过
+func (s *NodeHealthService) checkNodeHealth(ctx context.Context, node model.GostNode) error {
 	// 检查地址是否有效
 	if node.Address == "" || node.Port == 0 {
-		return model.NodeStatusOffline
+		return fmt.Errorf("节点地址未配置")
 	}
 
 	// 验证 Gost API 是否可用
-	client := utils.GetGostClient(&node)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.cfg.Timeout)*time.Second)
+	defer cancel()
 
-	if err := client.HealthCheck(); err != nil {
+	start := time.Now()
+	err := utils.GetGostClient(&node).HealthCheck(ctx)
+	metrics.ObserveHealthCheck(node.ID, node.Name, time.Since(start), err)
+	if err != nil {
 		logger.Debugf("节点 %d (%s) API 检查失败: %v", node.ID, node.Name, err)
-		return model.NodeStatusOffline
+		return err
 	}
 
-	return model.NodeStatusOnline
+	return nil
+}
+
+// evaluate 根据连续检测结果计算节点状态
+// 在线节点连续失败达到 FailureThreshold 次才判定离线，离线节点连续成功达到 RecoveryThreshold 次才判定恢复
+func (s *NodeHealthService) evaluate(node model.GostNode, probeErr error) model.NodeStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	streak, ok := s.streaks[node.ID]
+	if !ok {
+		streak = &healthStreak{}
+		s.streaks[node.ID] = streak
+	}
+
+	if probeErr == nil {
+		streak.successes++
+		streak.failures = 0
+	} else {
+		streak.failures++
+		streak.successes = 0
+	}
+
+	if node.Status == model.NodeStatusOnline {
+		if streak.failures >= s.cfg.FailureThreshold {
+			return model.NodeStatusOffline
+		}
+		return model.NodeStatusOnline
+	}
+
+	if streak.successes >= s.cfg.RecoveryThreshold {
+		return model.NodeStatusOnline
+	}
+	return node.Status
+}
+
+// recordTransition 记录节点状态变更
+func (s *NodeHealthService) recordTransition(node model.GostNode, status model.NodeStatus, probeErr error) {
+	history := &model.NodeStatusHistory{
+		NodeID:      node.ID,
+		FromStatus:  node.Status,
+		ToStatus:    status,
+		Maintenance: node.Maintenance,
+	}
+	if probeErr != nil {
+		history.Reason = fmt.Sprintf("连续 %d 次检测失败: %v", s.cfg.FailureThreshold, probeErr)
+	} else {
+		history.Reason = fmt.Sprintf("连续 %d 次检测成功", s.cfg.RecoveryThreshold)
+	}
+	if node.Maintenance {
+		history.Reason = "维护期间" + history.Reason
+	}
+
+	if err := s.historyRepo.Create(history); err != nil {
+		logger.Errorf("记录节点 %s 状态变更失败: %v", node.Name, err)
+	}
+}
+
+// cleanup 清理过期的节点状态变更记录和主机指标
+func (s *NodeHealthService) cleanup() {
+	now := time.Now()
+
+	if deleted, err := s.historyRepo.DeleteBefore(now.Add(-statusHistoryRetention)); err != nil {
+		logger.Errorf("清理节点状态变更记录失败: %v", err)
+	} else if deleted > 0 {
+		logger.Debugf("已清理 %d 条过期的节点状态变更记录", deleted)
+	}
+
+	if deleted, err := s.metricRepo.DeleteBefore(now.Add(-nodeMetricRetention)); err != nil {
+		logger.Errorf("清理节点主机指标失败: %v", err)
+	} else if deleted > 0 {
+		logger.Debugf("已清理 %d 条过期的节点主机指标", deleted)
+	}
 }
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/internal/utils"
+	"gost-panel/pkg/logger"
+)
+
+// 端口分配范围
+const (
+	minAllocPort = 1024
+	maxAllocPort = 65535
+)
+
+// portAllocator 节点端口分配器
+// 记录各节点上已被占用的端口，批量创建规则/隧道时避免冲突：
+// 规则监听端口、隧道 Relay 端口、节点 API 端口（TLS 模式下还有本机 API 端口）、指标端口，
+// 以及节点上实际运行的服务（含面板外创建的服务）监听的端口
+type portAllocator struct {
+	ctx        context.Context
+	nodeRepo   *repository.NodeRepository
+	ruleRepo   *repository.RuleRepository
+	tunnelRepo *repository.TunnelRepository
+	used       map[uint]map[int]bool
+}
+
+// newPortAllocator 创建节点端口分配器
+func newPortAllocator(ctx context.Context, nodeRepo *repository.NodeRepository, ruleRepo *repository.RuleRepository, tunnelRepo *repository.TunnelRepository) *portAllocator {
+	return &portAllocator{
+		ctx:        ctx,
+		nodeRepo:   nodeRepo,
+		ruleRepo:   ruleRepo,
+		tunnelRepo: tunnelRepo,
+		used:       make(map[uint]map[int]bool),
+	}
+}
+
+// usedPorts 获取节点上已占用的端口（首次访问时从数据库加载）
+func (a *portAllocator) usedPorts(nodeID uint) (map[int]bool, error) {
+	if ports, ok := a.used[nodeID]; ok {
+		return ports, nil
+	}
+
+	ports := make(map[int]bool)
+
+	rules, err := a.ruleRepo.FindByEntryNodeID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		ports[r.ListenPort] = true
+	}
+
+	tunnels, err := a.tunnelRepo.FindByNodeID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tunnels {
+		if t.ExitNodeID == nodeID {
+			ports[t.RelayPort] = true
+		}
+	}
+
+	node, err := a.nodeRepo.FindByID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	a.seedNodePorts(node, ports)
+
+	a.used[nodeID] = ports
+	return ports, nil
+}
+
+// seedNodePorts 记录节点自身及节点上实际运行的服务占用的端口
+func (a *portAllocator) seedNodePorts(node *model.GostNode, ports map[int]bool) {
+	ports[node.Port] = true
+	// TLS 模式下安装脚本让 API 监听本机 Port+1，由 node-api-tls 服务在 Port 上终止 TLS
+	if node.TLSEnabled {
+		ports[node.Port+1] = true
+	}
+	if node.MetricsPort != 0 {
+		ports[node.MetricsPort] = true
+	}
+
+	if node.Status != model.NodeStatusOnline {
+		return
+	}
+	cfg, err := utils.GetGostClient(node).GetConfig(a.ctx)
+	if err != nil {
+		logger.Warnf("获取节点 %s 配置失败，仅按面板记录分配端口: %v", node.Name, err)
+		return
+	}
+	for _, svc := range cfg.Services {
+		if port := listenPort(svc.Addr); port != 0 {
+			ports[port] = true
+		}
+	}
+}
+
+// reserve 占用指定端口，端口已被占用时返回 ErrRulePortExists
+func (a *portAllocator) reserve(nodeID uint, port int) error {
+	ports, err := a.usedPorts(nodeID)
+	if err != nil {
+		return err
+	}
+	if ports[port] {
+		return errors.ErrRulePortExists
+	}
+	ports[port] = true
+	return nil
+}
+
+// allocate 优先占用 preferred 端口，冲突时顺延到下一个空闲端口
+func (a *portAllocator) allocate(nodeID uint, preferred int) (int, error) {
+	ports, err := a.usedPorts(nodeID)
+	if err != nil {
+		return 0, err
+	}
+
+	start := preferred
+	if start < minAllocPort {
+		start = minAllocPort
+	}
+	if !ports[preferred] {
+		ports[preferred] = true
+		return preferred, nil
+	}
+	for i := 0; i <= maxAllocPort-minAllocPort; i++ {
+		port := minAllocPort + (start-minAllocPort+i)%(maxAllocPort-minAllocPort+1)
+		if !ports[port] {
+			ports[port] = true
+			return port, nil
+		}
+	}
+	return 0, errors.ErrPortExhausted
+}
+
+// release 释放已占用的端口（创建失败回滚时使用）
+func (a *portAllocator) release(nodeID uint, port int) {
+	if ports, ok := a.used[nodeID]; ok {
+		delete(ports, port)
+	}
+}
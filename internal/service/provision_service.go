@@ -0,0 +1,405 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/internal/sshtunnel"
+	"gost-panel/internal/utils"
+	"gost-panel/pkg/logger"
+	"gost-panel/scripts"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultProvisionAPIPort 部署节点默认 Gost API 端口
+	defaultProvisionAPIPort = 39000
+	// provisionSSHTimeout SSH 连接超时
+	provisionSSHTimeout = 10 * time.Second
+	// provisionScriptTimeout 脚本执行超时（含下载 Gost 二进制），超时后断开 SSH 连接
+	provisionScriptTimeout = 10 * time.Minute
+	// provisionVerifyTimeout 部署完成后等待节点 API 可用的时间
+	provisionVerifyTimeout = 30 * time.Second
+)
+
+// gostVersionPattern Gost 版本号格式
+var gostVersionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// provisionLocks 正在执行部署任务的服务器 SSH 地址，同一服务器同时只允许一个任务
+var provisionLocks sync.Map
+
+// ProvisionService 节点部署服务
+// 经 SSH 上传并执行节点安装脚本，在面板内完成节点部署、Gost 升级和卸载
+type ProvisionService struct {
+	nodeRepo    *repository.NodeRepository
+	nodeService *NodeService
+	logService  *LogService
+}
+
+// NewProvisionService 创建节点部署服务
+func NewProvisionService(db *gorm.DB) *ProvisionService {
+	return &ProvisionService{
+		nodeRepo:    repository.NewNodeRepository(db),
+		nodeService: NewNodeService(db),
+		logService:  NewLogService(db),
+	}
+}
+
+// ProvisionTask 节点部署任务
+// 由 Prepare 系列方法校验参数并锁定目标服务器后创建，必须调用 Run 执行，Run 结束时释放锁
+type ProvisionTask struct {
+	sshCfg  sshtunnel.Config
+	args    []string                         // 安装脚本参数
+	version string                           // Gost 版本，为空使用脚本默认版本
+	finish  func(out io.Writer) (any, error) // 脚本执行成功后的处理，返回任务结果
+}
+
+// Run 执行任务，脚本输出及执行进度写入 out
+func (t *ProvisionTask) Run(out io.Writer) (any, error) {
+	defer provisionLocks.Delete(t.sshCfg.Addr)
+
+	fmt.Fprintf(out, "正在连接 %s@%s ...\n", t.sshCfg.User, t.sshCfg.Addr)
+	client, err := sshtunnel.Dial(t.sshCfg)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	// 超时后断开连接，中止远端脚本
+	timer := time.AfterFunc(provisionScriptTimeout, func() { _ = client.Close() })
+	defer timer.Stop()
+
+	scriptPath := fmt.Sprintf("/tmp/gost-panel-install-%s.sh", utils.RandomToken(4))
+	upload := "umask 077 && cat > " + scriptPath
+	if err = sshtunnel.Run(client, upload, bytes.NewReader(scripts.InstallNode), io.Discard); err != nil {
+		return nil, fmt.Errorf("上传安装脚本失败: %w", err)
+	}
+
+	cmd := t.command(scriptPath)
+	if err = sshtunnel.Run(client, cmd, nil, out); err != nil {
+		fmt.Fprintf(out, "脚本执行失败: %v\n", err)
+		return nil, errors.ErrProvisionFailed
+	}
+
+	return t.finish(out)
+}
+
+// command 构建执行安装脚本的命令，非 root 用户经 sudo 执行，执行后删除脚本
+func (t *ProvisionTask) command(scriptPath string) string {
+	var b strings.Builder
+	if t.sshCfg.User != "root" {
+		b.WriteString("sudo -n ")
+	}
+	b.WriteString("env")
+	if t.version != "" {
+		b.WriteString(" GOST_VERSION=" + shellQuote(t.version))
+	}
+	b.WriteString(" bash " + scriptPath)
+	for _, arg := range t.args {
+		b.WriteString(" " + shellQuote(arg))
+	}
+	fmt.Fprintf(&b, "; rc=$?; rm -f %s; exit $rc", scriptPath)
+	return b.String()
+}
+
+// PrepareProvision 准备部署新节点：安装 Gost 并生成 API 凭据，成功后注册节点
+func (s *ProvisionService) PrepareProvision(req *dto.ProvisionNodeReq, userID uint, username string, ip, userAgent string) (*ProvisionTask, error) {
+	address := strings.TrimSpace(req.Address)
+	port := req.Port
+	if port == 0 {
+		port = defaultProvisionAPIPort
+	}
+	if req.GostVersion != "" && !gostVersionPattern.MatchString(req.GostVersion) {
+		return nil, errors.ErrGostVersionInvalid
+	}
+
+	mode := nodeConnectionMode(req.ConnectionMode)
+	panelURL := strings.TrimRight(strings.TrimSpace(req.PanelURL), "/")
+	if mode == model.NodeConnectionAgent && panelURL == "" {
+		return nil, errors.ErrPanelURLRequired
+	}
+
+	exists, err := s.nodeRepo.ExistsByName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.ErrNodeNameExists
+	}
+
+	sshCfg, err := provisionSSHConfig(address, &req.NodeSSHReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// 生成 API 凭据；填写面板地址时同时安装 Agent
+	apiUser := "gost" + utils.RandomToken(4)
+	apiPass := utils.RandomToken(12)
+	agentToken := NewAgentToken()
+	scriptToken := ""
+	if panelURL != "" {
+		scriptToken = agentToken
+	}
+
+	task := &ProvisionTask{
+		sshCfg:  sshCfg,
+		args:    []string{strconv.Itoa(port), apiUser, apiPass, panelURL, scriptToken, string(mode)},
+		version: req.GostVersion,
+	}
+	task.finish = func(out io.Writer) (any, error) {
+		createReq := &dto.CreateNodeReq{
+			Name:           req.Name,
+			Address:        address,
+			Port:           port,
+			Username:       apiUser,
+			Password:       apiPass,
+			Remark:         req.Remark,
+			ConnectionMode: string(mode),
+			NodeSSHReq:     req.NodeSSHReq,
+		}
+		createReq.SSHPort = sshPort(req.SSHPort)
+		createReq.SSHHostKey = sshCfg.HostKey
+
+		node, err := s.nodeService.create(createReq, agentToken, userID, username, ip, userAgent)
+		if err != nil {
+			fmt.Fprintf(out, "注册节点失败: %v，请使用以下信息手动添加节点: API 端口 %d，用户名 %s，密码 %s\n", err, port, apiUser, apiPass)
+			return nil, err
+		}
+		fmt.Fprintf(out, "节点已注册: %s\n", node.Name)
+
+		s.logService.Record(userID, username, model.ActionProvision, model.ResourceTypeNode, node.ID,
+			fmt.Sprintf("经 SSH 部署节点: %s (Gost %s)", node.Name, gostVersionLabel(req.GostVersion)), ip, userAgent)
+		logger.Infof("经 SSH 部署节点成功: %s (%s)", node.Name, sshCfg.Addr)
+
+		waitNodeReady(node, out)
+		return node, nil
+	}
+
+	if err = lockProvision(sshCfg.Addr); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// PrepareUpgrade 准备升级节点 Gost 版本，保留节点现有配置
+func (s *ProvisionService) PrepareUpgrade(id uint, req *dto.NodeUpgradeReq, userID uint, username string, ip, userAgent string) (*ProvisionTask, error) {
+	node, err := s.findNode(id)
+	if err != nil {
+		return nil, err
+	}
+	if !gostVersionPattern.MatchString(req.GostVersion) {
+		return nil, errors.ErrGostVersionInvalid
+	}
+
+	sshCfg, err := nodeSSHConfig(node, &req.NodeSSHReq)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &ProvisionTask{
+		sshCfg:  sshCfg,
+		args:    []string{"upgrade"},
+		version: req.GostVersion,
+	}
+	task.finish = func(out io.Writer) (any, error) {
+		s.logService.Record(userID, username, model.ActionProvision, model.ResourceTypeNode, node.ID,
+			fmt.Sprintf("经 SSH 升级节点 %s 的 Gost 到 %s", node.Name, gostVersionLabel(req.GostVersion)), ip, userAgent)
+		logger.Infof("经 SSH 升级节点 %s 的 Gost 到 v%s", node.Name, req.GostVersion)
+
+		waitNodeReady(node, out)
+		return node, nil
+	}
+
+	if err = lockProvision(sshCfg.Addr); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// PrepareUninstall 准备卸载节点上的 Gost 及 Agent，可选卸载后从面板删除节点
+func (s *ProvisionService) PrepareUninstall(id uint, req *dto.NodeUninstallReq, userID uint, username string, ip, userAgent string) (*ProvisionTask, error) {
+	node, err := s.nodeRepo.FindByIDWithRelations(id)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrNodeNotFound
+		}
+		return nil, err
+	}
+
+	// 需删除节点时提前检查关联，避免卸载后无法删除
+	if req.RemoveNode {
+		if len(node.Rules) > 0 {
+			return nil, errors.ErrNodeHasRules
+		}
+		if len(node.EntryTunnels) > 0 || len(node.ExitTunnels) > 0 {
+			return nil, errors.ErrNodeHasTunnels
+		}
+	}
+
+	sshCfg, err := nodeSSHConfig(node, &req.NodeSSHReq)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &ProvisionTask{
+		sshCfg: sshCfg,
+		args:   []string{"uninstall"},
+	}
+	task.finish = func(out io.Writer) (any, error) {
+		s.logService.Record(userID, username, model.ActionProvision, model.ResourceTypeNode, node.ID,
+			fmt.Sprintf("经 SSH 卸载节点: %s", node.Name), ip, userAgent)
+		logger.Infof("经 SSH 卸载节点成功: %s", node.Name)
+
+		if req.RemoveNode {
+			if err := s.nodeService.Delete(node.ID, userID, username, ip, userAgent); err != nil {
+				fmt.Fprintf(out, "从面板删除节点失败: %v\n", err)
+				return nil, err
+			}
+			fmt.Fprintf(out, "节点已从面板删除: %s\n", node.Name)
+		}
+		return node, nil
+	}
+
+	if err = lockProvision(sshCfg.Addr); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// findNode 查询节点
+func (s *ProvisionService) findNode(id uint) (*model.GostNode, error) {
+	node, err := s.nodeRepo.FindByID(id)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrNodeNotFound
+		}
+		return nil, err
+	}
+	return node, nil
+}
+
+// lockProvision 锁定目标服务器，已有任务执行时返回错误
+func lockProvision(addr string) error {
+	if _, busy := provisionLocks.LoadOrStore(addr, struct{}{}); busy {
+		return errors.ErrProvisionBusy
+	}
+	return nil
+}
+
+// nodeSSHConfig 获取运维已有节点使用的 SSH 配置
+// 请求中填写用户名时使用请求中的登录信息，否则使用节点已保存的 SSH 凭据（SSH 隧道连接方式）
+func nodeSSHConfig(node *model.GostNode, req *dto.NodeSSHReq) (sshtunnel.Config, error) {
+	if strings.TrimSpace(req.SSHUser) != "" {
+		// 端口未变时沿用已记录的主机公钥
+		if strings.TrimSpace(req.SSHHostKey) == "" && sshPort(req.SSHPort) == sshPort(node.SSHPort) {
+			req.SSHHostKey = node.SSHHostKey
+		}
+		return provisionSSHConfig(node.Address, req)
+	}
+
+	if node.SSHUser == "" {
+		return sshtunnel.Config{}, errors.ErrNodeSSHAuthRequired
+	}
+	cfg, err := utils.GetNodeSSHConfig(node)
+	if err != nil {
+		logger.Warnf("节点 %s SSH 凭据解密失败: %v", node.Name, err)
+		return sshtunnel.Config{}, errors.ErrNodeSSHKeyInvalid
+	}
+	cfg.Timeout = provisionSSHTimeout
+	return cfg, nil
+}
+
+// provisionSSHConfig 校验请求中的 SSH 登录信息并构建 SSH 配置，未填写主机公钥时连接服务器获取
+func provisionSSHConfig(address string, req *dto.NodeSSHReq) (sshtunnel.Config, error) {
+	cfg := sshtunnel.Config{
+		Addr:       net.JoinHostPort(address, strconv.Itoa(sshPort(req.SSHPort))),
+		User:       strings.TrimSpace(req.SSHUser),
+		Password:   req.SSHPassword,
+		PrivateKey: strings.TrimSpace(req.SSHPrivateKey),
+		Passphrase: req.SSHPassphrase,
+		HostKey:    strings.TrimSpace(req.SSHHostKey),
+		Timeout:    provisionSSHTimeout,
+	}
+
+	if cfg.User == "" || (cfg.Password == "" && cfg.PrivateKey == "") {
+		return cfg, errors.ErrNodeSSHAuthRequired
+	}
+	if _, err := sshtunnel.AuthMethods(cfg); err != nil {
+		logger.Warnf("SSH 私钥无效 (%s): %v", cfg.Addr, err)
+		return cfg, errors.ErrNodeSSHKeyInvalid
+	}
+
+	if cfg.HostKey != "" {
+		if err := sshtunnel.ValidateHostKey(cfg.HostKey); err != nil {
+			return cfg, errors.ErrNodeSSHKeyInvalid
+		}
+		return cfg, nil
+	}
+
+	hostKey, err := sshtunnel.FetchHostKey(cfg.Addr, cfg.Timeout)
+	if err != nil {
+		logger.Warnf("获取 SSH 主机公钥失败 (%s): %v", cfg.Addr, err)
+		return cfg, errors.ErrNodeSSHHostKeyFetchFailed
+	}
+	cfg.HostKey = hostKey
+	return cfg, nil
+}
+
+// waitNodeReady 等待节点 API 可用，超时仅提示，不影响任务结果
+func waitNodeReady(node *model.GostNode, out io.Writer) {
+	fmt.Fprintln(out, "正在检查节点 API ...")
+	client := utils.GetGostClient(node)
+	// 客户端断开后任务仍需完成，不继承请求上下文
+	ctx, cancel := context.WithTimeout(context.Background(), provisionVerifyTimeout)
+	defer cancel()
+	for {
+		err := client.HealthCheck(ctx)
+		if err == nil {
+			fmt.Fprintln(out, "节点 API 连接正常")
+			return
+		}
+		if ctx.Err() != nil {
+			fmt.Fprintf(out, "节点 API 暂不可用: %v，请稍后检查节点状态\n", err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// sshPort SSH 端口，未指定时为 22
+func sshPort(port int) int {
+	if port == 0 {
+		return 22
+	}
+	return port
+}
+
+// gostVersionLabel Gost 版本显示文本
+func gostVersionLabel(version string) string {
+	if version == "" {
+		return "默认版本"
+	}
+	return "v" + version
+}
+
+// shellQuote 将参数转义为单引号包裹的 shell 字符串
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,599 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/internal/utils"
+	"gost-panel/pkg/gost"
+	"gost-panel/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// 面板创建的 Gost 对象命名规则
+var (
+	ruleServicePattern  = regexp.MustCompile(`^rule-(\d+)$`)
+	relayServicePattern = regexp.MustCompile(`^relay-tunnel-(\d+)$`)
+	tunnelChainPattern  = regexp.MustCompile(`^tunnel-(\d+)-chain$`)
+)
+
+// nodeAPITLSServiceName 安装脚本创建的节点 API TLS 入口服务，不参与漂移检测和导入
+const nodeAPITLSServiceName = "node-api-tls"
+
+// ReconcileService 配置漂移检测服务
+// 对比数据库期望状态与节点实际配置，发现缺失、被修改及孤立的服务和链，并提供修复与清理操作
+type ReconcileService struct {
+	nodeRepo   *repository.NodeRepository
+	ruleRepo   *repository.RuleRepository
+	tunnelRepo *repository.TunnelRepository
+	sysRepo    *repository.SystemConfigRepository
+	logService *LogService
+}
+
+// NewReconcileService 创建配置漂移检测服务
+func NewReconcileService(db *gorm.DB) *ReconcileService {
+	return &ReconcileService{
+		nodeRepo:   repository.NewNodeRepository(db),
+		ruleRepo:   repository.NewRuleRepository(db),
+		tunnelRepo: repository.NewTunnelRepository(db),
+		sysRepo:    repository.NewSystemConfigRepository(db),
+		logService: NewLogService(db),
+	}
+}
+
+// desiredService 期望存在的服务
+type desiredService struct {
+	config       *gost.ServiceConfig
+	resourceType string
+	resourceID   uint
+}
+
+// desiredChain 期望存在的链
+type desiredChain struct {
+	config       *gost.ChainConfig
+	resourceType string
+	resourceID   uint
+}
+
+// ownedObject 数据库记录所引用的 Gost 对象
+type ownedObject struct {
+	resourceType string
+	resourceID   uint
+}
+
+// desiredState 节点的期望状态
+type desiredState struct {
+	services map[string]*desiredService
+	chains   map[string]*desiredChain
+	// 节点上所有规则/隧道记录引用的对象（不论是否运行），用于判断孤立对象的原因
+	ownedServices map[string]ownedObject
+	ownedChains   map[string]ownedObject
+}
+
+// owns 判断对象是否被节点上的规则/隧道记录引用
+func (d *desiredState) owns(kind, name string) (ownedObject, bool) {
+	if kind == dto.ReconcileKindChain {
+		o, ok := d.ownedChains[name]
+		return o, ok
+	}
+	o, ok := d.ownedServices[name]
+	return o, ok
+}
+
+// Inspect 检测节点配置漂移
+func (s *ReconcileService) Inspect(ctx context.Context, nodeID uint) (*dto.ReconcileReport, error) {
+	node, err := s.findOnlineNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, _, _, err := s.inspect(ctx, node)
+	return report, err
+}
+
+// Repair 修复节点配置漂移：重新下发缺失和被修改的服务与链
+func (s *ReconcileService) Repair(ctx context.Context, nodeID uint, req *dto.ReconcileActionReq, userID uint, username string, ip, userAgent string) (*dto.ReconcileActionResp, error) {
+	node, err := s.findOnlineNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, desired, client, err := s.inspect(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ReconcileActionResp{Failed: make(map[string]string)}
+	items := append(filterReconcileItems(report.Missing, req.Names), filterReconcileItems(report.Modified, req.Names)...)
+	if len(items) == 0 {
+		return resp, nil
+	}
+
+	// 规则服务依赖全局观察器，先确保其存在
+	for _, item := range items {
+		if d, ok := desired.services[item.Name]; ok && item.Kind == dto.ReconcileKindService && d.config.Observer != "" {
+			if _, err = EnsureGlobalObserver(ctx, client, node, s.sysRepo); err != nil {
+				logger.Warnf("[Reconcile] 节点 %s 确保观察器失败: %v", node.Name, err)
+			}
+			break
+		}
+	}
+
+	// 先处理链，再处理服务（隧道转发服务依赖链）
+	for _, kind := range []string{dto.ReconcileKindChain, dto.ReconcileKindService} {
+		for _, item := range items {
+			if item.Kind != kind {
+				continue
+			}
+			modified := len(item.Diffs) > 0
+			if err = s.applyDesired(ctx, client, desired, item, modified); err != nil {
+				resp.Failed[item.Name] = err.Error()
+				continue
+			}
+			resp.Succeeded = append(resp.Succeeded, item.Name)
+		}
+	}
+
+	_ = client.SaveConfig(ctx)
+
+	s.logService.Record(
+		userID,
+		username,
+		model.ActionRepair,
+		model.ResourceTypeNode,
+		node.ID,
+		fmt.Sprintf("修复节点配置漂移: %s (成功 %d, 失败 %d)", node.Name, len(resp.Succeeded), len(resp.Failed)),
+		ip,
+		userAgent)
+
+	logger.Infof("[Reconcile] 节点 %s 修复完成: 成功 %d, 失败 %d", node.Name, len(resp.Succeeded), len(resp.Failed))
+	return resp, nil
+}
+
+// Prune 清理节点上的孤立服务与链
+// 仅清理符合面板命名规则的对象，不会触碰手动创建的配置
+func (s *ReconcileService) Prune(ctx context.Context, nodeID uint, req *dto.ReconcileActionReq, userID uint, username string, ip, userAgent string) (*dto.ReconcileActionResp, error) {
+	node, err := s.findOnlineNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, _, client, err := s.inspect(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ReconcileActionResp{Failed: make(map[string]string)}
+	items := filterReconcileItems(report.Orphans, req.Names)
+	if len(items) == 0 {
+		return resp, nil
+	}
+
+	// 先删除服务，再删除链（服务可能引用链）
+	for _, kind := range []string{dto.ReconcileKindService, dto.ReconcileKindChain} {
+		for _, item := range items {
+			if item.Kind != kind {
+				continue
+			}
+			if kind == dto.ReconcileKindService {
+				err = client.DeleteService(ctx, item.Name)
+			} else {
+				err = client.DeleteChain(ctx, item.Name)
+			}
+			if err != nil {
+				resp.Failed[item.Name] = err.Error()
+				continue
+			}
+			resp.Succeeded = append(resp.Succeeded, item.Name)
+		}
+	}
+
+	_ = client.SaveConfig(ctx)
+
+	s.logService.Record(
+		userID,
+		username,
+		model.ActionPrune,
+		model.ResourceTypeNode,
+		node.ID,
+		fmt.Sprintf("清理节点孤立配置: %s (成功 %d, 失败 %d)", node.Name, len(resp.Succeeded), len(resp.Failed)),
+		ip,
+		userAgent)
+
+	logger.Infof("[Reconcile] 节点 %s 清理完成: 成功 %d, 失败 %d", node.Name, len(resp.Succeeded), len(resp.Failed))
+	return resp, nil
+}
+
+// findOnlineNode 查询节点并确认其在线
+func (s *ReconcileService) findOnlineNode(nodeID uint) (*model.GostNode, error) {
+	node, err := s.nodeRepo.FindByID(nodeID)
+	if err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrNodeNotFound
+		}
+		return nil, err
+	}
+	if node.Status == model.NodeStatusOffline {
+		return nil, errors.ErrNodeOffline
+	}
+	return node, nil
+}
+
+// inspect 拉取节点实际配置并与期望状态对比
+func (s *ReconcileService) inspect(ctx context.Context, node *model.GostNode) (*dto.ReconcileReport, *desiredState, *gost.Client, error) {
+	desired, err := s.buildDesiredState(node)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	client := utils.GetGostClient(node)
+	actual, err := client.GetConfig(ctx)
+	if err != nil {
+		logger.Warnf("[Reconcile] 获取节点 %s 配置失败: %v", node.Name, err)
+		return nil, nil, nil, errors.ErrNodeConfigFetchFailed
+	}
+
+	report := &dto.ReconcileReport{
+		NodeID:    node.ID,
+		NodeName:  node.Name,
+		Missing:   []dto.ReconcileItem{},
+		Modified:  []dto.ReconcileItem{},
+		Orphans:   []dto.ReconcileItem{},
+		Unmanaged: []dto.ReconcileItem{},
+		CheckedAt: time.Now(),
+	}
+
+	// 1. 服务
+	actualServices := make(map[string]*gost.ServiceConfig, len(actual.Services))
+	for i := range actual.Services {
+		svc := &actual.Services[i]
+		actualServices[svc.Name] = svc
+
+		if d, ok := desired.services[svc.Name]; ok {
+			if diffs := diffServiceConfig(d.config, svc); len(diffs) > 0 {
+				report.Modified = append(report.Modified, dto.ReconcileItem{
+					Kind:         dto.ReconcileKindService,
+					Name:         svc.Name,
+					ResourceType: d.resourceType,
+					ResourceID:   d.resourceID,
+					Reason:       "节点配置与面板生成的配置不一致",
+					Diffs:        diffs,
+				})
+			}
+			continue
+		}
+
+		classifyUndesired(report, dto.ReconcileKindService, svc.Name, desired)
+	}
+	for name, d := range desired.services {
+		if _, ok := actualServices[name]; !ok {
+			report.Missing = append(report.Missing, dto.ReconcileItem{
+				Kind:         dto.ReconcileKindService,
+				Name:         name,
+				ResourceType: d.resourceType,
+				ResourceID:   d.resourceID,
+				Reason:       "节点上缺少该服务",
+			})
+		}
+	}
+
+	// 2. 链
+	actualChains := make(map[string]*gost.ChainConfig, len(actual.Chains))
+	for i := range actual.Chains {
+		chain := &actual.Chains[i]
+		actualChains[chain.Name] = chain
+
+		if d, ok := desired.chains[chain.Name]; ok {
+			if diffs := diffChainConfig(d.config, chain); len(diffs) > 0 {
+				report.Modified = append(report.Modified, dto.ReconcileItem{
+					Kind:         dto.ReconcileKindChain,
+					Name:         chain.Name,
+					ResourceType: d.resourceType,
+					ResourceID:   d.resourceID,
+					Reason:       "节点配置与面板生成的配置不一致",
+					Diffs:        diffs,
+				})
+			}
+			continue
+		}
+
+		classifyUndesired(report, dto.ReconcileKindChain, chain.Name, desired)
+	}
+	for name, d := range desired.chains {
+		if _, ok := actualChains[name]; !ok {
+			report.Missing = append(report.Missing, dto.ReconcileItem{
+				Kind:         dto.ReconcileKindChain,
+				Name:         name,
+				ResourceType: d.resourceType,
+				ResourceID:   d.resourceID,
+				Reason:       "节点上缺少该链",
+			})
+		}
+	}
+
+	return report, desired, client, nil
+}
+
+// buildDesiredState 根据数据库记录生成节点的期望状态
+func (s *ReconcileService) buildDesiredState(node *model.GostNode) (*desiredState, error) {
+	desired := &desiredState{
+		services:      make(map[string]*desiredService),
+		chains:        make(map[string]*desiredChain),
+		ownedServices: make(map[string]ownedObject),
+		ownedChains:   make(map[string]ownedObject),
+	}
+
+	// 1. 以该节点为入口的规则
+	rules, err := s.ruleRepo.FindByEntryNodeID(node.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		desired.ownedServices[ruleServiceName(&r)] = ownedObject{model.ResourceTypeRule, r.ID}
+		if !r.DesiredRunning {
+			continue
+		}
+
+		chainID := ""
+		if r.Type == model.RuleTypeTunnel {
+			if r.Tunnel == nil {
+				continue
+			}
+			chainID = tunnelChainName(r.Tunnel)
+		}
+
+		name := ruleServiceName(&r)
+		svc := buildRuleService(&r, name, chainID)
+		applyRuleObserver(svc, r.ObserverID)
+		desired.services[name] = &desiredService{
+			config:       svc,
+			resourceType: model.ResourceTypeRule,
+			resourceID:   r.ID,
+		}
+	}
+
+	// 2. 与该节点相关的隧道（入口节点上的 Chain，出口节点上的 Relay 服务）
+	tunnels, err := s.tunnelRepo.FindByNodeID(node.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tunnels {
+		if t.ExitNodeID == node.ID {
+			desired.ownedServices[tunnelRelayServiceName(&t)] = ownedObject{model.ResourceTypeTunnel, t.ID}
+		}
+		if t.EntryNodeID == node.ID {
+			desired.ownedChains[tunnelChainName(&t)] = ownedObject{model.ResourceTypeTunnel, t.ID}
+		}
+		if !t.DesiredRunning {
+			continue
+		}
+
+		if t.ExitNodeID == node.ID {
+			name := tunnelRelayServiceName(&t)
+			desired.services[name] = &desiredService{
+				config:       buildTunnelRelayService(&t, name),
+				resourceType: model.ResourceTypeTunnel,
+				resourceID:   t.ID,
+			}
+		}
+
+		if t.EntryNodeID == node.ID {
+			exitNode, err := s.nodeRepo.FindByID(t.ExitNodeID)
+			if err != nil {
+				logger.Warnf("[Reconcile] 隧道 %d 出口节点不存在: %v", t.ID, err)
+				continue
+			}
+			name := tunnelChainName(&t)
+			desired.chains[name] = &desiredChain{
+				config:       buildTunnelChain(&t, name, exitNode.Address),
+				resourceType: model.ResourceTypeTunnel,
+				resourceID:   t.ID,
+			}
+		}
+	}
+
+	return desired, nil
+}
+
+// applyDesired 将期望配置下发到节点
+func (s *ReconcileService) applyDesired(ctx context.Context, client *gost.Client, desired *desiredState, item dto.ReconcileItem, modified bool) error {
+	if item.Kind == dto.ReconcileKindChain {
+		d, ok := desired.chains[item.Name]
+		if !ok {
+			return fmt.Errorf("链 %s 不在期望状态中", item.Name)
+		}
+		if modified {
+			return client.UpdateChain(ctx, d.config)
+		}
+		return client.CreateChain(ctx, d.config)
+	}
+
+	d, ok := desired.services[item.Name]
+	if !ok {
+		return fmt.Errorf("服务 %s 不在期望状态中", item.Name)
+	}
+	if modified {
+		return client.UpdateService(ctx, d.config)
+	}
+	return client.CreateService(ctx, d.config)
+}
+
+// classifyUndesired 将不在期望状态中的对象归类为孤立或非托管
+func classifyUndesired(report *dto.ReconcileReport, kind, name string, desired *desiredState) {
+	if kind == dto.ReconcileKindService && name == nodeAPITLSServiceName {
+		return
+	}
+
+	item := dto.ReconcileItem{Kind: kind, Name: name}
+
+	// 被记录引用但记录未运行
+	if o, ok := desired.owns(kind, name); ok {
+		item.ResourceType, item.ResourceID = o.resourceType, o.resourceID
+		item.Reason = "对应记录未设置为运行"
+		report.Orphans = append(report.Orphans, item)
+		return
+	}
+
+	// 符合面板命名规则，但节点上没有对应记录
+	resourceType, resourceID, ok := parsePanelObjectName(kind, name)
+	if !ok {
+		item.Reason = "非面板创建的对象"
+		report.Unmanaged = append(report.Unmanaged, item)
+		return
+	}
+	item.ResourceType, item.ResourceID = resourceType, resourceID
+	item.Reason = "节点上无对应记录"
+	report.Orphans = append(report.Orphans, item)
+}
+
+// parsePanelObjectName 解析面板命名规则的对象名称，返回关联的资源类型和 ID
+func parsePanelObjectName(kind, name string) (string, uint, bool) {
+	var pattern *regexp.Regexp
+	var resourceType string
+	switch {
+	case kind == dto.ReconcileKindService && ruleServicePattern.MatchString(name):
+		pattern, resourceType = ruleServicePattern, model.ResourceTypeRule
+	case kind == dto.ReconcileKindService && relayServicePattern.MatchString(name):
+		pattern, resourceType = relayServicePattern, model.ResourceTypeTunnel
+	case kind == dto.ReconcileKindChain && tunnelChainPattern.MatchString(name):
+		pattern, resourceType = tunnelChainPattern, model.ResourceTypeTunnel
+	default:
+		return "", 0, false
+	}
+
+	id, _ := strconv.ParseUint(pattern.FindStringSubmatch(name)[1], 10, 64)
+	return resourceType, uint(id), true
+}
+
+// filterReconcileItems 按名称过滤漂移对象，names 为空时返回全部
+func filterReconcileItems(items []dto.ReconcileItem, names []string) []dto.ReconcileItem {
+	if len(names) == 0 {
+		return items
+	}
+	result := make([]dto.ReconcileItem, 0, len(items))
+	for _, item := range items {
+		if slices.Contains(names, item.Name) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// sameListenAddr 判断节点上服务的监听地址是否与期望一致
+// 空主机、0.0.0.0 和 :: 都表示监听所有地址；期望地址未指定主机时只比较端口
+func sameListenAddr(want, got string) bool {
+	wantHost, wantPort := splitListenAddr(want)
+	gotHost, gotPort := splitListenAddr(got)
+	if wantPort == 0 || gotPort == 0 {
+		return want == got
+	}
+	return wantPort == gotPort && (wantHost == "" || wantHost == gotHost)
+}
+
+// diffServiceConfig 对比服务的关键配置，返回存在差异的字段
+func diffServiceConfig(want, got *gost.ServiceConfig) []string {
+	var diffs []string
+
+	if !sameListenAddr(want.Addr, got.Addr) {
+		diffs = append(diffs, fmt.Sprintf("addr: %s -> %s", want.Addr, got.Addr))
+	}
+
+	var wantHandler, gotHandler gost.HandlerConfig
+	if want.Handler != nil {
+		wantHandler = *want.Handler
+	}
+	if got.Handler != nil {
+		gotHandler = *got.Handler
+	}
+	if wantHandler.Type != gotHandler.Type {
+		diffs = append(diffs, fmt.Sprintf("handler.type: %s -> %s", wantHandler.Type, gotHandler.Type))
+	}
+	if wantHandler.Chain != gotHandler.Chain {
+		diffs = append(diffs, fmt.Sprintf("handler.chain: %s -> %s", wantHandler.Chain, gotHandler.Chain))
+	}
+
+	var wantListener, gotListener string
+	if want.Listener != nil {
+		wantListener = want.Listener.Type
+	}
+	if got.Listener != nil {
+		gotListener = got.Listener.Type
+	}
+	if wantListener != gotListener {
+		diffs = append(diffs, fmt.Sprintf("listener.type: %s -> %s", wantListener, gotListener))
+	}
+
+	wantTargets, wantStrategy := forwarderSummary(want.Forwarder)
+	gotTargets, gotStrategy := forwarderSummary(got.Forwarder)
+	if !slices.Equal(wantTargets, gotTargets) {
+		diffs = append(diffs, fmt.Sprintf("forwarder.nodes: %v -> %v", wantTargets, gotTargets))
+	}
+	if wantStrategy != gotStrategy {
+		diffs = append(diffs, fmt.Sprintf("forwarder.selector.strategy: %s -> %s", wantStrategy, gotStrategy))
+	}
+
+	if want.Observer != got.Observer {
+		diffs = append(diffs, fmt.Sprintf("observer: %s -> %s", want.Observer, got.Observer))
+	}
+	if wantHandler.Observer != gotHandler.Observer {
+		diffs = append(diffs, fmt.Sprintf("handler.observer: %s -> %s", wantHandler.Observer, gotHandler.Observer))
+	}
+
+	return diffs
+}
+
+// forwarderSummary 提取转发器的目标地址列表和负载均衡策略
+func forwarderSummary(fwd *gost.ForwarderConfig) ([]string, string) {
+	if fwd == nil {
+		return nil, ""
+	}
+	addrs := make([]string, 0, len(fwd.Nodes))
+	for _, n := range fwd.Nodes {
+		addrs = append(addrs, n.Addr)
+	}
+	// 未配置策略时 Gost 默认使用轮询
+	strategy := "round"
+	if fwd.Selector != nil && fwd.Selector.Strategy != "" {
+		strategy = fwd.Selector.Strategy
+	}
+	return addrs, strategy
+}
+
+// diffChainConfig 对比链的关键配置，返回存在差异的字段
+func diffChainConfig(want, got *gost.ChainConfig) []string {
+	wantNodes := chainSummary(want)
+	gotNodes := chainSummary(got)
+	if slices.Equal(wantNodes, gotNodes) {
+		return nil
+	}
+	return []string{fmt.Sprintf("hops: %v -> %v", wantNodes, gotNodes)}
+}
+
+// chainSummary 将链的跳和节点展开为可比较的描述列表
+func chainSummary(chain *gost.ChainConfig) []string {
+	var result []string
+	for i, hop := range chain.Hops {
+		for _, n := range hop.Nodes {
+			// 未配置拨号器时 Gost 默认使用 tcp
+			connector, dialer := "", "tcp"
+			if n.Connector != nil {
+				connector = n.Connector.Type
+			}
+			if n.Dialer != nil && n.Dialer.Type != "" {
+				dialer = n.Dialer.Type
+			}
+			result = append(result, fmt.Sprintf("%d:%s+%s://%s", i, connector, dialer, n.Addr))
+		}
+	}
+	return result
+}
@@ -0,0 +1,651 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/pkg/logger"
+	"gost-panel/pkg/mailer"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// reportCheckInterval 定时报表检查间隔
+	reportCheckInterval = 10 * time.Minute
+	// reportRetryInterval 发送失败后的重试间隔
+	reportRetryInterval = time.Hour
+	// reportTableLimit 节点、规则明细表最多行数
+	reportTableLimit = 100
+	// reportTopLimit 流量排行条数
+	reportTopLimit = 10
+	// reportStatusChangeLimit 状态变更最多条数
+	reportStatusChangeLimit = 100
+)
+
+// ReportKind 报表类型
+type ReportKind string
+
+const (
+	ReportKindDaily   ReportKind = "daily"   // 日报（前一天）
+	ReportKindWeekly  ReportKind = "weekly"  // 周报（上周一至周日）
+	ReportKindMonthly ReportKind = "monthly" // 月报（上个自然月）
+)
+
+// reportKinds 定时检查的报表类型
+var reportKinds = []ReportKind{ReportKindDaily, ReportKindWeekly, ReportKindMonthly}
+
+// title 报表名称
+func (k ReportKind) title() string {
+	switch k {
+	case ReportKindWeekly:
+		return "流量周报"
+	case ReportKindMonthly:
+		return "流量月报"
+	default:
+		return "流量日报"
+	}
+}
+
+// ReportService 定时流量报表服务
+// 每个周期结束后按配置的时间把上一周期的流量报表以 HTML 邮件发给收件人
+type ReportService struct {
+	sysRepo     *repository.SystemConfigRepository
+	nodeRepo    *repository.NodeRepository
+	ruleRepo    *repository.RuleRepository
+	tunnelRepo  *repository.TunnelRepository
+	trafficRepo *repository.TrafficRepository
+	clientRepo  *repository.RuleClientRepository
+	historyRepo *repository.NodeStatusHistoryRepository
+	stats       *StatsService
+
+	mu       sync.Mutex
+	failedAt map[ReportKind]time.Time // 最近一次发送失败时间，失败后按重试间隔重试
+
+	stopChan chan struct{}
+}
+
+// NewReportService 创建定时流量报表服务
+func NewReportService(db *gorm.DB) *ReportService {
+	return &ReportService{
+		sysRepo:     repository.NewSystemConfigRepository(db),
+		nodeRepo:    repository.NewNodeRepository(db),
+		ruleRepo:    repository.NewRuleRepository(db),
+		tunnelRepo:  repository.NewTunnelRepository(db),
+		trafficRepo: repository.NewTrafficRepository(db),
+		clientRepo:  repository.NewRuleClientRepository(db),
+		historyRepo: repository.NewNodeStatusHistoryRepository(db),
+		stats:       NewStatsService(db),
+		failedAt:    make(map[ReportKind]time.Time),
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start 启动定时报表任务
+func (s *ReportService) Start() {
+	go func() {
+		// 启动时先检查一次
+		s.processReports(time.Now())
+
+		ticker := time.NewTicker(reportCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.processReports(time.Now())
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+	logger.Info("定时报表服务已启动")
+}
+
+// Stop 停止定时报表任务
+func (s *ReportService) Stop() {
+	close(s.stopChan)
+	logger.Info("定时报表服务已停止")
+}
+
+// processReports 发送到期的报表，发送成功后记录已发送的周期
+func (s *ReportService) processReports(now time.Time) {
+	cfg, err := s.sysRepo.Get()
+	if err != nil {
+		logger.Errorf("定时报表检查失败: 获取系统配置错误: %v", err)
+		return
+	}
+
+	for _, kind := range reportKinds {
+		if !reportDue(cfg, kind, now) || !s.canRetry(kind, now) {
+			continue
+		}
+		start, end := reportPeriod(kind, now)
+		recipients, err := parseRecipients(cfg.ReportRecipients)
+		if err == nil && len(recipients) == 0 {
+			err = errors.ErrReportRecipientsEmpty
+		}
+		if err == nil {
+			err = s.send(cfg, kind, start, end, recipients, now)
+		}
+		if err != nil {
+			logger.Errorf("发送%s失败: %v", kind.title(), err)
+			s.setFailed(kind, now)
+			continue
+		}
+		s.setFailed(kind, time.Time{})
+		if err = s.sysRepo.UpdateColumn(reportSentColumn(kind), start); err != nil {
+			logger.Errorf("记录%s发送状态失败: %v", kind.title(), err)
+			continue
+		}
+		logger.Infof("%s已发送: %s, 收件人 %d 个", kind.title(), reportPeriodLabel(kind, start, end), len(recipients))
+	}
+}
+
+// canRetry 上次发送失败后是否已到重试时间
+func (s *ReportService) canRetry(kind ReportKind, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed, ok := s.failedAt[kind]
+	return !ok || now.Sub(failed) >= reportRetryInterval
+}
+
+// setFailed 记录发送失败时间，传入零值表示发送成功
+func (s *ReportService) setFailed(kind ReportKind, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if at.IsZero() {
+		delete(s.failedAt, kind)
+		return
+	}
+	s.failedAt[kind] = at
+}
+
+// SendNow 立即发送上一周期的报表（不影响定时发送记录）
+// 指定收件人时只发给该收件人，否则发给配置的收件人
+func (s *ReportService) SendNow(req *dto.SendReportReq) error {
+	cfg, err := s.sysRepo.Get()
+	if err != nil {
+		return err
+	}
+	recipients := cfg.ReportRecipients
+	if req.ToEmail != "" {
+		recipients = req.ToEmail
+	}
+	to, err := parseRecipients(recipients)
+	if err != nil {
+		return err
+	}
+	if len(to) == 0 {
+		return errors.ErrReportRecipientsEmpty
+	}
+
+	now := time.Now()
+	kind := ReportKind(req.Kind)
+	start, end := reportPeriod(kind, now)
+	return s.send(cfg, kind, start, end, to, now)
+}
+
+// send 生成并发送报表
+func (s *ReportService) send(cfg *model.SystemConfig, kind ReportKind, start, end time.Time, to []string, now time.Time) error {
+	m, err := newSystemMailer(cfg)
+	if err != nil {
+		return err
+	}
+	data, err := s.build(kind, start, end, now)
+	if err != nil {
+		return err
+	}
+	data.SiteTitle = cfg.SiteTitle
+	if data.SiteTitle == "" {
+		data.SiteTitle = "Gost Panel"
+	}
+	html, err := renderReport(data)
+	if err != nil {
+		return err
+	}
+
+	err = m.Send(&mailer.Message{
+		To:      to,
+		Subject: fmt.Sprintf("[%s] %s %s", data.SiteTitle, data.Title, data.Period),
+		Text:    reportText(data),
+		HTML:    html,
+	})
+	if err != nil {
+		return smtpError(err)
+	}
+	return nil
+}
+
+// reportPeriod 报表统计的时间段 [start, end)（本地时区的自然日、周、月，返回 UTC）
+func reportPeriod(kind ReportKind, now time.Time) (start, end time.Time) {
+	today := dayBucket(now).In(time.Local)
+	switch kind {
+	case ReportKindWeekly:
+		end = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		start = end.AddDate(0, 0, -7)
+	case ReportKindMonthly:
+		end = today.AddDate(0, 0, 1-today.Day())
+		start = end.AddDate(0, -1, 0)
+	default:
+		end = today
+		start = end.AddDate(0, 0, -1)
+	}
+	return start.UTC(), end.UTC()
+}
+
+// reportPeriodLabel 报表时间段的显示文本
+func reportPeriodLabel(kind ReportKind, start, end time.Time) string {
+	start, last := start.In(time.Local), end.In(time.Local).AddDate(0, 0, -1)
+	switch kind {
+	case ReportKindWeekly:
+		return start.Format("2006-01-02") + " ~ " + last.Format("2006-01-02")
+	case ReportKindMonthly:
+		return start.Format("2006-01")
+	default:
+		return start.Format("2006-01-02")
+	}
+}
+
+// reportDue 报表是否到期：已启用、上一周期结束后已到发送时间且该周期尚未发送
+func reportDue(cfg *model.SystemConfig, kind ReportKind, now time.Time) bool {
+	var enabled bool
+	var sentAt time.Time
+	switch kind {
+	case ReportKindDaily:
+		enabled, sentAt = cfg.ReportDaily, cfg.ReportDailySentAt
+	case ReportKindWeekly:
+		enabled, sentAt = cfg.ReportWeekly, cfg.ReportWeeklySentAt
+	case ReportKindMonthly:
+		enabled, sentAt = cfg.ReportMonthly, cfg.ReportMonthlySentAt
+	}
+	if !enabled {
+		return false
+	}
+	start, end := reportPeriod(kind, now)
+	sendAt := end.Add(time.Duration(cfg.ReportHour) * time.Hour)
+	return !now.Before(sendAt) && sentAt.Before(start)
+}
+
+// reportSentColumn 记录报表已发送周期的配置字段
+func reportSentColumn(kind ReportKind) string {
+	return "report_" + string(kind) + "_sent_at"
+}
+
+// reportData 报表内容
+type reportData struct {
+	SiteTitle   string
+	Title       string
+	Period      string
+	GeneratedAt string
+
+	Total    TrafficTotal // 本期流量（规则流量之和）
+	Previous TrafficTotal // 上一周期流量
+	Change   string       // 较上一周期的变化
+
+	Nodes        []reportRow
+	NodesOmitted int
+	Rules        []reportRow
+	RulesOmitted int
+
+	TopRules   []reportRow
+	TopClients []reportClient
+
+	StatusChanges   []reportStatusChange
+	StatusTruncated bool
+	Problems        []ProblemItem
+}
+
+// reportRow 节点或规则的流量明细
+type reportRow struct {
+	Name        string
+	Entry       string // 规则的入口节点或隧道
+	Status      string
+	Deleted     bool
+	InputBytes  int64
+	OutputBytes int64
+	TotalBytes  int64
+	Connections int64
+	Share       float64 // 占本期总流量的百分比
+}
+
+// reportClient 期间活跃的客户端
+type reportClient struct {
+	Client       string
+	Rule         string
+	TotalBytes   int64 // 累计流量
+	TotalConns   int64
+	CurrentConns int64
+	LastSeenAt   string
+}
+
+// reportStatusChange 节点状态变更
+type reportStatusChange struct {
+	Time   string
+	Node   string
+	From   string
+	To     string
+	Reason string
+}
+
+// build 统计报表内容，流量取天粒度采样
+func (s *ReportService) build(kind ReportKind, start, end, now time.Time) (*reportData, error) {
+	data := &reportData{
+		Title:       kind.title(),
+		Period:      reportPeriodLabel(kind, start, end),
+		GeneratedAt: now.In(time.Local).Format("2006-01-02 15:04"),
+	}
+
+	var err error
+	if data.Total, err = s.sumTraffic(start, end); err != nil {
+		return nil, err
+	}
+	prevStart, prevEnd := reportPeriod(kind, start)
+	if data.Previous, err = s.sumTraffic(prevStart, prevEnd); err != nil {
+		return nil, err
+	}
+	data.Change = trafficChange(data.Total.TotalBytes, data.Previous.TotalBytes)
+
+	nodes, err := s.nodeRepo.FindAllWithDeleted()
+	if err != nil {
+		return nil, err
+	}
+	nodeNames := make(map[uint]string, len(nodes))
+	nodeTraffic, err := s.trafficRepo.SumByResource(model.TrafficResourceNode, model.TrafficPeriodDay, start, end)
+	if err != nil {
+		return nil, err
+	}
+	var nodeRows []reportRow
+	for _, n := range nodes {
+		nodeNames[n.ID] = n.Name
+		row := reportRow{Name: n.Name, Status: nodeStatusText(n.Status), Deleted: n.DeletedAt.Valid}
+		switch {
+		case n.Maintenance:
+			row.Status = "维护中"
+		case n.Status == model.NodeStatusOnline:
+			row.Status = "在线"
+		}
+		if !fillReportRow(&row, nodeTraffic[n.ID], data.Total.TotalBytes) && row.Deleted {
+			continue
+		}
+		nodeRows = append(nodeRows, row)
+	}
+	sortReportRows(nodeRows)
+	data.Nodes, data.NodesOmitted = limitReportRows(nodeRows, reportTableLimit)
+
+	tunnels, err := s.tunnelRepo.FindAllWithDeleted()
+	if err != nil {
+		return nil, err
+	}
+	tunnelNames := make(map[uint]string, len(tunnels))
+	for _, t := range tunnels {
+		tunnelNames[t.ID] = t.Name
+	}
+	rules, err := s.ruleRepo.FindAllWithDeleted()
+	if err != nil {
+		return nil, err
+	}
+	ruleNames := make(map[uint]string, len(rules))
+	ruleTraffic, err := s.trafficRepo.SumByResource(model.TrafficResourceRule, model.TrafficPeriodDay, start, end)
+	if err != nil {
+		return nil, err
+	}
+	var ruleRows []reportRow
+	for _, r := range rules {
+		ruleNames[r.ID] = r.Name
+		row := reportRow{Name: r.Name, Status: ruleStatusText(r.Status), Deleted: r.DeletedAt.Valid}
+		switch {
+		case r.TunnelID != nil:
+			row.Entry = "隧道 " + tunnelNames[*r.TunnelID]
+		case r.NodeID != nil:
+			row.Entry = nodeNames[*r.NodeID]
+		}
+		if !fillReportRow(&row, ruleTraffic[r.ID], data.Total.TotalBytes) && row.Deleted {
+			continue
+		}
+		ruleRows = append(ruleRows, row)
+	}
+	sortReportRows(ruleRows)
+	for _, row := range ruleRows {
+		if row.TotalBytes == 0 || len(data.TopRules) >= reportTopLimit {
+			break
+		}
+		data.TopRules = append(data.TopRules, row)
+	}
+	data.Rules, data.RulesOmitted = limitReportRows(ruleRows, reportTableLimit)
+
+	clients, err := s.clientRepo.FindTopActive(start, reportTopLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range clients {
+		data.TopClients = append(data.TopClients, reportClient{
+			Client:       c.Client,
+			Rule:         ruleNames[c.RuleID],
+			TotalBytes:   c.TotalBytes,
+			TotalConns:   c.TotalConns,
+			CurrentConns: c.CurrentConns,
+			LastSeenAt:   c.LastSeenAt.In(time.Local).Format("2006-01-02 15:04"),
+		})
+	}
+
+	history, err := s.historyRepo.FindBetween(start, end, reportStatusChangeLimit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > reportStatusChangeLimit {
+		history, data.StatusTruncated = history[:reportStatusChangeLimit], true
+	}
+	for _, h := range history {
+		data.StatusChanges = append(data.StatusChanges, reportStatusChange{
+			Time:   h.CreatedAt.In(time.Local).Format("01-02 15:04"),
+			Node:   nodeNames[h.NodeID],
+			From:   nodeStatusText(h.FromStatus),
+			To:     nodeStatusText(h.ToStatus),
+			Reason: h.Reason,
+		})
+	}
+
+	// 当前异常对象（发送时的状态）
+	currentNodes, err := s.nodeRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	currentRules, err := s.ruleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	currentTunnels, err := s.tunnelRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	if data.Problems, err = s.stats.problems(currentNodes, currentRules, currentTunnels); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// sumTraffic 统计时间段内的规则流量之和（包含已删除规则）
+func (s *ReportService) sumTraffic(start, end time.Time) (TrafficTotal, error) {
+	t, err := s.trafficRepo.SumByType(model.TrafficResourceRule, model.TrafficPeriodDay, start, end)
+	if err != nil {
+		return TrafficTotal{}, err
+	}
+	return TrafficTotal{
+		InputBytes:  t.InputBytes,
+		OutputBytes: t.OutputBytes,
+		TotalBytes:  t.InputBytes + t.OutputBytes,
+		Connections: t.Connections,
+	}, nil
+}
+
+// fillReportRow 填充流量明细，返回期间是否有流量或连接
+func fillReportRow(row *reportRow, t *repository.TrafficTotals, total int64) bool {
+	if t == nil {
+		return false
+	}
+	row.InputBytes = t.InputBytes
+	row.OutputBytes = t.OutputBytes
+	row.TotalBytes = t.InputBytes + t.OutputBytes
+	row.Connections = t.Connections
+	if total > 0 {
+		row.Share = float64(row.TotalBytes) * 100 / float64(total)
+	}
+	return row.TotalBytes > 0 || row.Connections > 0
+}
+
+// sortReportRows 按流量降序排列，相同时按名称
+func sortReportRows(rows []reportRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].TotalBytes != rows[j].TotalBytes {
+			return rows[i].TotalBytes > rows[j].TotalBytes
+		}
+		return rows[i].Name < rows[j].Name
+	})
+}
+
+// limitReportRows 截取前 limit 行，返回省略的行数
+func limitReportRows(rows []reportRow, limit int) ([]reportRow, int) {
+	if len(rows) <= limit {
+		return rows, 0
+	}
+	return rows[:limit], len(rows) - limit
+}
+
+// trafficChange 较上一周期的变化
+func trafficChange(cur, prev int64) string {
+	if prev == 0 {
+		if cur == 0 {
+			return "持平"
+		}
+		return "上期无流量"
+	}
+	pct := float64(cur-prev) * 100 / float64(prev)
+	switch {
+	case pct > 0:
+		return fmt.Sprintf("增长 %.1f%%", pct)
+	case pct < 0:
+		return fmt.Sprintf("下降 %.1f%%", -pct)
+	default:
+		return "持平"
+	}
+}
+
+// ruleStatusText 规则状态的中文描述
+func ruleStatusText(status model.RuleStatus) string {
+	switch status {
+	case model.RuleStatusRunning:
+		return "运行中"
+	case model.RuleStatusStopped:
+		return "已停止"
+	case model.RuleStatusError:
+		return "错误"
+	default:
+		return string(status)
+	}
+}
+
+// formatBytes 流量的可读格式
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", value, "KMGTP"[exp])
+}
+
+// reportText 纯文本摘要（不支持 HTML 的邮件客户端显示）
+func reportText(data *reportData) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\r\n\r\n", data.SiteTitle, data.Title, data.Period)
+	fmt.Fprintf(&b, "总流量: %s (入站 %s, 出站 %s), 较上期%s\r\n",
+		formatBytes(data.Total.TotalBytes), formatBytes(data.Total.InputBytes), formatBytes(data.Total.OutputBytes), data.Change)
+	fmt.Fprintf(&b, "新建连接: %d\r\n", data.Total.Connections)
+	fmt.Fprintf(&b, "节点状态变更: %d 次, 当前异常: %d 个\r\n", len(data.StatusChanges), len(data.Problems))
+	b.WriteString("\r\n完整报表请使用支持 HTML 的邮件客户端查看。\r\n")
+	return b.String()
+}
+
+// renderReport 渲染 HTML 报表
+func renderReport(data *reportData) (string, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染报表失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bytes":   formatBytes,
+	"inc":     func(i int) int { return i + 1 },
+	"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", v) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>{{.Title}}</title></head>
+<body style="margin:0;padding:24px;background:#f5f7fa;font-family:-apple-system,'Segoe UI','PingFang SC','Microsoft YaHei',sans-serif;color:#303133;font-size:14px;">
+<div style="max-width:860px;margin:0 auto;background:#fff;border-radius:6px;padding:24px;">
+<h2 style="margin:0 0 4px;">{{.SiteTitle}} {{.Title}}</h2>
+<div style="color:#909399;margin-bottom:20px;">统计周期: {{.Period}} · 生成时间: {{.GeneratedAt}}</div>
+
+<table cellpadding="0" cellspacing="0" style="width:100%;margin-bottom:24px;"><tr>
+<td style="padding:12px;background:#ecf5ff;border-radius:4px;"><div style="color:#909399;">总流量</div><div style="font-size:20px;font-weight:bold;">{{bytes .Total.TotalBytes}}</div><div style="color:#909399;">较上期{{.Change}}</div></td>
+<td style="width:12px;"></td>
+<td style="padding:12px;background:#f0f9eb;border-radius:4px;"><div style="color:#909399;">入站 / 出站</div><div style="font-size:20px;font-weight:bold;">{{bytes .Total.InputBytes}} / {{bytes .Total.OutputBytes}}</div><div style="color:#909399;">上期 {{bytes .Previous.TotalBytes}}</div></td>
+<td style="width:12px;"></td>
+<td style="padding:12px;background:#fdf6ec;border-radius:4px;"><div style="color:#909399;">新建连接</div><div style="font-size:20px;font-weight:bold;">{{.Total.Connections}}</div><div style="color:#909399;">上期 {{.Previous.Connections}}</div></td>
+</tr></table>
+
+<h3>流量排行</h3>
+{{if .TopRules}}<table cellpadding="6" cellspacing="0" border="1" style="width:100%;border-collapse:collapse;border-color:#ebeef5;margin-bottom:12px;">
+<tr style="background:#f5f7fa;"><th align="left">#</th><th align="left">规则</th><th align="left">入口</th><th align="right">流量</th><th align="right">占比</th></tr>
+{{range $i, $r := .TopRules}}<tr><td>{{inc $i}}</td><td>{{$r.Name}}{{if $r.Deleted}} (已删除){{end}}</td><td>{{$r.Entry}}</td><td align="right">{{bytes $r.TotalBytes}}</td><td align="right">{{percent $r.Share}}</td></tr>
+{{end}}</table>{{else}}<p style="color:#909399;">本期无规则流量</p>{{end}}
+{{if .TopClients}}<table cellpadding="6" cellspacing="0" border="1" style="width:100%;border-collapse:collapse;border-color:#ebeef5;margin-bottom:24px;">
+<tr style="background:#f5f7fa;"><th align="left">客户端</th><th align="left">规则</th><th align="right">累计流量</th><th align="right">累计连接</th><th align="right">当前连接</th><th align="left">最近活跃</th></tr>
+{{range .TopClients}}<tr><td>{{.Client}}</td><td>{{.Rule}}</td><td align="right">{{bytes .TotalBytes}}</td><td align="right">{{.TotalConns}}</td><td align="right">{{.CurrentConns}}</td><td>{{.LastSeenAt}}</td></tr>
+{{end}}</table>{{end}}
+
+<h3>节点流量</h3>
+{{if .Nodes}}<table cellpadding="6" cellspacing="0" border="1" style="width:100%;border-collapse:collapse;border-color:#ebeef5;">
+<tr style="background:#f5f7fa;"><th align="left">节点</th><th align="left">状态</th><th align="right">入站</th><th align="right">出站</th><th align="right">合计</th><th align="right">连接</th></tr>
+{{range .Nodes}}<tr><td>{{.Name}}{{if .Deleted}} (已删除){{end}}</td><td>{{.Status}}</td><td align="right">{{bytes .InputBytes}}</td><td align="right">{{bytes .OutputBytes}}</td><td align="right">{{bytes .TotalBytes}}</td><td align="right">{{.Connections}}</td></tr>
+{{end}}</table>{{if .NodesOmitted}}<p style="color:#909399;">另有 {{.NodesOmitted}} 个节点未列出</p>{{end}}{{else}}<p style="color:#909399;">暂无节点</p>{{end}}
+
+<h3>规则流量</h3>
+{{if .Rules}}<table cellpadding="6" cellspacing="0" border="1" style="width:100%;border-collapse:collapse;border-color:#ebeef5;">
+<tr style="background:#f5f7fa;"><th align="left">规则</th><th align="left">入口</th><th align="left">状态</th><th align="right">入站</th><th align="right">出站</th><th align="right">合计</th><th align="right">连接</th></tr>
+{{range .Rules}}<tr><td>{{.Name}}{{if .Deleted}} (已删除){{end}}</td><td>{{.Entry}}</td><td>{{.Status}}</td><td align="right">{{bytes .InputBytes}}</td><td align="right">{{bytes .OutputBytes}}</td><td align="right">{{bytes .TotalBytes}}</td><td align="right">{{.Connections}}</td></tr>
+{{end}}</table>{{if .RulesOmitted}}<p style="color:#909399;">另有 {{.RulesOmitted}} 条规则未列出</p>{{end}}{{else}}<p style="color:#909399;">暂无规则</p>{{end}}
+
+<h3>节点状态变更</h3>
+{{if .StatusChanges}}<table cellpadding="6" cellspacing="0" border="1" style="width:100%;border-collapse:collapse;border-color:#ebeef5;">
+<tr style="background:#f5f7fa;"><th align="left">时间</th><th align="left">节点</th><th align="left">变更</th><th align="left">原因</th></tr>
+{{range .StatusChanges}}<tr><td>{{.Time}}</td><td>{{.Node}}</td><td>{{.From}} → {{.To}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{if .StatusTruncated}}<p style="color:#909399;">仅列出前 {{len .StatusChanges}} 条</p>{{end}}{{else}}<p style="color:#909399;">本期无状态变更</p>{{end}}
+
+<h3>当前异常</h3>
+{{if .Problems}}<table cellpadding="6" cellspacing="0" border="1" style="width:100%;border-collapse:collapse;border-color:#ebeef5;">
+<tr style="background:#f5f7fa;"><th align="left">类型</th><th align="left">名称</th><th align="left">状态</th><th align="left">原因</th></tr>
+{{range .Problems}}<tr><td>{{.Type}}</td><td>{{.Name}}</td><td>{{.Status}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{else}}<p style="color:#67c23a;">所有节点、规则和隧道运行正常</p>{{end}}
+
+<h3>配额状态</h3>
+<p style="color:#909399;">面板未配置流量配额，无配额使用情况。</p>
+</div>
+</body>
+</html>
+`))
@@ -2,8 +2,11 @@
 package service
 
 import (
+	"context"
 	stderrors "errors"
 	"fmt"
+	"net"
+	"strconv"
 
 	"gost-panel/internal/dto"
 	"gost-panel/internal/errors"
@@ -25,6 +28,8 @@ type RuleService struct {
 	nodeRepo      *repository.NodeRepository
 	tunnelRepo    *repository.TunnelRepository
 	sysRepo       *repository.SystemConfigRepository
+	counterRepo   *repository.ServiceCounterRepository
+	clientRepo    *repository.RuleClientRepository
 	logService    *LogService
 	tunnelService *TunnelService
 }
@@ -36,6 +41,8 @@ func NewRuleService(db *gorm.DB) *RuleService {
 		nodeRepo:      repository.NewNodeRepository(db),
 		tunnelRepo:    repository.NewTunnelRepository(db),
 		sysRepo:       repository.NewSystemConfigRepository(db),
+		counterRepo:   repository.NewServiceCounterRepository(db),
+		clientRepo:    repository.NewRuleClientRepository(db),
 		logService:    NewLogService(db),
 		tunnelService: NewTunnelService(db),
 	}
@@ -80,6 +87,15 @@ func (s *RuleService) Create(req *dto.CreateRuleReq, userID uint, username strin
 		return nil, errors.ErrRuleTypeInvalid
 	}
 
+	// 维护中的节点不允许新建规则
+	entryNode, err := s.nodeRepo.FindByID(entryNodeID)
+	if err != nil {
+		return nil, errors.ErrNodeNotFound
+	}
+	if entryNode.Maintenance {
+		return nil, errors.ErrNodeMaintenance
+	}
+
 	// 检查端口是否已被使用
 	exists, err := s.ruleRepo.ExistsByPort(entryNodeID, req.ListenPort)
 	if err != nil {
@@ -124,7 +140,7 @@ func (s *RuleService) Create(req *dto.CreateRuleReq, userID uint, username strin
 }
 
 // Update 更新规则（不能修改类型和入口）
-func (s *RuleService) Update(id uint, req *dto.UpdateRuleReq, userID uint, username string, ip, userAgent string) (*model.GostRule, error) {
+func (s *RuleService) Update(ctx context.Context, id uint, req *dto.UpdateRuleReq, userID uint, username string, ip, userAgent string) (*model.GostRule, error) {
 	rule, err := s.ruleRepo.FindByID(id)
 	if err != nil {
 		if stderrors.Is(err, gorm.ErrRecordNotFound) {
@@ -151,6 +167,7 @@ func (s *RuleService) Update(id uint, req *dto.UpdateRuleReq, userID uint, usern
 	}
 
 	// 更新规则（不修改类型和入口）
+	oldPort := rule.ListenPort
 	rule.Name = req.Name
 	rule.Protocol = model.RuleProtocol(req.Protocol)
 	rule.ListenPort = req.ListenPort
@@ -173,11 +190,14 @@ func (s *RuleService) Update(id uint, req *dto.UpdateRuleReq, userID uint, usern
 		ip,
 		userAgent)
 
+	// 同步到联动镜像规则
+	s.syncMirrors(ctx, rule, oldPort)
+
 	return rule, nil
 }
 
 // Delete 删除规则
-func (s *RuleService) Delete(id uint, userID uint, username string, ip, userAgent string) error {
+func (s *RuleService) Delete(ctx context.Context, id uint, userID uint, username string, ip, userAgent string) error {
 	rule, err := s.ruleRepo.FindByID(id)
 	if err != nil {
 		if stderrors.Is(err, gorm.ErrRecordNotFound) {
@@ -188,7 +208,7 @@ func (s *RuleService) Delete(id uint, userID uint, username string, ip, userAgen
 
 	// 如果正在运行，先停止
 	if rule.Status == model.RuleStatusRunning {
-		if err = s.Stop(id, userID, username, ip, userAgent); err != nil {
+		if err = s.Stop(ctx, id, userID, username, ip, userAgent); err != nil {
 			logger.Warnf("停止规则失败: %v", err)
 		}
 	}
@@ -198,6 +218,11 @@ func (s *RuleService) Delete(id uint, userID uint, username string, ip, userAgen
 		return err
 	}
 
+	// 解除联动镜像，镜像规则保留为独立规则
+	_ = s.ruleRepo.ClearMirrorOf(id)
+	_ = s.counterRepo.Delete(ruleEntryNodeID(rule), ruleServiceName(rule))
+	_ = s.clientRepo.DeleteByRuleID(id)
+
 	s.logService.Record(
 		userID,
 		username,
@@ -224,6 +249,30 @@ func (s *RuleService) GetByID(id uint) (*model.GostRule, error) {
 	return rule, nil
 }
 
+// TopClients 获取规则流量最多的客户端
+// 规则未运行时客户端的当前连接数均为 0
+func (s *RuleService) TopClients(id uint, req *dto.RuleClientsReq) ([]model.RuleClient, error) {
+	rule, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = 10
+	}
+	clients, err := s.clientRepo.FindTop(id, limit)
+	if err != nil {
+		return nil, err
+	}
+	if rule.Status != model.RuleStatusRunning {
+		for i := range clients {
+			clients[i].CurrentConns = 0
+		}
+	}
+	return clients, nil
+}
+
 // List 获取规则列表
 func (s *RuleService) List(req *dto.RuleListReq) ([]model.GostRule, int64, error) {
 	req.SetDefaults()
@@ -258,7 +307,7 @@ func (s *RuleService) List(req *dto.RuleListReq) ([]model.GostRule, int64, error
 }
 
 // Start 启动规则
-func (s *RuleService) Start(id uint, userID uint, username string, ip, userAgent string) error {
+func (s *RuleService) Start(ctx context.Context, id uint, userID uint, username string, ip, userAgent string) error {
 	rule, err := s.ruleRepo.FindByID(id)
 	if err != nil {
 		return err
@@ -266,6 +315,7 @@ func (s *RuleService) Start(id uint, userID uint, username string, ip, userAgent
 
 	// 已在运行中则跳过
 	if rule.Status == model.RuleStatusRunning {
+		_ = s.ruleRepo.UpdateDesiredRunning(id, true)
 		return nil
 	}
 
@@ -280,19 +330,22 @@ func (s *RuleService) Start(id uint, userID uint, username string, ip, userAgent
 	}
 
 	client := utils.GetGostClient(node)
-	serviceName := fmt.Sprintf("rule-%d", rule.ID)
+	// 沿用已记录的服务名称（导入的规则使用节点上原有的名称）
+	serviceName := ruleServiceName(rule)
 
 	// 根据规则类型处理
 	if rule.Type == model.RuleTypeTunnel {
-		if err = s.startTunnelRule(rule, client, serviceName); err != nil {
+		if err = s.startTunnelRule(ctx, node, rule, client, serviceName); err != nil {
 			return err
 		}
 	} else {
-		if err = s.startForwardRule(rule, client, serviceName); err != nil {
+		if err = s.startForwardRule(ctx, node, rule, client, serviceName); err != nil {
 			return err
 		}
 	}
 
+	_ = s.ruleRepo.UpdateDesiredRunning(id, true)
+
 	s.logService.Record(
 		userID,
 		username,
@@ -308,13 +361,13 @@ func (s *RuleService) Start(id uint, userID uint, username string, ip, userAgent
 }
 
 // startForwardRule 启动端口转发规则（直连目标）
-func (s *RuleService) startForwardRule(rule *model.GostRule, client *gost.Client, serviceName string) error {
+func (s *RuleService) startForwardRule(ctx context.Context, node *model.GostNode, rule *model.GostRule, client *gost.Client, serviceName string) error {
 	// 端口转发没有 Chain ID
-	return s.buildAndStartService(client, rule, serviceName, "")
+	return s.buildAndStartService(ctx, node, client, rule, serviceName, "")
 }
 
 // startTunnelRule 启动隧道转发规则（通过隧道链路）
-func (s *RuleService) startTunnelRule(rule *model.GostRule, client *gost.Client, serviceName string) error {
+func (s *RuleService) startTunnelRule(ctx context.Context, node *model.GostNode, rule *model.GostRule, client *gost.Client, serviceName string) error {
 	if rule.TunnelID == nil {
 		return errors.ErrTunnelRequired
 	}
@@ -332,21 +385,24 @@ func (s *RuleService) startTunnelRule(rule *model.GostRule, client *gost.Client,
 
 	// 检查隧道是否有 Chain ID
 	if tunnel.ChainID == "" {
-		_ = s.ruleRepo.UpdateStatus(rule.ID, model.RuleStatusError)
+		_ = s.ruleRepo.UpdateStatusMsg(rule.ID, model.RuleStatusError, errors.ErrTunnelChainNotFound.Message)
 		return errors.ErrTunnelChainNotFound
 	}
 
 	// 使用通用逻辑启动服务，传入 Chain ID
-	return s.buildAndStartService(client, rule, serviceName, tunnel.ChainID)
+	return s.buildAndStartService(ctx, node, client, rule, serviceName, tunnel.ChainID)
 }
 
 // Stop 停止规则
-func (s *RuleService) Stop(id uint, userID uint, username string, ip, userAgent string) error {
+func (s *RuleService) Stop(ctx context.Context, id uint, userID uint, username string, ip, userAgent string) error {
 	rule, err := s.ruleRepo.FindByID(id)
 	if err != nil {
 		return err
 	}
 
+	// 用户主动停止，清除期望运行状态，避免节点恢复时被自动重建
+	_ = s.ruleRepo.UpdateDesiredRunning(id, false)
+
 	if rule.Status != model.RuleStatusRunning {
 		return nil
 	}
@@ -370,13 +426,13 @@ func (s *RuleService) Stop(id uint, userID uint, username string, ip, userAgent
 
 	// 删除服务
 	if rule.ServiceID != "" {
-		if err = client.DeleteService(rule.ServiceID); err != nil {
+		if err = client.DeleteService(ctx, rule.ServiceID); err != nil {
 			logger.Warnf("删除 Gost 服务失败: %v", err)
 		}
 	}
 
 	_ = s.ruleRepo.UpdateStatus(id, model.RuleStatusStopped)
-	_ = client.SaveConfig()
+	_ = client.SaveConfig(ctx)
 
 	s.logService.Record(
 		userID,
@@ -392,6 +448,78 @@ func (s *RuleService) Stop(id uint, userID uint, username string, ip, userAgent
 	return nil
 }
 
+// syncMirrors 将源规则的修改同步到联动镜像规则
+// 镜像的监听端口在克隆时可能被重映射，仅当其与源规则原端口一致且新端口在镜像入口节点空闲时才跟随修改
+func (s *RuleService) syncMirrors(ctx context.Context, source *model.GostRule, oldPort int) {
+	mirrors, err := s.ruleRepo.FindMirrors(source.ID)
+	if err != nil {
+		logger.Warnf("查询规则 %s 的联动镜像失败: %v", source.Name, err)
+		return
+	}
+
+	for i := range mirrors {
+		mirror := &mirrors[i]
+		mirror.Name = source.Name
+		mirror.Protocol = source.Protocol
+		mirror.Targets = source.Targets
+		mirror.Strategy = source.Strategy
+		mirror.EnableTLS = source.EnableTLS
+		mirror.Remark = source.Remark
+
+		if mirror.ListenPort == oldPort && source.ListenPort != oldPort {
+			exists, err := s.ruleRepo.ExistsByPort(s.getEntryNodeID(mirror), source.ListenPort, mirror.ID)
+			if err == nil && !exists {
+				mirror.ListenPort = source.ListenPort
+			} else {
+				logger.Warnf("镜像规则 %d 的端口 %d 在其节点上已被占用，保留原端口 %d", mirror.ID, source.ListenPort, mirror.ListenPort)
+			}
+		}
+
+		if err = s.ruleRepo.Update(mirror); err != nil {
+			logger.Warnf("同步镜像规则 %d 失败: %v", mirror.ID, err)
+			continue
+		}
+
+		// 运行中的镜像直接更新节点上的服务
+		if mirror.Status == model.RuleStatusRunning {
+			if err = s.updateRunningService(ctx, mirror); err != nil {
+				logger.Warnf("更新镜像规则 %d 的 Gost 服务失败: %v", mirror.ID, err)
+				_ = s.ruleRepo.UpdateStatus(mirror.ID, model.RuleStatusError)
+				continue
+			}
+		}
+		logger.Infof("已同步规则 %s 的修改到镜像规则 %d", source.Name, mirror.ID)
+	}
+}
+
+// updateRunningService 按规则当前配置更新节点上运行中的服务
+func (s *RuleService) updateRunningService(ctx context.Context, rule *model.GostRule) error {
+	node, err := s.nodeRepo.FindByID(s.getEntryNodeID(rule))
+	if err != nil {
+		return errors.ErrNodeNotFound
+	}
+	if node.Status == model.NodeStatusOffline {
+		return errors.ErrNodeOffline
+	}
+
+	var chainID string
+	if rule.Type == model.RuleTypeTunnel && rule.TunnelID != nil {
+		if chainID, err = s.tunnelService.GetChainID(*rule.TunnelID); err != nil {
+			return errors.ErrTunnelNotFound
+		}
+	}
+
+	svc := buildRuleService(rule, ruleServiceName(rule), chainID)
+	applyRuleObserver(svc, rule.ObserverID)
+
+	client := utils.GetGostClient(node)
+	if err = client.UpdateService(ctx, svc); err != nil {
+		return err
+	}
+	_ = client.SaveConfig(ctx)
+	return nil
+}
+
 // getEntryNodeID 获取规则的入口节点 ID
 func (s *RuleService) getEntryNodeID(rule *model.GostRule) uint {
 	if rule.Type == model.RuleTypeTunnel && rule.TunnelID != nil {
@@ -409,9 +537,9 @@ func (s *RuleService) getEntryNodeID(rule *model.GostRule) uint {
 }
 
 // setupRuleObserver 配置规则的观察器
-func (s *RuleService) setupRuleObserver(client *gost.Client, rule *model.GostRule, svc *gost.ServiceConfig) error {
+func (s *RuleService) setupRuleObserver(ctx context.Context, node *model.GostNode, client *gost.Client, rule *model.GostRule, svc *gost.ServiceConfig) error {
 	// 确保全局观察器存在
-	observerName, err := EnsureGlobalObserver(client, s.sysRepo)
+	observerName, err := EnsureGlobalObserver(ctx, client, node, s.sysRepo)
 	if err != nil {
 		return err
 	}
@@ -420,20 +548,36 @@ func (s *RuleService) setupRuleObserver(client *gost.Client, rule *model.GostRul
 	_ = s.ruleRepo.UpdateObserverID(rule.ID, observerName)
 
 	// 配置服务的观察器参数
-	if observerName != "" {
-		svc.Observer = observerName
-		if svc.Metadata == nil {
-			svc.Metadata = make(map[string]any)
+	applyRuleObserver(svc, observerName)
+	return nil
+}
+
+// applyRuleObserver 为服务配置设置观察器参数
+func applyRuleObserver(svc *gost.ServiceConfig, observerName string) {
+	if observerName == "" {
+		return
+	}
+	svc.Observer = observerName
+	if svc.Metadata == nil {
+		svc.Metadata = make(map[string]any)
+	}
+	svc.Metadata["enableStats"] = true
+	svc.Metadata["observer.period"] = "5s"
+	svc.Metadata["observer.resetTraffic"] = false
+
+	// 处理器级别观察器按客户端上报统计
+	if svc.Handler != nil {
+		svc.Handler.Observer = observerName
+		if svc.Handler.Metadata == nil {
+			svc.Handler.Metadata = make(map[string]any)
 		}
-		svc.Metadata["enableStats"] = true
-		svc.Metadata["observer.period"] = "5s"
-		svc.Metadata["observer.resetTraffic"] = false
+		svc.Handler.Metadata["observer.period"] = "5s"
+		svc.Handler.Metadata["observer.resetTraffic"] = false
 	}
-	return nil
 }
 
-// buildAndStartService 构建并启动 Gost 服务 (处理通用逻辑)
-func (s *RuleService) buildAndStartService(client *gost.Client, rule *model.GostRule, serviceName string, chainID string) error {
+// buildRuleService 根据规则生成 Gost 服务配置（不包含观察器，不产生副作用）
+func buildRuleService(rule *model.GostRule, serviceName string, chainID string) *gost.ServiceConfig {
 	targets := rule.Targets
 	strategy := rule.Strategy
 	if strategy == "" || len(targets) == 1 {
@@ -447,22 +591,49 @@ func (s *RuleService) buildAndStartService(client *gost.Client, rule *model.Gost
 		svc = gost.BuildUDPForwardService(serviceName, rule.ListenPort, targets, strategy)
 	}
 
+	// 导入的规则保留原绑定地址
+	if rule.ListenHost != "" {
+		svc.Addr = net.JoinHostPort(rule.ListenHost, strconv.Itoa(rule.ListenPort))
+	}
+
 	// 如果有 Chain ID，则关联（用于隧道转发）
 	if chainID != "" {
 		svc.Handler.Chain = chainID
 	}
 
+	return svc
+}
+
+// ruleServiceName 获取规则在 Gost 中的服务名称
+func ruleServiceName(rule *model.GostRule) string {
+	if rule.ServiceID != "" {
+		return rule.ServiceID
+	}
+	return fmt.Sprintf("rule-%d", rule.ID)
+}
+
+// buildAndStartService 构建并启动 Gost 服务 (处理通用逻辑)
+func (s *RuleService) buildAndStartService(ctx context.Context, node *model.GostNode, client *gost.Client, rule *model.GostRule, serviceName string, chainID string) error {
+	svc := buildRuleService(rule, serviceName, chainID)
+
 	// 配置观察器
-	if err := s.setupRuleObserver(client, rule, svc); err != nil {
+	if err := s.setupRuleObserver(ctx, node, client, rule, svc); err != nil {
 		return err
 	}
 
-	if err := client.CreateService(svc); err != nil {
-		_ = s.ruleRepo.UpdateStatus(rule.ID, model.RuleStatusError)
+	created, err := client.EnsureService(ctx, svc)
+	if err != nil {
+		_ = s.ruleRepo.UpdateStatusMsg(rule.ID, model.RuleStatusError, err.Error())
 		return errors.ErrRuleStartFailed
 	}
+	// 新建的服务计数从零开始，清除上次记住的累计值和客户端连接数；
+	// 服务仍在节点上运行（如网络中断后恢复、面板重启）时 Gost 保留累计值，保留记住的累计值以免重复计入
+	if created {
+		_ = s.counterRepo.Delete(node.ID, serviceName)
+		_ = s.clientRepo.ResetCurrentConns(rule.ID)
+	}
 
-	_ = client.SaveConfig()
+	_ = client.SaveConfig(ctx)
 	_ = s.ruleRepo.UpdateStatus(rule.ID, model.RuleStatusRunning)
 	_ = s.ruleRepo.UpdateServiceID(rule.ID, serviceName)
 
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gost-panel/internal/config"
+	"gost-panel/internal/metrics"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// NodeTask 按节点周期执行的后台任务
+type NodeTask struct {
+	Name     string        // 任务名称
+	Interval time.Duration // 执行间隔
+	// Run 对单个节点执行任务，返回错误表示节点不可达，该节点的此任务将按指数退避延后执行
+	// ctx 在调度器停止时取消，用于中止执行中的节点请求
+	Run func(ctx context.Context, node model.GostNode) error
+}
+
+// NodeScheduler 节点任务调度器
+// 健康检测、规则同步等按节点执行的后台任务共用一个有界工作池：
+// 同一任务同一节点同时只会有一个执行中，不可达节点按指数退避，队列满时丢弃本轮任务
+type NodeScheduler struct {
+	nodeRepo   *repository.NodeRepository
+	workers    int
+	maxBackoff time.Duration
+	queue      chan *nodeJob
+	busy       int64
+
+	mu       sync.Mutex
+	inFlight map[nodeJobKey]bool
+	backoff  map[nodeJobKey]*nodeBackoff
+	stats    map[string]*taskStats
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// nodeJobKey 任务 + 节点 唯一标识
+type nodeJobKey struct {
+	task   string
+	nodeID uint
+}
+
+// nodeJob 待执行的节点任务
+type nodeJob struct {
+	task       *NodeTask
+	node       model.GostNode
+	enqueuedAt time.Time
+}
+
+// nodeBackoff 节点退避状态
+type nodeBackoff struct {
+	failures int       // 连续失败次数
+	until    time.Time // 退避截止时间
+}
+
+// taskStats 任务运行统计
+type taskStats struct {
+	interval        time.Duration
+	runs            int64
+	failures        int64
+	skippedInFlight int64
+	skippedBackoff  int64
+	dropped         int64
+	totalLag        time.Duration
+	lastLag         time.Duration
+	maxLag          time.Duration
+	lastDuration    time.Duration
+	lastDispatchAt  time.Time
+}
+
+// SchedulerStats 调度器运行指标
+type SchedulerStats struct {
+	Workers       int                  `json:"workers"`        // 工作协程数
+	BusyWorkers   int64                `json:"busy_workers"`   // 正在执行任务的协程数
+	QueueLength   int                  `json:"queue_length"`   // 队列中等待的任务数
+	QueueCapacity int                  `json:"queue_capacity"` // 队列容量
+	InFlight      int                  `json:"in_flight"`      // 排队或执行中的任务数
+	BackoffNodes  int                  `json:"backoff_nodes"`  // 处于退避中的任务节点数
+	Tasks         []SchedulerTaskStats `json:"tasks"`          // 各任务统计
+}
+
+// SchedulerTaskStats 单个任务的运行指标
+type SchedulerTaskStats struct {
+	Name            string     `json:"name"`
+	IntervalSeconds float64    `json:"interval_seconds"`  // 执行间隔
+	Runs            int64      `json:"runs"`              // 执行次数
+	Failures        int64      `json:"failures"`          // 失败次数
+	SkippedInFlight int64      `json:"skipped_in_flight"` // 因上次未完成而跳过的次数
+	SkippedBackoff  int64      `json:"skipped_backoff"`   // 因退避而跳过的次数
+	Dropped         int64      `json:"dropped"`           // 因队列已满而丢弃的次数
+	LastLagMs       int64      `json:"last_lag_ms"`       // 最近一次排队等待时间
+	AvgLagMs        int64      `json:"avg_lag_ms"`        // 平均排队等待时间
+	MaxLagMs        int64      `json:"max_lag_ms"`        // 最大排队等待时间
+	LastDurationMs  int64      `json:"last_duration_ms"`  // 最近一次执行耗时
+	LastDispatchAt  *time.Time `json:"last_dispatch_at"`  // 最近一次派发时间
+}
+
+// NewNodeScheduler 创建节点任务调度器
+func NewNodeScheduler(db *gorm.DB, cfg config.SchedulerConfig) *NodeScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &NodeScheduler{
+		nodeRepo:   repository.NewNodeRepository(db),
+		workers:    cfg.Workers,
+		maxBackoff: time.Duration(cfg.MaxBackoff) * time.Second,
+		queue:      make(chan *nodeJob, cfg.QueueSize),
+		inFlight:   make(map[nodeJobKey]bool),
+		backoff:    make(map[nodeJobKey]*nodeBackoff),
+		stats:      make(map[string]*taskStats),
+		ctx:        ctx,
+		cancel:     cancel,
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start 启动工作协程
+func (s *NodeScheduler) Start() {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	logger.Infof("节点任务调度器已启动 (工作协程 %d, 队列容量 %d)", s.workers, cap(s.queue))
+}
+
+// Stop 停止调度器，取消执行中任务的节点请求并等待任务结束
+func (s *NodeScheduler) Stop() {
+	close(s.stopChan)
+	s.cancel()
+	s.wg.Wait()
+	logger.Info("节点任务调度器已停止")
+}
+
+// Register 注册周期任务，立即派发一次后按间隔派发
+func (s *NodeScheduler) Register(task *NodeTask) {
+	s.mu.Lock()
+	s.stats[task.Name] = &taskStats{interval: task.Interval}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(task.Interval)
+		defer ticker.Stop()
+
+		s.dispatch(task)
+		for {
+			select {
+			case <-ticker.C:
+				s.dispatch(task)
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// dispatch 为每个节点派发一次任务
+func (s *NodeScheduler) dispatch(task *NodeTask) {
+	nodes, _, err := s.nodeRepo.List(nil)
+	if err != nil {
+		logger.Errorf("[Scheduler] 任务 %s 获取节点列表失败: %v", task.Name, err)
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats[task.Name]
+	stats.lastDispatchAt = now
+
+	for _, node := range nodes {
+		key := nodeJobKey{task: task.Name, nodeID: node.ID}
+		if s.inFlight[key] {
+			stats.skippedInFlight++
+			continue
+		}
+		if b, ok := s.backoff[key]; ok && now.Before(b.until) {
+			stats.skippedBackoff++
+			continue
+		}
+
+		select {
+		case s.queue <- &nodeJob{task: task, node: node, enqueuedAt: now}:
+			s.inFlight[key] = true
+		default:
+			stats.dropped++
+		}
+	}
+}
+
+// worker 从队列中取出任务执行
+func (s *NodeScheduler) worker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case job := <-s.queue:
+			s.execute(job)
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// execute 执行单个任务并更新退避状态和统计
+func (s *NodeScheduler) execute(job *nodeJob) {
+	start := time.Now()
+	lag := start.Sub(job.enqueuedAt)
+
+	atomic.AddInt64(&s.busy, 1)
+	err := s.run(job)
+	atomic.AddInt64(&s.busy, -1)
+	duration := time.Since(start)
+	metrics.ObserveNodeTask(job.task.Name, job.node.ID, job.node.Name, duration, err)
+
+	key := nodeJobKey{task: job.task.Name, nodeID: job.node.ID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, key)
+
+	stats := s.stats[job.task.Name]
+	stats.runs++
+	stats.totalLag += lag
+	stats.lastLag = lag
+	if lag > stats.maxLag {
+		stats.maxLag = lag
+	}
+	stats.lastDuration = duration
+
+	if err == nil {
+		delete(s.backoff, key)
+		return
+	}
+
+	stats.failures++
+	b, ok := s.backoff[key]
+	if !ok {
+		b = &nodeBackoff{}
+		s.backoff[key] = b
+	}
+	b.failures++
+	b.until = time.Now().Add(s.backoffDelay(job.task.Interval, b.failures))
+	logger.Debugf("[Scheduler] 任务 %s 节点 %s 失败 %d 次，退避至 %s: %v",
+		job.task.Name, job.node.Name, b.failures, b.until.Format(time.TimeOnly), err)
+}
+
+// run 执行任务，任务 panic 视为失败
+func (s *NodeScheduler) run(job *nodeJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("[Scheduler] 任务 %s 节点 %s panic: %v", job.task.Name, job.node.Name, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.task.Run(s.ctx, job.node)
+}
+
+// backoffDelay 计算退避时间：interval * 2^failures，不超过 maxBackoff
+func (s *NodeScheduler) backoffDelay(interval time.Duration, failures int) time.Duration {
+	delay := interval
+	for i := 0; i < failures && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.maxBackoff {
+		delay = s.maxBackoff
+	}
+	return delay
+}
+
+// Stats 获取调度器运行指标
+func (s *NodeScheduler) Stats() *SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	result := &SchedulerStats{
+		Workers:       s.workers,
+		BusyWorkers:   atomic.LoadInt64(&s.busy),
+		QueueLength:   len(s.queue),
+		QueueCapacity: cap(s.queue),
+		InFlight:      len(s.inFlight),
+		Tasks:         make([]SchedulerTaskStats, 0, len(s.stats)),
+	}
+	for _, b := range s.backoff {
+		if now.Before(b.until) {
+			result.BackoffNodes++
+		}
+	}
+
+	for name, st := range s.stats {
+		item := SchedulerTaskStats{
+			Name:            name,
+			IntervalSeconds: st.interval.Seconds(),
+			Runs:            st.runs,
+			Failures:        st.failures,
+			SkippedInFlight: st.skippedInFlight,
+			SkippedBackoff:  st.skippedBackoff,
+			Dropped:         st.dropped,
+			LastLagMs:       st.lastLag.Milliseconds(),
+			MaxLagMs:        st.maxLag.Milliseconds(),
+			LastDurationMs:  st.lastDuration.Milliseconds(),
+		}
+		if st.runs > 0 {
+			item.AvgLagMs = (st.totalLag / time.Duration(st.runs)).Milliseconds()
+		}
+		if !st.lastDispatchAt.IsZero() {
+			dispatchAt := st.lastDispatchAt
+			item.LastDispatchAt = &dispatchAt
+		}
+		result.Tasks = append(result.Tasks, item)
+	}
+	sort.Slice(result.Tasks, func(i, j int) bool { return result.Tasks[i].Name < result.Tasks[j].Name })
+
+	return result
+}
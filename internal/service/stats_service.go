@@ -1,6 +1,10 @@
 package service
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
 	"gost-panel/internal/config"
 	"gost-panel/internal/model"
 	"gost-panel/internal/repository"
@@ -8,30 +12,54 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	// dashboardTopLimit 排行榜条数
+	dashboardTopLimit = 10
+	// dashboardTrendHours 趋势图小时数
+	dashboardTrendHours = 24
+	// dashboardProblemLimit 异常列表最多条数
+	dashboardProblemLimit = 20
+	// dashboardLogLimit 最近操作条数
+	dashboardLogLimit = 10
+)
+
+// dashboardLogExcluded 最近操作中不展示的日常操作
+var dashboardLogExcluded = []string{model.ActionLogin, model.ActionLogout, model.ActionChangePassword}
+
 // StatsService 统计服务
 type StatsService struct {
-	nodeRepo   *repository.NodeRepository
-	ruleRepo   *repository.RuleRepository
-	tunnelRepo *repository.TunnelRepository
-	logRepo    *repository.OperationLogRepository
+	nodeRepo    *repository.NodeRepository
+	ruleRepo    *repository.RuleRepository
+	tunnelRepo  *repository.TunnelRepository
+	logRepo     *repository.OperationLogRepository
+	trafficRepo *repository.TrafficRepository
+	historyRepo *repository.NodeStatusHistoryRepository
 }
 
 // NewStatsService 创建统计服务
 func NewStatsService(db *gorm.DB) *StatsService {
 	return &StatsService{
-		nodeRepo:   repository.NewNodeRepository(db),
-		ruleRepo:   repository.NewRuleRepository(db),
-		tunnelRepo: repository.NewTunnelRepository(db),
-		logRepo:    repository.NewOperationLogRepository(db),
+		nodeRepo:    repository.NewNodeRepository(db),
+		ruleRepo:    repository.NewRuleRepository(db),
+		tunnelRepo:  repository.NewTunnelRepository(db),
+		logRepo:     repository.NewOperationLogRepository(db),
+		trafficRepo: repository.NewTrafficRepository(db),
+		historyRepo: repository.NewNodeStatusHistoryRepository(db),
 	}
 }
 
 // DashboardStats 仪表盘统计
 type DashboardStats struct {
-	Nodes   NodeStats   `json:"nodes"`
-	Rules   RuleStats   `json:"rules"`
-	Tunnels TunnelStats `json:"tunnels"`
-	Version string      `json:"version"`
+	Nodes      NodeStats            `json:"nodes"`
+	Rules      RuleStats            `json:"rules"`
+	Tunnels    TunnelStats          `json:"tunnels"`
+	Traffic    TrafficSummary       `json:"traffic"`     // 今日、本周、本月流量
+	Trend      []TrendPoint         `json:"trend"`       // 最近 24 小时每小时流量
+	TopRules   TopStats             `json:"top_rules"`   // 规则排行
+	TopNodes   TopStats             `json:"top_nodes"`   // 节点排行
+	Problems   []ProblemItem        `json:"problems"`    // 异常的节点、规则、隧道及原因
+	RecentLogs []model.OperationLog `json:"recent_logs"` // 最近操作（不含登录等日常操作）
+	Version    string               `json:"version"`
 }
 
 // NodeStats 节点统计
@@ -39,6 +67,7 @@ type NodeStats struct {
 	Total   int64 `json:"total"`
 	Online  int64 `json:"online"`
 	Offline int64 `json:"offline"`
+	Error   int64 `json:"error"` // 错误状态数量（计入 Offline）
 }
 
 // RuleStats 规则统计
@@ -46,6 +75,7 @@ type RuleStats struct {
 	Total       int64 `json:"total"`
 	Running     int64 `json:"running"`
 	Stopped     int64 `json:"stopped"`
+	Error       int64 `json:"error"`        // 错误状态数量（计入 Stopped）
 	ForwardType int64 `json:"forward_type"` // 端口转发类型数量
 	TunnelType  int64 `json:"tunnel_type"`  // 隧道转发类型数量
 }
@@ -55,6 +85,55 @@ type TunnelStats struct {
 	Total   int64 `json:"total"`
 	Running int64 `json:"running"`
 	Stopped int64 `json:"stopped"`
+	Error   int64 `json:"error"` // 错误状态数量（计入 Stopped）
+}
+
+// TrafficSummary 流量合计（按本地时区的自然日、周、月）
+type TrafficSummary struct {
+	Today TrafficTotal `json:"today"`
+	Week  TrafficTotal `json:"week"`  // 本周（周一起）
+	Month TrafficTotal `json:"month"` // 本月
+}
+
+// TrafficTotal 时间段内的流量合计
+type TrafficTotal struct {
+	InputBytes  int64 `json:"input_bytes"`
+	OutputBytes int64 `json:"output_bytes"`
+	TotalBytes  int64 `json:"total_bytes"`
+	Connections int64 `json:"connections"` // 新建连接数
+}
+
+// TrendPoint 趋势图数据点（一小时内的增量）
+type TrendPoint struct {
+	Time        time.Time `json:"time"`
+	TotalBytes  int64     `json:"total_bytes"`
+	Connections int64     `json:"connections"`
+}
+
+// TopStats 排行榜
+type TopStats struct {
+	ByTraffic     []TopItem `json:"by_traffic"`     // 最近 24 小时流量排行
+	ByConnections []TopItem `json:"by_connections"` // 当前连接数排行（相同时按 24 小时新建连接数）
+}
+
+// TopItem 排行榜条目
+type TopItem struct {
+	ID           uint    `json:"id"`
+	Name         string  `json:"name"`
+	Status       string  `json:"status"`
+	TotalBytes   int64   `json:"total_bytes"`   // 最近 24 小时流量
+	Connections  int64   `json:"connections"`   // 最近 24 小时新建连接数
+	CurrentConns int64   `json:"current_conns"` // 当前连接数
+	Spark        []int64 `json:"spark"`         // 最近 24 小时每小时流量
+}
+
+// ProblemItem 异常对象
+type ProblemItem struct {
+	Type   string `json:"type"` // node, rule, tunnel
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Reason string `json:"reason"` // 异常原因（最后一次错误信息）
 }
 
 // GetDashboardStats 获取仪表盘统计
@@ -70,10 +149,15 @@ func (s *StatsService) GetDashboardStats() (*DashboardStats, error) {
 	if err != nil {
 		return nil, err
 	}
+	nodeError, err := s.nodeRepo.CountByStatus(model.NodeStatusError)
+	if err != nil {
+		return nil, err
+	}
 	stats.Nodes = NodeStats{
 		Total:   nodeTotal,
 		Online:  nodeOnline,
 		Offline: nodeTotal - nodeOnline,
+		Error:   nodeError,
 	}
 
 	// 规则统计
@@ -85,6 +169,10 @@ func (s *StatsService) GetDashboardStats() (*DashboardStats, error) {
 	if err != nil {
 		return nil, err
 	}
+	ruleError, err := s.ruleRepo.CountByStatus(model.RuleStatusError)
+	if err != nil {
+		return nil, err
+	}
 	forwardType, err := s.ruleRepo.CountByType(model.RuleTypeForward)
 	if err != nil {
 		return nil, err
@@ -97,6 +185,7 @@ func (s *StatsService) GetDashboardStats() (*DashboardStats, error) {
 		Total:       ruleTotal,
 		Running:     ruleRunning,
 		Stopped:     ruleTotal - ruleRunning,
+		Error:       ruleError,
 		ForwardType: forwardType,
 		TunnelType:  tunnelType,
 	}
@@ -110,13 +199,263 @@ func (s *StatsService) GetDashboardStats() (*DashboardStats, error) {
 	if err != nil {
 		return nil, err
 	}
+	tunnelError, err := s.tunnelRepo.CountByStatus(model.TunnelStatusError)
+	if err != nil {
+		return nil, err
+	}
 	stats.Tunnels = TunnelStats{
 		Total:   tunnelTotal,
 		Running: tunnelRunning,
 		Stopped: tunnelTotal - tunnelRunning,
+		Error:   tunnelError,
+	}
+
+	now := time.Now()
+	if stats.Traffic, err = s.trafficSummary(now); err != nil {
+		return nil, err
+	}
+	if err = s.loadActivity(stats, now); err != nil {
+		return nil, err
+	}
+	if stats.RecentLogs, err = s.logRepo.ListRecent(dashboardLogLimit, dashboardLogExcluded...); err != nil {
+		return nil, err
 	}
 
 	stats.Version = config.Version
 
 	return stats, nil
 }
+
+// trafficSummary 统计今日、本周、本月的流量（规则流量之和，包含已删除规则）
+// 天粒度采样随汇总任务每分钟更新，当天数据同样可用
+func (s *StatsService) trafficSummary(now time.Time) (TrafficSummary, error) {
+	today := dayBucket(now)
+	local := today.In(time.Local)
+	week := local.AddDate(0, 0, -(int(local.Weekday())+6)%7)
+	month := local.AddDate(0, 0, 1-local.Day())
+	end := today.AddDate(0, 0, 1)
+
+	var summary TrafficSummary
+	for _, r := range []struct {
+		total *TrafficTotal
+		start time.Time
+	}{{&summary.Today, today}, {&summary.Week, week}, {&summary.Month, month}} {
+		t, err := s.trafficRepo.SumByType(model.TrafficResourceRule, model.TrafficPeriodDay, r.start, end)
+		if err != nil {
+			return summary, err
+		}
+		*r.total = TrafficTotal{
+			InputBytes:  t.InputBytes,
+			OutputBytes: t.OutputBytes,
+			TotalBytes:  t.InputBytes + t.OutputBytes,
+			Connections: t.Connections,
+		}
+	}
+	return summary, nil
+}
+
+// hourlyTraffic 对象最近 24 小时每小时的流量和新建连接数
+type hourlyTraffic struct {
+	bytes [dashboardTrendHours]int64
+	conns [dashboardTrendHours]int64
+}
+
+// total 24 小时合计
+func (h *hourlyTraffic) total() (bytes, conns int64) {
+	for i := range h.bytes {
+		bytes += h.bytes[i]
+		conns += h.conns[i]
+	}
+	return bytes, conns
+}
+
+// loadActivity 统计最近 24 小时趋势、规则和节点排行以及异常对象
+func (s *StatsService) loadActivity(stats *DashboardStats, now time.Time) error {
+	start := hourBucket(now).Add(-(dashboardTrendHours - 1) * time.Hour)
+	samples, err := s.trafficRepo.FindByPeriod(model.TrafficPeriodHour, start, start.Add(dashboardTrendHours*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	type resourceKey struct {
+		resourceType model.TrafficResourceType
+		id           uint
+	}
+	hourly := make(map[resourceKey]*hourlyTraffic)
+	stats.Trend = make([]TrendPoint, dashboardTrendHours)
+	for i := range stats.Trend {
+		stats.Trend[i].Time = start.Add(time.Duration(i) * time.Hour)
+	}
+	for _, sample := range samples {
+		i := int(sample.BucketTime.Sub(start) / time.Hour)
+		if i < 0 || i >= dashboardTrendHours {
+			continue
+		}
+		key := resourceKey{sample.ResourceType, sample.ResourceID}
+		h, ok := hourly[key]
+		if !ok {
+			h = &hourlyTraffic{}
+			hourly[key] = h
+		}
+		h.bytes[i] += sample.InputBytes + sample.OutputBytes
+		h.conns[i] += sample.Connections
+		if sample.ResourceType == model.TrafficResourceRule {
+			stats.Trend[i].TotalBytes += sample.InputBytes + sample.OutputBytes
+			stats.Trend[i].Connections += sample.Connections
+		}
+	}
+
+	nodes, err := s.nodeRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	tunnels, err := s.tunnelRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	rules, err := s.ruleRepo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	// topItem 组装排行榜条目
+	topItem := func(resourceType model.TrafficResourceType, id uint, name, status string, currentConns int64) TopItem {
+		item := TopItem{ID: id, Name: name, Status: status, CurrentConns: currentConns, Spark: make([]int64, dashboardTrendHours)}
+		if h := hourly[resourceKey{resourceType, id}]; h != nil {
+			item.TotalBytes, item.Connections = h.total()
+			copy(item.Spark, h.bytes[:])
+		}
+		return item
+	}
+
+	entryNodes := make(map[uint]uint, len(tunnels))
+	for _, t := range tunnels {
+		entryNodes[t.ID] = t.EntryNodeID
+	}
+	nodeConns := make(map[uint]int64, len(nodes))
+	ruleItems := make([]TopItem, 0, len(rules))
+	for _, r := range rules {
+		if r.Type == model.RuleTypeTunnel && r.TunnelID != nil {
+			nodeConns[entryNodes[*r.TunnelID]] += r.CurrentConns
+		} else if r.NodeID != nil {
+			nodeConns[*r.NodeID] += r.CurrentConns
+		}
+		ruleItems = append(ruleItems, topItem(model.TrafficResourceRule, r.ID, r.Name, string(r.Status), r.CurrentConns))
+	}
+	nodeItems := make([]TopItem, 0, len(nodes))
+	for _, n := range nodes {
+		nodeItems = append(nodeItems, topItem(model.TrafficResourceNode, n.ID, n.Name, string(n.Status), nodeConns[n.ID]))
+	}
+	stats.TopRules = rankTop(ruleItems)
+	stats.TopNodes = rankTop(nodeItems)
+
+	stats.Problems, err = s.problems(nodes, rules, tunnels)
+	return err
+}
+
+// rankTop 按流量、连接数排序取前若干条，均为 0 的条目不参与排行
+func rankTop(items []TopItem) TopStats {
+	pick := func(less func(a, b *TopItem) bool, active func(*TopItem) bool) []TopItem {
+		sorted := make([]TopItem, 0, len(items))
+		for i := range items {
+			if active(&items[i]) {
+				sorted = append(sorted, items[i])
+			}
+		}
+		sort.SliceStable(sorted, func(i, j int) bool { return less(&sorted[i], &sorted[j]) })
+		if len(sorted) > dashboardTopLimit {
+			sorted = sorted[:dashboardTopLimit]
+		}
+		return sorted
+	}
+
+	return TopStats{
+		ByTraffic: pick(
+			func(a, b *TopItem) bool { return a.TotalBytes > b.TotalBytes },
+			func(i *TopItem) bool { return i.TotalBytes > 0 },
+		),
+		ByConnections: pick(
+			func(a, b *TopItem) bool {
+				if a.CurrentConns != b.CurrentConns {
+					return a.CurrentConns > b.CurrentConns
+				}
+				return a.Connections > b.Connections
+			},
+			func(i *TopItem) bool { return i.CurrentConns > 0 || i.Connections > 0 },
+		),
+	}
+}
+
+// problems 列出异常的节点、规则和隧道
+// 节点原因取最近一次状态变更记录，规则取 Gost 返回的错误信息，隧道取异常的入口或出口节点
+func (s *StatsService) problems(nodes []model.GostNode, rules []model.GostRule, tunnels []model.GostTunnel) ([]ProblemItem, error) {
+	problems := []ProblemItem{}
+	add := func(p ProblemItem) bool {
+		if len(problems) >= dashboardProblemLimit {
+			return false
+		}
+		problems = append(problems, p)
+		return true
+	}
+
+	nodeByID := make(map[uint]*model.GostNode, len(nodes))
+	for i := range nodes {
+		n := &nodes[i]
+		nodeByID[n.ID] = n
+		if n.Status == model.NodeStatusOnline || n.Maintenance {
+			continue
+		}
+		p := ProblemItem{Type: model.ResourceTypeNode, ID: n.ID, Name: n.Name, Status: string(n.Status)}
+		history, err := s.historyRepo.ListRecent(n.ID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(history) > 0 {
+			p.Reason = history[0].Reason
+		}
+		if !add(p) {
+			return problems, nil
+		}
+	}
+
+	for _, r := range rules {
+		if r.Status != model.RuleStatusError {
+			continue
+		}
+		if !add(ProblemItem{Type: model.ResourceTypeRule, ID: r.ID, Name: r.Name, Status: string(r.Status), Reason: r.StatusMsg}) {
+			return problems, nil
+		}
+	}
+
+	for _, t := range tunnels {
+		if t.Status != model.TunnelStatusError {
+			continue
+		}
+		p := ProblemItem{Type: model.ResourceTypeTunnel, ID: t.ID, Name: t.Name, Status: string(t.Status)}
+		for _, end := range []struct {
+			label string
+			id    uint
+		}{{"入口", t.EntryNodeID}, {"出口", t.ExitNodeID}} {
+			if n := nodeByID[end.id]; n != nil && n.Status != model.NodeStatusOnline {
+				p.Reason = fmt.Sprintf("%s节点 %s %s", end.label, n.Name, nodeStatusText(n.Status))
+				break
+			}
+		}
+		if !add(p) {
+			return problems, nil
+		}
+	}
+	return problems, nil
+}
+
+// nodeStatusText 节点状态的中文描述
+func nodeStatusText(status model.NodeStatus) string {
+	switch status {
+	case model.NodeStatusOffline:
+		return "离线"
+	case model.NodeStatusError:
+		return "异常"
+	default:
+		return string(status)
+	}
+}
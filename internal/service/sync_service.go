@@ -1,108 +1,92 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
+	"gost-panel/internal/config"
 	"gost-panel/internal/model"
 	"gost-panel/internal/repository"
 	"gost-panel/internal/utils"
+	"gost-panel/pkg/gost"
 	"gost-panel/pkg/logger"
 
 	"gorm.io/gorm"
 )
 
+// maxStartRetryBackoff 期望运行对象重试启动的最大退避时间
+const maxStartRetryBackoff = 30 * time.Minute
+
 // RuleSyncService 规则状态同步服务
-// 定时从 Gost 节点同步规则的真实运行状态
+// 定时从 Gost 节点同步规则的真实运行状态，并重试启动期望运行但未运行的隧道和规则
 type RuleSyncService struct {
-	nodeRepo   *repository.NodeRepository
-	ruleRepo   *repository.RuleRepository
-	tunnelRepo *repository.TunnelRepository
-	ticker     *time.Ticker
-	stopChan   chan struct{}
-	wg         sync.WaitGroup
+	ruleRepo      *repository.RuleRepository
+	tunnelRepo    *repository.TunnelRepository
+	sysRepo       *repository.SystemConfigRepository
+	ruleService   *RuleService
+	tunnelService *TunnelService
+	scheduler     *NodeScheduler
+	interval      time.Duration
+
+	mu      sync.Mutex
+	retries map[string]*nodeBackoff // 启动失败的对象（rule-ID / tunnel-ID）-> 退避状态
 }
 
 // NewRuleSyncService 创建规则状态同步服务
-func NewRuleSyncService(db *gorm.DB) *RuleSyncService {
+func NewRuleSyncService(db *gorm.DB, scheduler *NodeScheduler) *RuleSyncService {
 	return &RuleSyncService{
-		nodeRepo:   repository.NewNodeRepository(db),
-		ruleRepo:   repository.NewRuleRepository(db),
-		tunnelRepo: repository.NewTunnelRepository(db),
-		stopChan:   make(chan struct{}),
+		ruleRepo:      repository.NewRuleRepository(db),
+		tunnelRepo:    repository.NewTunnelRepository(db),
+		sysRepo:       repository.NewSystemConfigRepository(db),
+		ruleService:   NewRuleService(db),
+		tunnelService: NewTunnelService(db),
+		scheduler:     scheduler,
+		interval:      time.Duration(config.Get().Sync.Interval) * time.Second,
+		retries:       make(map[string]*nodeBackoff),
 	}
 }
 
-// Start 启动定时同步任务（每 5 秒）
+// Start 注册定时同步任务（间隔由 sync.interval 配置）
 func (s *RuleSyncService) Start() {
-	s.ticker = time.NewTicker(5 * time.Second)
-	s.wg.Add(1)
-
-	go func() {
-		defer s.wg.Done()
-		logger.Info("规则状态同步服务已启动 (5s 间隔)")
-
-		// 立即执行一次
-		s.syncAll()
-
-		for {
-			select {
-			case <-s.ticker.C:
-				s.syncAll()
-			case <-s.stopChan:
-				logger.Info("规则状态同步服务已停止")
-				return
-			}
-		}
-	}()
-}
-
-// Stop 停止同步服务
-func (s *RuleSyncService) Stop() {
-	if s.ticker != nil {
-		s.ticker.Stop()
-	}
-	close(s.stopChan)
-	s.wg.Wait()
-}
-
-// syncAll 同步所有节点的规则状态
-func (s *RuleSyncService) syncAll() {
-	nodes, _, err := s.nodeRepo.List(nil)
-	if err != nil {
-		logger.Errorf("[Sync] 获取节点列表失败: %v", err)
-		return
-	}
-
-	for _, node := range nodes {
-		// 并发同步每个节点
-		go s.syncNodeRules(node)
-	}
+	s.scheduler.Register(&NodeTask{
+		Name:     "sync",
+		Interval: s.interval,
+		Run:      s.syncNodeRules,
+	})
+	logger.Infof("规则状态同步服务已启动 (%s 间隔)", s.interval)
 }
 
 // syncNodeRules 同步单个节点的规则
-func (s *RuleSyncService) syncNodeRules(node model.GostNode) {
+// 获取节点配置失败时返回错误，由调度器对该节点退避
+func (s *RuleSyncService) syncNodeRules(ctx context.Context, node model.GostNode) error {
 	// 如果节点离线，跳过规则同步
 	if node.Status == model.NodeStatusOffline {
-		return
+		return nil
 	}
 
-	client := utils.GetGostClient(&node)
-
 	// 获取节点真实运行配置
-	gostCfg, err := client.GetConfig()
+	client := utils.GetGostClient(&node)
+	gostCfg, err := client.GetConfig(ctx)
 	if err != nil {
 		logger.Debugf("[Sync] 获取节点 %d (%s) 配置失败: %v", node.ID, node.Name, err)
-		return
+		return err
 	}
 
+	// 0. 校正观察器上报地址
+	s.syncObserver(ctx, client, &node, gostCfg.Observers)
+
 	// 提取节点上的 Service 状态
-	serviceStates := make(map[string]string)
+	serviceStates := make(map[string]serviceState)
 	for _, svc := range gostCfg.Services {
-		state := "stopped"
+		state := serviceState{State: "stopped"}
 		if svc.Status != nil {
-			state = svc.Status.State
+			state.State = svc.Status.State
+			// 最近一条事件通常携带失败原因
+			if n := len(svc.Status.Events); n > 0 {
+				state.Msg = svc.Status.Events[n-1].Msg
+			}
 		}
 		serviceStates[svc.Name] = state
 	}
@@ -136,34 +120,157 @@ func (s *RuleSyncService) syncNodeRules(node model.GostNode) {
 			}
 		}
 	}
+
+	// 3. 重试启动期望运行但未运行的隧道和规则（维护中的节点由维护流程负责）
+	if node.Status == model.NodeStatusOnline && !node.Maintenance {
+		s.retryDesired(ctx, &node, serviceStates)
+	}
+
+	return nil
 }
 
-// syncRuleStatus 同步规则状态
-func (s *RuleSyncService) syncRuleStatus(r model.GostRule, serviceStates map[string]string) {
-	serviceID := r.ServiceID
-	if serviceID == "" {
-		serviceID = fmt.Sprintf("rule-%d", r.ID)
+// retryDesired 重试启动以该节点为入口、期望运行但未运行的隧道和规则
+// 节点恢复在线时只重建一次，启动失败（如端口占用、出口节点暂不可用）由此按对象指数退避重试
+// 服务仍在节点上（如运行失败）时由 Gost 负责，不重复创建
+func (s *RuleSyncService) retryDesired(ctx context.Context, node *model.GostNode, serviceStates map[string]serviceState) {
+	tunnels, err := s.tunnelRepo.FindByNodeID(node.ID)
+	if err != nil {
+		logger.Errorf("[Sync] 获取节点 %d 隧道失败: %v", node.ID, err)
+		return
+	}
+	for _, t := range tunnels {
+		if t.EntryNodeID != node.ID || !t.DesiredRunning || t.Status == model.TunnelStatusRunning {
+			continue
+		}
+		key := fmt.Sprintf("tunnel-%d", t.ID)
+		if !s.retryDue(key) {
+			continue
+		}
+		err = s.tunnelService.Start(ctx, t.ID, 0, systemUsername, "", "")
+		s.retryDone(key, err)
+		if err != nil {
+			logger.Warnf("[Sync] 节点 %s 重试启动隧道 %s 失败: %v", node.Name, t.Name, err)
+		} else {
+			logger.Infof("[Sync] 节点 %s 重试启动隧道 %s 成功", node.Name, t.Name)
+		}
 	}
 
-	state := serviceStates[serviceID]
-	newStatus := utils.GostStateToRuleStatus(state)
+	// 重新查询以获取本轮同步及隧道启动后的状态
+	rules, err := s.ruleRepo.FindByEntryNodeID(node.ID)
+	if err != nil {
+		logger.Errorf("[Sync] 获取节点 %d 入口规则失败: %v", node.ID, err)
+		return
+	}
+	for _, r := range rules {
+		if !r.DesiredRunning || r.Status == model.RuleStatusRunning {
+			continue
+		}
+		if _, exists := serviceStates[ruleServiceName(&r)]; exists {
+			continue
+		}
+		// 隧道未运行时规则无法启动，等待隧道恢复
+		if r.Type == model.RuleTypeTunnel && (r.Tunnel == nil || r.Tunnel.Status != model.TunnelStatusRunning) {
+			continue
+		}
+		key := fmt.Sprintf("rule-%d", r.ID)
+		if !s.retryDue(key) {
+			continue
+		}
+		err = s.ruleService.Start(ctx, r.ID, 0, systemUsername, "", "")
+		s.retryDone(key, err)
+		if err != nil {
+			logger.Warnf("[Sync] 节点 %s 重试启动规则 %s 失败: %v", node.Name, r.Name, err)
+		} else {
+			logger.Infof("[Sync] 节点 %s 重试启动规则 %s 成功", node.Name, r.Name)
+		}
+	}
+}
+
+// retryDue 判断对象是否已过退避时间
+func (s *RuleSyncService) retryDue(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.retries[key]
+	return !ok || !time.Now().Before(b.until)
+}
+
+// retryDone 记录重试结果：成功清除退避，失败按 interval * 2^failures 退避，不超过 maxStartRetryBackoff
+func (s *RuleSyncService) retryDone(key string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.retries, key)
+		return
+	}
+	b, ok := s.retries[key]
+	if !ok {
+		b = &nodeBackoff{}
+		s.retries[key] = b
+	}
+	b.failures++
+	delay := s.interval
+	for i := 0; i < b.failures && delay < maxStartRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxStartRetryBackoff {
+		delay = maxStartRetryBackoff
+	}
+	b.until = time.Now().Add(delay)
+}
+
+// syncObserver 节点全局观察器的上报地址与期望不一致时更新
+// 覆盖升级前创建的不带令牌的观察器，以及面板地址变更的情况
+func (s *RuleSyncService) syncObserver(ctx context.Context, client *gost.Client, node *model.GostNode, observers []gost.ObserverConfig) {
+	for _, o := range observers {
+		if o.Name != globalObserverName {
+			continue
+		}
+		desired, err := buildGlobalObserver(node, s.sysRepo)
+		if err != nil || (o.Plugin != nil && o.Plugin.Addr == desired.Plugin.Addr) {
+			return
+		}
+		if err = client.UpdateObserver(ctx, desired); err != nil {
+			logger.Warnf("[Sync] 更新节点 %s 观察器上报地址失败: %v", node.Name, err)
+			return
+		}
+		_ = client.SaveConfig(ctx)
+		logger.Infof("[Sync] 节点 %s 观察器上报地址已更新", node.Name)
+		return
+	}
+}
+
+// serviceState Gost 服务运行状态及最近一条事件消息
+type serviceState struct {
+	State string
+	Msg   string
+}
+
+// syncRuleStatus 同步规则状态
+func (s *RuleSyncService) syncRuleStatus(r model.GostRule, serviceStates map[string]serviceState) {
+	state := serviceStates[ruleServiceName(&r)]
+	newStatus := utils.GostStateToRuleStatus(state.State)
+
+	// 失败时保留 Gost 的错误信息
+	msg := ""
+	if newStatus == model.RuleStatusError {
+		msg = state.Msg
+		// 服务不存在等情况 Gost 没有事件，保留已记录的错误信息
+		if msg == "" {
+			msg = r.StatusMsg
+		}
+	}
 
 	// 如果状态不一致
-	if r.Status != newStatus {
-		logger.Infof("[Sync] 规则 %d (%s) 状态变更: %s -> %s (Gost State: %s)", r.ID, r.Name, r.Status, newStatus, state)
-		_ = s.ruleRepo.UpdateStatus(r.ID, newStatus)
+	if r.Status != newStatus || r.StatusMsg != msg {
+		logger.Infof("[Sync] 规则 %d (%s) 状态变更: %s -> %s (Gost State: %s)", r.ID, r.Name, r.Status, newStatus, state.State)
+		_ = s.ruleRepo.UpdateStatusMsg(r.ID, newStatus, msg)
 	}
 }
 
 // syncTunnelStatus 同步隧道状态
 func (s *RuleSyncService) syncTunnelStatus(t model.GostTunnel, chainStates map[string]bool) {
 	// 检查 Forward Chain 是否存在
-	chainID := t.ChainID
-	if chainID == "" {
-		chainID = fmt.Sprintf("tunnel-%d-chain", t.ID)
-	}
-
-	exists := chainStates[chainID]
+	exists := chainStates[tunnelChainName(&t)]
 	var newStatus model.TunnelStatus
 	if exists {
 		newStatus = model.TunnelStatusRunning
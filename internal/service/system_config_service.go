@@ -46,6 +46,18 @@ func (s *SystemConfigService) GetConfig() (*dto.SystemConfigResp, error) {
 			AutoBackup:     config.AutoBackup,
 			RetentionCount: config.BackupRetentionCount,
 		},
+		Traffic: dto.TrafficConfigResp{
+			MinuteRetentionDays: config.TrafficMinuteRetentionDays,
+			HourRetentionDays:   config.TrafficHourRetentionDays,
+			DayRetentionDays:    config.TrafficDayRetentionDays,
+		},
+		Report: dto.ReportConfigResp{
+			Daily:      config.ReportDaily,
+			Weekly:     config.ReportWeekly,
+			Monthly:    config.ReportMonthly,
+			Recipients: config.ReportRecipients,
+			Hour:       config.ReportHour,
+		},
 	}, nil
 }
 
@@ -93,5 +105,26 @@ func (s *SystemConfigService) UpdateConfig(req *dto.UpdateSystemConfigReq) error
 	config.AutoBackup = req.Backup.AutoBackup
 	config.BackupRetentionCount = req.Backup.RetentionCount
 
+	// 映射 Traffic（未填写时保持原值）
+	if req.Traffic.MinuteRetentionDays > 0 {
+		config.TrafficMinuteRetentionDays = req.Traffic.MinuteRetentionDays
+	}
+	if req.Traffic.HourRetentionDays > 0 {
+		config.TrafficHourRetentionDays = req.Traffic.HourRetentionDays
+	}
+	if req.Traffic.DayRetentionDays > 0 {
+		config.TrafficDayRetentionDays = req.Traffic.DayRetentionDays
+	}
+
+	// 映射 Report
+	if _, err = parseRecipients(req.Report.Recipients); err != nil {
+		return err
+	}
+	config.ReportDaily = req.Report.Daily
+	config.ReportWeekly = req.Report.Weekly
+	config.ReportMonthly = req.Report.Monthly
+	config.ReportRecipients = req.Report.Recipients
+	config.ReportHour = req.Report.Hour
+
 	return s.repo.Update(config)
 }
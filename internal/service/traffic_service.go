@@ -0,0 +1,551 @@
+package service
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gost-panel/internal/dto"
+	"gost-panel/internal/errors"
+	"gost-panel/internal/model"
+	"gost-panel/internal/repository"
+	"gost-panel/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// trafficRollupInterval 分钟采样汇总到小时、天的间隔
+	trafficRollupInterval = time.Minute
+	// trafficCleanupInterval 过期流量采样清理间隔
+	trafficCleanupInterval = time.Hour
+	// trafficDefaultRange 未指定开始时间时的默认查询范围
+	trafficDefaultRange = 24 * time.Hour
+	// trafficAutoPoints 自动选择步长时的目标数据点数
+	trafficAutoPoints = 300
+	// trafficMaxPoints 单次查询最多返回的数据点数
+	trafficMaxPoints = 2000
+
+	// oneDay 一天
+	oneDay = 24 * time.Hour
+)
+
+// trafficAutoSteps 自动选择步长的候选列表（由细到粗）
+var trafficAutoSteps = []time.Duration{
+	time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, oneDay, 7 * oneDay,
+}
+
+// trafficRetention 流量采样保留天数：默认值与允许的最小值
+// 最小值保证启动时补算汇总所需的源采样尚未被清理
+var trafficRetention = map[model.TrafficPeriod]struct{ def, min int }{
+	model.TrafficPeriodMinute: {def: 2, min: 1},
+	model.TrafficPeriodHour:   {def: 30, min: 7},
+	model.TrafficPeriodDay:    {def: 365, min: 7},
+}
+
+// TrafficDelta 一次上报的流量增量
+type TrafficDelta struct {
+	InputBytes  int64
+	OutputBytes int64
+	Connections int64
+	Errors      int64 // 错误数，不计入流量历史
+}
+
+// IsZero 是否无流量变化
+func (d TrafficDelta) IsZero() bool {
+	return d.InputBytes == 0 && d.OutputBytes == 0 && d.Connections == 0 && d.Errors == 0
+}
+
+// TrafficService 流量历史服务
+// 观察器上报的增量按分钟累加，后台定时汇总为小时、天粒度并按保留天数清理
+type TrafficService struct {
+	repo       *repository.TrafficRepository
+	sysRepo    *repository.SystemConfigRepository
+	ruleRepo   *repository.RuleRepository
+	nodeRepo   *repository.NodeRepository
+	tunnelRepo *repository.TunnelRepository
+	clientRepo *repository.RuleClientRepository
+	gostRepo   *repository.GostMetricRepository
+
+	stopChan chan struct{}
+}
+
+// NewTrafficService 创建流量历史服务
+func NewTrafficService(db *gorm.DB) *TrafficService {
+	return &TrafficService{
+		repo:       repository.NewTrafficRepository(db),
+		sysRepo:    repository.NewSystemConfigRepository(db),
+		ruleRepo:   repository.NewRuleRepository(db),
+		nodeRepo:   repository.NewNodeRepository(db),
+		tunnelRepo: repository.NewTunnelRepository(db),
+		clientRepo: repository.NewRuleClientRepository(db),
+		gostRepo:   repository.NewGostMetricRepository(db),
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start 启动后台汇总与清理任务
+func (s *TrafficService) Start() {
+	go func() {
+		// 启动时补算停机期间未汇总的时间段
+		now := time.Now()
+		if err := s.rollup(now, hourBucket(now).Add(-23*time.Hour), dayBucket(now).AddDate(0, 0, -6)); err != nil {
+			logger.Errorf("汇总流量历史失败: %v", err)
+		}
+		s.Cleanup(now)
+
+		rollupTicker := time.NewTicker(trafficRollupInterval)
+		defer rollupTicker.Stop()
+		cleanupTicker := time.NewTicker(trafficCleanupInterval)
+		defer cleanupTicker.Stop()
+
+		for {
+			select {
+			case now := <-rollupTicker.C:
+				if err := s.Rollup(now); err != nil {
+					logger.Errorf("汇总流量历史失败: %v", err)
+				}
+			case now := <-cleanupTicker.C:
+				s.Cleanup(now)
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+	logger.Info("流量历史服务已启动")
+}
+
+// Stop 停止后台任务
+func (s *TrafficService) Stop() {
+	close(s.stopChan)
+	logger.Info("流量历史服务已停止")
+}
+
+// Record 记录规则在某一时刻的流量增量，同时计入入口节点和所属隧道
+func (s *TrafficService) Record(rule *model.GostRule, nodeID uint, delta TrafficDelta, at time.Time) error {
+	if delta.IsZero() {
+		return nil
+	}
+
+	bucket := at.UTC().Truncate(time.Minute)
+	sample := func(resourceType model.TrafficResourceType, id uint) model.TrafficSample {
+		return model.TrafficSample{
+			ResourceType: resourceType,
+			ResourceID:   id,
+			Period:       model.TrafficPeriodMinute,
+			BucketTime:   bucket,
+			InputBytes:   delta.InputBytes,
+			OutputBytes:  delta.OutputBytes,
+			Connections:  delta.Connections,
+		}
+	}
+
+	samples := []model.TrafficSample{sample(model.TrafficResourceRule, rule.ID)}
+	if nodeID > 0 {
+		samples = append(samples, sample(model.TrafficResourceNode, nodeID))
+	}
+	if rule.Type == model.RuleTypeTunnel && rule.TunnelID != nil {
+		samples = append(samples, sample(model.TrafficResourceTunnel, *rule.TunnelID))
+	}
+	return s.repo.Accumulate(samples)
+}
+
+// Rollup 重新汇总当前及上一小时、当天及前一天的流量（幂等，可重复执行）
+func (s *TrafficService) Rollup(now time.Time) error {
+	return s.rollup(now, hourBucket(now).Add(-time.Hour), dayBucket(now).AddDate(0, 0, -1))
+}
+
+// rollup 由分钟采样汇总 hourSince 起的小时采样，再由小时采样汇总 daySince 起的天采样
+func (s *TrafficService) rollup(now, hourSince, daySince time.Time) error {
+	if err := s.aggregate(model.TrafficPeriodMinute, model.TrafficPeriodHour,
+		hourSince, hourBucket(now).Add(time.Hour), hourBucket); err != nil {
+		return fmt.Errorf("汇总小时流量: %w", err)
+	}
+	if err := s.aggregate(model.TrafficPeriodHour, model.TrafficPeriodDay,
+		daySince, dayBucket(now).AddDate(0, 0, 1), dayBucket); err != nil {
+		return fmt.Errorf("汇总天流量: %w", err)
+	}
+	return nil
+}
+
+// aggregate 将 [start, end) 内的 src 粒度采样按 bucket 分组求和，替换 dst 粒度在该范围内的采样
+// 替换而非逐桶覆盖，以清除时间桶对齐方式变化（如时区变更）前写入的旧桶
+func (s *TrafficService) aggregate(src, dst model.TrafficPeriod, start, end time.Time, bucket func(time.Time) time.Time) error {
+	samples, err := s.repo.FindByPeriod(src, start, end)
+	if err != nil {
+		return err
+	}
+
+	type bucketKey struct {
+		resourceType model.TrafficResourceType
+		resourceID   uint
+		bucket       int64
+	}
+	index := make(map[bucketKey]int)
+	var rolled []model.TrafficSample
+	for _, sample := range samples {
+		t := bucket(sample.BucketTime)
+		key := bucketKey{sample.ResourceType, sample.ResourceID, t.Unix()}
+		i, ok := index[key]
+		if !ok {
+			i = len(rolled)
+			index[key] = i
+			rolled = append(rolled, model.TrafficSample{
+				ResourceType: sample.ResourceType,
+				ResourceID:   sample.ResourceID,
+				Period:       dst,
+				BucketTime:   t,
+			})
+		}
+		rolled[i].InputBytes += sample.InputBytes
+		rolled[i].OutputBytes += sample.OutputBytes
+		rolled[i].Connections += sample.Connections
+	}
+	return s.repo.Replace(dst, start, end, rolled)
+}
+
+// Cleanup 按系统配置的保留天数清理过期流量采样
+func (s *TrafficService) Cleanup(now time.Time) {
+	cfg, err := s.sysRepo.Get()
+	if err != nil {
+		logger.Errorf("清理流量历史失败: 获取系统配置错误: %v", err)
+		return
+	}
+
+	days := map[model.TrafficPeriod]int{
+		model.TrafficPeriodMinute: cfg.TrafficMinuteRetentionDays,
+		model.TrafficPeriodHour:   cfg.TrafficHourRetentionDays,
+		model.TrafficPeriodDay:    cfg.TrafficDayRetentionDays,
+	}
+	for period, n := range days {
+		if deleted, err := s.repo.DeleteBefore(period, now.AddDate(0, 0, -retentionDays(period, n))); err != nil {
+			logger.Errorf("清理 %s 粒度流量历史失败: %v", period, err)
+		} else if deleted > 0 {
+			logger.Debugf("已清理 %d 条过期的 %s 粒度流量历史", deleted, period)
+		}
+	}
+
+	// 客户端统计与小时粒度历史保留相同天数
+	before := now.AddDate(0, 0, -retentionDays(model.TrafficPeriodHour, cfg.TrafficHourRetentionDays))
+	if deleted, err := s.clientRepo.DeleteInactiveBefore(before); err != nil {
+		logger.Errorf("清理不活跃的客户端统计失败: %v", err)
+	} else if deleted > 0 {
+		logger.Debugf("已清理 %d 条不活跃的客户端统计", deleted)
+	}
+
+	// Gost 指标只有分钟粒度，与分钟粒度流量历史保留相同天数
+	before = now.AddDate(0, 0, -retentionDays(model.TrafficPeriodMinute, cfg.TrafficMinuteRetentionDays))
+	if deleted, err := s.gostRepo.DeleteBefore(before); err != nil {
+		logger.Errorf("清理过期的 Gost 指标失败: %v", err)
+	} else if deleted > 0 {
+		logger.Debugf("已清理 %d 条过期的 Gost 指标", deleted)
+	}
+}
+
+// retentionDays 规整保留天数：未配置使用默认值，低于最小值取最小值
+func retentionDays(period model.TrafficPeriod, n int) int {
+	r := trafficRetention[period]
+	if n <= 0 {
+		return r.def
+	}
+	return max(n, r.min)
+}
+
+// RecomputeResult 累计流量重新计算结果
+type RecomputeResult struct {
+	Rules   int `json:"rules"`   // 参与计算的规则数（包含已删除规则）
+	Nodes   int `json:"nodes"`   // 更新的节点数
+	Tunnels int `json:"tunnels"` // 更新的隧道数
+}
+
+// RecomputeTotals 以规则累计流量重新计算节点和隧道的累计流量
+// 节点流量为以其为入口的规则流量之和，隧道流量为隧道规则流量之和，已删除规则产生的流量同样计入
+// 用于修复旧版本按累计值重复累加导致的节点流量虚高
+func (s *TrafficService) RecomputeTotals() (*RecomputeResult, error) {
+	rules, err := s.ruleRepo.FindAllWithDeleted()
+	if err != nil {
+		return nil, err
+	}
+	tunnels, err := s.tunnelRepo.FindAllWithDeleted()
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.nodeRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entryNodes := make(map[uint]uint, len(tunnels))
+	for _, t := range tunnels {
+		entryNodes[t.ID] = t.EntryNodeID
+	}
+
+	type totals struct{ in, out int64 }
+	nodeTotals := make(map[uint]*totals)
+	tunnelTotals := make(map[uint]*totals)
+	add := func(m map[uint]*totals, id uint, rule *model.GostRule) {
+		t, ok := m[id]
+		if !ok {
+			t = &totals{}
+			m[id] = t
+		}
+		t.in += rule.InputBytes
+		t.out += rule.OutputBytes
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Type == model.RuleTypeTunnel && rule.TunnelID != nil {
+			add(tunnelTotals, *rule.TunnelID, rule)
+			add(nodeTotals, entryNodes[*rule.TunnelID], rule)
+		} else if rule.NodeID != nil {
+			add(nodeTotals, *rule.NodeID, rule)
+		}
+	}
+
+	result := &RecomputeResult{Rules: len(rules)}
+	for _, node := range nodes {
+		t := nodeTotals[node.ID]
+		if t == nil {
+			t = &totals{}
+		}
+		if err = s.nodeRepo.SetStats(node.ID, t.in, t.out); err != nil {
+			return nil, fmt.Errorf("更新节点 %s 流量: %w", node.Name, err)
+		}
+		result.Nodes++
+	}
+	for _, tunnel := range tunnels {
+		if tunnel.DeletedAt.Valid {
+			continue
+		}
+		t := tunnelTotals[tunnel.ID]
+		if t == nil {
+			t = &totals{}
+		}
+		if err = s.tunnelRepo.SetStats(tunnel.ID, t.in, t.out); err != nil {
+			return nil, fmt.Errorf("更新隧道 %s 流量: %w", tunnel.Name, err)
+		}
+		result.Tunnels++
+	}
+	return result, nil
+}
+
+// QueryRule 查询规则流量历史
+func (s *TrafficService) QueryRule(id uint, req *dto.TrafficQueryReq) (*dto.TrafficSeriesResp, error) {
+	if _, err := s.ruleRepo.FindByID(id); err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrRuleNotFound
+		}
+		return nil, err
+	}
+	return s.query(model.TrafficResourceRule, id, req, time.Now())
+}
+
+// QueryNode 查询节点流量历史
+func (s *TrafficService) QueryNode(id uint, req *dto.TrafficQueryReq) (*dto.TrafficSeriesResp, error) {
+	if _, err := s.nodeRepo.FindByID(id); err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrNodeNotFound
+		}
+		return nil, err
+	}
+	return s.query(model.TrafficResourceNode, id, req, time.Now())
+}
+
+// QueryTunnel 查询隧道流量历史
+func (s *TrafficService) QueryTunnel(id uint, req *dto.TrafficQueryReq) (*dto.TrafficSeriesResp, error) {
+	if _, err := s.tunnelRepo.FindByID(id); err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrTunnelNotFound
+		}
+		return nil, err
+	}
+	return s.query(model.TrafficResourceTunnel, id, req, time.Now())
+}
+
+// query 按步长聚合对象的流量采样，空时间桶补零
+func (s *TrafficService) query(resourceType model.TrafficResourceType, id uint, req *dto.TrafficQueryReq, now time.Time) (*dto.TrafficSeriesResp, error) {
+	from, to, err := parseTrafficRange(req, now)
+	if err != nil {
+		return nil, err
+	}
+	step, err := parseTrafficStep(req.Step, to.Sub(from))
+	if err != nil {
+		return nil, err
+	}
+
+	// 计算时间桶边界
+	start := alignTrafficBucket(from, step)
+	var bounds []time.Time
+	for t := start; t.Before(to); t = nextTrafficBucket(t, step) {
+		if len(bounds) >= trafficMaxPoints {
+			return nil, errors.ErrTrafficTooManyPoints
+		}
+		bounds = append(bounds, t)
+	}
+
+	period := trafficSourcePeriod(step)
+	samples, err := s.repo.FindByResource(resourceType, id, period, start, to)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.TrafficSeriesResp{
+		ResourceType: string(resourceType),
+		ResourceID:   id,
+		From:         start,
+		To:           to,
+		Step:         formatTrafficStep(step),
+		Source:       string(period),
+		Points:       make([]dto.TrafficPoint, len(bounds)),
+	}
+	for i, t := range bounds {
+		resp.Points[i].Time = t
+	}
+
+	// 采样按时间正序，逐个归入所在时间桶
+	i := 0
+	for _, sample := range samples {
+		for i+1 < len(bounds) && !sample.BucketTime.Before(bounds[i+1]) {
+			i++
+		}
+		p := &resp.Points[i]
+		p.InputBytes += sample.InputBytes
+		p.OutputBytes += sample.OutputBytes
+		p.TotalBytes += sample.InputBytes + sample.OutputBytes
+		p.Connections += sample.Connections
+
+		resp.InputBytes += sample.InputBytes
+		resp.OutputBytes += sample.OutputBytes
+		resp.TotalBytes += sample.InputBytes + sample.OutputBytes
+		resp.Connections += sample.Connections
+	}
+	return resp, nil
+}
+
+// parseTrafficRange 解析查询时间范围，默认查询最近 24 小时
+func parseTrafficRange(req *dto.TrafficQueryReq, now time.Time) (time.Time, time.Time, error) {
+	to := now
+	if req.To != "" {
+		t, err := parseTrafficTime(req.To)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.ErrTrafficRangeInvalid
+		}
+		to = t
+	}
+
+	from := to.Add(-trafficDefaultRange)
+	if req.From != "" {
+		t, err := parseTrafficTime(req.From)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.ErrTrafficRangeInvalid
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.ErrTrafficRangeInvalid
+	}
+	return from, to, nil
+}
+
+// parseTrafficTime 解析 RFC3339 或 Unix 秒格式的时间
+func parseTrafficTime(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseTrafficStep 解析查询步长，支持 Go 时长格式及 "d"（天）后缀
+// 未指定时按时间范围选择数据点不超过 trafficAutoPoints 的最小步长
+func parseTrafficStep(s string, span time.Duration) (time.Duration, error) {
+	if s == "" {
+		for _, step := range trafficAutoSteps {
+			if span/step <= trafficAutoPoints {
+				return step, nil
+			}
+		}
+		return trafficAutoSteps[len(trafficAutoSteps)-1], nil
+	}
+
+	var step time.Duration
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, errors.ErrTrafficStepInvalid
+		}
+		step = time.Duration(days) * oneDay
+	} else {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, errors.ErrTrafficStepInvalid
+		}
+		step = d
+	}
+
+	if step < time.Minute || step%time.Minute != 0 {
+		return 0, errors.ErrTrafficStepInvalid
+	}
+	return step, nil
+}
+
+// formatTrafficStep 格式化步长（如 5m、1h、1d）
+func formatTrafficStep(step time.Duration) string {
+	switch {
+	case step%oneDay == 0:
+		return fmt.Sprintf("%dd", step/oneDay)
+	case step%time.Hour == 0:
+		return fmt.Sprintf("%dh", step/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", step/time.Minute)
+	}
+}
+
+// trafficSourcePeriod 选择能整除步长的最粗采样粒度
+func trafficSourcePeriod(step time.Duration) model.TrafficPeriod {
+	switch {
+	case step%oneDay == 0:
+		return model.TrafficPeriodDay
+	case step%time.Hour == 0:
+		return model.TrafficPeriodHour
+	default:
+		return model.TrafficPeriodMinute
+	}
+}
+
+// alignTrafficBucket 将时间对齐到步长的时间桶起点
+// 能整除一天的步长按本地时区零点对齐，使小时和天的时间桶与本地日期一致
+func alignTrafficBucket(t time.Time, step time.Duration) time.Time {
+	if step%oneDay == 0 {
+		return dayBucket(t)
+	}
+	if oneDay%step == 0 {
+		midnight := dayBucket(t)
+		return midnight.Add(t.Sub(midnight) / step * step)
+	}
+	return t.Truncate(step)
+}
+
+// nextTrafficBucket 下一个时间桶起点，按天的步长按日历日递增以适应夏令时
+func nextTrafficBucket(t time.Time, step time.Duration) time.Time {
+	if step%oneDay == 0 {
+		return t.In(time.Local).AddDate(0, 0, int(step/oneDay)).UTC()
+	}
+	return t.Add(step)
+}
+
+// hourBucket 本地时区整点 (UTC)
+// 按本地时区偏移对齐，非整小时时区（如 UTC+5:30）的小时桶才能完整落入本地日
+func hourBucket(t time.Time) time.Time {
+	_, offset := t.In(time.Local).Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.UTC().Add(shift).Truncate(time.Hour).Add(-shift)
+}
+
+// dayBucket 本地时区当天零点 (UTC)
+func dayBucket(t time.Time) time.Time {
+	local := t.In(time.Local)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local).UTC()
+}
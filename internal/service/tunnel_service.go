@@ -2,6 +2,7 @@
 package service
 
 import (
+	"context"
 	stderrors "errors"
 	"fmt"
 
@@ -59,6 +60,11 @@ func (s *TunnelService) Create(req *dto.CreateTunnelReq, userID uint, username s
 		return nil, err
 	}
 
+	// 维护中的节点不允许新建隧道
+	if entryNode.Maintenance || exitNode.Maintenance {
+		return nil, errors.ErrNodeMaintenance
+	}
+
 	// 创建隧道
 	tunnel := &model.GostTunnel{
 		Name:        req.Name,
@@ -129,7 +135,7 @@ func (s *TunnelService) Update(id uint, req *dto.UpdateTunnelReq, userID uint, u
 
 // Delete 删除隧道
 // 如果有规则正在使用此隧道，不允许删除
-func (s *TunnelService) Delete(id uint, userID uint, username string, ip, userAgent string) error {
+func (s *TunnelService) Delete(ctx context.Context, id uint, userID uint, username string, ip, userAgent string) error {
 	tunnel, err := s.tunnelRepo.FindByID(id)
 	if err != nil {
 		if stderrors.Is(err, gorm.ErrRecordNotFound) {
@@ -149,7 +155,7 @@ func (s *TunnelService) Delete(id uint, userID uint, username string, ip, userAg
 
 	// 如果隧道正在运行，先停止
 	if tunnel.Status == model.TunnelStatusRunning {
-		if err = s.Stop(id, userID, username, ip, userAgent); err != nil {
+		if err = s.Stop(ctx, id, userID, username, ip, userAgent); err != nil {
 			logger.Warnf("停止隧道失败: %v", err)
 		}
 	}
@@ -214,7 +220,7 @@ func (s *TunnelService) List(req *dto.TunnelListReq) ([]model.GostTunnel, int64,
 
 // Start 启动隧道
 // 在出口节点创建 Relay 服务，在入口节点创建 Chain 连接到出口节点
-func (s *TunnelService) Start(id uint, userID uint, username string, ip, userAgent string) error {
+func (s *TunnelService) Start(ctx context.Context, id uint, userID uint, username string, ip, userAgent string) error {
 	tunnel, err := s.tunnelRepo.FindByID(id)
 	if err != nil {
 		return err
@@ -222,6 +228,7 @@ func (s *TunnelService) Start(id uint, userID uint, username string, ip, userAge
 
 	// 已在运行中则跳过
 	if tunnel.Status == model.TunnelStatusRunning {
+		_ = s.tunnelRepo.UpdateDesiredRunning(id, true)
 		return nil
 	}
 
@@ -245,26 +252,18 @@ func (s *TunnelService) Start(id uint, userID uint, username string, ip, userAge
 
 	// 步骤1：在出口节点创建 Relay 服务
 	exitClient := utils.GetGostClient(exitNode)
-	relayServiceName := fmt.Sprintf("relay-tunnel-%d", tunnel.ID)
+	// 沿用已记录的服务和链名称（导入的隧道使用节点上原有的名称）
+	relayServiceName := tunnelRelayServiceName(tunnel)
 
-	relaySvc := &gost.ServiceConfig{
-		Name: relayServiceName,
-		Addr: fmt.Sprintf(":%d", tunnel.RelayPort),
-		Handler: &gost.HandlerConfig{
-			Type: "relay",
-		},
-		Listener: &gost.ListenerConfig{
-			Type: tunnel.Protocol,
-		},
-	}
+	relaySvc := buildTunnelRelayService(tunnel, relayServiceName)
 
-	if err = exitClient.CreateService(relaySvc); err != nil {
+	if err = exitClient.CreateService(ctx, relaySvc); err != nil {
 		_ = s.tunnelRepo.UpdateStatus(id, model.TunnelStatusError)
 		return errors.ErrTunnelRelayCreateFailed
 	}
 
 	// 保存出口节点配置
-	_ = exitClient.SaveConfig()
+	_ = exitClient.SaveConfig(ctx)
 
 	// 步骤2：在入口节点创建 Chain 连接到出口节点的 Relay 服务
 	entryClient := utils.GetGostClient(entryNode)
@@ -272,51 +271,33 @@ func (s *TunnelService) Start(id uint, userID uint, username string, ip, userAge
 	// 从出口节点配置中获取主机 IP
 	exitHost := exitNode.Address
 	if exitHost == "" {
-		// 回滚：删除出口节点的 Relay 服务
-		_ = exitClient.DeleteService(relayServiceName)
-		_ = exitClient.SaveConfig()
+		// 回滚：删除出口节点的 Relay 服务（请求已取消时仍需完成）
+		rollbackCtx := context.WithoutCancel(ctx)
+		_ = exitClient.DeleteService(rollbackCtx, relayServiceName)
+		_ = exitClient.SaveConfig(rollbackCtx)
 		_ = s.tunnelRepo.UpdateStatus(id, model.TunnelStatusError)
 		return errors.ErrExtractHostFailed
 	}
 
-	chainName := fmt.Sprintf("tunnel-%d-chain", tunnel.ID)
-	relayAddr := fmt.Sprintf("%s:%d", exitHost, tunnel.RelayPort)
+	chainName := tunnelChainName(tunnel)
+	chain := buildTunnelChain(tunnel, chainName, exitHost)
 
-	chain := &gost.ChainConfig{
-		Name: chainName,
-		Hops: []*gost.HopConfig{
-			{
-				Name: "hop-0",
-				Nodes: []*gost.NodeConfig{
-					{
-						Name: "exit-relay",
-						Addr: relayAddr,
-						Connector: &gost.ConnectorConfig{
-							Type: "relay",
-						},
-						Dialer: &gost.DialerConfig{
-							Type: tunnel.Protocol,
-						},
-					},
-				},
-			},
-		},
-	}
-
-	if err = entryClient.CreateChain(chain); err != nil {
-		// 回滚：删除出口节点的 Relay 服务
-		_ = exitClient.DeleteService(relayServiceName)
-		_ = exitClient.SaveConfig()
+	if err = entryClient.CreateChain(ctx, chain); err != nil {
+		// 回滚：删除出口节点的 Relay 服务（请求已取消时仍需完成）
+		rollbackCtx := context.WithoutCancel(ctx)
+		_ = exitClient.DeleteService(rollbackCtx, relayServiceName)
+		_ = exitClient.SaveConfig(rollbackCtx)
 		_ = s.tunnelRepo.UpdateStatus(id, model.TunnelStatusError)
 		return errors.ErrTunnelChainCreateFailed
 	}
 
 	// 保存入口节点配置
-	_ = entryClient.SaveConfig()
+	_ = entryClient.SaveConfig(ctx)
 
 	// 更新隧道状态和服务 ID
 	_ = s.tunnelRepo.UpdateServiceInfo(id, relayServiceName, chainName)
 	_ = s.tunnelRepo.UpdateStatus(id, model.TunnelStatusRunning)
+	_ = s.tunnelRepo.UpdateDesiredRunning(id, true)
 
 	s.logService.Record(
 		userID,
@@ -334,12 +315,15 @@ func (s *TunnelService) Start(id uint, userID uint, username string, ip, userAge
 
 // Stop 停止隧道
 // 删除入口节点的 Chain 和出口节点的 Relay 服务
-func (s *TunnelService) Stop(id uint, userID uint, username string, ip, userAgent string) error {
+func (s *TunnelService) Stop(ctx context.Context, id uint, userID uint, username string, ip, userAgent string) error {
 	tunnel, err := s.tunnelRepo.FindByID(id)
 	if err != nil {
 		return err
 	}
 
+	// 用户主动停止，清除期望运行状态，避免节点恢复时被自动重建
+	_ = s.tunnelRepo.UpdateDesiredRunning(id, false)
+
 	// 未运行则跳过
 	if tunnel.Status != model.TunnelStatusRunning {
 		return nil
@@ -352,19 +336,19 @@ func (s *TunnelService) Stop(id uint, userID uint, username string, ip, userAgen
 	// 步骤1：删除入口节点的 Chain
 	if entryNode != nil && entryNode.Status == model.NodeStatusOnline && tunnel.ChainID != "" {
 		entryClient := utils.GetGostClient(entryNode)
-		if err = entryClient.DeleteChain(tunnel.ChainID); err != nil {
+		if err = entryClient.DeleteChain(ctx, tunnel.ChainID); err != nil {
 			logger.Warnf("删除隧道 Chain 失败: %v", err)
 		}
-		_ = entryClient.SaveConfig()
+		_ = entryClient.SaveConfig(ctx)
 	}
 
 	// 步骤2：删除出口节点的 Relay 服务
 	if exitNode != nil && exitNode.Status == model.NodeStatusOnline && tunnel.ServiceID != "" {
 		exitClient := utils.GetGostClient(exitNode)
-		if err = exitClient.DeleteService(tunnel.ServiceID); err != nil {
+		if err = exitClient.DeleteService(ctx, tunnel.ServiceID); err != nil {
 			logger.Warnf("删除隧道 Relay 服务失败: %v", err)
 		}
-		_ = exitClient.SaveConfig()
+		_ = exitClient.SaveConfig(ctx)
 	}
 
 	// 更新状态
@@ -401,3 +385,57 @@ func (s *TunnelService) GetEntryNodeID(tunnelID uint) (uint, error) {
 	}
 	return tunnel.EntryNodeID, nil
 }
+
+// buildTunnelRelayService 生成隧道出口节点的 Relay 服务配置
+func buildTunnelRelayService(tunnel *model.GostTunnel, serviceName string) *gost.ServiceConfig {
+	return &gost.ServiceConfig{
+		Name: serviceName,
+		Addr: fmt.Sprintf(":%d", tunnel.RelayPort),
+		Handler: &gost.HandlerConfig{
+			Type: "relay",
+		},
+		Listener: &gost.ListenerConfig{
+			Type: tunnel.Protocol,
+		},
+	}
+}
+
+// buildTunnelChain 生成隧道入口节点连接出口 Relay 服务的 Chain 配置
+func buildTunnelChain(tunnel *model.GostTunnel, chainName, exitHost string) *gost.ChainConfig {
+	return &gost.ChainConfig{
+		Name: chainName,
+		Hops: []*gost.HopConfig{
+			{
+				Name: "hop-0",
+				Nodes: []*gost.NodeConfig{
+					{
+						Name: "exit-relay",
+						Addr: fmt.Sprintf("%s:%d", exitHost, tunnel.RelayPort),
+						Connector: &gost.ConnectorConfig{
+							Type: "relay",
+						},
+						Dialer: &gost.DialerConfig{
+							Type: tunnel.Protocol,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// tunnelRelayServiceName 获取隧道出口 Relay 服务名称
+func tunnelRelayServiceName(tunnel *model.GostTunnel) string {
+	if tunnel.ServiceID != "" {
+		return tunnel.ServiceID
+	}
+	return fmt.Sprintf("relay-tunnel-%d", tunnel.ID)
+}
+
+// tunnelChainName 获取隧道入口 Chain 名称
+func tunnelChainName(tunnel *model.GostTunnel) string {
+	if tunnel.ChainID != "" {
+		return tunnel.ChainID
+	}
+	return fmt.Sprintf("tunnel-%d-chain", tunnel.ID)
+}
@@ -0,0 +1,308 @@
+// Package sshtunnel 经 SSH 隧道访问节点 Gost API
+// 用于 Gost API 仅监听本机、不对外开放端口的节点：面板以 SSH 登录节点，
+// 通过 direct-tcpip 通道连接节点本机的 API 端口。每个节点复用一条 SSH 连接。
+// 同时提供独立 SSH 连接执行节点运维命令（部署、升级、卸载）
+package sshtunnel
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"gost-panel/pkg/gost"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Config 节点 SSH 连接配置
+type Config struct {
+	Addr       string        // SSH 地址 host:port
+	User       string        // 用户名
+	Password   string        // 密码（与私钥至少设置一项）
+	PrivateKey string        // PEM 格式私钥
+	Passphrase string        // 私钥密码
+	HostKey    string        // authorized_keys 格式的主机公钥，用于校验服务器身份
+	Timeout    time.Duration // 连接超时
+}
+
+// Pool 节点 SSH 连接池，按节点缓存 SSH 连接及基于其构建的 HTTP 传输
+type Pool struct {
+	mu      sync.Mutex
+	entries map[uint]*entry
+}
+
+// entry 单个节点的 SSH 连接
+type entry struct {
+	cfg       Config
+	tlsCfg    gost.TLSConfig
+	transport *http.Transport
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// defaultPool 全局连接池
+var defaultPool = NewPool()
+
+// Default 获取全局连接池
+func Default() *Pool {
+	return defaultPool
+}
+
+// NewPool 创建连接池
+func NewPool() *Pool {
+	return &Pool{entries: make(map[uint]*entry)}
+}
+
+// Transport 获取经节点 SSH 连接发送请求的 HTTP 传输
+// tlsCfg 不为空时经隧道使用 HTTPS；连接配置或 TLS 配置变化时重建连接
+func (p *Pool) Transport(nodeID uint, cfg Config, tlsCfg *gost.TLSConfig) http.RoundTripper {
+	var tlsValue gost.TLSConfig
+	if tlsCfg != nil {
+		tlsValue = *tlsCfg
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.entries[nodeID]; ok {
+		if e.cfg == cfg && e.tlsCfg == tlsValue {
+			return e.transport
+		}
+		e.close()
+		delete(p.entries, nodeID)
+	}
+
+	var tlsClientCfg *tls.Config
+	if tlsCfg != nil {
+		built, err := tlsCfg.Build()
+		if err != nil {
+			return gost.ErrorTransport(fmt.Errorf("TLS 配置无效: %w", err))
+		}
+		tlsClientCfg = built
+	}
+
+	e := &entry{cfg: cfg, tlsCfg: tlsValue}
+	e.transport = &http.Transport{
+		DialContext:         e.dial,
+		TLSClientConfig:     tlsClientCfg,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	p.entries[nodeID] = e
+
+	return e.transport
+}
+
+// Close 关闭节点 SSH 连接（节点修改或删除时调用）
+func (p *Pool) Close(nodeID uint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.entries[nodeID]; ok {
+		e.close()
+		delete(p.entries, nodeID)
+	}
+}
+
+// dial 经 SSH 连接打开到节点本机地址的 TCP 通道，SSH 连接断开时自动重连
+func (e *entry) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := e.sshClient()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := client.DialContext(ctx, network, addr)
+	if err != nil {
+		// 通道打开失败可能是 SSH 连接已失效，丢弃后下次请求重连
+		e.mu.Lock()
+		if e.client == client {
+			_ = client.Close()
+			e.client = nil
+		}
+		e.mu.Unlock()
+		return nil, fmt.Errorf("SSH 隧道连接 %s 失败: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// sshClient 获取或建立 SSH 连接
+func (e *entry) sshClient() (*ssh.Client, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.client != nil {
+		return e.client, nil
+	}
+
+	client, err := Dial(e.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// 连接断开后清理，下次请求重连
+	go func() {
+		_ = client.Wait()
+		e.mu.Lock()
+		if e.client == client {
+			e.client = nil
+		}
+		e.mu.Unlock()
+	}()
+
+	e.client = client
+	return client, nil
+}
+
+// close 关闭 SSH 连接及空闲 HTTP 连接
+func (e *entry) close() {
+	e.transport.CloseIdleConnections()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.client != nil {
+		_ = e.client.Close()
+		e.client = nil
+	}
+}
+
+// Dial 建立独立的 SSH 连接（不进入连接池），用于执行运维命令，使用后需关闭
+func Dial(cfg Config) (*ssh.Client, error) {
+	clientCfg, err := clientConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := ssh.Dial("tcp", cfg.Addr, clientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("SSH 连接 %s 失败: %w", cfg.Addr, err)
+	}
+	return client, nil
+}
+
+// Run 在 SSH 连接上执行命令，stdin 不为空时作为命令标准输入，标准输出与错误输出写入 out
+// 命令以非零状态退出时返回 *ssh.ExitError
+func Run(client *ssh.Client, cmd string, stdin io.Reader, out io.Writer) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("创建 SSH 会话失败: %w", err)
+	}
+	defer session.Close()
+
+	// 标准输出与错误输出由会话并发写入，串行化后写入 out
+	w := &syncWriter{w: out}
+	session.Stdin = stdin
+	session.Stdout = w
+	session.Stderr = w
+	return session.Run(cmd)
+}
+
+// syncWriter 并发安全的 io.Writer
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write 实现 io.Writer
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// clientConfig 构建 SSH 客户端配置
+func clientConfig(cfg Config) (*ssh.ClientConfig, error) {
+	if cfg.HostKey == "" {
+		return nil, errors.New("未记录 SSH 主机公钥")
+	}
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cfg.HostKey))
+	if err != nil {
+		return nil, fmt.Errorf("SSH 主机公钥无效: %w", err)
+	}
+
+	auths, err := AuthMethods(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            cfg.User,
+		Auth:            auths,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         cfg.Timeout,
+	}, nil
+}
+
+// AuthMethods 根据配置构建 SSH 认证方式，私钥优先
+func AuthMethods(cfg Config) ([]ssh.AuthMethod, error) {
+	var auths []ssh.AuthMethod
+
+	if cfg.PrivateKey != "" {
+		signer, err := parsePrivateKey(cfg.PrivateKey, cfg.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auths = append(auths, ssh.Password(cfg.Password))
+	}
+	if len(auths) == 0 {
+		return nil, errors.New("未配置 SSH 密码或私钥")
+	}
+	return auths, nil
+}
+
+// parsePrivateKey 解析私钥，有密码时使用密码解密
+func parsePrivateKey(key, passphrase string) (ssh.Signer, error) {
+	var (
+		signer ssh.Signer
+		err    error
+	)
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(key), []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(key))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SSH 私钥解析失败: %w", err)
+	}
+	return signer, nil
+}
+
+// ValidateHostKey 校验 authorized_keys 格式的主机公钥
+func ValidateHostKey(hostKey string) error {
+	_, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	return err
+}
+
+// errHostKeyCaptured 获取主机公钥后中止握手
+var errHostKeyCaptured = errors.New("host key captured")
+
+// FetchHostKey 连接 SSH 服务器获取主机公钥（authorized_keys 格式），不进行登录
+func FetchHostKey(addr string, timeout time.Duration) (string, error) {
+	var hostKey ssh.PublicKey
+	cfg := &ssh.ClientConfig{
+		User: "gost-panel",
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyCaptured
+		},
+		Timeout: timeout,
+	}
+
+	client, err := ssh.Dial("tcp", addr, cfg)
+	if client != nil {
+		_ = client.Close()
+	}
+	if hostKey == nil {
+		return "", fmt.Errorf("获取 SSH 主机公钥失败: %w", err)
+	}
+	return string(bytes.TrimSpace(ssh.MarshalAuthorizedKey(hostKey))), nil
+}
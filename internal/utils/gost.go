@@ -1,19 +1,230 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"gost-panel/internal/agent"
 	"gost-panel/internal/model"
+	"gost-panel/internal/sshtunnel"
 	"gost-panel/pkg/gost"
 )
 
-// GetGostClient 根据节点配置创建 Gost 客户端
+const (
+	// gostRequestTimeout Gost API 单次请求默认超时，调用方可通过 context 截止时间覆盖
+	gostRequestTimeout = 5 * time.Second
+	// sshConnectTimeout SSH 连接超时
+	sshConnectTimeout = 10 * time.Second
+)
+
+// gostClients 节点 Gost 客户端缓存（节点 ID -> *cachedGostClient），同一节点复用客户端及连接
+var gostClients sync.Map
+
+// cachedGostClient 缓存的客户端及构建时的节点连接配置
+// transport 为直连 TLS 节点专用的 HTTP 传输，客户端失效时关闭其空闲连接
+type cachedGostClient struct {
+	key       gostClientKey
+	client    *gost.Client
+	transport http.RoundTripper
+}
+
+// close 关闭客户端专用传输上的空闲连接
+func (c *cachedGostClient) close() {
+	if c.transport != nil {
+		gost.CloseIdleTransport(c.transport)
+	}
+}
+
+// gostClientKey 影响客户端构建的节点连接配置，与缓存不一致时重建客户端
+type gostClientKey struct {
+	mode      model.NodeConnectionMode
+	address   string
+	port      int
+	username  string
+	password  string
+	tls       gost.TLSConfig
+	tlsOn     bool
+	sshPort   int
+	sshUser   string
+	sshPass   string
+	sshKey    string
+	sshPhrase string
+	sshHost   string
+}
+
+// newGostClientKey 提取节点连接配置
+func newGostClientKey(node *model.GostNode) gostClientKey {
+	key := gostClientKey{
+		mode:      node.ConnectionMode,
+		address:   node.Address,
+		port:      node.Port,
+		username:  node.Username,
+		password:  node.Password,
+		tlsOn:     node.TLSEnabled,
+		sshPort:   node.SSHPort,
+		sshUser:   node.SSHUser,
+		sshPass:   node.SSHPassword,
+		sshKey:    node.SSHPrivateKey,
+		sshPhrase: node.SSHPassphrase,
+		sshHost:   node.SSHHostKey,
+	}
+	if tlsCfg := GetNodeTLSConfig(node); tlsCfg != nil {
+		key.tls = *tlsCfg
+	}
+	return key
+}
+
+// GetGostClient 获取节点的 Gost 客户端
+// 客户端按节点 ID 缓存复用，节点连接配置变化时自动重建
 func GetGostClient(node *model.GostNode) *gost.Client {
-	return gost.NewClient(&gost.Config{
-		APIURL:   fmt.Sprintf("%s://%s:%d/api", "http", node.Address, node.Port),
+	// 未保存的节点不缓存
+	if node.ID == 0 {
+		client, _ := newGostClient(node)
+		return client
+	}
+
+	key := newGostClientKey(node)
+	if v, ok := gostClients.Load(node.ID); ok {
+		if cached := v.(*cachedGostClient); cached.key == key {
+			return cached.client
+		}
+	}
+
+	// 连接配置变化（如证书轮换）时替换客户端，并释放旧客户端的连接
+	client, transport := newGostClient(node)
+	if old, loaded := gostClients.Swap(node.ID, &cachedGostClient{key: key, client: client, transport: transport}); loaded {
+		old.(*cachedGostClient).close()
+	}
+	return client
+}
+
+// InvalidateGostClient 移除节点缓存的客户端并释放其连接（节点修改或删除时调用）
+func InvalidateGostClient(nodeID uint) {
+	if old, loaded := gostClients.LoadAndDelete(nodeID); loaded {
+		old.(*cachedGostClient).close()
+	}
+}
+
+// newGostClient 根据节点配置创建 Gost 客户端
+// 直连 TLS 节点返回客户端专用的 HTTP 传输，其余情况返回 nil
+func newGostClient(node *model.GostNode) (*gost.Client, http.RoundTripper) {
+	scheme := "http"
+	if node.TLSEnabled {
+		scheme = "https"
+	}
+
+	cfg := &gost.Config{
+		APIURL:   fmt.Sprintf("%s://%s:%d/api", scheme, node.Address, node.Port),
 		Username: node.Username,
 		Password: node.Password,
-		Timeout:  5 * time.Second,
-	})
+		Timeout:  gostRequestTimeout,
+		TLS:      GetNodeTLSConfig(node),
+	}
+
+	switch node.ConnectionMode {
+	case model.NodeConnectionAgent:
+		// Agent 连接方式：请求经反向连接转发，路径相对 Gost API 根路径，由 Agent 拼接本机 API 地址
+		cfg.APIURL = fmt.Sprintf("http://agent-node-%d", node.ID)
+		cfg.Transport = agent.Default().Transport(node.ID)
+	case model.NodeConnectionSSH:
+		// SSH 连接方式：经 SSH 隧道访问节点本机的 API 端口
+		cfg.APIURL = fmt.Sprintf("%s://127.0.0.1:%d/api", scheme, node.Port)
+		sshCfg, err := GetNodeSSHConfig(node)
+		if err != nil {
+			cfg.Transport = gost.ErrorTransport(err)
+		} else {
+			cfg.Transport = sshtunnel.Default().Transport(node.ID, sshCfg, cfg.TLS)
+		}
+	default:
+		if cfg.TLS != nil {
+			cfg.Transport = gost.NewTLSTransport(*cfg.TLS)
+			return gost.NewClient(cfg), cfg.Transport
+		}
+	}
+
+	return gost.NewClient(cfg), nil
+}
+
+// GetGostMetricsClient 获取抓取节点 Gost 指标接口的 HTTP 客户端及地址
+// 指标接口为 Gost 单独监听的 HTTP 端口：直连方式直接访问节点地址，SSH 方式经隧道访问节点本机端口
+func GetGostMetricsClient(node *model.GostNode) (*http.Client, string, error) {
+	if node.MetricsPort == 0 {
+		return nil, "", errors.New("节点未配置 Gost 指标端口")
+	}
+	path := node.MetricsPath
+	if path == "" {
+		path = "/metrics"
+	}
+
+	client := &http.Client{Timeout: gostRequestTimeout}
+	switch node.ConnectionMode {
+	case model.NodeConnectionAgent:
+		// Agent 反向连接只转发 Gost API 请求
+		return nil, "", errors.New("Agent 连接方式暂不支持抓取 Gost 指标")
+	case model.NodeConnectionSSH:
+		sshCfg, err := GetNodeSSHConfig(node)
+		if err != nil {
+			return nil, "", err
+		}
+		// 与 API 请求共用隧道连接（TLS 配置只作用于 https 请求）
+		client.Transport = sshtunnel.Default().Transport(node.ID, sshCfg, GetNodeTLSConfig(node))
+		return client, fmt.Sprintf("http://127.0.0.1:%d%s", node.MetricsPort, path), nil
+	}
+	return client, fmt.Sprintf("http://%s%s", net.JoinHostPort(node.Address, strconv.Itoa(node.MetricsPort)), path), nil
+}
+
+// GetNodeSSHConfig 获取节点 SSH 连接配置（解密凭据）
+func GetNodeSSHConfig(node *model.GostNode) (sshtunnel.Config, error) {
+	password, err := DecryptSecret(node.SSHPassword)
+	if err != nil {
+		return sshtunnel.Config{}, fmt.Errorf("解密 SSH 密码失败: %w", err)
+	}
+	privateKey, err := DecryptSecret(node.SSHPrivateKey)
+	if err != nil {
+		return sshtunnel.Config{}, fmt.Errorf("解密 SSH 私钥失败: %w", err)
+	}
+	passphrase, err := DecryptSecret(node.SSHPassphrase)
+	if err != nil {
+		return sshtunnel.Config{}, fmt.Errorf("解密 SSH 私钥密码失败: %w", err)
+	}
+
+	return sshtunnel.Config{
+		Addr:       GetNodeSSHAddr(node),
+		User:       node.SSHUser,
+		Password:   password,
+		PrivateKey: privateKey,
+		Passphrase: passphrase,
+		HostKey:    node.SSHHostKey,
+		Timeout:    sshConnectTimeout,
+	}, nil
+}
+
+// GetNodeSSHAddr 获取节点 SSH 地址
+func GetNodeSSHAddr(node *model.GostNode) string {
+	port := node.SSHPort
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(node.Address, strconv.Itoa(port))
+}
+
+// GetNodeTLSConfig 获取节点 API 的 TLS 配置（解密客户端私钥），未开启 TLS 返回 nil
+// 私钥解密失败时不设置私钥，构建 TLS 配置时报错
+func GetNodeTLSConfig(node *model.GostNode) *gost.TLSConfig {
+	if !node.TLSEnabled {
+		return nil
+	}
+	clientKey, _ := DecryptSecret(node.TLSClientKey)
+	return &gost.TLSConfig{
+		CACert:             node.TLSCACert,
+		ClientCert:         node.TLSClientCert,
+		ClientKey:          clientKey,
+		ServerName:         node.TLSServerName,
+		InsecureSkipVerify: node.TLSSkipVerify,
+	}
 }
@@ -2,21 +2,40 @@ package gost
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
-	"gost-panel/internal/errors"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"gost-panel/pkg/logger"
 )
 
+const (
+	// defaultTimeout 单次请求默认超时
+	defaultTimeout = 10 * time.Second
+	// maxAttempts 幂等请求最大尝试次数
+	maxAttempts = 3
+	// retryBaseDelay 重试退避基础间隔，每次重试翻倍
+	retryBaseDelay = 200 * time.Millisecond
+)
+
+// Gost API 错误码
+const (
+	apiCodeDuplicated = 40002 // 对象已存在
+	apiCodeNotFound   = 40004 // 对象不存在
+)
+
 // Client Gost API 客户端
+// 客户端可并发使用，同一节点应复用客户端以复用连接
 type Client struct {
 	baseURL    string
 	username   string
 	password   string
+	timeout    time.Duration
 	httpClient *http.Client
 }
 
@@ -25,345 +44,221 @@ type Config struct {
 	APIURL   string
 	Username string
 	Password string
-	Timeout  time.Duration
+	// Timeout 单次请求超时，ctx 未设置截止时间时生效，默认 10 秒
+	Timeout time.Duration
+	// TLS HTTPS 连接的 TLS 配置，为空使用系统默认 CA 校验
+	TLS *TLSConfig
+	// Transport 自定义 HTTP 传输（如经节点 Agent 反向连接转发），设置后忽略 TLS
+	Transport http.RoundTripper
 }
 
 // NewClient 创建 Gost 客户端
 func NewClient(cfg *Config) *Client {
 	timeout := cfg.Timeout
 	if timeout == 0 {
-		timeout = 10 * time.Second
+		timeout = defaultTimeout
 	}
 
-	return &Client{
-		baseURL:  cfg.APIURL,
-		username: cfg.Username,
-		password: cfg.Password,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
+	transport := cfg.Transport
+	if transport == nil && cfg.TLS != nil {
+		transport = NewTLSTransport(*cfg.TLS)
 	}
-}
-
-// ServiceConfig 服务配置
-type ServiceConfig struct {
-	Name      string           `json:"name"`
-	Addr      string           `json:"addr"`
-	Handler   *HandlerConfig   `json:"handler,omitempty"`
-	Listener  *ListenerConfig  `json:"listener,omitempty"`
-	Forwarder *ForwarderConfig `json:"forwarder,omitempty"`
-	Limiter   string           `json:"limiter,omitempty"`  // 流量速率限制器名称
-	CLimiter  string           `json:"climiter,omitempty"` // 并发连接数限制器名称
-	RLimiter  string           `json:"rlimiter,omitempty"` // 请求速率限制器名称
-	Observer  string           `json:"observer,omitempty"` // 观察器名称
-	Metadata  map[string]any   `json:"metadata,omitempty"` // 元数据配置
-	Status    *ServiceStatus   `json:"status,omitempty"`   // 服务运行状态
-}
-
-// ServiceStatus 服务运行时状态信息
-type ServiceStatus struct {
-	State string `json:"state"` // 状态: ready, running, failed, closed.
-}
-
-// GostResponse 通用 API 响应包裹
-type GostResponse struct {
-	Data json.RawMessage `json:"data"`
-}
-
-// HandlerConfig 处理器配置
-type HandlerConfig struct {
-	Type  string      `json:"type"`
-	Chain string      `json:"chain,omitempty"` // 链名称
-	Auth  *AuthConfig `json:"auth,omitempty"`
-}
 
-// ListenerConfig 监听器配置
-type ListenerConfig struct {
-	Type     string         `json:"type"`
-	Metadata map[string]any `json:"metadata,omitempty"` // 元数据配置
-}
-
-// ForwarderConfig 转发器配置
-type ForwarderConfig struct {
-	Nodes    []*ForwarderNode `json:"nodes"`
-	Selector *SelectorConfig  `json:"selector,omitempty"`
-}
-
-// SelectorConfig 选择器配置
-type SelectorConfig struct {
-	Strategy    string        `json:"strategy,omitempty"`
-	MaxFails    int           `json:"maxFails,omitempty"`
-	FailTimeout time.Duration `json:"failTimeout,omitempty"`
-}
-
-// ForwarderNode 转发目标节点
-type ForwarderNode struct {
-	Name string `json:"name"`
-	Addr string `json:"addr"`
-}
-
-// AuthConfig 认证配置
-type AuthConfig struct {
-	Username string `json:"username,omitempty"`
-	Password string `json:"password,omitempty"`
-}
-
-// ChainConfig 链配置
-type ChainConfig struct {
-	Name string       `json:"name"`
-	Hops []*HopConfig `json:"hops,omitempty"`
-}
-
-// HopConfig 跳配置
-type HopConfig struct {
-	Name  string        `json:"name"`
-	Nodes []*NodeConfig `json:"nodes,omitempty"`
-}
-
-// NodeConfig 链节点配置
-type NodeConfig struct {
-	Name      string           `json:"name"`
-	Addr      string           `json:"addr"`
-	Connector *ConnectorConfig `json:"connector,omitempty"`
-	Dialer    *DialerConfig    `json:"dialer,omitempty"`
-}
-
-// ConnectorConfig 连接器配置
-type ConnectorConfig struct {
-	Type string      `json:"type"`
-	Auth *AuthConfig `json:"auth,omitempty"`
-}
-
-// DialerConfig 拨号器配置
-type DialerConfig struct {
-	Type string `json:"type"`
+	return &Client{
+		baseURL:    cfg.APIURL,
+		username:   cfg.Username,
+		password:   cfg.Password,
+		timeout:    timeout,
+		httpClient: &http.Client{Transport: transport},
+	}
 }
 
-// LimiterConfig 流量速率限制器配置
-// limits 格式: "$ 100MB 100MB" (服务级别 入站 出站)
-// limits 格式: "$$ 10MB" (连接级别)
-// limits 格式: "192.168.1.1 1MB 5MB" (IP级别)
-type LimiterConfig struct {
-	Name   string        `json:"name"`
-	Limits []string      `json:"limits,omitempty"` // 限制规则列表
-	Plugin *PluginConfig `json:"plugin,omitempty"` // 插件配置
+// APIError Gost API 错误响应
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
 }
 
-// CLimiterConfig 并发连接数限制器配置
-// limits 格式: "$ 1000" (服务级别最大连接数)
-// limits 格式: "$$ 100" (IP级别默认最大连接数)
-type CLimiterConfig struct {
-	Name   string        `json:"name"`
-	Limits []string      `json:"limits,omitempty"` // 限制规则列表
-	Plugin *PluginConfig `json:"plugin,omitempty"` // 插件配置
+// Error 实现 error 接口
+func (e *APIError) Error() string {
+	if e.Msg == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s (code %d)", e.Msg, e.Code)
 }
 
-// RLimiterConfig 请求速率限制器配置
-// limits 格式: "$ 100" (服务级别每秒请求数)
-// limits 格式: "$$ 10" (IP级别默认每秒请求数)
-type RLimiterConfig struct {
-	Name   string        `json:"name"`
-	Limits []string      `json:"limits,omitempty"` // 限制规则列表
-	Plugin *PluginConfig `json:"plugin,omitempty"` // 插件配置
+// IsDuplicated 判断错误是否为对象已存在
+func IsDuplicated(err error) bool {
+	var apiErr *APIError
+	if !stderrors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == apiCodeDuplicated || strings.Contains(apiErr.Msg, "duplicated")
 }
 
-// ObserverConfig 观察器配置
-type ObserverConfig struct {
-	Name   string        `json:"name"`
-	Plugin *PluginConfig `json:"plugin,omitempty"` // 插件配置
+// IsNotFound 判断错误是否为对象不存在
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if !stderrors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == apiCodeNotFound || apiErr.StatusCode == http.StatusNotFound ||
+		strings.Contains(apiErr.Msg, "not found")
 }
 
-// PluginConfig 插件配置
-type PluginConfig struct {
-	Type    string `json:"type,omitempty"`    // 插件类型: grpc, http
-	Addr    string `json:"addr,omitempty"`    // 插件地址
-	Timeout string `json:"timeout,omitempty"` // 超时时间
+// newAPIError 从非 2xx 响应构建错误
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Msg == "" {
+		apiErr.Msg = strings.TrimSpace(string(body))
+	}
+	return apiErr
 }
 
 // HealthCheck 健康检查
-func (c *Client) HealthCheck() error {
-	resp, err := c.doRequest("GET", "/config", nil)
-	if err != nil {
-		return err
+func (c *Client) HealthCheck(ctx context.Context) error {
+	if err := c.call(ctx, http.MethodGet, "/config", nil, nil); err != nil {
+		return fmt.Errorf("健康检查失败: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("健康检查失败，状态码: %d", resp.StatusCode)
-	}
-
 	return nil
 }
 
-// GostConfig Gost 完整配置
-type GostConfig struct {
-	Services  []ServiceConfig  `json:"services"`
-	Chains    []ChainConfig    `json:"chains"`
-	Limiters  []LimiterConfig  `json:"limiters"`
-	CLimiters []CLimiterConfig `json:"climiters"`
-	RLimiters []RLimiterConfig `json:"rlimiters"`
-	Observers []ObserverConfig `json:"observers"`
-}
-
 // GetConfig 获取节点配置
-func (c *Client) GetConfig() (*GostConfig, error) {
-	resp, err := c.doRequest("GET", "/config", nil)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("获取配置失败: %s", string(body))
-	}
-
+func (c *Client) GetConfig(ctx context.Context) (*GostConfig, error) {
 	var config GostConfig
-	if err = json.NewDecoder(resp.Body).Decode(&config); err != nil {
-		return nil, fmt.Errorf("解析配置失败: %v", err)
+	if err := c.call(ctx, http.MethodGet, "/config", nil, &config); err != nil {
+		return nil, fmt.Errorf("获取配置失败: %w", err)
 	}
-
 	return &config, nil
 }
 
 // SaveConfig 保存节点配置
-func (c *Client) SaveConfig() error {
-	resp, err := c.doRequest("POST", "/config?format=yaml", nil)
-	if err != nil {
-		return err
+func (c *Client) SaveConfig(ctx context.Context) error {
+	if err := c.call(ctx, http.MethodPost, "/config?format=yaml", nil, nil); err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("保存配置失败: %s", string(body))
-	}
-
 	return nil
 }
 
-// exists 检查指定路径的资源是否存在
-func (c *Client) exists(path string) bool {
-	resp, err := c.doRequest("GET", path, nil)
-	if err != nil {
-		return false
-	}
-	defer resp.Body.Close()
+// create 创建配置对象，对象已存在时视为成功
+func (c *Client) create(ctx context.Context, kind, label, name string, obj any) error {
+	_, err := c.createObject(ctx, kind, label, name, obj)
+	return err
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return false
+// createObject 创建配置对象，返回是否新建（对象已存在时返回 false）
+// 直接提交创建请求，由 Gost 返回的已存在错误判断，避免额外的存在性查询
+func (c *Client) createObject(ctx context.Context, kind, label, name string, obj any) (bool, error) {
+	err := c.call(ctx, http.MethodPost, "/config/"+kind, obj, nil)
+	if IsDuplicated(err) {
+		logger.Debugf("%s %s 已存在，跳过创建", label, name)
+		return false, nil
 	}
-
-	var gResp GostResponse
-	if err = json.NewDecoder(resp.Body).Decode(&gResp); err != nil {
-		return false
+	if err != nil {
+		return false, fmt.Errorf("创建%s失败: %w", label, err)
 	}
-
-	// 根据用户提供的 curl 结果，不存在时 data 为 null (RawMessage 为 "null")
-	return string(gResp.Data) != "null" && len(gResp.Data) > 0
+	return true, nil
 }
 
-// CreateService 创建服务 (幂等)
-func (c *Client) CreateService(svc *ServiceConfig) error {
-	path := fmt.Sprintf("/config/services/%s", svc.Name)
-	if c.exists(path) {
-		logger.Debugf("服务 %s 已存在，跳过创建", svc.Name)
+// delete 删除配置对象，对象不存在时视为成功
+func (c *Client) delete(ctx context.Context, kind, label, name string) error {
+	err := c.call(ctx, http.MethodDelete, fmt.Sprintf("/config/%s/%s", kind, name), nil, nil)
+	if IsNotFound(err) {
+		logger.Debugf("%s %s 不存在，跳过删除", label, name)
 		return nil
 	}
-
-	resp, err := c.doRequest("POST", "/config/services", svc)
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("创建服务失败: %s", string(body))
+		return fmt.Errorf("删除%s失败: %w", label, err)
 	}
-
 	return nil
 }
 
-// DeleteService 删除服务 (幂等)
-func (c *Client) DeleteService(name string) error {
-	path := fmt.Sprintf("/config/services/%s", name)
-	if !c.exists(path) {
-		logger.Debugf("服务 %s 不存在，跳过删除", name)
-		return nil
+// update 更新配置对象
+func (c *Client) update(ctx context.Context, kind, label, name string, obj any) error {
+	if err := c.call(ctx, http.MethodPut, fmt.Sprintf("/config/%s/%s", kind, name), obj, nil); err != nil {
+		return fmt.Errorf("更新%s失败: %w", label, err)
 	}
+	return nil
+}
 
-	resp, err := c.doRequest("DELETE", path, nil)
+// call 执行请求：非 2xx 响应返回 *APIError，out 不为空时解码响应体
+func (c *Client) call(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.doRequest(ctx, method, path, body)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("删除服务失败: %s", string(body))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newAPIError(resp)
 	}
-
-	return nil
-}
-
-// CreateChain 创建链 (幂等)
-func (c *Client) CreateChain(chain *ChainConfig) error {
-	path := fmt.Sprintf("/config/chains/%s", chain.Name)
-	if c.exists(path) {
-		logger.Debugf("链 %s 已存在，跳过创建", chain.Name)
+	if out == nil {
+		// 读完响应体以复用连接
+		_, _ = io.Copy(io.Discard, resp.Body)
 		return nil
 	}
-
-	resp, err := c.doRequest("POST", "/config/chains", chain)
-	if err != nil {
-		return err
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("创建链失败: %s", string(body))
-	}
-
 	return nil
 }
 
-// DeleteChain 删除链 (幂等)
-func (c *Client) DeleteChain(name string) error {
-	path := fmt.Sprintf("/config/chains/%s", name)
-	if !c.exists(path) {
-		logger.Debugf("链 %s 不存在，跳过删除", name)
-		return nil
+// doRequest 执行 HTTP 请求
+// 幂等请求（GET、PUT、DELETE）遇到网络错误或网关错误时按指数退避重试
+func (c *Client) doRequest(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
 	}
 
-	resp, err := c.doRequest("DELETE", path, nil)
-	if err != nil {
-		return err
+	attempts := 1
+	if method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete {
+		attempts = maxAttempts
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("删除链失败: %s", string(body))
-	}
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			if err = sleepContext(ctx, retryBaseDelay<<(i-1)); err != nil {
+				return nil, err
+			}
+			logger.Debugf("重试 Gost API 请求 %s %s (第 %d 次)", method, path, i)
+		}
 
-	return nil
+		resp, err = c.send(ctx, method, path, data)
+		if !retryable(ctx, resp, err) || i == attempts-1 {
+			break
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+	return resp, err
 }
 
-// doRequest 执行 HTTP 请求
-func (c *Client) doRequest(method, path string, body interface{}) (*http.Response, error) {
+// send 发送单次请求，ctx 未设置截止时间时使用客户端超时
+func (c *Client) send(ctx context.Context, method, path string, data []byte) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	}
+
 	var reqBody io.Reader
-	if body != nil {
-		data, err := json.Marshal(body)
-		if err != nil {
-			return nil, err
-		}
+	if data != nil {
 		reqBody = bytes.NewReader(data)
 	}
-
-	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
@@ -374,7 +269,54 @@ func (c *Client) doRequest(method, path string, body interface{}) (*http.Respons
 		req.SetBasicAuth(c.username, c.password)
 	}
 
-	return c.httpClient.Do(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// 超时上下文在响应体关闭后释放
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryable 判断请求是否可重试：调用方已取消、配置无效时不重试
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !IsPermanent(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// sleepContext 等待指定时间，ctx 取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cancelBody 关闭时释放请求上下文的响应体
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close 关闭响应体并释放上下文
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
 }
 
 // BuildTCPForwardService 构建 TCP 转发服务配置
@@ -406,7 +348,7 @@ func BuildTCPForwardService(name string, listenPort int, targets []string, strat
 			Selector: &SelectorConfig{
 				Strategy:    strategy,
 				MaxFails:    3,
-				FailTimeout: 30 * time.Second,
+				FailTimeout: Duration(30 * time.Second),
 			},
 		},
 	}
@@ -449,183 +391,8 @@ func BuildUDPForwardService(name string, listenPort int, targets []string, strat
 			Selector: &SelectorConfig{
 				Strategy:    strategy,
 				MaxFails:    3,
-				FailTimeout: 30 * time.Second,
+				FailTimeout: Duration(30 * time.Second),
 			},
 		},
 	}
 }
-
-// CreateLimiter 创建限流器 (幂等)
-func (c *Client) CreateLimiter(limiter *LimiterConfig) error {
-	path := fmt.Sprintf("/config/limiters/%s", limiter.Name)
-	if c.exists(path) {
-		logger.Debugf("限流器 %s 已存在，跳过创建", limiter.Name)
-		return nil
-	}
-
-	resp, err := c.doRequest("POST", "/config/limiters", limiter)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("创建限流器失败: %s", string(body))
-	}
-
-	return nil
-}
-
-// DeleteLimiter 删除限流器 (幂等)
-func (c *Client) DeleteLimiter(name string) error {
-	path := fmt.Sprintf("/config/limiters/%s", name)
-	if !c.exists(path) {
-		logger.Debugf("限流器 %s 不存在，跳过删除", name)
-		return nil
-	}
-
-	resp, err := c.doRequest("DELETE", path, nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("删除限流器失败: %s", string(body))
-	}
-
-	return nil
-}
-
-// CreateObserver 创建观察器 (幂等)
-func (c *Client) CreateObserver(observer *ObserverConfig) error {
-	path := fmt.Sprintf("/config/observers/%s", observer.Name)
-	if c.exists(path) {
-		logger.Debugf("观察器 %s 已存在，跳过创建", observer.Name)
-		return nil
-	}
-
-	resp, err := c.doRequest("POST", "/config/observers", observer)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return errors.ErrTunnelObserverCreateFailed
-	}
-
-	return nil
-}
-
-// DeleteObserver 删除观察器 (幂等)
-func (c *Client) DeleteObserver(name string) error {
-	path := fmt.Sprintf("/config/observers/%s", name)
-	if !c.exists(path) {
-		logger.Debugf("观察器 %s 不存在，跳过删除", name)
-		return nil
-	}
-
-	resp, err := c.doRequest("DELETE", path, nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("删除观察器失败: %s", string(body))
-	}
-
-	return nil
-}
-
-// CreateCLimiter 创建并发连接数限制器 (幂等)
-func (c *Client) CreateCLimiter(climiter *CLimiterConfig) error {
-	path := fmt.Sprintf("/config/climiters/%s", climiter.Name)
-	if c.exists(path) {
-		logger.Debugf("并发连接限制器 %s 已存在，跳过创建", climiter.Name)
-		return nil
-	}
-
-	resp, err := c.doRequest("POST", "/config/climiters", climiter)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("创建并发连接限制器失败: %s", string(body))
-	}
-
-	return nil
-}
-
-// DeleteCLimiter 删除并发连接数限制器 (幂等)
-func (c *Client) DeleteCLimiter(name string) error {
-	path := fmt.Sprintf("/config/climiters/%s", name)
-	if !c.exists(path) {
-		logger.Debugf("并发连接限制器 %s 不存在，跳过删除", name)
-		return nil
-	}
-
-	resp, err := c.doRequest("DELETE", path, nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("删除并发连接限制器失败: %s", string(body))
-	}
-
-	return nil
-}
-
-// CreateRLimiter 创建请求速率限制器 (幂等)
-func (c *Client) CreateRLimiter(rlimiter *RLimiterConfig) error {
-	path := fmt.Sprintf("/config/rlimiters/%s", rlimiter.Name)
-	if c.exists(path) {
-		logger.Debugf("请求速率限制器 %s 已存在，跳过创建", rlimiter.Name)
-		return nil
-	}
-
-	resp, err := c.doRequest("POST", "/config/rlimiters", rlimiter)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("创建请求速率限制器失败: %s", string(body))
-	}
-
-	return nil
-}
-
-// DeleteRLimiter 删除请求速率限制器 (幂等)
-func (c *Client) DeleteRLimiter(name string) error {
-	path := fmt.Sprintf("/config/rlimiters/%s", name)
-	if !c.exists(path) {
-		logger.Debugf("请求速率限制器 %s 不存在，跳过删除", name)
-		return nil
-	}
-
-	resp, err := c.doRequest("DELETE", path, nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("删除请求速率限制器失败: %s", string(body))
-	}
-
-	return nil
-}
@@ -0,0 +1,204 @@
+package gost_test
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"gost-panel/pkg/gost"
+	"gost-panel/pkg/gost/gosttest"
+	"gost-panel/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	if err := logger.Init(&logger.Config{Level: "error", Format: "console"}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestClient 创建连接模拟服务的客户端
+func newTestClient(t *testing.T, srv *gosttest.Server, timeout time.Duration) *gost.Client {
+	t.Helper()
+	return gost.NewClient(&gost.Config{
+		APIURL:  srv.URL + "/api",
+		Timeout: timeout,
+	})
+}
+
+func TestCreateAndDeleteAreIdempotent(t *testing.T) {
+	srv := gosttest.NewServer()
+	defer srv.Close()
+	client := newTestClient(t, srv, time.Second)
+	ctx := context.Background()
+
+	svc := gost.BuildTCPForwardService("rule-1", 10000, []string{"127.0.0.1:80"}, "")
+	for i := 0; i < 2; i++ {
+		if err := client.CreateService(ctx, svc); err != nil {
+			t.Fatalf("第 %d 次创建服务失败: %v", i+1, err)
+		}
+	}
+	if got := srv.Requests(http.MethodPost, "/config/services"); got != 2 {
+		t.Fatalf("创建请求数 = %d, 期望 2（每次创建一个请求，不做存在性查询）", got)
+	}
+	if got := srv.Requests(http.MethodGet, "/config/services"); got != 0 {
+		t.Fatalf("存在性查询请求数 = %d, 期望 0", got)
+	}
+
+	var stored gost.ServiceConfig
+	if !srv.Get("services", "rule-1", &stored) || stored.Addr != ":10000" {
+		t.Fatalf("服务未按配置创建: %+v", stored)
+	}
+	if created, err := client.EnsureService(ctx, svc); err != nil || created {
+		t.Fatalf("服务已存在时 EnsureService = %v, %v, 期望未新建", created, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.DeleteService(ctx, "rule-1"); err != nil {
+			t.Fatalf("第 %d 次删除服务失败: %v", i+1, err)
+		}
+	}
+	if srv.Has("services", "rule-1") {
+		t.Fatal("服务删除后仍存在")
+	}
+}
+
+func TestUpdateMissingObject(t *testing.T) {
+	srv := gosttest.NewServer()
+	defer srv.Close()
+	client := newTestClient(t, srv, time.Second)
+
+	err := client.UpdateChain(context.Background(), &gost.ChainConfig{Name: "missing"})
+	if !gost.IsNotFound(err) {
+		t.Fatalf("更新不存在的链应返回不存在错误，实际: %v", err)
+	}
+}
+
+func TestGetConfigDecodesFullTree(t *testing.T) {
+	srv := gosttest.NewServer()
+	defer srv.Close()
+	client := newTestClient(t, srv, time.Second)
+	ctx := context.Background()
+
+	if err := client.CreateService(ctx, gost.BuildTCPForwardService("rule-1", 10000, []string{"127.0.0.1:80"}, "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateBypass(ctx, &gost.BypassConfig{Name: "bypass-1", Matchers: []string{"10.0.0.0/8"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateHosts(ctx, &gost.HostsConfig{
+		Name:     "hosts-1",
+		Mappings: []*gost.HostMappingConfig{{IP: "127.0.0.1", Hostname: "example.com"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	srv.SetServiceState("rule-1", "failed")
+	srv.SetServiceStats("rule-1", gost.ServiceStats{TotalConns: 3, InputBytes: 100})
+
+	cfg, err := client.GetConfig(ctx)
+	if err != nil {
+		t.Fatalf("获取配置失败: %v", err)
+	}
+	if len(cfg.Services) != 1 || cfg.Services[0].Status == nil || cfg.Services[0].Status.State != "failed" {
+		t.Fatalf("服务状态解析错误: %+v", cfg.Services)
+	}
+	if stats := cfg.Services[0].Status.Stats; stats == nil || stats.TotalConns != 3 || stats.InputBytes != 100 {
+		t.Fatalf("服务统计解析错误: %+v", stats)
+	}
+	if got := cfg.Services[0].Forwarder.Selector.FailTimeout; got != gost.Duration(30*time.Second) {
+		t.Fatalf("failTimeout = %s, 期望 30s", got)
+	}
+	if len(cfg.Bypasses) != 1 || cfg.Bypasses[0].Matchers[0] != "10.0.0.0/8" {
+		t.Fatalf("分流器解析错误: %+v", cfg.Bypasses)
+	}
+	if len(cfg.Hosts) != 1 || cfg.Hosts[0].Mappings[0].Hostname != "example.com" {
+		t.Fatalf("主机映射解析错误: %+v", cfg.Hosts)
+	}
+}
+
+func TestIdempotentRequestsRetry(t *testing.T) {
+	srv := gosttest.NewServer()
+	defer srv.Close()
+	client := newTestClient(t, srv, time.Second)
+
+	srv.Inject(gosttest.Fault{Method: http.MethodGet, Path: "/config", Status: http.StatusServiceUnavailable, Times: 2})
+	if _, err := client.GetConfig(context.Background()); err != nil {
+		t.Fatalf("重试后应成功，实际: %v", err)
+	}
+	if got := srv.Requests(http.MethodGet, "/config"); got != 3 {
+		t.Fatalf("请求次数 = %d, 期望 3", got)
+	}
+}
+
+func TestCreateIsNotRetried(t *testing.T) {
+	srv := gosttest.NewServer()
+	defer srv.Close()
+	client := newTestClient(t, srv, time.Second)
+
+	srv.Inject(gosttest.Fault{Method: http.MethodPost, Path: "/config/chains", Status: http.StatusServiceUnavailable, Times: 1})
+	err := client.CreateChain(context.Background(), &gost.ChainConfig{Name: "tunnel-1-chain"})
+
+	var apiErr *gost.APIError
+	if !stderrors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("应返回 503 错误，实际: %v", err)
+	}
+	if got := srv.Requests(http.MethodPost, "/config/chains"); got != 1 {
+		t.Fatalf("请求次数 = %d, 期望 1", got)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	srv := gosttest.NewServer()
+	defer srv.Close()
+	client := newTestClient(t, srv, 50*time.Millisecond)
+
+	srv.Inject(gosttest.Fault{Path: "/config", Delay: time.Second})
+	if err := client.HealthCheck(context.Background()); err == nil {
+		t.Fatal("请求超时应返回错误")
+	}
+	if got := srv.Requests(http.MethodGet, "/config"); got != 3 {
+		t.Fatalf("超时后重试次数 = %d, 期望 3", got)
+	}
+}
+
+func TestContextCancelStopsRetries(t *testing.T) {
+	srv := gosttest.NewServer()
+	defer srv.Close()
+	client := newTestClient(t, srv, time.Second)
+
+	srv.Inject(gosttest.Fault{Path: "/config", Delay: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := client.HealthCheck(ctx); !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("应返回上下文超时错误，实际: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("上下文取消后未及时返回，耗时 %s", elapsed)
+	}
+	if got := srv.Requests(http.MethodGet, "/config"); got != 1 {
+		t.Fatalf("请求次数 = %d, 期望 1", got)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	srv := gosttest.NewServer()
+	defer srv.Close()
+	srv.SetAuth("admin", "secret")
+	ctx := context.Background()
+
+	var apiErr *gost.APIError
+	err := newTestClient(t, srv, time.Second).HealthCheck(ctx)
+	if !stderrors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("未认证请求应返回 401，实际: %v", err)
+	}
+
+	client := gost.NewClient(&gost.Config{APIURL: srv.URL + "/api", Username: "admin", Password: "secret"})
+	if err = client.HealthCheck(ctx); err != nil {
+		t.Fatalf("认证请求失败: %v", err)
+	}
+}
@@ -0,0 +1,597 @@
+package gost
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 本文件定义 Gost v3 配置 API 的对象结构，字段与 Gost 配置文件（JSON 格式）一一对应
+
+// Duration 时间间隔
+// Gost 以纳秒数编码时间间隔，解码时同时兼容 "10s" 形式的字符串
+type Duration time.Duration
+
+// MarshalJSON 编码为纳秒数
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(d), 10)), nil
+}
+
+// UnmarshalJSON 解码纳秒数或时间字符串
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if s == "" {
+			*d = 0
+			return nil
+		}
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("无效的时间间隔 %q: %w", s, err)
+		}
+		*d = Duration(v)
+		return nil
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("无效的时间间隔 %s: %w", data, err)
+	}
+	*d = Duration(n)
+	return nil
+}
+
+// String 实现 fmt.Stringer
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// GostConfig Gost 完整配置
+type GostConfig struct {
+	Services   []ServiceConfig   `json:"services,omitempty"`
+	Chains     []ChainConfig     `json:"chains,omitempty"`
+	Hops       []HopConfig       `json:"hops,omitempty"`
+	Authers    []AutherConfig    `json:"authers,omitempty"`
+	Admissions []AdmissionConfig `json:"admissions,omitempty"`
+	Bypasses   []BypassConfig    `json:"bypasses,omitempty"`
+	Resolvers  []ResolverConfig  `json:"resolvers,omitempty"`
+	Hosts      []HostsConfig     `json:"hosts,omitempty"`
+	Ingresses  []IngressConfig   `json:"ingresses,omitempty"`
+	Routers    []RouterConfig    `json:"routers,omitempty"`
+	SDs        []SDConfig        `json:"sds,omitempty"`
+	Recorders  []RecorderConfig  `json:"recorders,omitempty"`
+	Limiters   []LimiterConfig   `json:"limiters,omitempty"`
+	CLimiters  []CLimiterConfig  `json:"climiters,omitempty"`
+	RLimiters  []RLimiterConfig  `json:"rlimiters,omitempty"`
+	Observers  []ObserverConfig  `json:"observers,omitempty"`
+	Loggers    []LoggerConfig    `json:"loggers,omitempty"`
+	TLS        *GostTLSConfig    `json:"tls,omitempty"`       // 全局默认证书
+	Log        *LogConfig        `json:"log,omitempty"`       // 全局日志
+	Profiling  *ProfilingConfig  `json:"profiling,omitempty"` // pprof 服务
+	API        *APIConfig        `json:"api,omitempty"`       // Web API 服务
+	Metrics    *MetricsConfig    `json:"metrics,omitempty"`   // Prometheus 指标服务
+}
+
+// ServiceConfig 服务配置
+type ServiceConfig struct {
+	Name       string            `json:"name"`
+	Addr       string            `json:"addr"`
+	Interface  string            `json:"interface,omitempty"`  // 出口网卡或 IP
+	SockOpts   *SockOptsConfig   `json:"sockopts,omitempty"`   // Socket 选项
+	Admission  string            `json:"admission,omitempty"`  // 准入控制器名称
+	Admissions []string          `json:"admissions,omitempty"` // 准入控制器名称列表
+	Bypass     string            `json:"bypass,omitempty"`     // 分流器名称
+	Bypasses   []string          `json:"bypasses,omitempty"`   // 分流器名称列表
+	Resolver   string            `json:"resolver,omitempty"`   // 域名解析器名称
+	Hosts      string            `json:"hosts,omitempty"`      // 主机映射器名称
+	Handler    *HandlerConfig    `json:"handler,omitempty"`
+	Listener   *ListenerConfig   `json:"listener,omitempty"`
+	Forwarder  *ForwarderConfig  `json:"forwarder,omitempty"`
+	Limiter    string            `json:"limiter,omitempty"`   // 流量速率限制器名称
+	CLimiter   string            `json:"climiter,omitempty"`  // 并发连接数限制器名称
+	RLimiter   string            `json:"rlimiter,omitempty"`  // 请求速率限制器名称
+	Logger     string            `json:"logger,omitempty"`    // 日志记录器名称
+	Loggers    []string          `json:"loggers,omitempty"`   // 日志记录器名称列表
+	Observer   string            `json:"observer,omitempty"`  // 观察器名称
+	Recorders  []*RecorderObject `json:"recorders,omitempty"` // 记录器
+	Metadata   map[string]any    `json:"metadata,omitempty"`  // 元数据配置
+	Status     *ServiceStatus    `json:"status,omitempty"`    // 服务运行状态
+}
+
+// ServiceStatus 服务运行时状态信息
+type ServiceStatus struct {
+	CreateTime int64          `json:"createTime,omitempty"`
+	State      string         `json:"state"` // 状态: ready, running, failed, closed.
+	Events     []ServiceEvent `json:"events,omitempty"`
+	Stats      *ServiceStats  `json:"stats,omitempty"`
+}
+
+// ServiceEvent 服务事件
+type ServiceEvent struct {
+	Time int64  `json:"time"`
+	Msg  string `json:"msg"`
+}
+
+// ServiceStats 服务统计
+type ServiceStats struct {
+	TotalConns   uint64 `json:"totalConns"`
+	CurrentConns uint64 `json:"currentConns"`
+	TotalErrs    uint64 `json:"totalErrs"`
+	InputBytes   uint64 `json:"inputBytes"`
+	OutputBytes  uint64 `json:"outputBytes"`
+}
+
+// SockOptsConfig Socket 选项
+type SockOptsConfig struct {
+	Mark int `json:"mark,omitempty"` // SO_MARK
+}
+
+// RecorderObject 服务引用的记录器
+type RecorderObject struct {
+	Name     string         `json:"name"`
+	Record   string         `json:"record"` // 记录内容，如 recorder.service.client.address
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// HandlerConfig 处理器配置
+type HandlerConfig struct {
+	Type       string            `json:"type"`
+	Retries    int               `json:"retries,omitempty"`
+	Chain      string            `json:"chain,omitempty"` // 链名称
+	ChainGroup *ChainGroupConfig `json:"chainGroup,omitempty"`
+	Auther     string            `json:"auther,omitempty"`
+	Authers    []string          `json:"authers,omitempty"`
+	Auth       *AuthConfig       `json:"auth,omitempty"`
+	TLS        *GostTLSConfig    `json:"tls,omitempty"`
+	Limiter    string            `json:"limiter,omitempty"`
+	Observer   string            `json:"observer,omitempty"`
+	Metadata   map[string]any    `json:"metadata,omitempty"`
+}
+
+// ListenerConfig 监听器配置
+type ListenerConfig struct {
+	Type       string            `json:"type"`
+	Chain      string            `json:"chain,omitempty"`
+	ChainGroup *ChainGroupConfig `json:"chainGroup,omitempty"`
+	Auther     string            `json:"auther,omitempty"`
+	Authers    []string          `json:"authers,omitempty"`
+	Auth       *AuthConfig       `json:"auth,omitempty"`
+	TLS        *GostTLSConfig    `json:"tls,omitempty"`
+	Metadata   map[string]any    `json:"metadata,omitempty"` // 元数据配置
+}
+
+// ForwarderConfig 转发器配置
+type ForwarderConfig struct {
+	Nodes    []*ForwarderNode `json:"nodes"`
+	Selector *SelectorConfig  `json:"selector,omitempty"`
+}
+
+// SelectorConfig 选择器配置
+type SelectorConfig struct {
+	Strategy    string   `json:"strategy,omitempty"`
+	MaxFails    int      `json:"maxFails,omitempty"`
+	FailTimeout Duration `json:"failTimeout,omitempty"`
+}
+
+// ForwarderNode 转发目标节点
+type ForwarderNode struct {
+	Name     string            `json:"name"`
+	Addr     string            `json:"addr"`
+	Network  string            `json:"network,omitempty"`
+	Bypass   string            `json:"bypass,omitempty"`
+	Bypasses []string          `json:"bypasses,omitempty"`
+	Protocol string            `json:"protocol,omitempty"`
+	Host     string            `json:"host,omitempty"`
+	Path     string            `json:"path,omitempty"`
+	Auth     *AuthConfig       `json:"auth,omitempty"`
+	Filter   *NodeFilterConfig `json:"filter,omitempty"`
+	HTTP     *HTTPNodeConfig   `json:"http,omitempty"`
+	TLS      *TLSNodeConfig    `json:"tls,omitempty"`
+	Metadata map[string]any    `json:"metadata,omitempty"`
+}
+
+// NodeFilterConfig 节点过滤条件
+type NodeFilterConfig struct {
+	Host     string `json:"host,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// HTTPNodeConfig 节点 HTTP 设置
+type HTTPNodeConfig struct {
+	Host           string                 `json:"host,omitempty"`
+	Header         map[string]string      `json:"header,omitempty"`
+	ResponseHeader map[string]string      `json:"responseHeader,omitempty"`
+	Auth           *AuthConfig            `json:"auth,omitempty"`
+	Rewrite        []HTTPURLRewriteConfig `json:"rewrite,omitempty"`
+}
+
+// HTTPURLRewriteConfig HTTP 路径重写规则
+type HTTPURLRewriteConfig struct {
+	Match       string `json:"match"`
+	Replacement string `json:"replacement"`
+}
+
+// TLSNodeConfig 节点 TLS 设置
+type TLSNodeConfig struct {
+	ServerName string      `json:"serverName,omitempty"`
+	Secure     bool        `json:"secure,omitempty"`
+	Options    *TLSOptions `json:"options,omitempty"`
+}
+
+// GostTLSConfig Gost 对象的 TLS 证书配置
+type GostTLSConfig struct {
+	Validity     Duration    `json:"validity,omitempty"` // 自签名证书有效期
+	CertFile     string      `json:"certFile,omitempty"`
+	KeyFile      string      `json:"keyFile,omitempty"`
+	CAFile       string      `json:"caFile,omitempty"`
+	Secure       bool        `json:"secure,omitempty"`
+	ServerName   string      `json:"serverName,omitempty"`
+	Options      *TLSOptions `json:"options,omitempty"`
+	Organization string      `json:"organization,omitempty"`
+	CommonName   string      `json:"commonName,omitempty"`
+}
+
+// TLSOptions TLS 协议选项
+type TLSOptions struct {
+	MinVersion   string   `json:"minVersion,omitempty"`
+	MaxVersion   string   `json:"maxVersion,omitempty"`
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+	ALPN         []string `json:"alpn,omitempty"`
+}
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// ChainConfig 链配置
+type ChainConfig struct {
+	Name     string         `json:"name"`
+	Hops     []*HopConfig   `json:"hops,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// ChainGroupConfig 链组配置
+type ChainGroupConfig struct {
+	Chains   []string        `json:"chains,omitempty"`
+	Selector *SelectorConfig `json:"selector,omitempty"`
+}
+
+// HopConfig 跳配置，可在链中内联或作为独立对象被链引用
+type HopConfig struct {
+	Name      string          `json:"name"`
+	Interface string          `json:"interface,omitempty"`
+	SockOpts  *SockOptsConfig `json:"sockopts,omitempty"`
+	Selector  *SelectorConfig `json:"selector,omitempty"`
+	Bypass    string          `json:"bypass,omitempty"`
+	Bypasses  []string        `json:"bypasses,omitempty"`
+	Resolver  string          `json:"resolver,omitempty"`
+	Hosts     string          `json:"hosts,omitempty"`
+	Nodes     []*NodeConfig   `json:"nodes,omitempty"`
+	Reload    Duration        `json:"reload,omitempty"`
+	File      *FileLoader     `json:"file,omitempty"`
+	Redis     *RedisLoader    `json:"redis,omitempty"`
+	HTTP      *HTTPLoader     `json:"http,omitempty"`
+	Plugin    *PluginConfig   `json:"plugin,omitempty"`
+}
+
+// NodeConfig 链节点配置
+type NodeConfig struct {
+	Name      string            `json:"name"`
+	Addr      string            `json:"addr"`
+	Network   string            `json:"network,omitempty"`
+	Bypass    string            `json:"bypass,omitempty"`
+	Bypasses  []string          `json:"bypasses,omitempty"`
+	Resolver  string            `json:"resolver,omitempty"`
+	Hosts     string            `json:"hosts,omitempty"`
+	Connector *ConnectorConfig  `json:"connector,omitempty"`
+	Dialer    *DialerConfig     `json:"dialer,omitempty"`
+	Interface string            `json:"interface,omitempty"`
+	Netns     string            `json:"netns,omitempty"`
+	SockOpts  *SockOptsConfig   `json:"sockopts,omitempty"`
+	Filter    *NodeFilterConfig `json:"filter,omitempty"`
+	HTTP      *HTTPNodeConfig   `json:"http,omitempty"`
+	TLS       *TLSNodeConfig    `json:"tls,omitempty"`
+	Metadata  map[string]any    `json:"metadata,omitempty"`
+}
+
+// ConnectorConfig 连接器配置
+type ConnectorConfig struct {
+	Type     string         `json:"type"`
+	Auth     *AuthConfig    `json:"auth,omitempty"`
+	TLS      *GostTLSConfig `json:"tls,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// DialerConfig 拨号器配置
+type DialerConfig struct {
+	Type     string         `json:"type"`
+	Auth     *AuthConfig    `json:"auth,omitempty"`
+	TLS      *GostTLSConfig `json:"tls,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// FileLoader 从文件加载数据
+type FileLoader struct {
+	Path string `json:"path"`
+}
+
+// RedisLoader 从 Redis 加载数据
+type RedisLoader struct {
+	Addr     string `json:"addr"`
+	DB       int    `json:"db,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Type     string `json:"type,omitempty"` // set, list
+}
+
+// HTTPLoader 从 HTTP 地址加载数据
+type HTTPLoader struct {
+	URL     string   `json:"url"`
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// AutherConfig 认证器配置
+type AutherConfig struct {
+	Name   string        `json:"name"`
+	Auths  []*AuthConfig `json:"auths,omitempty"`
+	Reload Duration      `json:"reload,omitempty"`
+	File   *FileLoader   `json:"file,omitempty"`
+	Redis  *RedisLoader  `json:"redis,omitempty"`
+	HTTP   *HTTPLoader   `json:"http,omitempty"`
+	Plugin *PluginConfig `json:"plugin,omitempty"`
+}
+
+// AdmissionConfig 准入控制器配置
+// matchers 为 IP、CIDR 列表，whitelist 为 true 时仅允许匹配的客户端
+type AdmissionConfig struct {
+	Name      string        `json:"name"`
+	Whitelist bool          `json:"whitelist,omitempty"`
+	Matchers  []string      `json:"matchers,omitempty"`
+	Reload    Duration      `json:"reload,omitempty"`
+	File      *FileLoader   `json:"file,omitempty"`
+	Redis     *RedisLoader  `json:"redis,omitempty"`
+	HTTP      *HTTPLoader   `json:"http,omitempty"`
+	Plugin    *PluginConfig `json:"plugin,omitempty"`
+}
+
+// BypassConfig 分流器配置
+// matchers 为 IP、CIDR、域名列表，whitelist 为 true 时仅匹配的目标走代理
+type BypassConfig struct {
+	Name      string        `json:"name"`
+	Whitelist bool          `json:"whitelist,omitempty"`
+	Matchers  []string      `json:"matchers,omitempty"`
+	Reload    Duration      `json:"reload,omitempty"`
+	File      *FileLoader   `json:"file,omitempty"`
+	Redis     *RedisLoader  `json:"redis,omitempty"`
+	HTTP      *HTTPLoader   `json:"http,omitempty"`
+	Plugin    *PluginConfig `json:"plugin,omitempty"`
+}
+
+// ResolverConfig 域名解析器配置
+type ResolverConfig struct {
+	Name        string              `json:"name"`
+	Nameservers []*NameserverConfig `json:"nameservers,omitempty"`
+	Plugin      *PluginConfig       `json:"plugin,omitempty"`
+}
+
+// NameserverConfig 上游 DNS 服务器
+type NameserverConfig struct {
+	Addr     string   `json:"addr"`
+	Chain    string   `json:"chain,omitempty"`
+	Prefer   string   `json:"prefer,omitempty"` // ipv4, ipv6
+	ClientIP string   `json:"clientIP,omitempty"`
+	Hostname string   `json:"hostname,omitempty"`
+	TTL      Duration `json:"ttl,omitempty"`
+	Timeout  Duration `json:"timeout,omitempty"`
+	Async    bool     `json:"async,omitempty"`
+	Only     string   `json:"only,omitempty"` // ipv4, ipv6
+}
+
+// HostsConfig 主机映射器配置
+type HostsConfig struct {
+	Name     string               `json:"name"`
+	Mappings []*HostMappingConfig `json:"mappings,omitempty"`
+	Reload   Duration             `json:"reload,omitempty"`
+	File     *FileLoader          `json:"file,omitempty"`
+	Redis    *RedisLoader         `json:"redis,omitempty"`
+	HTTP     *HTTPLoader          `json:"http,omitempty"`
+	Plugin   *PluginConfig        `json:"plugin,omitempty"`
+}
+
+// HostMappingConfig 主机映射
+type HostMappingConfig struct {
+	IP       string   `json:"ip"`
+	Hostname string   `json:"hostname"`
+	Aliases  []string `json:"aliases,omitempty"`
+}
+
+// IngressConfig Ingress 配置，用于反向代理隧道按主机名路由
+type IngressConfig struct {
+	Name   string               `json:"name"`
+	Rules  []*IngressRuleConfig `json:"rules,omitempty"`
+	Reload Duration             `json:"reload,omitempty"`
+	File   *FileLoader          `json:"file,omitempty"`
+	Redis  *RedisLoader         `json:"redis,omitempty"`
+	HTTP   *HTTPLoader          `json:"http,omitempty"`
+	Plugin *PluginConfig        `json:"plugin,omitempty"`
+}
+
+// IngressRuleConfig Ingress 规则
+type IngressRuleConfig struct {
+	Hostname string `json:"hostname"`
+	Endpoint string `json:"endpoint"`
+}
+
+// RouterConfig 路由器配置
+type RouterConfig struct {
+	Name   string               `json:"name"`
+	Routes []*RouterRouteConfig `json:"routes,omitempty"`
+	Reload Duration             `json:"reload,omitempty"`
+	File   *FileLoader          `json:"file,omitempty"`
+	Redis  *RedisLoader         `json:"redis,omitempty"`
+	HTTP   *HTTPLoader          `json:"http,omitempty"`
+	Plugin *PluginConfig        `json:"plugin,omitempty"`
+}
+
+// RouterRouteConfig 路由规则
+type RouterRouteConfig struct {
+	Net     string `json:"net,omitempty"` // 已废弃，使用 dst
+	Dst     string `json:"dst,omitempty"`
+	Gateway string `json:"gateway"`
+}
+
+// SDConfig 服务发现配置
+type SDConfig struct {
+	Name   string        `json:"name"`
+	Plugin *PluginConfig `json:"plugin,omitempty"`
+}
+
+// RecorderConfig 记录器配置
+type RecorderConfig struct {
+	Name   string         `json:"name"`
+	File   *FileRecorder  `json:"file,omitempty"`
+	TCP    *TCPRecorder   `json:"tcp,omitempty"`
+	HTTP   *HTTPRecorder  `json:"http,omitempty"`
+	Redis  *RedisRecorder `json:"redis,omitempty"`
+	Plugin *PluginConfig  `json:"plugin,omitempty"`
+}
+
+// FileRecorder 记录到文件
+type FileRecorder struct {
+	Path     string             `json:"path"`
+	Sep      string             `json:"sep,omitempty"`
+	Rotation *LogRotationConfig `json:"rotation,omitempty"`
+}
+
+// TCPRecorder 记录到 TCP 服务
+type TCPRecorder struct {
+	Addr    string   `json:"addr"`
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// HTTPRecorder 记录到 HTTP 服务
+type HTTPRecorder struct {
+	URL     string            `json:"url"`
+	Timeout Duration          `json:"timeout,omitempty"`
+	Header  map[string]string `json:"header,omitempty"`
+}
+
+// RedisRecorder 记录到 Redis
+type RedisRecorder struct {
+	Addr     string `json:"addr"`
+	DB       int    `json:"db,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Key      string `json:"key"`
+	Type     string `json:"type,omitempty"` // set, list, sset
+}
+
+// LimiterConfig 流量速率限制器配置
+// limits 格式: "$ 100MB 100MB" (服务级别 入站 出站)
+// limits 格式: "$$ 10MB" (连接级别)
+// limits 格式: "192.168.1.1 1MB 5MB" (IP级别)
+type LimiterConfig struct {
+	Name   string        `json:"name"`
+	Limits []string      `json:"limits,omitempty"` // 限制规则列表
+	Reload Duration      `json:"reload,omitempty"`
+	File   *FileLoader   `json:"file,omitempty"`
+	Redis  *RedisLoader  `json:"redis,omitempty"`
+	HTTP   *HTTPLoader   `json:"http,omitempty"`
+	Plugin *PluginConfig `json:"plugin,omitempty"` // 插件配置
+}
+
+// CLimiterConfig 并发连接数限制器配置
+// limits 格式: "$ 1000" (服务级别最大连接数)
+// limits 格式: "$$ 100" (IP级别默认最大连接数)
+type CLimiterConfig struct {
+	Name   string        `json:"name"`
+	Limits []string      `json:"limits,omitempty"` // 限制规则列表
+	Reload Duration      `json:"reload,omitempty"`
+	File   *FileLoader   `json:"file,omitempty"`
+	Redis  *RedisLoader  `json:"redis,omitempty"`
+	HTTP   *HTTPLoader   `json:"http,omitempty"`
+	Plugin *PluginConfig `json:"plugin,omitempty"` // 插件配置
+}
+
+// RLimiterConfig 请求速率限制器配置
+// limits 格式: "$ 100" (服务级别每秒请求数)
+// limits 格式: "$$ 10" (IP级别默认每秒请求数)
+type RLimiterConfig struct {
+	Name   string        `json:"name"`
+	Limits []string      `json:"limits,omitempty"` // 限制规则列表
+	Reload Duration      `json:"reload,omitempty"`
+	File   *FileLoader   `json:"file,omitempty"`
+	Redis  *RedisLoader  `json:"redis,omitempty"`
+	HTTP   *HTTPLoader   `json:"http,omitempty"`
+	Plugin *PluginConfig `json:"plugin,omitempty"` // 插件配置
+}
+
+// ObserverConfig 观察器配置
+type ObserverConfig struct {
+	Name   string        `json:"name"`
+	Plugin *PluginConfig `json:"plugin,omitempty"` // 插件配置
+}
+
+// LoggerConfig 日志记录器配置
+type LoggerConfig struct {
+	Name string     `json:"name"`
+	Log  *LogConfig `json:"log,omitempty"`
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Output   string             `json:"output,omitempty"` // stderr, stdout, none 或文件路径
+	Level    string             `json:"level,omitempty"`
+	Format   string             `json:"format,omitempty"` // text, json
+	Rotation *LogRotationConfig `json:"rotation,omitempty"`
+}
+
+// LogRotationConfig 日志文件轮转配置
+type LogRotationConfig struct {
+	MaxSize    int  `json:"maxSize,omitempty"` // MB
+	MaxAge     int  `json:"maxAge,omitempty"`  // 天
+	MaxBackups int  `json:"maxBackups,omitempty"`
+	LocalTime  bool `json:"localTime,omitempty"`
+	Compress   bool `json:"compress,omitempty"`
+}
+
+// ProfilingConfig pprof 服务配置
+type ProfilingConfig struct {
+	Addr string `json:"addr"`
+}
+
+// APIConfig Web API 服务配置
+type APIConfig struct {
+	Addr       string      `json:"addr"`
+	PathPrefix string      `json:"pathPrefix,omitempty"`
+	AccessLog  bool        `json:"accesslog,omitempty"`
+	Auth       *AuthConfig `json:"auth,omitempty"`
+	Auther     string      `json:"auther,omitempty"`
+}
+
+// MetricsConfig Prometheus 指标服务配置
+type MetricsConfig struct {
+	Addr   string      `json:"addr"`
+	Path   string      `json:"path,omitempty"`
+	Auth   *AuthConfig `json:"auth,omitempty"`
+	Auther string      `json:"auther,omitempty"`
+}
+
+// PluginConfig 插件配置
+type PluginConfig struct {
+	Type    string         `json:"type,omitempty"`    // 插件类型: grpc, http
+	Addr    string         `json:"addr,omitempty"`    // 插件地址
+	TLS     *GostTLSConfig `json:"tls,omitempty"`     // 插件 TLS 配置
+	Timeout string         `json:"timeout,omitempty"` // 超时时间，如 10s
+	Token   string         `json:"token,omitempty"`   // 认证令牌
+}
@@ -0,0 +1,514 @@
+// Package gosttest 提供基于 httptest 的 Gost API 模拟服务，用于集成测试
+// 模拟 /api/config 下各类配置对象的增删改查及配置保存，支持基础认证校验、
+// 服务运行状态设置和故障注入（延迟、错误状态码），无需真实节点即可测试面板与节点的交互；
+// 设置指标文本后同时在 /metrics 模拟 Gost 自身的 Prometheus 指标接口
+package gosttest
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gost-panel/pkg/gost"
+)
+
+// Gost API 错误码
+const (
+	codeInvalid      = 40001 // 对象无效
+	codeDuplicated   = 40002 // 对象已存在
+	codeNotFound     = 40004 // 对象不存在
+	codeUnauthorized = 40100 // 认证失败
+	codeInternal     = 50000 // 内部错误
+)
+
+// kinds 支持的配置对象类型
+var kinds = map[string]bool{
+	"services":   true,
+	"chains":     true,
+	"hops":       true,
+	"authers":    true,
+	"admissions": true,
+	"bypasses":   true,
+	"resolvers":  true,
+	"hosts":      true,
+	"ingresses":  true,
+	"routers":    true,
+	"sds":        true,
+	"recorders":  true,
+	"limiters":   true,
+	"climiters":  true,
+	"rlimiters":  true,
+	"observers":  true,
+	"loggers":    true,
+}
+
+// Fault 故障注入规则，按注入顺序匹配第一条生效的规则
+type Fault struct {
+	Method string        // 匹配的请求方法，为空匹配全部
+	Path   string        // 匹配的路径前缀（相对 /api，如 /config/chains），为空匹配全部
+	Status int           // 返回的 HTTP 状态码，为 0 时延迟后正常处理请求
+	Delay  time.Duration // 处理前延迟，用于模拟超时；客户端断开时提前结束
+	Times  int           // 生效次数，为 0 时一直生效
+}
+
+// Server Gost API 模拟服务
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	username string
+	password string
+	objects  map[string]map[string]map[string]any // 类型 -> 名称 -> 对象
+	states   map[string]string                    // 服务名称 -> 运行状态
+	stats    map[string]gost.ServiceStats         // 服务名称 -> 统计数据
+	faults   []*Fault
+	requests []string // 已接收请求，格式 "METHOD /path"
+	saves    int
+	metrics  string // /metrics 返回的 Prometheus 文本，为空时返回 404
+}
+
+// NewServer 创建并启动模拟服务，使用后需调用 Close
+func NewServer() *Server {
+	s := &Server{
+		objects: make(map[string]map[string]map[string]any),
+		states:  make(map[string]string),
+		stats:   make(map[string]gost.ServiceStats),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Host 服务监听地址
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Listener.Addr().String())
+	return host
+}
+
+// Port 服务监听端口
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// SetAuth 开启基础认证，用户名为空时关闭
+func (s *Server) SetAuth(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username = username
+	s.password = password
+}
+
+// Inject 注入故障
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults 清除全部故障
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// SetMetrics 设置 /metrics 返回的 Prometheus 文本格式指标
+func (s *Server) SetMetrics(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = text
+}
+
+// SetServiceState 设置服务运行状态（ready, running, failed, closed），未设置时为 running
+func (s *Server) SetServiceState(name, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[name] = state
+}
+
+// SetServiceStats 设置服务统计数据
+func (s *Server) SetServiceStats(name string, stats gost.ServiceStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[name] = stats
+}
+
+// Put 直接写入配置对象，模拟面板之外创建的对象
+func (s *Server) Put(kind string, obj any) {
+	m, err := toMap(obj)
+	if err != nil {
+		panic("gosttest: " + err.Error())
+	}
+	name, _ := m["name"].(string)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(kind, name, m)
+}
+
+// Remove 直接删除配置对象，模拟节点上的对象丢失
+func (s *Server) Remove(kind, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects[kind], name)
+}
+
+// Has 判断配置对象是否存在
+func (s *Server) Has(kind, name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.objects[kind][name]
+	return ok
+}
+
+// Names 获取指定类型的全部对象名称（按名称排序）
+func (s *Server) Names(kind string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.objects[kind]))
+	for name := range s.objects[kind] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get 将配置对象解码到 v，对象不存在返回 false
+func (s *Server) Get(kind, name string, v any) bool {
+	s.mu.Lock()
+	obj, ok := s.objects[kind][name]
+	var data []byte
+	if ok {
+		data, _ = json.Marshal(obj)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// Saves 配置保存次数
+func (s *Server) Saves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
+
+// Requests 统计匹配的已接收请求数，method 为空匹配全部，path 为相对 /api 的路径前缀
+func (s *Server) Requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		m, p, _ := strings.Cut(r, " ")
+		if (method == "" || m == method) && strings.HasPrefix(p, path) {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset 清空全部对象、状态、故障及请求记录，认证设置保持不变
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = make(map[string]map[string]map[string]any)
+	s.states = make(map[string]string)
+	s.stats = make(map[string]gost.ServiceStats)
+	s.faults = nil
+	s.requests = nil
+	s.saves = 0
+}
+
+// handle 处理 API 请求
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/metrics" {
+		s.serveMetrics(w, r)
+		return
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/api")
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNotFound, "not found")
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+path)
+	username, password := s.username, s.password
+	fault := s.matchFault(r.Method, path)
+	s.mu.Unlock()
+
+	if username != "" {
+		u, p, ok := r.BasicAuth()
+		if !ok || u != username || p != password {
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
+			return
+		}
+	}
+
+	if fault != nil {
+		if fault.Delay > 0 {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 {
+			writeError(w, fault.Status, codeInternal, http.StatusText(fault.Status))
+			return
+		}
+	}
+
+	s.route(w, r, path)
+}
+
+// serveMetrics 返回设置的 Prometheus 指标
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	text := s.metrics
+	s.mu.Unlock()
+
+	if text == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(text))
+}
+
+// matchFault 查找匹配的故障并扣减生效次数，调用方需持有锁
+func (s *Server) matchFault(method, path string) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != method {
+			continue
+		}
+		if !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		matched := *f
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return &matched
+	}
+	return nil
+}
+
+// route 按路径分发请求
+func (s *Server) route(w http.ResponseWriter, r *http.Request, path string) {
+	if path == "/config" {
+		switch r.Method {
+		case http.MethodGet:
+			s.getConfig(w)
+		case http.MethodPost:
+			s.mu.Lock()
+			s.saves++
+			s.mu.Unlock()
+			writeOK(w)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, codeInvalid, "method not allowed")
+		}
+		return
+	}
+
+	rest, ok := strings.CutPrefix(path, "/config/")
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNotFound, "not found")
+		return
+	}
+	kind, name, _ := strings.Cut(rest, "/")
+	if !kinds[kind] {
+		writeError(w, http.StatusNotFound, codeNotFound, "not found")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && name == "":
+		s.createObject(w, r, kind)
+	case r.Method == http.MethodGet && name != "":
+		s.getObject(w, kind, name)
+	case r.Method == http.MethodPut && name != "":
+		s.updateObject(w, r, kind, name)
+	case r.Method == http.MethodDelete && name != "":
+		s.deleteObject(w, kind, name)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, codeInvalid, "method not allowed")
+	}
+}
+
+// getConfig 返回完整配置，服务附带运行状态
+func (s *Server) getConfig(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg := make(map[string]any)
+	for kind, objs := range s.objects {
+		names := make([]string, 0, len(objs))
+		for name := range objs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		list := make([]map[string]any, 0, len(names))
+		for _, name := range names {
+			obj := objs[name]
+			if kind == "services" {
+				obj = s.withStatus(name, obj)
+			}
+			list = append(list, obj)
+		}
+		cfg[kind] = list
+	}
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// withStatus 复制服务对象并附加运行状态，调用方需持有锁
+func (s *Server) withStatus(name string, obj map[string]any) map[string]any {
+	state := s.states[name]
+	if state == "" {
+		state = "running"
+	}
+	status := gost.ServiceStatus{State: state}
+	if stats, ok := s.stats[name]; ok {
+		status.Stats = &stats
+	}
+
+	out := make(map[string]any, len(obj)+1)
+	for k, v := range obj {
+		out[k] = v
+	}
+	out["status"] = status
+	return out
+}
+
+// getObject 查询单个对象
+func (s *Server) getObject(w http.ResponseWriter, kind, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[kind][name]
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNotFound, "object not found")
+		return
+	}
+	if kind == "services" {
+		obj = s.withStatus(name, obj)
+	}
+	writeJSON(w, http.StatusOK, obj)
+}
+
+// createObject 创建对象，同名对象已存在时返回错误
+func (s *Server) createObject(w http.ResponseWriter, r *http.Request, kind string) {
+	obj, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+	name, _ := obj["name"].(string)
+	if name == "" {
+		writeError(w, http.StatusBadRequest, codeInvalid, "invalid object")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.objects[kind][name]; exists {
+		writeError(w, http.StatusBadRequest, codeDuplicated, "object duplicated")
+		return
+	}
+	s.store(kind, name, obj)
+	writeOK(w)
+}
+
+// updateObject 替换对象，对象不存在时返回错误
+func (s *Server) updateObject(w http.ResponseWriter, r *http.Request, kind, name string) {
+	obj, ok := decodeObject(w, r)
+	if !ok {
+		return
+	}
+	obj["name"] = name
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.objects[kind][name]; !exists {
+		writeError(w, http.StatusNotFound, codeNotFound, "object not found")
+		return
+	}
+	s.store(kind, name, obj)
+	writeOK(w)
+}
+
+// deleteObject 删除对象，对象不存在时返回错误
+func (s *Server) deleteObject(w http.ResponseWriter, kind, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.objects[kind][name]; !exists {
+		writeError(w, http.StatusNotFound, codeNotFound, "object not found")
+		return
+	}
+	delete(s.objects[kind], name)
+	writeOK(w)
+}
+
+// store 保存对象，运行状态由服务端维护，忽略请求中的 status，调用方需持有锁
+func (s *Server) store(kind, name string, obj map[string]any) {
+	delete(obj, "status")
+	if s.objects[kind] == nil {
+		s.objects[kind] = make(map[string]map[string]any)
+	}
+	s.objects[kind][name] = obj
+}
+
+// decodeObject 解析请求体中的对象
+func decodeObject(w http.ResponseWriter, r *http.Request) (map[string]any, bool) {
+	var obj map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&obj); err != nil || obj == nil {
+		writeError(w, http.StatusBadRequest, codeInvalid, "invalid object")
+		return nil, false
+	}
+	return obj, true
+}
+
+// toMap 将对象转换为 JSON 对象
+func toMap(obj any) (map[string]any, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// writeOK 返回成功响应
+func writeOK(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]any{"code": 0, "msg": "OK"})
+}
+
+// writeError 返回 Gost 格式的错误响应
+func writeError(w http.ResponseWriter, status, code int, msg string) {
+	writeJSON(w, status, map[string]any{"code": code, "msg": msg})
+}
+
+// writeJSON 返回 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
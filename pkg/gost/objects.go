@@ -0,0 +1,276 @@
+package gost
+
+import (
+	"context"
+
+	"gost-panel/internal/errors"
+	"gost-panel/pkg/logger"
+)
+
+// 本文件为 Gost 配置 API 各类对象的增删改方法
+// 创建时对象已存在、删除时对象不存在均视为成功，可安全重复调用
+
+// CreateService 创建服务 (幂等)
+func (c *Client) CreateService(ctx context.Context, svc *ServiceConfig) error {
+	return c.create(ctx, "services", "服务", svc.Name, svc)
+}
+
+// EnsureService 创建服务 (幂等)，返回是否新建
+// 服务已存在时保留节点上正在运行的服务，其流量计数不会清零
+func (c *Client) EnsureService(ctx context.Context, svc *ServiceConfig) (bool, error) {
+	return c.createObject(ctx, "services", "服务", svc.Name, svc)
+}
+
+// UpdateService 更新服务配置
+func (c *Client) UpdateService(ctx context.Context, svc *ServiceConfig) error {
+	return c.update(ctx, "services", "服务", svc.Name, svc)
+}
+
+// DeleteService 删除服务 (幂等)
+func (c *Client) DeleteService(ctx context.Context, name string) error {
+	return c.delete(ctx, "services", "服务", name)
+}
+
+// CreateChain 创建链 (幂等)
+func (c *Client) CreateChain(ctx context.Context, chain *ChainConfig) error {
+	return c.create(ctx, "chains", "链", chain.Name, chain)
+}
+
+// UpdateChain 更新链配置
+func (c *Client) UpdateChain(ctx context.Context, chain *ChainConfig) error {
+	return c.update(ctx, "chains", "链", chain.Name, chain)
+}
+
+// DeleteChain 删除链 (幂等)
+func (c *Client) DeleteChain(ctx context.Context, name string) error {
+	return c.delete(ctx, "chains", "链", name)
+}
+
+// CreateHop 创建跳 (幂等)
+func (c *Client) CreateHop(ctx context.Context, hop *HopConfig) error {
+	return c.create(ctx, "hops", "跳", hop.Name, hop)
+}
+
+// UpdateHop 更新跳配置
+func (c *Client) UpdateHop(ctx context.Context, hop *HopConfig) error {
+	return c.update(ctx, "hops", "跳", hop.Name, hop)
+}
+
+// DeleteHop 删除跳 (幂等)
+func (c *Client) DeleteHop(ctx context.Context, name string) error {
+	return c.delete(ctx, "hops", "跳", name)
+}
+
+// CreateAuther 创建认证器 (幂等)
+func (c *Client) CreateAuther(ctx context.Context, auther *AutherConfig) error {
+	return c.create(ctx, "authers", "认证器", auther.Name, auther)
+}
+
+// UpdateAuther 更新认证器配置
+func (c *Client) UpdateAuther(ctx context.Context, auther *AutherConfig) error {
+	return c.update(ctx, "authers", "认证器", auther.Name, auther)
+}
+
+// DeleteAuther 删除认证器 (幂等)
+func (c *Client) DeleteAuther(ctx context.Context, name string) error {
+	return c.delete(ctx, "authers", "认证器", name)
+}
+
+// CreateAdmission 创建准入控制器 (幂等)
+func (c *Client) CreateAdmission(ctx context.Context, admission *AdmissionConfig) error {
+	return c.create(ctx, "admissions", "准入控制器", admission.Name, admission)
+}
+
+// UpdateAdmission 更新准入控制器配置
+func (c *Client) UpdateAdmission(ctx context.Context, admission *AdmissionConfig) error {
+	return c.update(ctx, "admissions", "准入控制器", admission.Name, admission)
+}
+
+// DeleteAdmission 删除准入控制器 (幂等)
+func (c *Client) DeleteAdmission(ctx context.Context, name string) error {
+	return c.delete(ctx, "admissions", "准入控制器", name)
+}
+
+// CreateBypass 创建分流器 (幂等)
+func (c *Client) CreateBypass(ctx context.Context, bypass *BypassConfig) error {
+	return c.create(ctx, "bypasses", "分流器", bypass.Name, bypass)
+}
+
+// UpdateBypass 更新分流器配置
+func (c *Client) UpdateBypass(ctx context.Context, bypass *BypassConfig) error {
+	return c.update(ctx, "bypasses", "分流器", bypass.Name, bypass)
+}
+
+// DeleteBypass 删除分流器 (幂等)
+func (c *Client) DeleteBypass(ctx context.Context, name string) error {
+	return c.delete(ctx, "bypasses", "分流器", name)
+}
+
+// CreateResolver 创建域名解析器 (幂等)
+func (c *Client) CreateResolver(ctx context.Context, resolver *ResolverConfig) error {
+	return c.create(ctx, "resolvers", "域名解析器", resolver.Name, resolver)
+}
+
+// UpdateResolver 更新域名解析器配置
+func (c *Client) UpdateResolver(ctx context.Context, resolver *ResolverConfig) error {
+	return c.update(ctx, "resolvers", "域名解析器", resolver.Name, resolver)
+}
+
+// DeleteResolver 删除域名解析器 (幂等)
+func (c *Client) DeleteResolver(ctx context.Context, name string) error {
+	return c.delete(ctx, "resolvers", "域名解析器", name)
+}
+
+// CreateHosts 创建主机映射器 (幂等)
+func (c *Client) CreateHosts(ctx context.Context, hosts *HostsConfig) error {
+	return c.create(ctx, "hosts", "主机映射器", hosts.Name, hosts)
+}
+
+// UpdateHosts 更新主机映射器配置
+func (c *Client) UpdateHosts(ctx context.Context, hosts *HostsConfig) error {
+	return c.update(ctx, "hosts", "主机映射器", hosts.Name, hosts)
+}
+
+// DeleteHosts 删除主机映射器 (幂等)
+func (c *Client) DeleteHosts(ctx context.Context, name string) error {
+	return c.delete(ctx, "hosts", "主机映射器", name)
+}
+
+// CreateIngress 创建Ingress (幂等)
+func (c *Client) CreateIngress(ctx context.Context, ingress *IngressConfig) error {
+	return c.create(ctx, "ingresses", "Ingress", ingress.Name, ingress)
+}
+
+// UpdateIngress 更新Ingress配置
+func (c *Client) UpdateIngress(ctx context.Context, ingress *IngressConfig) error {
+	return c.update(ctx, "ingresses", "Ingress", ingress.Name, ingress)
+}
+
+// DeleteIngress 删除Ingress (幂等)
+func (c *Client) DeleteIngress(ctx context.Context, name string) error {
+	return c.delete(ctx, "ingresses", "Ingress", name)
+}
+
+// CreateRouter 创建路由器 (幂等)
+func (c *Client) CreateRouter(ctx context.Context, router *RouterConfig) error {
+	return c.create(ctx, "routers", "路由器", router.Name, router)
+}
+
+// UpdateRouter 更新路由器配置
+func (c *Client) UpdateRouter(ctx context.Context, router *RouterConfig) error {
+	return c.update(ctx, "routers", "路由器", router.Name, router)
+}
+
+// DeleteRouter 删除路由器 (幂等)
+func (c *Client) DeleteRouter(ctx context.Context, name string) error {
+	return c.delete(ctx, "routers", "路由器", name)
+}
+
+// CreateSD 创建服务发现 (幂等)
+func (c *Client) CreateSD(ctx context.Context, sd *SDConfig) error {
+	return c.create(ctx, "sds", "服务发现", sd.Name, sd)
+}
+
+// UpdateSD 更新服务发现配置
+func (c *Client) UpdateSD(ctx context.Context, sd *SDConfig) error {
+	return c.update(ctx, "sds", "服务发现", sd.Name, sd)
+}
+
+// DeleteSD 删除服务发现 (幂等)
+func (c *Client) DeleteSD(ctx context.Context, name string) error {
+	return c.delete(ctx, "sds", "服务发现", name)
+}
+
+// CreateRecorder 创建记录器 (幂等)
+func (c *Client) CreateRecorder(ctx context.Context, recorder *RecorderConfig) error {
+	return c.create(ctx, "recorders", "记录器", recorder.Name, recorder)
+}
+
+// UpdateRecorder 更新记录器配置
+func (c *Client) UpdateRecorder(ctx context.Context, recorder *RecorderConfig) error {
+	return c.update(ctx, "recorders", "记录器", recorder.Name, recorder)
+}
+
+// DeleteRecorder 删除记录器 (幂等)
+func (c *Client) DeleteRecorder(ctx context.Context, name string) error {
+	return c.delete(ctx, "recorders", "记录器", name)
+}
+
+// CreateLimiter 创建限流器 (幂等)
+func (c *Client) CreateLimiter(ctx context.Context, limiter *LimiterConfig) error {
+	return c.create(ctx, "limiters", "限流器", limiter.Name, limiter)
+}
+
+// UpdateLimiter 更新限流器配置
+func (c *Client) UpdateLimiter(ctx context.Context, limiter *LimiterConfig) error {
+	return c.update(ctx, "limiters", "限流器", limiter.Name, limiter)
+}
+
+// DeleteLimiter 删除限流器 (幂等)
+func (c *Client) DeleteLimiter(ctx context.Context, name string) error {
+	return c.delete(ctx, "limiters", "限流器", name)
+}
+
+// CreateCLimiter 创建并发连接限制器 (幂等)
+func (c *Client) CreateCLimiter(ctx context.Context, climiter *CLimiterConfig) error {
+	return c.create(ctx, "climiters", "并发连接限制器", climiter.Name, climiter)
+}
+
+// UpdateCLimiter 更新并发连接限制器配置
+func (c *Client) UpdateCLimiter(ctx context.Context, climiter *CLimiterConfig) error {
+	return c.update(ctx, "climiters", "并发连接限制器", climiter.Name, climiter)
+}
+
+// DeleteCLimiter 删除并发连接限制器 (幂等)
+func (c *Client) DeleteCLimiter(ctx context.Context, name string) error {
+	return c.delete(ctx, "climiters", "并发连接限制器", name)
+}
+
+// CreateRLimiter 创建请求速率限制器 (幂等)
+func (c *Client) CreateRLimiter(ctx context.Context, rlimiter *RLimiterConfig) error {
+	return c.create(ctx, "rlimiters", "请求速率限制器", rlimiter.Name, rlimiter)
+}
+
+// UpdateRLimiter 更新请求速率限制器配置
+func (c *Client) UpdateRLimiter(ctx context.Context, rlimiter *RLimiterConfig) error {
+	return c.update(ctx, "rlimiters", "请求速率限制器", rlimiter.Name, rlimiter)
+}
+
+// DeleteRLimiter 删除请求速率限制器 (幂等)
+func (c *Client) DeleteRLimiter(ctx context.Context, name string) error {
+	return c.delete(ctx, "rlimiters", "请求速率限制器", name)
+}
+
+// CreateObserver 创建观察器 (幂等)
+func (c *Client) CreateObserver(ctx context.Context, observer *ObserverConfig) error {
+	if err := c.create(ctx, "observers", "观察器", observer.Name, observer); err != nil {
+		logger.Warnf("%v", err)
+		return errors.ErrTunnelObserverCreateFailed
+	}
+	return nil
+}
+
+// UpdateObserver 更新观察器配置
+func (c *Client) UpdateObserver(ctx context.Context, observer *ObserverConfig) error {
+	return c.update(ctx, "observers", "观察器", observer.Name, observer)
+}
+
+// DeleteObserver 删除观察器 (幂等)
+func (c *Client) DeleteObserver(ctx context.Context, name string) error {
+	return c.delete(ctx, "observers", "观察器", name)
+}
+
+// CreateLogger 创建日志记录器 (幂等)
+func (c *Client) CreateLogger(ctx context.Context, logger *LoggerConfig) error {
+	return c.create(ctx, "loggers", "日志记录器", logger.Name, logger)
+}
+
+// UpdateLogger 更新日志记录器配置
+func (c *Client) UpdateLogger(ctx context.Context, logger *LoggerConfig) error {
+	return c.update(ctx, "loggers", "日志记录器", logger.Name, logger)
+}
+
+// DeleteLogger 删除日志记录器 (幂等)
+func (c *Client) DeleteLogger(ctx context.Context, name string) error {
+	return c.delete(ctx, "loggers", "日志记录器", name)
+}
@@ -0,0 +1,116 @@
+package gost
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// TLSConfig 节点 API 的 TLS 配置
+type TLSConfig struct {
+	CACert             string // PEM 格式 CA 证书，设置后仅信任该 CA 签发的服务端证书（证书固定）
+	ClientCert         string // PEM 格式客户端证书，与 ClientKey 同时设置时启用双向认证
+	ClientKey          string // PEM 格式客户端私钥
+	ServerName         string // 校验证书使用的服务器名称，为空使用 API 地址中的主机名
+	InsecureSkipVerify bool   // 跳过服务端证书校验（仅用于测试环境）
+}
+
+// Build 构建 tls.Config
+func (c *TLSConfig) Build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.CACert)) {
+			return nil, errors.New("CA 证书解析失败")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if c.ClientCert != "" || c.ClientKey != "" {
+		if c.ClientCert == "" || c.ClientKey == "" {
+			return nil, errors.New("客户端证书和私钥需同时设置")
+		}
+		cert, err := tls.X509KeyPair([]byte(c.ClientCert), []byte(c.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("客户端证书解析失败: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// NewTLSTransport 创建使用 TLS 配置的 HTTP 传输，配置无效时每次请求均返回该错误
+// 传输不做全局缓存（TLS 配置包含客户端私钥），由调用方随客户端复用，不再使用时调用 CloseIdleTransport
+func NewTLSTransport(c TLSConfig) http.RoundTripper {
+	tlsCfg, err := c.Build()
+	if err != nil {
+		return ErrorTransport(fmt.Errorf("TLS 配置无效: %w", err))
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	return transport
+}
+
+// CloseIdleTransport 关闭传输上的空闲连接
+func CloseIdleTransport(t http.RoundTripper) {
+	if c, ok := t.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// ErrorTransport 返回始终以 err 失败的 HTTP 传输，用于客户端配置无效时在请求阶段报告错误
+// 返回的错误为永久错误，不会重试
+func ErrorTransport(err error) http.RoundTripper {
+	return errorTransport{err: Permanent(err)}
+}
+
+// permanentError 重试无法恢复的错误（如配置无效、节点未连接）
+type permanentError struct {
+	err error
+}
+
+// Error 实现 error 接口
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent 将错误标记为永久错误，传输层返回该错误时客户端不再重试
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent 判断错误是否为永久错误
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// errorTransport 始终返回错误的 HTTP 传输
+type errorTransport struct {
+	err error
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	return nil, t.err
+}
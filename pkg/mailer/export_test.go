@@ -0,0 +1,6 @@
+package mailer
+
+// SetDialAddr 将发件器的连接重定向到测试服务器，Host 仍用于 STARTTLS 判断和证书校验
+func SetDialAddr(m *Mailer, addr string) {
+	m.dialAddr = addr
+}
@@ -0,0 +1,288 @@
+// Package mailer 通过 SMTP 发送邮件
+//
+// 465 端口使用隐式 TLS（SMTPS），其余端口必须升级为 STARTTLS，两种方式都校验服务器证书。
+// 只有发往本机的服务器允许在不支持 STARTTLS 时使用未加密的连接。
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTimeout 连接及整个发送过程的默认超时
+const defaultTimeout = 30 * time.Second
+
+// Stage 发送失败所处的阶段
+type Stage string
+
+const (
+	StageConfig    Stage = "config"    // 配置不完整或地址无效
+	StageConnect   Stage = "connect"   // 建立连接
+	StageTLS       Stage = "tls"       // TLS 握手或证书校验
+	StageAuth      Stage = "auth"      // 认证
+	StageSender    Stage = "sender"    // MAIL FROM
+	StageRecipient Stage = "recipient" // RCPT TO
+	StageData      Stage = "data"      // 写入邮件内容
+)
+
+// Error 发送错误，记录失败阶段
+type Error struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("smtp %s: %v", e.Stage, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Config SMTP 服务器配置
+type Config struct {
+	Host     string
+	Port     int
+	Username string // 为空时不认证
+	Password string
+	From     string // 发件人地址，可带显示名称，如 "Gost Panel <noreply@example.com>"
+	Timeout  time.Duration
+
+	// TLSConfig 自定义 TLS 配置（如测试时指定根证书），为空时使用系统根证书校验 Host
+	TLSConfig *tls.Config
+}
+
+// Message 邮件内容
+type Message struct {
+	To      []string
+	Subject string
+	Text    string // 纯文本正文
+	HTML    string // HTML 正文，与纯文本同时存在时作为 multipart/alternative 发送
+}
+
+// Mailer SMTP 发件器
+type Mailer struct {
+	cfg  Config
+	from *mail.Address
+
+	dialAddr string // 测试时覆盖实际连接的地址
+}
+
+// New 创建发件器
+func New(cfg Config) (*Mailer, error) {
+	if cfg.Host == "" || cfg.Port == 0 || cfg.From == "" {
+		return nil, &Error{StageConfig, fmt.Errorf("SMTP 服务器、端口和发件人不能为空")}
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, &Error{StageConfig, fmt.Errorf("发件人地址无效: %w", err)}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Mailer{cfg: cfg, from: from}, nil
+}
+
+// Send 发送邮件
+func (m *Mailer) Send(msg *Message) error {
+	if len(msg.To) == 0 {
+		return &Error{StageRecipient, fmt.Errorf("收件人不能为空")}
+	}
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			return &Error{StageRecipient, fmt.Errorf("收件人地址 %q 无效: %w", addr, err)}
+		}
+		to = append(to, parsed.Address)
+	}
+	data, err := m.build(msg, to)
+	if err != nil {
+		return &Error{StageData, err}
+	}
+
+	client, err := m.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if m.cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return &Error{StageAuth, fmt.Errorf("服务器不支持认证")}
+		}
+		// smtp.PlainAuth 拒绝在未加密的连接上向非本机服务器发送密码
+		if err = client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return &Error{StageAuth, err}
+		}
+	}
+	if err = client.Mail(m.from.Address); err != nil {
+		return &Error{StageSender, err}
+	}
+	for _, addr := range to {
+		if err = client.Rcpt(addr); err != nil {
+			return &Error{StageRecipient, fmt.Errorf("%s: %w", addr, err)}
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return &Error{StageData, err}
+	}
+	if _, err = w.Write(data); err != nil {
+		return &Error{StageData, err}
+	}
+	if err = w.Close(); err != nil {
+		return &Error{StageData, err}
+	}
+	// 服务器已接收邮件，QUIT 失败不影响投递，不能当作发送失败而重发
+	_ = client.Quit()
+	return nil
+}
+
+// dial 连接服务器：465 端口使用隐式 TLS，其余端口升级为 STARTTLS
+func (m *Mailer) dial() (*smtp.Client, error) {
+	addr := m.dialAddr
+	if addr == "" {
+		addr = net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	}
+	dialer := &net.Dialer{Timeout: m.cfg.Timeout}
+	tlsCfg := m.tlsConfig()
+
+	var conn net.Conn
+	var err error
+	if m.cfg.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsCfg)
+		if err != nil {
+			if _, ok := err.(net.Error); ok {
+				return nil, &Error{StageConnect, err}
+			}
+			return nil, &Error{StageTLS, err}
+		}
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+		if err != nil {
+			return nil, &Error{StageConnect, err}
+		}
+	}
+	_ = conn.SetDeadline(time.Now().Add(m.cfg.Timeout))
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, &Error{StageConnect, err}
+	}
+	if m.cfg.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(tlsCfg); err != nil {
+				_ = client.Close()
+				return nil, &Error{StageTLS, err}
+			}
+		} else if !isLoopback(m.cfg.Host) {
+			// 中间人可以去掉 STARTTLS 声明，不支持时不降级为明文发送
+			_ = client.Close()
+			return nil, &Error{StageTLS, fmt.Errorf("服务器不支持 STARTTLS")}
+		}
+	}
+	return client, nil
+}
+
+// isLoopback 判断服务器是否为本机
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// tlsConfig 校验服务器证书的 TLS 配置
+func (m *Mailer) tlsConfig() *tls.Config {
+	if m.cfg.TLSConfig != nil {
+		cfg := m.cfg.TLSConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = m.cfg.Host
+		}
+		return cfg
+	}
+	return &tls.Config{ServerName: m.cfg.Host, MinVersion: tls.VersionTLS12}
+}
+
+// build 生成 MIME 邮件内容
+func (m *Mailer) build(msg *Message, to []string) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+
+	header("From", m.from.String())
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", m.messageID())
+	header("MIME-Version", "1.0")
+
+	switch {
+	case msg.HTML != "" && msg.Text != "":
+		boundary, err := randomHex(12)
+		if err != nil {
+			return nil, err
+		}
+		header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+		buf.WriteString("\r\n")
+		for _, part := range []struct{ typ, body string }{{"text/plain", msg.Text}, {"text/html", msg.HTML}} {
+			buf.WriteString("--" + boundary + "\r\n")
+			writePart(&buf, part.typ, part.body)
+		}
+		buf.WriteString("--" + boundary + "--\r\n")
+	case msg.HTML != "":
+		writePart(&buf, "text/html", msg.HTML)
+	default:
+		writePart(&buf, "text/plain", msg.Text)
+	}
+	return buf.Bytes(), nil
+}
+
+// writePart 写入 base64 编码的正文（含内容头）
+func writePart(buf *bytes.Buffer, contentType, body string) {
+	buf.WriteString("Content-Type: " + contentType + "; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
+
+// messageID 生成邮件 ID
+func (m *Mailer) messageID() string {
+	id, err := randomHex(16)
+	if err != nil {
+		id = strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	domain := m.cfg.Host
+	if at := strings.LastIndex(m.from.Address, "@"); at >= 0 {
+		domain = m.from.Address[at+1:]
+	}
+	return "<" + id + "@" + domain + ">"
+}
+
+// randomHex 生成 n 字节的随机十六进制串
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,246 @@
+package mailer_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gost-panel/pkg/mailer"
+)
+
+// smtpServer 模拟 SMTP 服务器，记录收到的邮件
+type smtpServer struct {
+	ln       net.Listener
+	tlsCfg   *tls.Config // 不为空时支持 STARTTLS
+	mu       sync.Mutex
+	messages []string
+	authed   bool
+	quitErr  bool // QUIT 返回错误
+}
+
+// newSMTPServer 启动模拟服务器
+func newSMTPServer(t *testing.T, tlsCfg *tls.Config) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{ln: ln, tlsCfg: tlsCfg}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// received 获取收到的邮件及是否认证过
+func (s *smtpServer) received() ([]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...), s.authed
+}
+
+func (s *smtpServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 mock ESMTP")
+	secure := false
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			lines := []string{"250-mock"}
+			if s.tlsCfg != nil && !secure {
+				lines = append(lines, "250-STARTTLS")
+			}
+			lines = append(lines, "250 AUTH PLAIN")
+			for _, l := range lines {
+				_ = tp.PrintfLine("%s", l)
+			}
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.tlsCfg)
+			if err = tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			secure = true
+		case "AUTH":
+			s.mu.Lock()
+			s.authed = true
+			s.mu.Unlock()
+			_ = tp.PrintfLine("235 ok")
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 queued")
+		case "QUIT":
+			if s.quitErr {
+				_ = tp.PrintfLine("421 closing")
+				return
+			}
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 unknown")
+		}
+	}
+}
+
+// selfSignedCert 生成 127.0.0.1 的自签名证书
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mock smtp"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestSendHTML(t *testing.T) {
+	srv := newSMTPServer(t, nil)
+	m, err := mailer.New(mailer.Config{Host: "127.0.0.1", Port: srv.port(), From: "Gost Panel <panel@example.com>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Send(&mailer.Message{To: []string{"ops@example.com"}, Subject: "流量日报", HTML: "<h1>日报</h1>"}); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+
+	messages, _ := srv.received()
+	if len(messages) != 1 {
+		t.Fatalf("收到 %d 封邮件, 期望 1", len(messages))
+	}
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(messages[0])))
+	if err != nil {
+		t.Fatalf("解析邮件失败: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "流量日报" || !strings.HasPrefix(msg.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("主题 = %q, 类型 = %q", subject, msg.Header.Get("Content-Type"))
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if got, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(body), "\r\n", "")); string(got) != "<h1>日报</h1>" {
+		t.Errorf("正文 = %q", got)
+	}
+}
+
+func TestStartTLSVerifiesCertificate(t *testing.T) {
+	cert, pool := selfSignedCert(t)
+	srv := newSMTPServer(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	msg := &mailer.Message{To: []string{"ops@example.com"}, Subject: "test", Text: "hello"}
+
+	// 系统根证书不信任自签名证书
+	m, _ := mailer.New(mailer.Config{Host: "127.0.0.1", Port: srv.port(), From: "panel@example.com",
+		Username: "user", Password: "pass"})
+	err := m.Send(msg)
+	var mailErr *mailer.Error
+	if !errors.As(err, &mailErr) || mailErr.Stage != mailer.StageTLS {
+		t.Fatalf("未受信任证书发送错误 = %v, 期望 TLS 阶段错误", err)
+	}
+	if messages, authed := srv.received(); len(messages) != 0 || authed {
+		t.Fatal("证书校验失败后仍发送了凭据或邮件")
+	}
+
+	// 信任该证书后经 STARTTLS 认证并发送
+	m, _ = mailer.New(mailer.Config{Host: "127.0.0.1", Port: srv.port(), From: "panel@example.com",
+		Username: "user", Password: "pass", TLSConfig: &tls.Config{RootCAs: pool}})
+	if err = m.Send(msg); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	if messages, authed := srv.received(); len(messages) != 1 || !authed {
+		t.Fatalf("收到 %d 封邮件, 认证 = %v", len(messages), authed)
+	}
+}
+
+func TestRequireStartTLS(t *testing.T) {
+	srv := newSMTPServer(t, nil)
+	msg := &mailer.Message{To: []string{"ops@example.com"}, Subject: "test", Text: "hello"}
+
+	// 非本机服务器未声明 STARTTLS（可能被中间人去掉）时不以明文发送
+	m, _ := mailer.New(mailer.Config{Host: "smtp.example.com", Port: 587, From: "panel@example.com",
+		Username: "user", Password: "pass"})
+	mailer.SetDialAddr(m, srv.ln.Addr().String())
+	err := m.Send(msg)
+	var mailErr *mailer.Error
+	if !errors.As(err, &mailErr) || mailErr.Stage != mailer.StageTLS {
+		t.Fatalf("服务器不支持 STARTTLS 时发送错误 = %v, 期望 TLS 阶段错误", err)
+	}
+	if messages, authed := srv.received(); len(messages) != 0 || authed {
+		t.Fatal("未加密的连接上发送了凭据或邮件")
+	}
+}
+
+func TestQuitErrorAfterDelivery(t *testing.T) {
+	srv := newSMTPServer(t, nil)
+	srv.quitErr = true
+	m, _ := mailer.New(mailer.Config{Host: "127.0.0.1", Port: srv.port(), From: "panel@example.com"})
+
+	// 服务器已接收邮件后 QUIT 失败不算发送失败，避免重发
+	if err := m.Send(&mailer.Message{To: []string{"ops@example.com"}, Subject: "test", Text: "hello"}); err != nil {
+		t.Fatalf("QUIT 失败时发送错误 = %v, 期望成功", err)
+	}
+	if messages, _ := srv.received(); len(messages) != 1 {
+		t.Fatalf("收到 %d 封邮件, 期望 1", len(messages))
+	}
+}
@@ -19,8 +19,15 @@ PLAIN='\033[0m'
 BASE_PATH="/etc/gost-node"
 BIN_PATH="/usr/local/bin/gost-node"
 CONF_FILE="${BASE_PATH}/config.yaml"
-GOST_VERSION="3.2.6"
+CERT_PATH="${BASE_PATH}/certs"
+AGENT_BIN_PATH="/usr/local/bin/gost-node-agent"
+AGENT_REPO="code-gopher/gostPanel"
+GOST_VERSION=${GOST_VERSION:-"3.2.6"}
 GH_PROXY=${GH_PROXY:-""}
+# API TLS: 留空为 http；tls 为 https；mtls 为 https + 客户端证书双向认证
+NODE_TLS=${NODE_TLS:-""}
+# 证书额外包含的域名（通过域名访问节点时设置）
+NODE_TLS_HOST=${NODE_TLS_HOST:-""}
 
 # 日志输出
 info() { echo -e "${GREEN}[信息]${PLAIN} $1"; }
@@ -46,6 +53,11 @@ get_arch() {
     esac
 }
 
+# 获取公网 IP
+get_public_ip() {
+    curl -s -4 https://api.ipify.org 2>/dev/null || curl -s -4 https://ifconfig.me 2>/dev/null || echo ""
+}
+
 # 检查端口占用
 check_port() {
     local port=$1
@@ -73,11 +85,44 @@ uninstall() {
         rc-update del gost-node default || true
         rm -f /etc/init.d/gost-node
     fi
+    uninstall_agent
     rm -rf "$BASE_PATH"
     rm -f "$BIN_PATH"
     info "Gost 节点已成功移除。"
 }
 
+# 升级 Gost：替换二进制文件并重启服务，保留现有配置
+upgrade() {
+    if [[ ! -f "$CONF_FILE" ]]; then
+        error "未检测到已安装的 Gost 节点，请先安装"
+    fi
+    info "正在升级 Gost 到 v${GOST_VERSION}..."
+    install_bin
+    if command -v systemctl >/dev/null 2>&1 && [[ -f /etc/systemd/system/gost-node.service ]]; then
+        systemctl restart gost-node
+    elif [[ -f /etc/init.d/gost-node ]]; then
+        rc-service gost-node restart
+    else
+        warn "未识别的服务管理器，请手动重启: $BIN_PATH -C $CONF_FILE"
+    fi
+    info "Gost 已升级到 v${GOST_VERSION}"
+}
+
+# 卸载 Agent
+uninstall_agent() {
+    if command -v systemctl >/dev/null 2>&1 && [[ -f /etc/systemd/system/gost-node-agent.service ]]; then
+        systemctl stop gost-node-agent || true
+        systemctl disable gost-node-agent || true
+        rm -f /etc/systemd/system/gost-node-agent.service
+        systemctl daemon-reload
+    elif [[ -f /etc/init.d/gost-node-agent ]]; then
+        rc-service gost-node-agent stop || true
+        rc-update del gost-node-agent default || true
+        rm -f /etc/init.d/gost-node-agent
+    fi
+    rm -f "$AGENT_BIN_PATH"
+}
+
 # 安装二进制文件
 install_bin() {
     local arch=$(get_arch)
@@ -106,11 +151,51 @@ install_bin() {
     info "二进制文件已安装到 $BIN_PATH"
 }
 
+# 生成 API TLS 证书：自签 CA + 服务端证书，mtls 模式额外生成客户端证书
+gen_certs() {
+    local ip="$1"
+
+    if ! command -v openssl >/dev/null 2>&1; then
+        error "生成证书需要 openssl，请先安装"
+    fi
+
+    info "正在生成 API TLS 证书..."
+    mkdir -p "$CERT_PATH"
+    cd "$CERT_PATH"
+
+    local san="IP:127.0.0.1,DNS:localhost"
+    [[ -n "$ip" ]] && san="${san},IP:${ip}"
+    [[ -n "$NODE_TLS_HOST" ]] && san="${san},DNS:${NODE_TLS_HOST}"
+
+    openssl req -x509 -newkey rsa:2048 -nodes -sha256 -days 3650 \
+        -subj "/CN=Gost Node CA" -keyout ca.key -out ca.crt >/dev/null 2>&1
+
+    openssl req -newkey rsa:2048 -nodes -sha256 \
+        -subj "/CN=gost-node" -keyout server.key -out server.csr >/dev/null 2>&1
+    printf "subjectAltName=%s\nextendedKeyUsage=serverAuth\n" "$san" > server.ext
+    openssl x509 -req -in server.csr -CA ca.crt -CAkey ca.key -CAcreateserial \
+        -days 3650 -sha256 -extfile server.ext -out server.crt >/dev/null 2>&1
+
+    if [[ "$NODE_TLS" == "mtls" ]]; then
+        openssl req -newkey rsa:2048 -nodes -sha256 \
+            -subj "/CN=gost-panel" -keyout client.key -out client.csr >/dev/null 2>&1
+        printf "extendedKeyUsage=clientAuth\n" > client.ext
+        openssl x509 -req -in client.csr -CA ca.crt -CAkey ca.key -CAcreateserial \
+            -days 3650 -sha256 -extfile client.ext -out client.crt >/dev/null 2>&1
+    fi
+
+    rm -f ./*.csr ./*.ext ./*.srl
+    chmod 600 ./*.key
+    cd - >/dev/null
+    info "证书已保存到 $CERT_PATH"
+}
+
 # 生成配置文件
 configure() {
     local api_port="${1:-39000}"
     local user="${2:-}"
     local pass="${3:-}"
+    local mode="${4:-direct}"
     
     info "正在生成配置文件..."
 
@@ -126,19 +211,55 @@ configure() {
         pass=$(head -c 32 /dev/urandom | base64 | tr -dc 'a-zA-Z0-9' | head -c 12)
     fi
     
+    # Agent 反向连接及 SSH 隧道模式下面板经 Agent 或 SSH 访问 API，仅监听本机
+    # TLS 模式下 API 同样仅监听本机，由 node-api-tls 服务在对外端口终止 TLS 后转发
+    local api_addr=":$api_port"
+    local internal_port=$((api_port + 1))
+    if local_api_mode "$mode"; then
+        api_addr="127.0.0.1:$api_port"
+    elif [[ -n "$NODE_TLS" ]]; then
+        check_port "$internal_port"
+        api_addr="127.0.0.1:$internal_port"
+    fi
+
     mkdir -p "$BASE_PATH"
     cat > "$CONF_FILE" <<EOF
 api:
-  addr: ":$api_port"
+  addr: "$api_addr"
   pathPrefix: /api
   auth:
     username: $user
     password: $pass
 EOF
+
+    if ! local_api_mode "$mode" && [[ -n "$NODE_TLS" ]]; then
+        local ca_line=""
+        if [[ "$NODE_TLS" == "mtls" ]]; then
+            ca_line="      caFile: ${CERT_PATH}/ca.crt"
+        fi
+        cat >> "$CONF_FILE" <<EOF
+services:
+- name: node-api-tls
+  addr: ":$api_port"
+  handler:
+    type: tcp
+  listener:
+    type: tls
+    tls:
+      certFile: ${CERT_PATH}/server.crt
+      keyFile: ${CERT_PATH}/server.key
+${ca_line}
+  forwarder:
+    nodes:
+    - name: api
+      addr: 127.0.0.1:$internal_port
+EOF
+    fi
     info "配置文件已保存到 $CONF_FILE"
     
-    # 导出变量用于最后显示
+    # 导出变量用于最后显示及 Agent 访问本机 API
     GLOBAL_API_PORT=$api_port
+    GLOBAL_LOCAL_API="http://127.0.0.1:${api_addr##*:}/api"
     GLOBAL_USER=$user
     GLOBAL_PASS=$pass
 }
@@ -185,15 +306,114 @@ EOF
     fi
 }
 
+# 安装 Agent（上报主机指标到面板）
+install_agent() {
+    local panel_url="$1"
+    local token="$2"
+    local mode="${3:-direct}"
+    local arch=$(get_arch)
+
+    if [[ "$arch" != "amd64" && "$arch" != "arm64" ]]; then
+        warn "Agent 暂不支持该架构: $arch，跳过安装"
+        return
+    fi
+
+    local url="${GH_PROXY}https://github.com/${AGENT_REPO}/releases/latest/download/gost-panel-agent-linux-${arch}.tar.gz"
+    info "正在下载节点 Agent (${arch})..."
+    info "下载地址: $url"
+
+    local tmp_dir=$(mktemp -d)
+    if ! wget -q --show-progress -O- "$url" | tar -zx -C "$tmp_dir"; then
+        rm -rf "$tmp_dir"
+        warn "Agent 下载失败，跳过安装（不影响 Gost 节点使用）"
+        return
+    fi
+
+    mv "${tmp_dir}/gost-panel-agent-linux-${arch}" "$AGENT_BIN_PATH"
+    chmod +x "$AGENT_BIN_PATH"
+    rm -rf "$tmp_dir"
+
+    local args="-panel $panel_url -token $token -gost $BIN_PATH -gost-api ${GLOBAL_LOCAL_API}"
+    if [[ "$mode" == "agent" ]]; then
+        args="$args -tunnel"
+    fi
+    if command -v systemctl >/dev/null 2>&1; then
+        info "正在配置 Agent systemd 服务..."
+        cat > /etc/systemd/system/gost-node-agent.service <<EOF
+[Unit]
+Description=Gost Panel Node Agent
+After=network.target gost-node.service
+
+[Service]
+Type=simple
+ExecStart=$AGENT_BIN_PATH $args
+Restart=always
+RestartSec=5
+User=root
+
+[Install]
+WantedBy=multi-user.target
+EOF
+        systemctl daemon-reload
+        systemctl enable gost-node-agent
+        systemctl restart gost-node-agent
+    elif [[ -f /sbin/openrc-run ]]; then
+        info "正在配置 Agent OpenRC 服务..."
+        cat > /etc/init.d/gost-node-agent <<EOF
+#!/sbin/openrc-run
+description="Gost Panel Node Agent"
+command="$AGENT_BIN_PATH"
+command_args="$args"
+command_background=true
+pidfile="/run/gost-node-agent.pid"
+depend() {
+    need net
+}
+EOF
+        chmod +x /etc/init.d/gost-node-agent
+        rc-update add gost-node-agent default
+        rc-service gost-node-agent start
+    else
+        warn "未识别的服务管理器。请手动启动: $AGENT_BIN_PATH $args"
+    fi
+    info "节点 Agent 已安装"
+}
+
+# 判断连接方式下 API 是否仅监听本机（Agent 反向连接、SSH 隧道）
+local_api_mode() {
+    [[ "$1" == "agent" || "$1" == "ssh" ]]
+}
+
 # 显示安装成功信息
 show_info() {
-    local ip=$(curl -s -4 https://api.ipify.org 2>/dev/null || curl -s -4 https://ifconfig.me 2>/dev/null || echo "您的公网IP")
+    local ip="${1:-您的公网IP}"
+    local mode="${2:-direct}"
+    local scheme="http"
+    if ! local_api_mode "$mode" && [[ -n "$NODE_TLS" ]]; then
+        scheme="https"
+    fi
     echo -e "\n${GREEN}================================================${PLAIN}"
     echo -e "${GREEN}       Gost 节点安装成功！${PLAIN}"
     echo -e "------------------------------------------------"
-    echo -e "  API 地址   : ${BLUE}http://${ip}:${GLOBAL_API_PORT}/api${PLAIN}"
+    echo -e "  API 地址   : ${BLUE}${scheme}://${ip}:${GLOBAL_API_PORT}/api${PLAIN}"
     echo -e "  用户名     : ${BLUE}${GLOBAL_USER}${PLAIN}"
     echo -e "  密码       : ${BLUE}${GLOBAL_PASS}${PLAIN}"
+    if [[ "$scheme" == "https" ]]; then
+        echo -e "------------------------------------------------"
+        echo -e "  请在面板节点的 TLS 设置中开启 HTTPS，并填入以下内容："
+        echo -e "  CA 证书    : ${BLUE}${CERT_PATH}/ca.crt${PLAIN}"
+        cat "${CERT_PATH}/ca.crt"
+        if [[ "$NODE_TLS" == "mtls" ]]; then
+            echo -e "  客户端证书 : ${BLUE}${CERT_PATH}/client.crt${PLAIN}"
+            cat "${CERT_PATH}/client.crt"
+            echo -e "  客户端私钥 : ${BLUE}${CERT_PATH}/client.key${PLAIN}"
+            cat "${CERT_PATH}/client.key"
+        fi
+    fi
+    if [[ "$mode" == "ssh" ]]; then
+        echo -e "------------------------------------------------"
+        echo -e "  API 仅监听本机，请在面板节点中选择 SSH 隧道连接方式并填写 SSH 登录信息"
+    fi
     echo -e "------------------------------------------------"
     echo -e "${YELLOW}  请使用以上信息在面板中添加节点。${PLAIN}"
     echo -e "${GREEN}================================================${PLAIN}\n"
@@ -207,27 +427,56 @@ main() {
     
     check_root
     
-    # 检查第一个参数是否为 uninstall
+    # 检查第一个参数是否为 uninstall 或 upgrade
     if [[ "${1:-}" == "uninstall" ]]; then
         uninstall
         exit 0
     fi
+    if [[ "${1:-}" == "upgrade" ]]; then
+        upgrade
+        exit 0
+    fi
     
     # 解析安装参数
     local api_port="${1:-39000}"
     local user="${2:-}"
     local pass="${3:-}"
-    
+    local panel_url="${4:-}"
+    local agent_token="${5:-}"
+    local connection_mode="${6:-direct}"
+
     info "开始安装 Gost 节点..."
-    info "配置参数: API端口=$api_port, 用户=$user"
+    info "配置参数: API端口=$api_port, 用户=$user, 连接方式=$connection_mode, TLS=${NODE_TLS:-关闭}"
+
+    if [[ -n "$NODE_TLS" && "$NODE_TLS" != "tls" && "$NODE_TLS" != "mtls" ]]; then
+        error "NODE_TLS 仅支持 tls 或 mtls"
+    fi
+    if [[ "$connection_mode" != "direct" && "$connection_mode" != "agent" && "$connection_mode" != "ssh" ]]; then
+        error "连接方式仅支持 direct、agent 或 ssh"
+    fi
+    if local_api_mode "$connection_mode" && [[ -n "$NODE_TLS" ]]; then
+        warn "Agent 反向连接及 SSH 隧道模式下 API 仅监听本机，忽略 TLS 设置"
+    fi
+
+    if [[ "$connection_mode" == "agent" && ( -z "$panel_url" || -z "$agent_token" ) ]]; then
+        error "Agent 反向连接模式需要指定面板地址和 Agent 令牌"
+    fi
     
     # 检查端口占用
     check_port "$api_port"
     
+    local public_ip=$(get_public_ip)
+
     install_bin
-    configure "$api_port" "$user" "$pass"
+    if ! local_api_mode "$connection_mode" && [[ -n "$NODE_TLS" ]]; then
+        gen_certs "$public_ip"
+    fi
+    configure "$api_port" "$user" "$pass" "$connection_mode"
     setup_service
-    show_info
+    if [[ -n "$panel_url" && -n "$agent_token" ]]; then
+        install_agent "$panel_url" "$agent_token" "$connection_mode"
+    fi
+    show_info "$public_ip" "$connection_mode"
 }
 
 main "$@"
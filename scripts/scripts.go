@@ -0,0 +1,9 @@
+// Package scripts 内嵌节点安装脚本，供面板经 SSH 部署、升级及卸载节点时上传执行
+package scripts
+
+import _ "embed"
+
+// InstallNode 节点安装脚本 install_node.sh
+//
+//go:embed install_node.sh
+var InstallNode []byte
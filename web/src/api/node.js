@@ -62,3 +62,212 @@ export function getNodeConfig(id) {
         method: 'get'
     })
 }
+
+/**
+ * 检测节点配置漂移
+ */
+export function getNodeReconcile(id) {
+    return request({
+        url: `/nodes/${id}/reconcile`,
+        method: 'get'
+    })
+}
+
+/**
+ * 修复节点配置漂移
+ */
+export function repairNode(id, data) {
+    return request({
+        url: `/nodes/${id}/reconcile/repair`,
+        method: 'post',
+        data
+    })
+}
+
+/**
+ * 清理节点孤立配置
+ */
+export function pruneNode(id, data) {
+    return request({
+        url: `/nodes/${id}/reconcile/prune`,
+        method: 'post',
+        data
+    })
+}
+
+/**
+ * 获取节点上可导入的服务和链
+ */
+export function getNodeImportCandidates(id) {
+    return request({
+        url: `/nodes/${id}/import`,
+        method: 'get'
+    })
+}
+
+/**
+ * 导入节点上的现有服务和链
+ */
+export function importNodeConfig(id, data) {
+    return request({
+        url: `/nodes/${id}/import`,
+        method: 'post',
+        data
+    })
+}
+
+/**
+ * 节点进入维护模式（可选迁移到替换节点）
+ */
+export function enterNodeMaintenance(id, data) {
+    return request({
+        url: `/nodes/${id}/maintenance`,
+        method: 'post',
+        data
+    })
+}
+
+/**
+ * 节点退出维护模式
+ */
+export function exitNodeMaintenance(id) {
+    return request({
+        url: `/nodes/${id}/maintenance`,
+        method: 'delete'
+    })
+}
+
+/**
+ * 克隆节点规则（及隧道）到目标节点
+ */
+export function cloneNode(id, data) {
+    return request({
+        url: `/nodes/${id}/clone`,
+        method: 'post',
+        data
+    })
+}
+
+/**
+ * 获取节点安装命令所需的 Agent 令牌
+ */
+export function getInstallToken(id) {
+    return request({
+        url: `/nodes/${id}/install-command`,
+        method: 'get'
+    })
+}
+
+/**
+ * 重置节点 Agent 令牌
+ */
+export function resetAgentToken(id) {
+    return request({
+        url: `/nodes/${id}/agent-token`,
+        method: 'post'
+    })
+}
+
+/**
+ * 执行节点部署任务（Server-Sent Events 流式输出）
+ * onOutput 接收每行脚本输出；任务成功时 resolve 任务结果，失败时 reject 错误
+ */
+function streamNodeTask(url, data, onOutput) {
+    return fetch(`/api/v1${url}`, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+            Authorization: `Bearer ${localStorage.getItem('token') || ''}`
+        },
+        body: JSON.stringify(data || {})
+    }).then(async (res) => {
+        // 参数校验失败等情况返回普通 JSON 响应
+        if (!(res.headers.get('Content-Type') || '').includes('text/event-stream')) {
+            const body = await res.json().catch(() => ({}))
+            throw new Error(body.message || `请求失败 (${res.status})`)
+        }
+
+        const reader = res.body.getReader()
+        const decoder = new TextDecoder()
+        let buffer = ''
+        let result = null
+        let finished = false
+        let error = null
+
+        const handleEvent = (block) => {
+            let event = 'message'
+            const lines = []
+            for (const line of block.split('\n')) {
+                if (line.startsWith('event:')) event = line.slice(6).trim()
+                else if (line.startsWith('data:')) lines.push(line.slice(5))
+            }
+            const payload = lines.join('\n')
+            if (event === 'output') onOutput(payload)
+            else if (event === 'done') {
+                finished = true
+                result = payload ? JSON.parse(payload) : null
+            }
+            else if (event === 'error') error = payload
+        }
+
+        for (;;) {
+            const { done, value } = await reader.read()
+            if (done) break
+            buffer += decoder.decode(value, { stream: true })
+            let idx
+            while ((idx = buffer.indexOf('\n\n')) >= 0) {
+                handleEvent(buffer.slice(0, idx))
+                buffer = buffer.slice(idx + 2)
+            }
+        }
+
+        if (error !== null) throw new Error(error)
+        if (!finished) throw new Error('连接已中断，请稍后刷新查看节点状态')
+        return result
+    })
+}
+
+/**
+ * 经 SSH 部署新节点
+ */
+export function provisionNode(data, onOutput) {
+    return streamNodeTask('/nodes/provision', data, onOutput)
+}
+
+/**
+ * 经 SSH 升级节点 Gost
+ */
+export function upgradeNode(id, data, onOutput) {
+    return streamNodeTask(`/nodes/${id}/upgrade`, data, onOutput)
+}
+
+/**
+ * 经 SSH 卸载节点
+ */
+export function uninstallNode(id, data, onOutput) {
+    return streamNodeTask(`/nodes/${id}/uninstall`, data, onOutput)
+}
+
+/**
+ * 获取节点流量历史
+ * @param {Object} params - { from, to, step }，时间为 RFC3339 或 Unix 秒，步长如 5m、1h、1d
+ */
+export function getNodeTraffic(id, params) {
+    return request({
+        url: `/nodes/${id}/traffic`,
+        method: 'get',
+        params
+    })
+}
+
+/**
+ * 获取节点 Gost 指标历史
+ * @param {Object} params - { metric, from, to, step }，metric 如 gost_service_handler_errors、gost_chain_errors
+ */
+export function getNodeGostMetrics(id, params) {
+    return request({
+        url: `/nodes/${id}/gost-metrics`,
+        method: 'get',
+        params
+    })
+}
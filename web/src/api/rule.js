@@ -71,4 +71,28 @@ export function stopRule(id) {
         url: `/rules/${id}/stop`,
         method: 'post'
     })
-}
\ No newline at end of file
+}
+
+/**
+ * 获取规则流量历史
+ * @param {Object} params - { from, to, step }，时间为 RFC3339 或 Unix 秒，步长如 5m、1h、1d
+ */
+export function getRuleTraffic(id, params) {
+    return request({
+        url: `/rules/${id}/traffic`,
+        method: 'get',
+        params
+    })
+}
+
+/**
+ * 获取规则流量最多的客户端
+ * @param {Object} params - { limit }，默认 10
+ */
+export function getRuleClients(id, params) {
+    return request({
+        url: `/rules/${id}/clients`,
+        method: 'get',
+        params
+    })
+}
@@ -9,3 +9,13 @@ export function getDashboardStats() {
         method: 'get'
     })
 }
+
+/**
+ * 获取后台任务调度器指标
+ */
+export function getSchedulerStats() {
+    return request({
+        url: '/dashboard/scheduler',
+        method: 'get'
+    })
+}
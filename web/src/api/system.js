@@ -39,6 +39,14 @@ export function sendTestEmail(data) {
     })
 }
 
+export function sendReport(data) {
+    return request({
+        url: '/system/report/send',
+        method: 'post',
+        data
+    })
+}
+
 export function backupSystem() {
     return request({
         url: '/system/backup',
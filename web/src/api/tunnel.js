@@ -72,3 +72,15 @@ export function stopTunnel(id) {
         method: 'post'
     })
 }
+
+/**
+ * 获取隧道流量历史
+ * @param {Object} params - { from, to, step }，时间为 RFC3339 或 Unix 秒，步长如 5m、1h、1d
+ */
+export function getTunnelTraffic(id, params) {
+    return request({
+        url: `/tunnels/${id}/traffic`,
+        method: 'get',
+        params
+    })
+}